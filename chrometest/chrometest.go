@@ -0,0 +1,293 @@
+// Package chrometest runs browser integration tests against a headless
+// Chrome it launches, with an optional fixture server for the pages under
+// test. Each test gets a Client bound to a fresh tab:
+//
+//	func TestLogin(t *testing.T) {
+//		c := chrometest.New(t, chrometest.Options{Dir: "testdata/site"})
+//		if err := c.Navigate("/login.html"); err != nil {
+//			t.Fatal(err)
+//		}
+//		if err := c.Fill("#user", "alice"); err != nil {
+//			t.Fatal(err)
+//		}
+//		if err := c.Click("button[type=submit]"); err != nil {
+//			t.Fatal(err)
+//		}
+//		if err := c.WaitVisible(".welcome"); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// New launches a browser per test. To share one across a package, Start an
+// Env in TestMain and call Env.NewClient in each test. Tests are skipped
+// when no Chrome is installed (see ErrNoChrome).
+package chrometest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+// ErrNoChrome is returned by Start when no Chrome or Chromium is found (see
+// lib.FindChrome and $CHROME_PATH). New skips the test instead.
+var ErrNoChrome = errors.New("chrometest: no Chrome found, set CHROME_PATH")
+
+// Options configure Start and New.
+type Options struct {
+	// Dir is a directory of pages served by a fixture server, whose URL
+	// relative Navigate paths resolve against. Empty for no server.
+	Dir string
+	// SPA serves Dir/index.html for paths without a file.
+	SPA bool
+	// ExecPath is the browser to launch (default: lib.FindChrome).
+	ExecPath string
+	// Headful shows the browser window, for debugging a test.
+	Headful bool
+	// Timeout bounds each Client call (default: lib.DefaultTimeout).
+	Timeout time.Duration
+	// Flags are extra Chrome command-line flags, without the leading --.
+	Flags map[string]any
+}
+
+// Env is a headless browser, and the fixture server when Options.Dir is
+// set. Close it when done.
+type Env struct {
+	// URL is the fixture server's base URL, or "" without one.
+	URL     string
+	opts    Options
+	fixture *lib.FixtureServer
+	browser context.Context
+	cancel  context.CancelFunc
+}
+
+// Start launches the browser with a fresh profile, and the fixture server.
+func Start(opts Options) (*Env, error) {
+	if opts.ExecPath == "" {
+		opts.ExecPath = lib.FindChrome()
+		if opts.ExecPath == "" {
+			return nil, ErrNoChrome
+		}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = lib.DefaultTimeout
+	}
+	env := &Env{opts: opts}
+	if opts.Dir != "" {
+		server, err := lib.StartFixtureServer(opts.Dir, lib.FixtureOptions{SPA: opts.SPA})
+		if err != nil {
+			return nil, err
+		}
+		env.fixture = server
+		env.URL = server.URL
+	}
+
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(opts.ExecPath),
+		chromedp.DisableGPU,
+		chromedp.NoFirstRun,
+		chromedp.NoDefaultBrowserCheck,
+	)
+	if opts.Headful {
+		allocOpts = append(allocOpts, chromedp.Flag("headless", false))
+	}
+	// Chrome refuses to start as root with its sandbox, as in most CI containers
+	if os.Geteuid() == 0 {
+		allocOpts = append(allocOpts, chromedp.NoSandbox)
+	}
+	for name, value := range opts.Flags {
+		allocOpts = append(allocOpts, chromedp.Flag(name, value))
+	}
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+	browser, browserCancel := chromedp.NewContext(allocCtx)
+	env.browser = browser
+	env.cancel = func() {
+		browserCancel()
+		allocCancel()
+	}
+	// Launch now, so a browser that won't start fails here and not in a test
+	if err := chromedp.Run(browser); err != nil {
+		env.Close()
+		return nil, err
+	}
+	return env, nil
+}
+
+// Close stops the browser, removing its profile, and the fixture server.
+func (e *Env) Close() {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	if e.fixture != nil {
+		_ = e.fixture.Close()
+	}
+}
+
+// NewClient opens a fresh tab and returns a Client bound to it. The tab
+// closes when the test ends.
+func (e *Env) NewClient(t testing.TB) *Client {
+	t.Helper()
+	c, err := e.Open()
+	if err != nil {
+		t.Fatalf("chrometest: opening a tab: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// Open opens a fresh tab outside a test. Close the Client when done.
+func (e *Env) Open() (*Client, error) {
+	ctx, cancel := chromedp.NewContext(e.browser)
+	c := &Client{ctx: ctx, cancel: cancel, timeout: e.opts.Timeout, baseURL: e.URL}
+	// Enabling console capture creates the tab
+	if err := lib.ListenConsole(ctx, c.record); err != nil {
+		cancel()
+		return nil, err
+	}
+	return c, nil
+}
+
+// New starts an Env for a single test and returns a Client bound to a
+// fresh tab. Everything is cleaned up when the test ends. The test is
+// skipped when no Chrome is installed, and fails if it won't start.
+func New(t testing.TB, opts Options) *Client {
+	t.Helper()
+	env, err := Start(opts)
+	if errors.Is(err, ErrNoChrome) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("chrometest: starting Chrome: %v", err)
+	}
+	t.Cleanup(env.Close)
+	return env.NewClient(t)
+}
+
+// Client drives one tab. Each call is bounded by Options.Timeout. For
+// anything it doesn't wrap, pass Context to the lib and chromedp functions.
+type Client struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	baseURL string
+	mu      sync.Mutex
+	console []lib.ConsoleMessage
+}
+
+// Context returns the tab's context. It has no deadline of its own.
+func (c *Client) Context() context.Context {
+	return c.ctx
+}
+
+// Close closes the tab.
+func (c *Client) Close() {
+	c.cancel()
+}
+
+func (c *Client) call(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// URL resolves path against the fixture server: "/login.html" becomes
+// <Env.URL>/login.html. Absolute URLs are returned unchanged.
+func (c *Client) URL(path string) string {
+	if c.baseURL == "" || strings.Contains(path, "://") || strings.HasPrefix(path, "about:") || strings.HasPrefix(path, "data:") {
+		return path
+	}
+	return c.baseURL + "/" + strings.TrimPrefix(path, "/")
+}
+
+// Navigate loads a URL, or a path on the fixture server, and waits for the
+// load event.
+func (c *Client) Navigate(url string) error {
+	return c.call(func(ctx context.Context) error { return lib.Navigate(ctx, c.URL(url)) })
+}
+
+// Click clicks the first element matching selector once it is visible.
+func (c *Client) Click(selector string) error {
+	return c.call(func(ctx context.Context) error { return lib.Click(ctx, selector) })
+}
+
+// ClickText clicks the first button, link, or role=button whose text is text.
+func (c *Client) ClickText(text string) error {
+	return c.call(func(ctx context.Context) error { return lib.ClickText(ctx, text, "") })
+}
+
+// Fill sets a form field's value as a user would, see lib.Fill.
+func (c *Client) Fill(selector string, value string) error {
+	return c.call(func(ctx context.Context) error {
+		_, err := lib.Fill(ctx, selector, value)
+		return err
+	})
+}
+
+// Type focuses an element and types text into it, replacing its contents.
+func (c *Client) Type(selector string, text string) error {
+	return c.call(func(ctx context.Context) error { return lib.Type(ctx, selector, text, false) })
+}
+
+// WaitVisible waits for an element matching selector to be visible.
+func (c *Client) WaitVisible(selector string) error {
+	return c.call(func(ctx context.Context) error { return lib.WaitVisible(ctx, selector) })
+}
+
+// WaitFn waits for a JavaScript expression to be truthy and returns its
+// value, see lib.WaitFn.
+func (c *Client) WaitFn(expression string) (any, error) {
+	var value any
+	err := c.call(func(ctx context.Context) error {
+		var err error
+		value, err = lib.WaitFn(ctx, expression, 0)
+		return err
+	})
+	return value, err
+}
+
+// Text returns the visible text of the first element matching selector.
+func (c *Client) Text(selector string) (string, error) {
+	var text string
+	err := c.call(func(ctx context.Context) error {
+		return chromedp.Run(ctx, chromedp.Text(selector, &text, chromedp.ByQuery))
+	})
+	return strings.TrimSpace(text), err
+}
+
+// Eval evaluates script in the page and decodes its result into res (may
+// be nil).
+func (c *Client) Eval(script string, res any) error {
+	return c.call(func(ctx context.Context) error { return lib.Eval(ctx, script, res) })
+}
+
+// Screenshot captures the viewport as PNG.
+func (c *Client) Screenshot() ([]byte, error) {
+	var buf []byte
+	err := c.call(func(ctx context.Context) error {
+		var err error
+		buf, err = lib.Screenshot(ctx)
+		return err
+	})
+	return buf, err
+}
+
+// Console returns the console messages and exceptions logged in the tab
+// so far.
+func (c *Client) Console() []lib.ConsoleMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]lib.ConsoleMessage{}, c.console...)
+}
+
+func (c *Client) record(msg lib.ConsoleMessage) {
+	c.mu.Lock()
+	c.console = append(c.console, msg)
+	c.mu.Unlock()
+}
@@ -0,0 +1,106 @@
+package chrometest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func TestClient(t *testing.T) {
+	c := New(t, Options{Dir: "testdata/site"})
+	if err := c.Navigate("/index.html"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Fill("#name", "Ada"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Click("#greet"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitVisible(".greeting"); err != nil {
+		t.Fatal(err)
+	}
+	text, err := c.Text(".greeting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hello, Ada" {
+		t.Fatalf("greeting %q", text)
+	}
+	value, err := c.WaitFn("window.app.ready && document.querySelector('#name').value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "Ada" {
+		t.Fatalf("WaitFn returned %#v", value)
+	}
+}
+
+func TestClientURL(t *testing.T) {
+	c := &Client{baseURL: "http://127.0.0.1:8000"}
+	cases := map[string]string{
+		"/login.html":         "http://127.0.0.1:8000/login.html",
+		"login.html":          "http://127.0.0.1:8000/login.html",
+		"https://example.com": "https://example.com",
+		"about:blank":         "about:blank",
+		"data:text/html,hi":   "data:text/html,hi",
+	}
+	for path, want := range cases {
+		if got := c.URL(path); got != want {
+			t.Errorf("URL(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := (&Client{}).URL("/login.html"); got != "/login.html" {
+		t.Errorf("without a fixture server URL(%q) = %q", "/login.html", got)
+	}
+}
+
+func TestSessionKeepsEmulationAfterFailedClick(t *testing.T) {
+	// Session attaches over the debugging port, like chrome serve
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	t.Setenv("CHROME_PORT", fmt.Sprint(port))
+	c := New(t, Options{Dir: "testdata/site", Flags: map[string]any{"remote-debugging-port": port}})
+	if err := c.Navigate("/index.html"); err != nil {
+		t.Fatal(err)
+	}
+	id := string(chromedp.FromContext(c.Context()).Target.TargetID)
+
+	session, err := lib.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	preset := lib.Preset{Viewport: &lib.PresetViewport{Width: 400, Height: 300}}
+	if err := session.Emulate(id, preset, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Fails at once, without timing out, on a connection that is fine
+	err = session.Run(id, 0, chromedp.ActionFunc(func(ctx context.Context) error {
+		return lib.ClickNth(ctx, "#missing", "", 1)
+	}))
+	if err == nil {
+		t.Fatal("clicking a missing element succeeded")
+	}
+	var width int
+	if err := session.Run(id, 0, chromedp.Evaluate("window.innerWidth", &width)); err != nil {
+		t.Fatal(err)
+	}
+	if width != 400 {
+		t.Fatalf("innerWidth %d after a failed click, want 400", width)
+	}
+	overrides, err := session.Overrides(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overrides.Emulation == nil {
+		t.Fatal("emulation dropped after a failed click")
+	}
+}
@@ -0,0 +1,156 @@
+// abort fails requests matching URL patterns with a network error.
+package abort
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["abort"] = abort
+	lib.Args["abort"] = abortArgs{}
+}
+
+type abortArgs struct {
+	lib.TargetArgs
+	URLs    []string `arg:"-u,--url,separate" help:"URL pattern ('*' any run of characters, '?' one) or @group (repeatable)"`
+	Reason  string   `arg:"-r,--reason" default:"failed" help:"network error the requests fail with (see --reasons)"`
+	Method  string   `arg:"-m,--method" help:"only fail requests with this method, e.g. POST"`
+	Reasons bool     `arg:"--reasons" help:"list the error reasons and exit"`
+	Release bool     `arg:"--release" help:"remove every interception rule from the tab, mocks included (daemon only)"`
+	Hold    bool     `arg:"--hold" help:"intercept from this process until Ctrl+C even when the daemon is running"`
+	Command []string `arg:"positional" help:"chrome command and args to run while requests fail (after --)"`
+}
+
+func (abortArgs) Description() string {
+	return `abort - Fail requests matching URL patterns with a network error
+
+Intercepts the tab's requests with the Fetch domain and fails those whose
+URL matches a --url pattern with Fetch.failRequest, so the page sees a
+real network error (net::ERR_CONNECTION_REFUSED, net::ERR_TIMED_OUT, ...)
+as it would during an outage. Use it to exercise error states, retries,
+and offline fallbacks for one API while the rest of the app works.
+Unmatched requests continue untouched.
+
+Patterns are globs matched against the whole URL, as in 'chrome mock';
+@groups from 'chrome block --groups' work too. --method limits the rules
+to one method. --reason picks the error:
+  failed, aborted, timed-out, access-denied, connection-closed,
+  connection-reset, connection-refused, connection-aborted,
+  connection-failed, name-not-resolved, internet-disconnected,
+  address-unreachable, blocked-by-client, blocked-by-response
+
+These are 'chrome mock' rules with "fail" set, so they combine with mocks
+in the same tab; the last matching rule wins. Chrome drops interception
+when the DevTools connection closes. With COMMAND (after --), requests
+fail while it runs against the same tab and abort exits 1 if it fails.
+Otherwise, when the daemon is running (chrome serve), rules are handed to
+it and stay until 'chrome abort --release' (or 'chrome mock --release')
+or 'chrome state clear'; without it, this command holds them until Ctrl+C.
+
+Example:
+  chrome abort -u "*/api/*" --reason connection-refused -- run checkout.yaml
+  chrome abort -u "*/api/save" -m POST --reason timed-out
+  chrome abort -u "https://cdn.example.com/*" --reason name-not-resolved
+  chrome abort --release
+  chrome abort --reasons`
+}
+
+func abort() {
+	var args abortArgs
+	arg.MustParse(&args)
+
+	if args.Reasons {
+		for _, name := range lib.FailReasonNames() {
+			fmt.Printf("%-22s %s\n", name, lib.FailReasons[name])
+		}
+		return
+	}
+
+	if args.Release {
+		if !lib.DaemonRunning() {
+			fmt.Fprintf(os.Stderr, "error: nothing to release, aborts without the daemon (chrome serve) end with the command that set them\n")
+			lib.Exit(1)
+		}
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs)}
+		if err := lib.DaemonCall("/unmock", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println("released")
+		return
+	}
+
+	if _, err := lib.ParseFailReason(args.Reason); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	patterns, err := lib.ExpandBlockPatterns(args.URLs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if len(patterns) == 0 {
+		fmt.Fprintf(os.Stderr, "error: usage: chrome abort --url PATTERN [--reason REASON] [-- COMMAND...]\n")
+		lib.Exit(1)
+	}
+	rules := make([]lib.MockRule, 0, len(patterns))
+	for _, pattern := range patterns {
+		rules = append(rules, lib.MockRule{URL: pattern, Method: args.Method, Fail: args.Reason})
+	}
+
+	if len(args.Command) == 0 && !args.Hold && lib.DaemonRunning() {
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs), "rules": rules}
+		if err := lib.DaemonCall("/mock", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Printf("aborting %d pattern(s) with %s (held by daemon)\n", len(patterns), args.Reason)
+		return
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	addCtx, addCancel := context.WithTimeout(ctx, lib.DefaultTimeout)
+	err = lib.NewInterceptor(ctx).Add(addCtx, rules...)
+	addCancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if len(args.Command) > 0 {
+		output, err := lib.RunCommand(tabID, args.Command)
+		_, _ = os.Stdout.Write(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("aborting %d pattern(s) with %s\n", len(patterns), args.Reason)
+	fmt.Fprintf(os.Stderr, "holding aborts, Ctrl+C to release\n")
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
@@ -0,0 +1,65 @@
+// assertselector checks that an element matches a CSS selector.
+package assertselector
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["assertselector"] = assertselector
+	lib.Args["assertselector"] = assertselectorArgs{}
+}
+
+type assertselectorArgs struct {
+	lib.TargetArgs
+	Selector string `arg:"positional,required" help:"CSS selector that must match"`
+	Count    int    `arg:"-c,--count" default:"-1" help:"require exactly this many matches"`
+	Not      bool   `arg:"--not" help:"require that nothing matches"`
+}
+
+func (assertselectorArgs) Description() string {
+	return `assertselector - Assert an element matches a selector
+
+Checks once, without waiting, that SELECTOR matches at least one element,
+exactly --count elements, or with --not none. Visibility is not checked;
+'chrome waitfor' waits for a visible element. Exits 4 with the expected and
+actual match count when the check fails, and 1 when it could not run. Under
+'chrome step' both are saved in the StepRecord's assertion.
+
+Example:
+  chrome assertselector "#dashboard"
+  chrome assertselector --count 3 "ul.cart > li"
+  chrome assertselector --not ".spinner"
+  chrome step assertselector --not ".error-banner"`
+}
+
+func assertselector() {
+	var args assertselectorArgs
+	arg.MustParse(&args)
+
+	if args.Not && args.Count >= 0 {
+		fmt.Fprintln(os.Stderr, "error: use --count or --not, not both")
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	a, err := lib.AssertSelector(targetCtx, args.Selector, args.Count, args.Not)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	lib.FinishAssertion(a)
+}
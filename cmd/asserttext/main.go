@@ -0,0 +1,59 @@
+// asserttext checks that the page contains some text.
+package asserttext
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["asserttext"] = asserttext
+	lib.Args["asserttext"] = asserttextArgs{}
+}
+
+type asserttextArgs struct {
+	lib.TargetArgs
+	Text     string `arg:"positional,required" help:"text the page must contain"`
+	Selector string `arg:"-s,--selector" help:"only search the first element matching this CSS selector"`
+}
+
+func (asserttextArgs) Description() string {
+	return `asserttext - Assert the page contains text
+
+Checks once, without waiting, that the page's visible text (or the first
+element matching --selector) contains TEXT. Exits 4 with the expected and
+actual text when it does not, and 1 when the check could not run. Under
+'chrome step' both are saved in the StepRecord's assertion.
+
+Use 'chrome wait' first when the text may still be loading.
+
+Example:
+  chrome asserttext "Welcome back"
+  chrome asserttext --selector h1 "Dashboard"
+  chrome step asserttext --selector ".total" '$42.00'`
+}
+
+func asserttext() {
+	var args asserttextArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	a, err := lib.AssertText(targetCtx, args.Text, args.Selector)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	lib.FinishAssertion(a)
+}
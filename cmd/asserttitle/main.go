@@ -0,0 +1,57 @@
+// asserttitle checks the page's title.
+package asserttitle
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["asserttitle"] = asserttitle
+	lib.Args["asserttitle"] = asserttitleArgs{}
+}
+
+type asserttitleArgs struct {
+	lib.TargetArgs
+	Title    string `arg:"positional,required" help:"title the page must have"`
+	Contains bool   `arg:"--contains" help:"only require the title to contain TITLE"`
+}
+
+func (asserttitleArgs) Description() string {
+	return `asserttitle - Assert the page's title
+
+Checks once, without waiting, that the page's title equals TITLE, or with
+--contains includes it. Exits 4 with the expected and actual title when it
+does not, and 1 when the check could not run. Under 'chrome step' both are
+saved in the StepRecord's assertion.
+
+Example:
+  chrome asserttitle "Dashboard - Acme"
+  chrome asserttitle --contains Dashboard
+  chrome step asserttitle --contains "Order #"`
+}
+
+func asserttitle() {
+	var args asserttitleArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	a, err := lib.AssertTitle(targetCtx, args.Title, args.Contains)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	lib.FinishAssertion(a)
+}
@@ -0,0 +1,57 @@
+// asserturl checks the page's URL.
+package asserturl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["asserturl"] = asserturl
+	lib.Args["asserturl"] = asserturlArgs{}
+}
+
+type asserturlArgs struct {
+	lib.TargetArgs
+	URL   string `arg:"positional,required" help:"URL prefix the page must be on"`
+	Exact bool   `arg:"--exact" help:"require the whole URL to match"`
+}
+
+func (asserturlArgs) Description() string {
+	return `asserturl - Assert the page's URL
+
+Checks once, without waiting, that the page's URL starts with URL, or with
+--exact equals it. Exits 4 with the expected and actual URL when it does
+not, and 1 when the check could not run. Under 'chrome step' both are saved
+in the StepRecord's assertion.
+
+Example:
+  chrome asserturl http://localhost:3000/dashboard
+  chrome asserturl --exact "http://localhost:3000/search?q=shoes"
+  chrome step asserturl http://localhost:3000/orders/`
+}
+
+func asserturl() {
+	var args asserturlArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	a, err := lib.AssertURL(targetCtx, args.URL, args.Exact)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	lib.FinishAssertion(a)
+}
@@ -0,0 +1,185 @@
+// at reports the element at a viewport coordinate.
+package at
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["at"] = at
+	lib.Args["at"] = atArgs{}
+}
+
+type atArgs struct {
+	lib.TargetArgs
+	X          string `arg:"positional,required" help:"X coordinate in pixels"`
+	Y          string `arg:"positional,required" help:"Y coordinate in pixels"`
+	Screenshot bool   `arg:"-s,--screenshot" help:"coordinates are screenshot pixels: divide by devicePixelRatio"`
+}
+
+func (atArgs) Description() string {
+	return `at - Describe the element at a viewport coordinate
+
+Looks up the element at X, Y with DOM.getNodeForLocation (the same hit test
+a click at that point would use) and prints it as JSON: tag, role,
+accessible name, text, rect, and selector candidates that uniquely match it,
+best first. If the element is inside a link, button, or form control,
+"clickable" describes that ancestor too.
+
+Coordinates are viewport CSS pixels, as used by clickxy. Screenshots are
+captured in device pixels, so pass --screenshot when reading coordinates
+off a screenshot on a high-DPI display or emulated device.
+
+Example:
+  chrome at 300 200
+  chrome at --screenshot 600 400
+  chrome click "$(chrome at 300 200 | jq -r '.selectors[0]')"`
+}
+
+// describeFunction is called with this bound to the hit element.
+const describeFunction = `function() {
+  ` + lib.CSSPathJS + `
+  const unique = (el, sel) => {
+    try { const all = document.querySelectorAll(sel); return all.length === 1 && all[0] === el; } catch (e) { return false; }
+  };
+  const implicitRoles = {A: 'link', BUTTON: 'button', SELECT: 'combobox', TEXTAREA: 'textbox', IMG: 'img',
+    NAV: 'navigation', MAIN: 'main', HEADER: 'banner', FOOTER: 'contentinfo', FORM: 'form', UL: 'list', OL: 'list',
+    LI: 'listitem', TABLE: 'table', H1: 'heading', H2: 'heading', H3: 'heading', H4: 'heading', H5: 'heading', H6: 'heading'};
+  const inputRoles = {checkbox: 'checkbox', radio: 'radio', button: 'button', submit: 'button', reset: 'button',
+    range: 'slider', search: 'searchbox'};
+  const role = (el) => {
+    if (el.getAttribute('role')) return el.getAttribute('role');
+    if (el.tagName === 'A' && !el.hasAttribute('href')) return '';
+    if (el.tagName === 'INPUT') return inputRoles[el.type] || 'textbox';
+    return implicitRoles[el.tagName] || '';
+  };
+  const clean = (s) => (s || '').trim().replace(/\s+/g, ' ');
+  const name = (el) => {
+    if (el.getAttribute('aria-label')) return clean(el.getAttribute('aria-label'));
+    const by = el.getAttribute('aria-labelledby');
+    if (by) return clean(by.split(/\s+/).map(id => (document.getElementById(id) || {}).textContent || '').join(' '));
+    if (el.labels && el.labels.length) return clean(el.labels[0].textContent);
+    return clean(el.getAttribute('alt') || el.getAttribute('title') || el.getAttribute('placeholder') || el.innerText || el.value).slice(0, 100);
+  };
+  const selectors = (el) => {
+    const tag = el.tagName.toLowerCase();
+    const out = [];
+    const add = (sel) => { if (!out.includes(sel) && unique(el, sel)) out.push(sel); };
+    if (el.id) add('#' + CSS.escape(el.id));
+    for (const attr of ['data-testid', 'data-test', 'data-cy', 'name', 'aria-label', 'placeholder', 'alt', 'title']) {
+      const v = el.getAttribute(attr);
+      if (v) add(tag + '[' + attr + '=' + JSON.stringify(v) + ']');
+    }
+    if (el.tagName === 'A' && el.getAttribute('href')) add('a[href=' + JSON.stringify(el.getAttribute('href')) + ']');
+    const classes = Array.from(el.classList).filter(c => !/\d{3,}|^css-|^sc-/.test(c)).map(c => '.' + CSS.escape(c)).join('');
+    if (classes) add(tag + classes);
+    add(cssPath(el));
+    return out;
+  };
+  const describe = (el) => {
+    const r = el.getBoundingClientRect();
+    return {
+      tag: el.tagName.toLowerCase(),
+      role: role(el),
+      name: name(el),
+      text: clean(el.innerText || el.textContent).slice(0, 200),
+      rect: {x: r.x, y: r.y, width: r.width, height: r.height},
+      selectors: selectors(el),
+    };
+  };
+  let el = this.nodeType === Node.ELEMENT_NODE ? this : this.parentElement;
+  if (!el) return null;
+  const result = describe(el);
+  const clickable = el.closest('a[href], button, input, select, textarea, label, summary, [role=button], [role=link], [onclick], [contenteditable=true]');
+  if (clickable && clickable !== el) result.clickable = describe(clickable);
+  return result;
+}`
+
+func at() {
+	var args atArgs
+	arg.MustParse(&args)
+
+	x, err := strconv.ParseFloat(args.X, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid x coordinate: %v\n", err)
+		lib.Exit(1)
+	}
+	y, err := strconv.ParseFloat(args.Y, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid y coordinate: %v\n", err)
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	var result json.RawMessage
+	err = chromedp.Run(targetCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		if args.Screenshot {
+			var dpr float64
+			if err := chromedp.Evaluate(`window.devicePixelRatio || 1`, &dpr).Do(ctx); err != nil {
+				return err
+			}
+			x, y = x/dpr, y/dpr
+		}
+		backendID, _, _, err := dom.GetNodeForLocation(int64(math.Round(x)), int64(math.Round(y))).
+			WithIgnorePointerEventsNone(true).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("no element at %.0f, %.0f: %w", x, y, err)
+		}
+		object, err := dom.ResolveNode().WithBackendNodeID(backendID).Do(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = runtime.ReleaseObject(object.ObjectID).Do(ctx) }()
+		value, exception, err := runtime.CallFunctionOn(describeFunction).
+			WithObjectID(object.ObjectID).WithReturnByValue(true).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if exception != nil {
+			return errors.New(exception.Text)
+		}
+		result = json.RawMessage(value.Value)
+		return nil
+	}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if len(result) == 0 || string(result) == "null" {
+		fmt.Fprintf(os.Stderr, "error: no element at %s, %s\n", args.X, args.Y)
+		lib.Exit(1)
+	}
+
+	var pretty any
+	if err := json.Unmarshal(result, &pretty); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	jsonBytes, err := json.MarshalIndent(pretty, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -0,0 +1,161 @@
+// autoscroll loads infinite-scroll feeds by scrolling until content stops arriving.
+package autoscroll
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["autoscroll"] = autoscroll
+	lib.Args["autoscroll"] = autoscrollArgs{}
+}
+
+type autoscrollArgs struct {
+	lib.TargetArgs
+	UntilSelector string   `arg:"-u,--until-selector" help:"stop once an element matching this CSS selector exists"`
+	MaxRounds     int      `arg:"-m,--max-rounds" help:"stop after this many scrolls (0: no limit)"`
+	SettleMs      int      `arg:"-s,--settle-ms" default:"1500" help:"milliseconds to wait for new content after each scroll"`
+	Container     string   `arg:"-c,--container" help:"CSS selector of the scrolling element (default: the page)"`
+	Command       []string `arg:"positional" help:"chrome command and args to run when done (after --)"`
+}
+
+func (autoscrollArgs) Description() string {
+	return `autoscroll - Scroll a feed to the bottom until it stops loading
+
+Scrolls to the bottom, waits up to --settle-ms for the scroll height to grow,
+and repeats. Stops when no new content arrives within --settle-ms, when
+--until-selector matches, or after --max-rounds scrolls. Progress goes to
+stderr.
+
+If COMMAND is given (after --), it runs against the same tab when scrolling
+stops and its output is written to stdout.
+
+Pages that scroll an inner element instead of the window need --container.
+
+Example:
+  chrome autoscroll
+  chrome autoscroll --until-selector ".end-of-feed" -- eval "document.querySelectorAll('article').length"
+  chrome autoscroll --max-rounds 20 --settle-ms 3000 -- html
+  chrome autoscroll --container "#timeline" -m 10`
+}
+
+// scrollScript scrolls the container (or the page) to the bottom and returns
+// its scroll height, or -1 if the container does not exist.
+const scrollScript = `(() => {
+  const sel = %s;
+  const el = sel ? document.querySelector(sel) : (document.scrollingElement || document.documentElement);
+  if (!el) return -1;
+  el.scrollTop = el.scrollHeight;
+  return el.scrollHeight;
+})()`
+
+const heightScript = `(() => {
+  const sel = %s;
+  const el = sel ? document.querySelector(sel) : (document.scrollingElement || document.documentElement);
+  return el ? el.scrollHeight : -1;
+})()`
+
+func autoscroll() {
+	var args autoscrollArgs
+	arg.MustParse(&args)
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	settle := time.Duration(args.SettleMs) * time.Millisecond
+	rounds := 0
+	var stop string
+	for {
+		if args.UntilSelector != "" {
+			var found bool
+			script := fmt.Sprintf(`document.querySelector(%s) !== null`, strconv.Quote(args.UntilSelector))
+			if err := run(ctx, chromedp.Evaluate(script, &found)); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				lib.Exit(1)
+			}
+			if found {
+				stop = fmt.Sprintf("found %s", args.UntilSelector)
+				break
+			}
+		}
+		if args.MaxRounds > 0 && rounds >= args.MaxRounds {
+			stop = "reached --max-rounds"
+			break
+		}
+
+		height, grew, err := scroll(ctx, args.Container, settle)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		rounds++
+		if !grew {
+			stop = fmt.Sprintf("no new content within %dms", args.SettleMs)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "round %d: height %d\n", rounds, height)
+	}
+	fmt.Fprintf(os.Stderr, "stopped after %d round(s): %s\n", rounds, stop)
+
+	if len(args.Command) > 0 {
+		output, err := lib.RunCommand(tabID, args.Command)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		_, _ = os.Stdout.Write(output)
+	}
+}
+
+// scroll scrolls to the bottom once and polls until the scroll height grows
+// or settle elapses. It returns the new height and whether it grew.
+func scroll(ctx context.Context, container string, settle time.Duration) (int, bool, error) {
+	var before int
+	if err := run(ctx, chromedp.Evaluate(fmt.Sprintf(scrollScript, strconv.Quote(container)), &before)); err != nil {
+		return 0, false, err
+	}
+	if before < 0 {
+		return 0, false, fmt.Errorf("no element matches --container %s", container)
+	}
+
+	deadline := time.Now().Add(settle)
+	for time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		var height int
+		if err := run(ctx, chromedp.Evaluate(fmt.Sprintf(heightScript, strconv.Quote(container)), &height)); err != nil {
+			return 0, false, err
+		}
+		if height > before {
+			return height, true, nil
+		}
+	}
+	return before, false, nil
+}
+
+func run(tabCtx context.Context, actions ...chromedp.Action) error {
+	ctx, cancel := context.WithTimeout(tabCtx, lib.DefaultTimeout)
+	defer cancel()
+	return chromedp.Run(ctx, actions...)
+}
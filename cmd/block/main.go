@@ -0,0 +1,130 @@
+// block blocks requests matching URL patterns in a tab.
+package block
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["block"] = block
+	lib.Args["block"] = blockArgs{}
+}
+
+type blockArgs struct {
+	lib.TargetArgs
+	Patterns []string `arg:"positional" help:"URL patterns ('*' wildcards) or @group names"`
+	Release  bool     `arg:"--release" help:"unblock the given patterns, or all of them when none are given (daemon only)"`
+	Hold     bool     `arg:"--hold" help:"block from this process until Ctrl+C even when the daemon is running"`
+	Groups   bool     `arg:"--groups" help:"list the @group names and their patterns"`
+}
+
+func (blockArgs) Description() string {
+	return `block - Block requests matching URL patterns
+
+Blocks every request from the tab whose URL matches a pattern, as
+Network.setBlockedURLs does: '*' matches any run of characters and the
+pattern must match the whole URL. Blocked requests fail with
+net::ERR_BLOCKED_BY_CLIENT, which shows as "blockedReason" in 'chrome
+network'. Use it to cut ads, analytics, and heavy assets, or to see how the
+app behaves when a backend is down.
+
+@name selects a group of patterns:
+  @fonts      external font hosts
+  @trackers   common ad and analytics networks
+  @images     png, jpg, gif, webp, avif, svg, ico
+  @media      video, audio, and HLS segments
+
+Chrome drops blocking when the DevTools connection closes. When the daemon
+is running (chrome serve), patterns are handed to it and stay blocked until
+'chrome block --release' or 'chrome state clear'. Otherwise this command
+holds them until Ctrl+C.
+
+Example:
+  chrome block @trackers @fonts
+  chrome block "*/api/recommendations*" "*.mp4"
+  chrome block --release "*.mp4"
+  chrome block --release
+  chrome block --groups`
+}
+
+func block() {
+	var args blockArgs
+	arg.MustParse(&args)
+
+	if args.Groups {
+		for _, name := range lib.BlockGroupNames() {
+			fmt.Printf("@%s\t%s\n", name, strings.Join(lib.BlockGroups[name], " "))
+		}
+		return
+	}
+
+	if args.Release {
+		if !lib.DaemonRunning() {
+			fmt.Fprintf(os.Stderr, "error: nothing to release, blocking without the daemon (chrome serve) ends with the command that set it\n")
+			lib.Exit(1)
+		}
+		patterns, err := lib.ExpandBlockPatterns(args.Patterns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs), "patterns": patterns}
+		var result struct {
+			Blocked []string `json:"blocked"`
+		}
+		if err := lib.DaemonCall("/unblock", body, &result); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Printf("released, %d pattern(s) still blocked\n", len(result.Blocked))
+		return
+	}
+
+	patterns, err := lib.ExpandBlockPatterns(args.Patterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if len(patterns) == 0 {
+		fmt.Fprintf(os.Stderr, "error: usage: chrome block PATTERN...\n")
+		lib.Exit(1)
+	}
+
+	if !args.Hold && lib.DaemonRunning() {
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs), "patterns": patterns}
+		if err := lib.DaemonCall("/block", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Printf("blocked %d pattern(s) (held by daemon)\n", len(patterns))
+		return
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	if err := chromedp.Run(targetCtx, network.Enable(), network.SetBlockedURLs(patterns)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("blocked %d pattern(s)\n", len(patterns))
+	fmt.Fprintf(os.Stderr, "holding blocks, Ctrl+C to release\n")
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
@@ -0,0 +1,177 @@
+// canvas extracts a canvas element's bitmap from inside the page.
+package canvas
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["canvas"] = canvas
+	lib.Args["canvas"] = canvasArgs{}
+}
+
+type canvasArgs struct {
+	lib.TargetArgs
+	Action    string `arg:"positional,required" help:"dump"`
+	Selector  string `arg:"positional,required" help:"CSS selector of the canvas element"`
+	Path      string `arg:"--path" help:"exact file path for the image (overrides output dir)"`
+	OutputDir string `arg:"-o,--output-dir" help:"directory to store images (default: ~/chrome-shots)"`
+	Label     string `arg:"-l,--label" default:"canvas" help:"label embedded in filename"`
+	Format    string `arg:"-f,--format" help:"png, jpeg, or webp (default: from --path extension, else png)"`
+	Quality   int    `arg:"--quality" help:"jpeg/webp quality 1-100 (default: Chrome's)"`
+	Timeout   int    `arg:"--timeout" default:"3" help:"seconds to wait for a frame from captureStream"`
+}
+
+func (canvasArgs) Description() string {
+	return `canvas - Extract a canvas element's bitmap
+
+'canvas dump' saves the pixels of the first canvas matching SELECTOR at the
+canvas's own resolution, independent of CSS size, scrolling, and overlapping
+elements.
+
+WebGL canvases created without preserveDrawingBuffer are cleared once a
+frame is presented, so toDataURL and screenshots often come back black.
+When the direct read is blank, the frame is taken from canvas.captureStream
+instead, which sees what was presented. The method used is printed.
+
+Canvases tainted by cross-origin images cannot be read and fail.
+
+Example:
+  chrome canvas dump "#chart"
+  chrome canvas dump canvas.webgl --path /tmp/scene.png
+  chrome canvas dump "#game" --format jpeg --quality 80`
+}
+
+// dumpScript reads the canvas via toDataURL and, when that is blank (all
+// black or transparent), via a captureStream frame.
+const dumpScript = `(async () => {
+  const el = document.querySelector(%s);
+  if (!el) return { error: 'element not found' };
+  if (!(el instanceof HTMLCanvasElement)) return { error: 'element is a <' + el.tagName.toLowerCase() + '>, not a canvas' };
+  if (!el.width || !el.height) return { error: 'canvas is ' + el.width + 'x' + el.height };
+  const type = %s, quality = %s, timeout = %d;
+  const encode = (source) => {
+    const out = document.createElement('canvas');
+    out.width = el.width;
+    out.height = el.height;
+    out.getContext('2d').drawImage(source, 0, 0, out.width, out.height);
+    const data = out.getContext('2d').getImageData(0, 0, out.width, out.height).data;
+    let blank = true;
+    for (let i = 0; i < data.length && blank; i += 4) blank = !data[i] && !data[i + 1] && !data[i + 2];
+    return { data: out.toDataURL(type, quality), blank };
+  };
+  let direct;
+  try {
+    direct = encode(el);
+  } catch (e) {
+    return { error: String(e) };
+  }
+  if (!direct.blank || !el.captureStream || !window.ImageCapture) {
+    return { data: direct.data, method: 'toDataURL', width: el.width, height: el.height };
+  }
+  const track = el.captureStream().getVideoTracks()[0];
+  try {
+    const frame = await Promise.race([
+      new ImageCapture(track).grabFrame(),
+      new Promise((resolve) => setTimeout(() => resolve(null), timeout * 1000)),
+    ]);
+    if (frame) return { data: encode(frame).data, method: 'captureStream', width: el.width, height: el.height };
+  } catch (e) {
+  } finally {
+    track.stop();
+  }
+  return { data: direct.data, method: 'toDataURL (blank, no frame from captureStream)', width: el.width, height: el.height };
+})()`
+
+type dumpResult struct {
+	Data   string `json:"data"`
+	Method string `json:"method"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Error  string `json:"error"`
+}
+
+var mimeTypes = map[string]string{"png": "image/png", "jpeg": "image/jpeg", "webp": "image/webp"}
+
+func canvas() {
+	var args canvasArgs
+	arg.MustParse(&args)
+
+	if args.Action != "dump" {
+		fmt.Fprintf(os.Stderr, "error: unknown action %q (want dump)\n", args.Action)
+		lib.Exit(1)
+	}
+	format := args.Format
+	if format == "" && args.Path != "" {
+		format, _ = lib.ParseScreenshotFormat(strings.TrimPrefix(filepath.Ext(args.Path), "."))
+	}
+	format, err := lib.ParseScreenshotFormat(format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if args.Quality < 0 || args.Quality > 100 {
+		fmt.Fprintf(os.Stderr, "error: --quality must be 1-100\n")
+		lib.Exit(1)
+	}
+	quality := "undefined"
+	if args.Quality > 0 {
+		quality = strconv.FormatFloat(float64(args.Quality)/100, 'f', 2, 64)
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(time.Duration(args.Timeout+30) * time.Second)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	var result dumpResult
+	script := fmt.Sprintf(dumpScript, strconv.Quote(args.Selector), strconv.Quote(mimeTypes[format]), quality, args.Timeout)
+	err = chromedp.Run(targetCtx, chromedp.Evaluate(script, &result, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if result.Error != "" {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", args.Selector, result.Error)
+		lib.Exit(1)
+	}
+	_, encoded, ok := strings.Cut(result.Data, ",")
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unexpected canvas data\n")
+		lib.Exit(1)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	path, err := lib.PrepareOutputPath(args.Path, args.OutputDir, args.Label, lib.ScreenshotExtension(format))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("saved %s (%dx%d, %s)\n", path, result.Width, result.Height, result.Method)
+}
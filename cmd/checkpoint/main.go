@@ -0,0 +1,140 @@
+// checkpoint saves and restores named snapshots of a tab's state.
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["checkpoint"] = checkpoint
+	lib.Args["checkpoint"] = checkpointArgs{}
+}
+
+type checkpointArgs struct {
+	lib.TargetArgs
+	Action string `arg:"positional,required" help:"save, restore, list, or delete"`
+	Name   string `arg:"positional" help:"save/restore/delete: checkpoint name"`
+}
+
+func (checkpointArgs) Description() string {
+	return `checkpoint - Save and restore named snapshots of a tab
+
+'checkpoint save NAME' records the tab's URL, scroll position, every cookie
+in its browser context, and the localStorage and sessionStorage of its
+origin. 'checkpoint restore NAME' rolls a tab back to it: cookies are
+replaced, the tab navigates to the saved URL, the origin's web storage is
+replaced, and the page is reloaded and scrolled back. Restore works on the
+same tab or any other, so a long session can return to a known state after
+a failed branch.
+
+Restoring does not undo server-side changes, and storage of other origins
+is left alone. Checkpoints hold login sessions and are written readable
+only by you.
+
+Actions:
+  save NAME       snapshot the tab, replacing any checkpoint of that name
+  restore NAME    roll the tab back to the checkpoint
+  list            name, age, and URL of each checkpoint, newest first
+  delete NAME     remove the checkpoint
+
+Example:
+  chrome checkpoint save logged-in
+  chrome click "#delete-account"
+  chrome checkpoint restore logged-in
+  chrome -t localhost checkpoint restore logged-in
+  chrome checkpoint list`
+}
+
+func checkpoint() {
+	var args checkpointArgs
+	arg.MustParse(&args)
+
+	switch args.Action {
+	case "list":
+		list()
+		return
+	case "delete":
+		if args.Name == "" {
+			fail(fmt.Errorf("usage: chrome checkpoint delete NAME"))
+		}
+		if err := lib.DeleteCheckpoint(args.Name); err != nil {
+			fail(err)
+		}
+		fmt.Println("deleted", args.Name)
+		return
+	case "save", "restore":
+		if args.Name == "" {
+			fail(fmt.Errorf("usage: chrome checkpoint %s NAME", args.Action))
+		}
+	default:
+		fail(fmt.Errorf("unknown action %q, expected save, restore, list, or delete", args.Action))
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fail(err)
+	}
+	defer targetCancel()
+
+	if args.Action == "save" {
+		save(targetCtx, args.Name)
+	} else {
+		restore(targetCtx, args.Name)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	lib.Exit(1)
+}
+
+func save(ctx context.Context, name string) {
+	cp, err := lib.SaveCheckpoint(ctx, name)
+	if err != nil {
+		fail(err)
+	}
+	keys := 0
+	for _, o := range cp.State.Origins {
+		keys += len(o.LocalStorage) + len(o.SessionStorage)
+	}
+	fmt.Printf("saved %s: %s, %d cookie(s), %d key(s)\n", name, cp.URL, len(cp.State.Cookies), keys)
+}
+
+func restore(ctx context.Context, name string) {
+	cp, err := lib.LoadCheckpoint(name)
+	if err != nil {
+		fail(err)
+	}
+	res, err := lib.RestoreCheckpoint(ctx, cp)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("restored %s: %s, %d cookie(s), %d key(s)", name, res.URL, res.Cookies, res.Keys)
+	if res.ExpiredCookies > 0 {
+		fmt.Printf(", skipped %d expired cookie(s)", res.ExpiredCookies)
+	}
+	fmt.Println()
+	if res.URL != cp.URL {
+		fmt.Fprintf(os.Stderr, "warning: the tab was redirected from %s\n", cp.URL)
+	}
+}
+
+func list() {
+	cps, err := lib.ListCheckpoints()
+	if err != nil {
+		fail(err)
+	}
+	for _, cp := range cps {
+		age := time.Since(cp.Created).Round(time.Second)
+		fmt.Printf("%s\t%s ago\t%s\n", cp.Name, age, cp.URL)
+	}
+}
@@ -0,0 +1,131 @@
+// clear wipes cookies, cache, storage, and service workers for an origin.
+package clear
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["clear"] = clearSite
+	lib.Args["clear"] = clearArgs{}
+}
+
+type clearArgs struct {
+	lib.TargetArgs
+	Origin         string `arg:"positional" help:"origin or URL to clear (default: the tab's origin)"`
+	Cookies        bool   `arg:"--cookies" help:"delete the origin's cookies"`
+	Cache          bool   `arg:"--cache" help:"empty the HTTP cache, for every site: Chrome cannot clear it per origin"`
+	Storage        bool   `arg:"--storage" help:"wipe localStorage, sessionStorage, IndexedDB, Cache Storage, and other site storage"`
+	ServiceWorkers bool   `arg:"--service-workers" help:"unregister the origin's service workers"`
+	All            bool   `arg:"-a,--all" help:"all of the above"`
+}
+
+func (clearArgs) Description() string {
+	return `clear - Clear site data for an origin
+
+Wipes the selected categories for one origin, the tab's by default, so the
+next load behaves like a first visit without deleting the whole profile.
+Pick at least one category, or --all.
+
+sessionStorage belongs to a tab, so --storage clears it only in the
+selected tab, when that tab is on the origin. --cache empties the whole
+HTTP cache. Reload (chrome navigate) afterwards: the page still holds what
+it already read.
+
+Example:
+  chrome clear --all
+  chrome clear --cookies --storage https://app.example.com
+  chrome clear --service-workers --cache -t localhost:3000`
+}
+
+func clearSite() {
+	var args clearArgs
+	arg.MustParse(&args)
+
+	if args.All {
+		args.Cookies, args.Cache, args.Storage, args.ServiceWorkers = true, true, true, true
+	}
+	if !args.Cookies && !args.Cache && !args.Storage && !args.ServiceWorkers {
+		fmt.Fprintf(os.Stderr, "error: nothing to clear, pass --cookies, --cache, --storage, --service-workers, or --all\n")
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	var current string
+	if err := chromedp.Run(targetCtx, chromedp.Evaluate("location.origin", &current)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	origin := args.Origin
+	if origin == "" {
+		if !strings.HasPrefix(current, "http") {
+			fmt.Fprintf(os.Stderr, "error: the tab has no origin (%s), pass one\n", current)
+			lib.Exit(1)
+		}
+		origin = current
+	}
+	origin, err = lib.NormalizeOrigin(origin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	var types, cleared []string
+	if args.Cookies {
+		types = append(types, "cookies")
+		cleared = append(cleared, "cookies")
+	}
+	if args.Storage {
+		types = append(types, "local_storage", "indexeddb", "websql", "file_systems", "cache_storage", "shared_storage", "storage_buckets")
+		cleared = append(cleared, "storage")
+	}
+	if args.ServiceWorkers {
+		types = append(types, "service_workers")
+		cleared = append(cleared, "service workers")
+	}
+
+	err = chromedp.Run(targetCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		if len(types) > 0 {
+			if err := storage.ClearDataForOrigin(origin, strings.Join(types, ",")).Do(ctx); err != nil {
+				return err
+			}
+		}
+		if args.Storage && origin == current {
+			if err := chromedp.Evaluate("sessionStorage.clear()", nil).Do(ctx); err != nil {
+				return lib.StorageError(err)
+			}
+		}
+		if args.Cache {
+			if err := network.ClearBrowserCache().Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if args.Cache {
+		cleared = append(cleared, "HTTP cache (all sites)")
+	}
+	fmt.Printf("cleared %s for %s\n", strings.Join(cleared, ", "), origin)
+}
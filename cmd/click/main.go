@@ -6,7 +6,6 @@ import (
 	"os"
 
 	"github.com/alexflint/go-arg"
-	"github.com/chromedp/chromedp"
 	"github.com/nathants/chrome/lib"
 )
 
@@ -18,6 +17,8 @@ func init() {
 type clickArgs struct {
 	lib.TargetArgs
 	Selector string `arg:"positional,required" help:"CSS selector of element to click"`
+	Index    int    `arg:"--index" default:"0" help:"if multiple matches, which one to click (0-based)"`
+	Within   string `arg:"--within" help:"CSS selector of a container; only match elements inside its first match"`
 }
 
 func (clickArgs) Description() string {
@@ -35,6 +36,11 @@ Valid CSS selectors:
   chrome click "input[type=\"email\"]"   # by attribute
   chrome click "canvas"                  # by tag
 
+Use --index to click the Nth match and --within to scope matching to a container:
+  chrome click "button" --index 2                   # third button on the page
+  chrome click "button.delete" --within "#row-42"   # delete button inside row 42
+  chrome click "a" --within "nav" --index 1         # second link inside nav
+
 Invalid (these are Playwright selectors, not CSS):
   chrome click "button:has-text(\"Login\")"  # WRONG - use clicktext instead
   chrome click "text=Login"                  # WRONG - use clicktext instead`
@@ -50,13 +56,17 @@ func click() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
-	err = chromedp.Run(targetCtx, chromedp.Click(args.Selector, chromedp.ByQuery))
-	if err != nil {
+	if args.Index < 0 {
+		fmt.Fprintf(os.Stderr, "error: --index must be >= 0\n")
+		lib.Exit(1)
+	}
+
+	if err := lib.ClickNth(targetCtx, args.Selector, args.Within, args.Index); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
-}
\ No newline at end of file
+}
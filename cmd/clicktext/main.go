@@ -2,6 +2,7 @@
 package clicktext
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -21,6 +22,8 @@ type clickTextArgs struct {
 	Text     string `arg:"positional,required" help:"exact button/link text to click"`
 	Selector string `arg:"--selector" default:"button, a, [role='button']" help:"CSS selector to limit search domain"`
 	Index    int    `arg:"--index" default:"0" help:"if multiple matches, which one to click (0-based)"`
+	JSON     bool   `arg:"--json" help:"print match info as JSON"`
+	DryRun   bool   `arg:"--dry-run" help:"list all matches with selectors and rects without clicking"`
 }
 
 func (clickTextArgs) Description() string {
@@ -33,6 +36,9 @@ Examples:
   chrome clicktext "Sign In"                    # click button/link with text "Sign In"
   chrome clicktext "Submit" --index 1           # click the second "Submit" button
   chrome clicktext "Save" --selector "button"   # only match buttons, not links
+  chrome clicktext "Submit" --dry-run           # list every "Submit" match, no click
+  chrome clicktext "Submit" --dry-run --json    # same, as JSON for scripts
+  chrome clicktext "Submit" --json              # click and print what was clicked
 
 This is the correct way to click by text. Do NOT use 'click' with Playwright selectors:
   chrome clicktext "Login"                          # CORRECT
@@ -49,38 +55,114 @@ func clicktext() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
 	script := `(() => {
+	  ` + lib.CSSPathJS + `
 	  const sel = ` + strconv.Quote(args.Selector) + `;
 	  const want = ` + strconv.Quote(args.Text) + `;
 	  const idx = ` + strconv.Itoa(args.Index) + `;
+	  const dryRun = ` + strconv.FormatBool(args.DryRun) + `;
 	  const nodes = Array.from(document.querySelectorAll(sel));
 	  const matches = nodes.filter(n => (n.textContent || '').trim() === want);
+	  const describe = (el, i) => {
+	    const r = el.getBoundingClientRect();
+	    const style = getComputedStyle(el);
+	    return {
+	      index: i,
+	      selector: cssPath(el),
+	      tag: el.tagName.toLowerCase(),
+	      text: (el.textContent || '').trim(),
+	      visible: r.width > 0 && r.height > 0 && style.display !== 'none' && style.visibility !== 'hidden',
+	      rect: { x: r.x, y: r.y, width: r.width, height: r.height },
+	    };
+	  };
+	  if (dryRun) return { ok: true, count: matches.length, matches: matches.map(describe) };
 	  const el = matches[idx];
 	  if (!el) return { ok: false, count: matches.length };
-	  const rect = el.getBoundingClientRect();
+	  const info = describe(el, idx);
 	  el.scrollIntoView({block:'center', inline:'center'});
 	  el.click();
-	  return { ok: true, count: matches.length, x: rect.left + rect.width/2, y: rect.top + rect.height/2 };
+	  return { ok: true, count: matches.length, x: info.rect.x + info.rect.width/2, y: info.rect.y + info.rect.height/2, matches: [info] };
 	})()`
 
+	type rect struct {
+		X      float64 `json:"x"`
+		Y      float64 `json:"y"`
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	}
+	type match struct {
+		Index    int    `json:"index"`
+		Selector string `json:"selector"`
+		Tag      string `json:"tag"`
+		Text     string `json:"text"`
+		Visible  bool   `json:"visible"`
+		Rect     rect   `json:"rect"`
+	}
 	type result struct {
-		Ok    bool    `json:"ok"`
-		Count int     `json:"count"`
-		X     float64 `json:"x"`
-		Y     float64 `json:"y"`
+		Ok      bool    `json:"ok"`
+		Count   int     `json:"count"`
+		X       float64 `json:"x"`
+		Y       float64 `json:"y"`
+		Matches []match `json:"matches"`
 	}
 	var res result
 	if err := chromedp.Run(targetCtx, chromedp.Evaluate(script, &res)); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
+	}
+
+	if args.DryRun {
+		if args.JSON {
+			printJSON(map[string]any{"text": args.Text, "count": res.Count, "matches": nonNil(res.Matches)})
+			return
+		}
+		if res.Count == 0 {
+			fmt.Printf("no element with text %q (selector %q)\n", args.Text, args.Selector)
+			return
+		}
+		for _, m := range res.Matches {
+			visible := ""
+			if !m.Visible {
+				visible = " (hidden)"
+			}
+			fmt.Printf("[%d] %s at %.0f,%.0f %.0fx%.0f%s\n", m.Index, m.Selector, m.Rect.X, m.Rect.Y, m.Rect.Width, m.Rect.Height, visible)
+		}
+		return
 	}
 
 	if !res.Ok {
+		if args.JSON {
+			printJSON(map[string]any{"clicked": false, "text": args.Text, "count": res.Count})
+		}
 		fmt.Fprintf(os.Stderr, "error: no element with text %q (selector %q), matches=%d\n", args.Text, args.Selector, res.Count)
-		os.Exit(1)
+		lib.Exit(1)
 	}
-}
\ No newline at end of file
+
+	if args.JSON {
+		out := map[string]any{"clicked": true, "text": args.Text, "count": res.Count, "x": res.X, "y": res.Y}
+		if len(res.Matches) > 0 {
+			out["match"] = res.Matches[0]
+		}
+		printJSON(out)
+	}
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func printJSON(value any) {
+	jsonBytes, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -40,13 +40,13 @@ func clickxy() {
 	x, err := strconv.ParseFloat(args.X, 64)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: invalid x coordinate: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	y, err := strconv.ParseFloat(args.Y, 64)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: invalid y coordinate: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	ctx, cancel := lib.SetupContext()
@@ -55,13 +55,13 @@ func clickxy() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
 	err = chromedp.Run(targetCtx, chromedp.MouseClickXY(x, y))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 }
\ No newline at end of file
@@ -36,17 +36,17 @@ func closeTab() {
 
 	if !lib.IsChromeRunning() {
 		fmt.Fprintf(os.Stderr, "error: Chrome not running on port %d\n", lib.GetPort())
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	targetID, _, err := lib.ResolveTargetWithArgs(args.TargetArgs)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	if targetID == "" {
 		fmt.Fprintf(os.Stderr, "error: no target found\n")
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	// Close tab via HTTP endpoint (simpler than chromedp context)
@@ -55,14 +55,14 @@ func closeTab() {
 	resp, err := client.Get(url)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
 		fmt.Fprintf(os.Stderr, "error: status %d: %s\n", resp.StatusCode, string(body))
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	fmt.Printf("Closed tab: %s\n", targetID)
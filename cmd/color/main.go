@@ -0,0 +1,137 @@
+// color reports an element's computed and rendered colors.
+package color
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["color"] = colorCmd
+	lib.Args["color"] = colorArgs{}
+}
+
+type colorArgs struct {
+	lib.TargetArgs
+	Selector string `arg:"positional,required" help:"CSS selector of element"`
+	Top      int    `arg:"--top" default:"5" help:"number of most common rendered colors to list"`
+}
+
+func (colorArgs) Description() string {
+	return `color - Report an element's computed and rendered colors
+
+Prints JSON with two views of the first element matching SELECTOR:
+
+  computed   CSS color and the effective background-color: the first
+             non-transparent background among the element and its
+             ancestors, with the selector of the element providing it
+  rendered   sampled from a capture of the element: background is the
+             most common pixel color, foreground the most common color
+             clearly distinct from it (text, icons), and colors the --top
+             most common colors with the share of pixels each covers
+
+The two differ when gradients, images, opacity, filters, or overlapping
+elements change what is actually drawn. Colors are #rrggbb, or #rrggbbaa
+when not opaque.
+
+Example:
+  chrome color "button.primary"
+  chrome color "body" | jq -r .rendered.background
+  chrome color ".alert" --top 10`
+}
+
+// computedScript resolves the element's computed colors through a canvas so
+// every CSS color syntax comes back as hex.
+const computedScript = `(() => {
+  ` + lib.CSSPathJS + `
+  const el = document.querySelector(%s);
+  if (!el) return null;
+  const ctx = document.createElement('canvas').getContext('2d');
+  const hex = (value) => {
+    ctx.fillStyle = '#000';
+    ctx.fillStyle = value;
+    const v = ctx.fillStyle;
+    const m = v.match(/^rgba\((\d+), (\d+), (\d+), ([\d.]+)\)$/);
+    if (!m) return v;
+    const h = (n) => Number(n).toString(16).padStart(2, '0');
+    return '#' + h(m[1]) + h(m[2]) + h(m[3]) + h(Math.round(Number(m[4]) * 255));
+  };
+  let bg = el;
+  while (bg && getComputedStyle(bg).backgroundColor.match(/^(transparent|rgba\(0, 0, 0, 0\))$/)) bg = bg.parentElement;
+  const style = getComputedStyle(el);
+  return {
+    color: hex(style.color),
+    background: bg ? hex(getComputedStyle(bg).backgroundColor) : '#ffffff',
+    background_from: bg ? cssPath(bg) : 'canvas',
+  };
+})()`
+
+type computedColors struct {
+	Color          string `json:"color"`
+	Background     string `json:"background"`
+	BackgroundFrom string `json:"background_from"`
+}
+
+type renderedColors struct {
+	Background string           `json:"background"`
+	Foreground string           `json:"foreground,omitempty"`
+	Colors     []lib.ColorShare `json:"colors"`
+}
+
+func colorCmd() {
+	var args colorArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	var computed *computedColors
+	if err := chromedp.Run(targetCtx, chromedp.Evaluate(fmt.Sprintf(computedScript, strconv.Quote(args.Selector)), &computed)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if computed == nil {
+		fmt.Fprintf(os.Stderr, "error: element not found: %s\n", args.Selector)
+		lib.Exit(1)
+	}
+
+	buf, err := lib.ScreenshotWithOptions(targetCtx, lib.ScreenshotOptions{Element: args.Selector})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	colors := lib.DominantColors(img, max(1, args.Top))
+	rendered := renderedColors{Background: colors[0].Color, Colors: colors}
+	rendered.Foreground = lib.ForegroundColor(img, rendered.Background)
+
+	out, err := json.MarshalIndent(struct {
+		Selector string         `json:"selector"`
+		Computed computedColors `json:"computed"`
+		Rendered renderedColors `json:"rendered"`
+	}{args.Selector, *computed, rendered}, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(out))
+}
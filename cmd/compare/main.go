@@ -0,0 +1,251 @@
+// compare loads two URLs and reports how the pages differ.
+package compare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["compare"] = compare
+	lib.Args["compare"] = compareArgs{}
+}
+
+type compareArgs struct {
+	A         string   `arg:"positional,required" help:"baseline URL, e.g. production"`
+	B         string   `arg:"positional,required" help:"URL to compare against the baseline, e.g. staging"`
+	Selector  string   `arg:"-s,--selector" help:"CSS selector: compare only this element"`
+	Only      string   `arg:"--only" default:"text,outline,screenshot" help:"comma-separated views to compare: text, outline, screenshot"`
+	Ignore    []string `arg:"--ignore,separate" help:"regex masked to * before comparing text, outline, and title (repeatable)"`
+	Preset    string   `arg:"-p,--preset" help:"device preset applied to both tabs before loading"`
+	Settle    int      `arg:"--settle" default:"500" help:"ms without DOM changes to wait for after load"`
+	Context   int      `arg:"-C,--context" default:"2" help:"unchanged lines shown around each change"`
+	OutputDir string   `arg:"-o,--output-dir" help:"directory for screenshots and the diff image (default: ~/chrome-shots/compare/<timestamp>)"`
+	Threshold float64  `arg:"--threshold" default:"0.001" help:"fraction of pixels allowed to differ"`
+	Tolerance int      `arg:"--tolerance" default:"8" help:"per-channel color difference (0-255) treated as equal"`
+	JSON      bool     `arg:"--json" help:"print the comparison as JSON"`
+	Fail      bool     `arg:"--fail" help:"exit 1 if the pages differ"`
+}
+
+func (compareArgs) Description() string {
+	return `compare - Load two URLs and report how the pages differ
+
+Opens URL_A and URL_B one after the other in new tabs of the running
+browser, waits for each to load and settle, and compares:
+
+  text        visible text, line by line, as a diff
+  outline     headings by level and landmarks (header, nav, main, forms)
+  screenshot  viewport pixels, as in 'chrome vr check'
+
+The title is always compared. --selector limits every view to the first
+element matching it on both pages. Use --ignore for content expected to
+differ between environments, such as dates, build hashes, or hostnames.
+
+Screenshots, a.png, b.png, and diff.png (red: different), are written to
+--output-dir. Both tabs share the browser's cookies and viewport; --preset
+emulates a device in both. Tabs are closed afterwards.
+
+Exits 1 with --fail if anything differs, for staging-vs-production smoke
+checks.
+
+Example:
+  chrome compare https://example.com https://staging.example.com
+  chrome compare https://example.com/pricing https://staging.example.com/pricing --selector main --only text,outline
+  chrome compare $PROD $STAGING --ignore 'v\d+\.\d+\.\d+' --ignore 'staging\.' --fail
+  chrome compare $PROD $STAGING --preset iphone --json | jq .text`
+}
+
+func compare() {
+	var args compareArgs
+	arg.MustParse(&args)
+
+	views := map[string]bool{}
+	for _, view := range strings.Split(args.Only, ",") {
+		view = strings.ToLower(strings.TrimSpace(view))
+		switch view {
+		case "":
+		case "text", "outline", "screenshot":
+			views[view] = true
+		default:
+			fmt.Fprintf(os.Stderr, "error: unknown view %q, expected text, outline, or screenshot\n", view)
+			lib.Exit(1)
+		}
+	}
+	var ignore []*regexp.Regexp
+	for _, pattern := range args.Ignore {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --ignore %q: %v\n", pattern, err)
+			lib.Exit(1)
+		}
+		ignore = append(ignore, re)
+	}
+	var preset lib.Preset
+	if args.Preset != "" {
+		var err error
+		preset, err = lib.LoadPreset("", args.Preset)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	var snaps [2]lib.PageSnapshot
+	for i, url := range []string{args.A, args.B} {
+		snap, err := load(url, args, preset, views["screenshot"])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", url, err)
+			lib.Exit(1)
+		}
+		if !views["text"] {
+			snap.Text = nil
+		}
+		if !views["outline"] {
+			snap.Outline = nil
+		}
+		snap.Mask(ignore)
+		snaps[i] = snap
+	}
+
+	cmp, diffImage, err := lib.ComparePages(snaps[0], snaps[1], lib.CompareOptions{
+		Context:   args.Context,
+		Threshold: args.Threshold,
+		Diff:      lib.DiffOptions{Tolerance: args.Tolerance, AntiAlias: true},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	out := ""
+	if diffImage != nil {
+		out, err = saveImages(args.OutputDir, snaps, diffImage)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	if args.JSON {
+		data, err := json.MarshalIndent(cmp, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		report(cmp, views, out)
+	}
+
+	if args.Fail && cmp.Different {
+		lib.Exit(1)
+	}
+}
+
+// load opens url in a new tab, with preset applied first, waits for it to
+// settle, and snapshots it. The tab is closed on return.
+func load(url string, args compareArgs, preset lib.Preset, screenshot bool) (lib.PageSnapshot, error) {
+	if err := lib.PoliteWait(context.Background(), url); err != nil {
+		return lib.PageSnapshot{}, err
+	}
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(context.Background(), lib.ChromeURL())
+	defer allocCancel()
+	// Cancelling a context that created its tab closes the tab
+	tabCtx, tabCancel := chromedp.NewContext(allocCtx)
+	defer tabCancel()
+	ctx, cancel := context.WithTimeout(tabCtx, 2*lib.DefaultTimeout)
+	defer cancel()
+
+	actions := append(preset.Actions(), chromedp.Navigate(url))
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return lib.PageSnapshot{}, err
+	}
+	if args.Settle > 0 {
+		if _, err := lib.WaitStable(ctx, time.Duration(args.Settle)*time.Millisecond); err != nil {
+			return lib.PageSnapshot{}, err
+		}
+	}
+	return lib.SnapshotPage(ctx, args.Selector, screenshot)
+}
+
+func saveImages(dir string, snaps [2]lib.PageSnapshot, diffImage *image.RGBA) (string, error) {
+	if dir == "" {
+		dir = filepath.Join(lib.DefaultShotsDir(), "compare", lib.NewRunID(""))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	for i, name := range []string{"a.png", "b.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), snaps[i].Screenshot, 0644); err != nil {
+			return "", err
+		}
+	}
+	if err := lib.SavePNG(filepath.Join(dir, "diff.png"), diffImage); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func report(cmp lib.PageComparison, views map[string]bool, out string) {
+	fmt.Printf("a: %s\nb: %s\n\n", cmp.URLA, cmp.URLB)
+	if cmp.TitleA != cmp.TitleB {
+		fmt.Printf("title: %q -> %q\n", cmp.TitleA, cmp.TitleB)
+	} else {
+		fmt.Printf("title: same\n")
+	}
+	if views["outline"] {
+		section("outline", cmp.Outline)
+	}
+	if views["text"] {
+		section("text", cmp.Text)
+	}
+	if s := cmp.Screenshot; s != nil {
+		status := "same"
+		switch {
+		case s.SizeMismatch:
+			status = "size differs"
+		case !cmp.ScreenshotOK:
+			status = "differs"
+		}
+		fmt.Printf("screenshot: %s, %d of %d pixels differ (%.4f%%), %s\n", status, s.Different, s.Pixels, s.Ratio*100, out)
+	}
+	fmt.Println()
+	if cmp.Different {
+		fmt.Println("pages differ")
+	} else {
+		fmt.Println("pages match")
+	}
+}
+
+// section prints a line diff, with "..." where unchanged lines were left out.
+func section(name string, diff lib.LineDiff) {
+	if !diff.Changed() {
+		fmt.Printf("%s: same\n", name)
+		return
+	}
+	fmt.Printf("%s: -%d +%d\n", name, diff.Removed, diff.Added)
+	lastA, lastB := 0, 0
+	for _, line := range diff.Lines {
+		if (line.A != 0 && line.A > lastA+1) || (line.B != 0 && line.B > lastB+1) {
+			fmt.Println("  ...")
+		}
+		fmt.Printf("  %s %s\n", line.Op, line.Text)
+		if line.A != 0 {
+			lastA = line.A
+		}
+		if line.B != 0 {
+			lastB = line.B
+		}
+	}
+}
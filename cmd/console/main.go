@@ -2,15 +2,17 @@
 package console
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/alexflint/go-arg"
-	cdplog "github.com/chromedp/cdproto/log"
-	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
 	"github.com/nathants/chrome/lib"
 )
@@ -22,37 +24,115 @@ func init() {
 
 type consoleArgs struct {
 	lib.TargetArgs
-	Duration int  `arg:"-d,--duration" default:"5" help:"duration in seconds to capture logs"`
-	Follow   bool `arg:"-f,--follow" help:"follow mode, capture logs continuously"`
+	Action   string `arg:"positional" help:"tail: read back a --out file instead of capturing"`
+	File     string `arg:"positional" help:"tail: the --out file"`
+	Lines    int    `arg:"-n,--lines" default:"20" help:"tail: messages to show before following, -1 for all"`
+	Types    string `arg:"--type" help:"tail: comma-separated types or levels to show, e.g. error,exception"`
+	JSON     bool   `arg:"--json" help:"tail: print the NDJSON lines as they are"`
+	Duration int    `arg:"-d,--duration" default:"5" help:"duration in seconds to capture logs"`
+	Follow   bool   `arg:"-f,--follow" help:"follow mode, capture logs continuously"`
 	Eval     string `arg:"--eval" help:"JavaScript to evaluate after enabling log capture"`
+	Stack    bool   `arg:"--stack" help:"include each message's full stack trace"`
+	Depth    int    `arg:"--depth" default:"2" help:"levels of object arguments' properties to include, 0 for just their type"`
+	Until    string `arg:"--until" help:"regex: exit 0 as soon as a message matches (-d is the timeout)"`
+	FailOn   string `arg:"--fail-on" help:"regex: exit 4 as soon as a message matches"`
+	lib.OutArgs
+	Buffer int `arg:"--buffer" default:"10000" help:"messages held while output catches up; beyond it the oldest are dropped"`
 }
 
 func (consoleArgs) Description() string {
 	return `console - Capture console logs
 
+  console [-d SECONDS | -f] [--out FILE]
+  console tail FILE [-n LINES] [-f] [--type TYPES] [--json]
+
 Captures console.log, console.warn, console.error, exceptions, and browser log
 events (CSP violations, security errors, deprecation warnings, etc) from the page.
 Output is JSON, one object per line (NDJSON).
 Use --eval to run JavaScript after capture starts (handy for triggering logs).
 
+Each message carries where it was logged or thrown, when known: the
+script's "url" and 1-based "line" and "column". --stack adds "stack", the
+full call stack innermost first, each frame with its function, url, line,
+and column.
+
+Object arguments are expanded to their own enumerable properties, array
+elements, and Map and Set entries, --depth levels deep (default 2);
+deeper objects, functions, errors, and DOM nodes appear as their
+description, e.g. "Object" or "HTMLDivElement". Getters aren't run, and
+each object keeps its first 100 items. --depth 0 prints only each
+object's type, e.g. "object".
+
+Messages wait in a fixed buffer of --buffer messages until written, so
+memory stays flat in multi-hour -f sessions. If output falls that far
+behind, the oldest are dropped and a {"type": "dropped", "count": N} line
+marks the gap. --out writes to a file instead of stdout, rotated to FILE.1,
+FILE.2, ... at --rotate-size, or when it is --rotate-every old (rotation happens as
+messages are written, so a quiet file rotates with its next message).
+--keep old files are kept.
+
+'console tail FILE' reads such a file back as one line per message: time,
+type (and level when it differs), source location, and text. It prints the
+last -n messages, and with -f, follows new ones across rotations until
+Ctrl+C. --type keeps messages whose type or level is listed; --json prints
+the lines unchanged for jq. 'chrome network' --out files can be tailed with
+--json too.
+
+--until and --fail-on make console a sync point and an assertion for
+scripts. Each message's text (its message, else its args as JSON) is
+matched against the regexes as it is printed: on an --until match console
+exits 0, on a --fail-on match it exits 4. With --until, -d is the timeout
+and console exits 1 if nothing matched in time (-f waits indefinitely).
+With only --fail-on, console captures for -d and exits 0 if nothing
+matched.
+
+Ctrl+C (or SIGTERM) stops capture cleanly: buffered messages are written
+and --out is closed before exiting. A second Ctrl+C exits at once.
+
 Example:
   chrome console                    # Capture for 5 seconds
   chrome console -d 10              # Capture for 10 seconds
-  chrome console -f                 # Follow mode (continuous, Ctrl+C to stop)`
-}
-
-type ConsoleMessage struct {
-	Type      string      `json:"type"`
-	Message   string      `json:"message,omitempty"`
-	Args      interface{} `json:"args,omitempty"`
-	Timestamp time.Time   `json:"timestamp"`
-	Level     string      `json:"level,omitempty"`
+  chrome console -f                 # Follow mode (continuous, Ctrl+C to stop)
+  chrome console -f --depth 4       # Expand nested objects further
+  chrome console -f --stack | jq 'select(.level == "error" or .type == "error")'
+  chrome console -f --out console.ndjson --rotate-size 10MB
+  chrome console -f --out /tmp/console.ndjson --rotate-every 1h --keep 24 &
+  chrome console tail /tmp/console.ndjson -f --type error,exception
+  chrome console --until "app ready" -d 30 --eval "location.reload()"
+  chrome console --until "checkout complete" --fail-on "(?i)error|failed" -d 60`
 }
 
 func console() {
 	var args consoleArgs
 	arg.MustParse(&args)
 
+	switch args.Action {
+	case "":
+	case "tail":
+		tail(args)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown action %q, expected tail\n", args.Action)
+		lib.Exit(1)
+	}
+
+	until, err := compile("--until", args.Until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	failOn, err := compile("--fail-on", args.FailOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	sink, err := args.OpenSink()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer func() { _ = sink.Close() }()
+
 	ctxTimeout := lib.DefaultTimeout
 	if args.Follow {
 		ctxTimeout = 0
@@ -69,108 +149,155 @@ func console() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
-	messages := make(chan ConsoleMessage, 100)
-
-	chromedp.ListenTarget(targetCtx, func(ev interface{}) {
-		switch ev := ev.(type) {
-		case *runtime.EventConsoleAPICalled:
-			msg := ConsoleMessage{
-				Type:      string(ev.Type),
-				Timestamp: time.Now(),
-			}
-
-			// Extract argument values.
-			if len(ev.Args) > 0 {
-				var args []interface{}
-				for _, arg := range ev.Args {
-					var val interface{}
-					if arg.Value != nil {
-						err := json.Unmarshal(arg.Value, &val)
-						if err != nil {
-							val = string(arg.Value)
-						}
-						args = append(args, val)
-					} else {
-						args = append(args, arg.Type.String())
-					}
-				}
-				if len(args) == 1 {
-					if s, ok := args[0].(string); ok {
-						msg.Message = s
-					} else {
-						msg.Args = args[0]
-					}
-				} else {
-					msg.Args = args
-				}
-			}
-
-			select {
-			case messages <- msg:
-			default:
-			}
-		case *runtime.EventExceptionThrown:
-			msg := ConsoleMessage{
-				Type:      "exception",
-				Level:     "error",
-				Timestamp: time.Now(),
-			}
-			if ev.ExceptionDetails.Exception != nil {
-				msg.Message = ev.ExceptionDetails.Exception.Description
-			} else {
-				msg.Message = ev.ExceptionDetails.Text
-			}
-
-			select {
-			case messages <- msg:
-			default:
-			}
-		case *cdplog.EventEntryAdded:
-			// Capture Log domain events (CSP violations, security errors, etc).
-			msg := ConsoleMessage{
-				Type:      string(ev.Entry.Source),
-				Level:     string(ev.Entry.Level),
-				Message:   ev.Entry.Text,
-				Timestamp: time.Now(),
-			}
-
-			select {
-			case messages <- msg:
-			default:
-			}
-		}
-	})
-
-	if err := chromedp.Run(targetCtx, runtime.Enable(), cdplog.Enable()); err != nil {
+	messages := lib.NewEventRing[lib.ConsoleMessage](args.Buffer)
+	err = lib.ListenConsoleWithOptions(targetCtx, lib.ConsoleOptions{Stack: args.Stack, Depth: args.Depth}, messages.Push)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	if strings.TrimSpace(args.Eval) != "" {
 		err := chromedp.Run(targetCtx, chromedp.Evaluate(args.Eval, nil))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+			lib.Exit(1)
 		}
 	}
 
-	if args.Follow {
-		for {
-			msg := <-messages
-			lib.PrintJSONLine(msg)
+	waitCtx, stop := lib.InterruptContext(context.Background())
+	defer stop()
+	if !args.Follow {
+		var waitCancel context.CancelFunc
+		waitCtx, waitCancel = context.WithTimeout(waitCtx, time.Duration(args.Duration)*time.Second)
+		defer waitCancel()
+	}
+	code := 0
+	for {
+		msg, dropped, ok := messages.Pop(waitCtx)
+		if !ok {
+			if until != nil {
+				fmt.Fprintf(os.Stderr, "error: no console message matched --until %q\n", args.Until)
+				code = 1
+			}
+			break
+		}
+		if err := sink.Write(msg, dropped); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
 		}
+		if failOn != nil && failOn.MatchString(text(msg)) {
+			fmt.Fprintf(os.Stderr, "error: console message matched --fail-on %q\n", args.FailOn)
+			code = lib.ExitAssertion
+			break
+		}
+		if until != nil && until.MatchString(text(msg)) {
+			break
+		}
+	}
+	if n := sink.Dropped(); n > 0 {
+		fmt.Fprintf(os.Stderr, "warning: dropped %d messages, output fell behind\n", n)
+	}
+	if code != 0 {
+		_ = sink.Close()
+		lib.Exit(code)
+	}
+}
+
+func compile(flag string, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
 	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %v", flag, pattern, err)
+	}
+	return re, nil
+}
 
-	deadline := time.After(time.Duration(args.Duration) * time.Second)
-	for {
-		select {
-		case msg := <-messages:
-			lib.PrintJSONLine(msg)
-		case <-deadline:
-			return
+// text is what --until and --fail-on match: the message, else its args as
+// JSON.
+func text(msg lib.ConsoleMessage) string {
+	if msg.Message != "" || msg.Args == nil {
+		return msg.Message
+	}
+	data, err := json.Marshal(msg.Args)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// tail prints a --out file's messages, following it with -f.
+func tail(args consoleArgs) {
+	if args.File == "" {
+		fmt.Fprintf(os.Stderr, "error: usage: chrome console tail FILE [-n LINES] [-f]\n")
+		lib.Exit(1)
+	}
+	types := map[string]bool{}
+	for _, t := range strings.Split(args.Types, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types[t] = true
+		}
+	}
+	ctx, stop := lib.InterruptContext(context.Background())
+	defer stop()
+	err := lib.TailLines(ctx, args.File, args.Lines, args.Follow, func(line []byte) error {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		var msg lib.ConsoleMessage
+		var marker struct {
+			Count int `json:"count"`
 		}
+		if json.Unmarshal(line, &msg) != nil {
+			// Not ours, pass it through
+			fmt.Println(string(line))
+			return nil
+		}
+		if len(types) > 0 && !types[msg.Type] && !types[msg.Level] {
+			return nil
+		}
+		if args.JSON {
+			fmt.Println(string(line))
+			return nil
+		}
+		if msg.Type == "dropped" && json.Unmarshal(line, &marker) == nil {
+			fmt.Printf("%s ... %d messages dropped\n", msg.Timestamp.Local().Format("15:04:05.000"), marker.Count)
+			return nil
+		}
+		fmt.Println(format(msg))
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+}
+
+// format renders a message as one line: time, type (and level when it
+// differs), location, and text.
+func format(msg lib.ConsoleMessage) string {
+	parts := []string{msg.Timestamp.Local().Format("15:04:05.000"), msg.Type}
+	if msg.Level != "" && msg.Level != msg.Type {
+		parts[1] += "/" + msg.Level
+	}
+	if msg.URL != "" {
+		location := msg.URL
+		if msg.Line > 0 {
+			location += fmt.Sprintf(":%d", msg.Line)
+			if msg.Column > 0 {
+				location += fmt.Sprintf(":%d", msg.Column)
+			}
+		}
+		parts = append(parts, location)
+	}
+	parts = append(parts, text(msg))
+	line := strings.Join(parts, " ")
+	for _, frame := range msg.Stack {
+		line += fmt.Sprintf("\n    at %s (%s:%d:%d)", cmp.Or(frame.Function, "<anonymous>"), frame.URL, frame.Line, frame.Column)
 	}
+	return line
 }
@@ -0,0 +1,300 @@
+// cookies lists, sets, deletes, exports, and imports browser cookies.
+package cookies
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["cookies"] = cookies
+	lib.Args["cookies"] = cookiesArgs{}
+}
+
+type cookiesArgs struct {
+	lib.TargetArgs
+	Action   string   `arg:"positional,required" help:"list, get, set, delete, export, or import"`
+	Args     []string `arg:"positional" help:"get/delete: NAME; set: NAME VALUE; import: FILE"`
+	Domain   string   `arg:"-d,--domain" help:"only cookies on this domain or its subdomains; for set, the cookie's domain (default: the tab's host)"`
+	Path     string   `arg:"--path" help:"for set and delete, the cookie's path (set default: /)"`
+	Expires  string   `arg:"--expires" help:"for set, a duration from now (24h) or an RFC 3339 time (default: session cookie)"`
+	Secure   bool     `arg:"--secure" help:"for set, only send over HTTPS"`
+	HTTPOnly bool     `arg:"--http-only" help:"for set, hide from page scripts"`
+	SameSite string   `arg:"--same-site" help:"for set, Strict, Lax, or None"`
+	Format   string   `arg:"-f,--format" help:"export: json (default) or netscape; import: auto (default), json, or netscape"`
+	Output   string   `arg:"-o,--output" help:"export to this file instead of stdout"`
+	JSON     bool     `arg:"--json" help:"list as JSON"`
+}
+
+func (cookiesArgs) Description() string {
+	return `cookies - Manage the browser's cookies
+
+Works on the cookies of the tab's browser context, which is the whole
+profile unless the tab is in an isolated context: logging in on one site
+and exporting carries that login to another profile or port.
+
+Actions:
+  list              name, domain, path, expiry, and flags (--json for all fields)
+  get NAME          print the value (one line per domain with --domain unset)
+  set NAME VALUE    add or replace a cookie, on the tab's host by default
+  delete NAME       delete it from every domain, or --domain/--path only
+  export            write cookies as JSON (a Playwright storage state's
+                    cookies array) or Netscape cookies.txt (curl, wget, yt-dlp)
+  import FILE       add cookies from a JSON or cookies.txt file ("-" for stdin)
+
+Example:
+  chrome cookies list --domain example.com
+  chrome cookies get session_id
+  chrome cookies set theme dark --expires 720h
+  chrome cookies set token abc123 --domain .example.com --secure --http-only --same-site Lax
+  chrome cookies delete session_id --domain example.com
+  chrome cookies export -o auth.json
+  chrome cookies export -f netscape -o cookies.txt && curl -b cookies.txt https://example.com/api
+  chrome -p 9223 cookies import auth.json`
+}
+
+func cookies() {
+	var args cookiesArgs
+	arg.MustParse(&args)
+
+	want := map[string]int{"list": 0, "get": 1, "set": 2, "delete": 1, "export": 0, "import": 1}
+	n, ok := want[args.Action]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown action %q, expected list, get, set, delete, export, or import\n", args.Action)
+		lib.Exit(1)
+	}
+	if len(args.Args) != n {
+		usage := map[string]string{"get": "get NAME", "set": "set NAME VALUE", "delete": "delete NAME", "import": "import FILE"}[args.Action]
+		if usage == "" {
+			usage = args.Action + " (no arguments)"
+		}
+		fmt.Fprintf(os.Stderr, "error: usage: chrome cookies %s\n", usage)
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	switch args.Action {
+	case "list":
+		list(targetCtx, args)
+	case "get":
+		get(targetCtx, args)
+	case "set":
+		set(targetCtx, args)
+	case "delete":
+		remove(targetCtx, args)
+	case "export":
+		export(targetCtx, args)
+	case "import":
+		load(targetCtx, args)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	lib.Exit(1)
+}
+
+func matching(ctx context.Context, name string, domain string) []lib.Cookie {
+	all, err := lib.GetCookies(ctx)
+	if err != nil {
+		fail(err)
+	}
+	return lib.FilterCookies(all, name, domain)
+}
+
+func list(ctx context.Context, args cookiesArgs) {
+	found := matching(ctx, "", args.Domain)
+	if args.JSON {
+		data, err := lib.FormatCookies(found, "json")
+		if err != nil {
+			fail(err)
+		}
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+	for _, c := range found {
+		expires := "session"
+		if !c.Session() {
+			expires = time.Unix(int64(c.Expires), 0).UTC().Format(time.RFC3339)
+		}
+		var flags []string
+		if c.Secure {
+			flags = append(flags, "secure")
+		}
+		if c.HTTPOnly {
+			flags = append(flags, "httponly")
+		}
+		if c.SameSite != "" {
+			flags = append(flags, "samesite="+c.SameSite)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n", c.Name, c.Domain, c.Path, expires, strings.Join(flags, ","))
+	}
+}
+
+func get(ctx context.Context, args cookiesArgs) {
+	found := matching(ctx, args.Args[0], args.Domain)
+	if len(found) == 0 {
+		fail(fmt.Errorf("no cookie named %q", args.Args[0]))
+	}
+	for _, c := range found {
+		if len(found) > 1 {
+			fmt.Printf("%s\t%s\n", c.Domain, c.Value)
+		} else {
+			fmt.Println(c.Value)
+		}
+	}
+}
+
+func set(ctx context.Context, args cookiesArgs) {
+	cookie := lib.Cookie{
+		Name:     args.Args[0],
+		Value:    args.Args[1],
+		Domain:   args.Domain,
+		Path:     args.Path,
+		Expires:  -1,
+		Secure:   args.Secure,
+		HTTPOnly: args.HTTPOnly,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	switch strings.ToLower(args.SameSite) {
+	case "":
+	case "strict", "lax", "none":
+		cookie.SameSite = strings.ToUpper(args.SameSite[:1]) + strings.ToLower(args.SameSite[1:])
+	default:
+		fail(fmt.Errorf("--same-site must be Strict, Lax, or None, got %q", args.SameSite))
+	}
+	if args.Expires != "" {
+		expires, err := parseExpires(args.Expires)
+		if err != nil {
+			fail(err)
+		}
+		cookie.Expires = float64(expires.Unix())
+	}
+	var url string
+	if cookie.Domain == "" {
+		if err := chromedp.Run(ctx, chromedp.Evaluate("location.origin + "+strconv.Quote(cookie.Path), &url)); err != nil {
+			fail(err)
+		}
+		if !strings.HasPrefix(url, "http") {
+			fail(fmt.Errorf("the tab is on %s, pass --domain", url))
+		}
+	}
+	if err := lib.SetCookies(ctx, []lib.Cookie{cookie}, url); err != nil {
+		fail(err)
+	}
+	// Chrome silently drops cookies it rejects, such as SameSite=None
+	// without --secure, so read it back
+	if len(matching(ctx, cookie.Name, cookie.Domain)) == 0 {
+		fail(fmt.Errorf("chrome rejected cookie %q (SameSite=None needs --secure)", cookie.Name))
+	}
+	fmt.Printf("set %s\n", cookie.Name)
+}
+
+func remove(ctx context.Context, args cookiesArgs) {
+	found := matching(ctx, args.Args[0], args.Domain)
+	if args.Path != "" {
+		var onPath []lib.Cookie
+		for _, c := range found {
+			if c.Path == args.Path {
+				onPath = append(onPath, c)
+			}
+		}
+		found = onPath
+	}
+	if len(found) == 0 {
+		fail(fmt.Errorf("no cookie named %q", args.Args[0]))
+	}
+	if err := lib.DeleteCookies(ctx, found); err != nil {
+		fail(err)
+	}
+	fmt.Printf("deleted %d cookie(s)\n", len(found))
+}
+
+func export(ctx context.Context, args cookiesArgs) {
+	format := args.Format
+	if format == "" {
+		format = "json"
+	}
+	data, err := lib.FormatCookies(matching(ctx, "", args.Domain), format)
+	if err != nil {
+		fail(err)
+	}
+	if args.Output == "" || args.Output == "-" {
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+	// Cookies are credentials
+	if err := os.WriteFile(args.Output, data, 0600); err != nil {
+		fail(err)
+	}
+	fmt.Printf("exported to %s\n", args.Output)
+}
+
+func load(ctx context.Context, args cookiesArgs) {
+	path := args.Args[0]
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fail(err)
+	}
+	parsed, err := lib.ParseCookies(data, args.Format)
+	if err != nil {
+		fail(fmt.Errorf("parsing %s: %w", path, err))
+	}
+	parsed = lib.FilterCookies(parsed, "", args.Domain)
+	// Expired cookies would be deleted on arrival
+	now := float64(time.Now().Unix())
+	var live []lib.Cookie
+	for _, c := range parsed {
+		if c.Session() || c.Expires > now {
+			live = append(live, c)
+		}
+	}
+	if len(live) > 0 {
+		if err := lib.SetCookies(ctx, live, ""); err != nil {
+			fail(err)
+		}
+	}
+	fmt.Printf("imported %d cookie(s)", len(live))
+	if skipped := len(parsed) - len(live); skipped > 0 {
+		fmt.Printf(", skipped %d expired", skipped)
+	}
+	fmt.Println()
+}
+
+// parseExpires accepts a duration from now or an RFC 3339 time.
+func parseExpires(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--expires must be a duration like 24h or an RFC 3339 time, got %q", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,110 @@
+// dismissbanners accepts or rejects cookie consent banners.
+package dismissbanners
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["dismiss-banners"] = dismissBanners
+	lib.Args["dismiss-banners"] = dismissArgs{}
+}
+
+type dismissArgs struct {
+	lib.TargetArgs
+	Reject  bool `arg:"--reject" help:"click reject (or necessary only) instead of accept"`
+	Wait    int  `arg:"-w,--wait" default:"5" help:"seconds to wait for a banner to appear"`
+	Require bool `arg:"--require" help:"exit 1 when no banner is dismissed"`
+	JSON    bool `arg:"--json" help:"print the result as JSON"`
+	List    bool `arg:"--list" help:"list the recognized consent platforms"`
+}
+
+func (dismissArgs) Description() string {
+	return `dismiss-banners - Accept or reject cookie consent banners
+
+Looks for a cookie banner and clicks its accept button, or its reject
+button with --reject. Known consent-management platforms (OneTrust,
+Cookiebot, Didomi, Quantcast, Usercentrics, TrustArc, Osano, CookieYes,
+and more, see --list) are matched by selector first; otherwise buttons
+reading "Accept all", "Reject all", "Only necessary", and their German,
+French, Spanish, Italian, Dutch, and Portuguese equivalents are clicked
+inside elements that look like cookie banners.
+
+Searches the page, open shadow roots, and same-origin iframes, polling
+for up to --wait seconds since banners often load late. Banners in
+cross-origin iframes (some Sourcepoint and TrustArc setups) are not
+reached. With --reject, a banner without a reject button is left alone,
+never accepted.
+
+Finding no banner is not an error unless --require is set, so it can
+start every flow.
+
+Example:
+  chrome navigate https://example.com && chrome dismiss-banners
+  chrome dismiss-banners --reject
+  chrome dismiss-banners --wait 10 --require --json
+  chrome dismiss-banners --list`
+}
+
+func dismissBanners() {
+	var args dismissArgs
+	arg.MustParse(&args)
+
+	if args.List {
+		for _, p := range lib.ConsentPlatforms {
+			fmt.Printf("%s\t%s\n", p.Name, p.Banner)
+		}
+		return
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(lib.DefaultTimeout + time.Duration(args.Wait)*time.Second)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	res, err := lib.DismissBanners(targetCtx, args.Reject, time.Duration(args.Wait)*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if args.JSON {
+		jsonBytes, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(jsonBytes))
+	} else {
+		platform := res.Platform
+		if platform == "" {
+			platform = "generic"
+		}
+		switch {
+		case res.Dismissed:
+			fmt.Printf("%s: clicked %q (%s)", platform, res.Text, res.Selector)
+			if !res.Hidden {
+				fmt.Printf(", banner still visible")
+			}
+			fmt.Println()
+		case res.Found:
+			fmt.Printf("%s: banner found but no %s button\n", platform, res.Action)
+		default:
+			fmt.Println("no cookie banner found")
+		}
+	}
+	if args.Require && !res.Dismissed {
+		lib.Exit(1)
+	}
+}
@@ -0,0 +1,285 @@
+// doctor diagnoses common setup problems and prints fixes.
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["doctor"] = doctor
+	lib.Args["doctor"] = doctorArgs{}
+}
+
+type doctorArgs struct {
+	Fix bool `arg:"--fix" help:"remove stale instance metadata"`
+}
+
+func (doctorArgs) Description() string {
+	return `doctor - Diagnose setup problems
+
+Checks Chrome discovery, debug port reachability, WSL networking and path
+assumptions, ffmpeg (for slideshow), shots directory writability, and
+instance metadata left behind by Chrome processes that have exited. Each
+problem is printed with a suggested fix.
+
+Exits 1 if any check fails; warnings do not affect the exit code.
+
+Example:
+  chrome doctor
+  chrome -p 9223 doctor
+  chrome doctor --fix            # Also remove stale instance metadata`
+}
+
+type check struct {
+	status string // ok, warn, or fail
+	name   string
+	detail string
+	fix    string
+}
+
+func doctor() {
+	var args doctorArgs
+	arg.MustParse(&args)
+
+	checks := []check{checkChromePath(), checkDebugPort()}
+	if lib.IsWSL() {
+		checks = append(checks, checkWSL())
+	}
+	checks = append(checks, checkFFmpeg(), checkShotsDir(), checkInstances(args.Fix))
+
+	failed := 0
+	for _, c := range checks {
+		fmt.Printf("%-5s %-10s %s\n", c.status, c.name, c.detail)
+		if c.fix != "" && c.status != "ok" {
+			for _, line := range strings.Split(c.fix, "\n") {
+				fmt.Printf("      %-10s %s\n", "", line)
+			}
+		}
+		if c.status == "fail" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "error: %d check(s) failed\n", failed)
+		lib.Exit(1)
+	}
+}
+
+func checkChromePath() check {
+	c := check{name: "chrome"}
+	if p := strings.TrimSpace(os.Getenv("CHROME_PATH")); p != "" {
+		if _, err := os.Stat(p); err != nil {
+			c.status = "fail"
+			c.detail = fmt.Sprintf("CHROME_PATH=%s does not exist", p)
+			c.fix = "point CHROME_PATH at the Chrome executable, or unset it to search the usual locations"
+			return c
+		}
+	}
+	path := lib.FindChrome()
+	if path == "" {
+		c.status = "fail"
+		c.detail = "no Chrome or Chromium executable found"
+		c.fix = chromeInstallHint() + "\nor set CHROME_PATH to the executable"
+		return c
+	}
+	c.status = "ok"
+	c.detail = path
+	return c
+}
+
+func checkDebugPort() check {
+	port := lib.GetPort()
+	c := check{name: "port"}
+	client := http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("%s/json/version", lib.ChromeURL()))
+	if err == nil {
+		defer func() { _ = resp.Body.Close() }()
+		var version struct {
+			Browser string `json:"Browser"`
+		}
+		if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&version) == nil {
+			c.status = "ok"
+			c.detail = fmt.Sprintf("%s on port %d", version.Browser, port)
+			if pages, err := lib.PageTargets(); err == nil {
+				c.detail += fmt.Sprintf(", %d tab(s)", len(pages))
+			}
+			return c
+		}
+	}
+
+	conn, dialErr := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", port), time.Second)
+	if dialErr == nil {
+		_ = conn.Close()
+		c.status = "fail"
+		c.detail = fmt.Sprintf("port %d is open but is not a Chrome debug endpoint", port)
+		c.fix = fmt.Sprintf("another process owns port %d; launch Chrome elsewhere:\nchrome launch --port %d && export CHROME_PORT=%d", port, port+1, port+1)
+		return c
+	}
+
+	c.status = "warn"
+	c.detail = fmt.Sprintf("nothing listening on port %d (commands fall back to a throwaway headless Chrome)", port)
+	c.fix = "chrome launch"
+	if port != lib.DefaultPort {
+		c.fix = fmt.Sprintf("chrome launch --port %d", port)
+	}
+	if running, _, _ := scanInstances(); len(running) > 0 {
+		c.fix += fmt.Sprintf("\nor use a running instance: chrome -p %d list", running[0])
+	}
+	if lib.IsWSL() {
+		c.fix += "\nif Chrome is already running on Windows, see the wsl check below"
+	}
+	return c
+}
+
+func checkWSL() check {
+	c := check{name: "wsl", status: "ok"}
+	var problems, fixes []string
+
+	path := lib.FindChrome()
+	if path != "" && !strings.HasSuffix(strings.ToLower(path), ".exe") {
+		problems = append(problems, fmt.Sprintf("using Linux Chrome %s inside WSL", path))
+		fixes = append(fixes, "install Chrome on Windows, or set CHROME_PATH=/mnt/c/.../chrome.exe")
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".exe") && !lib.IsChromeRunningOnPort(lib.GetPort()) {
+		// Windows Chrome binds 127.0.0.1 on the Windows side; NAT-mode WSL
+		// only sees it with localhost forwarding or mirrored networking
+		problems = append(problems, "debug port not reachable from WSL")
+		fixes = append(fixes, `if Chrome is running on Windows, in %UserProfile%\.wslconfig set [wsl2] networkingMode=mirrored (or localhostForwarding=true), then: wsl --shutdown`)
+	}
+	if len(problems) == 0 {
+		c.detail = fmt.Sprintf("Windows Chrome, profile %s", lib.DefaultUserDataDir())
+		return c
+	}
+	c.status = "warn"
+	c.detail = strings.Join(problems, "; ")
+	c.fix = strings.Join(fixes, "\n") + "\n--user-data-dir must be a Windows path, e.g. C:\\temp\\chrome-myprofile"
+	return c
+}
+
+func checkFFmpeg() check {
+	c := check{name: "ffmpeg"}
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		c.status = "warn"
+		c.detail = "ffmpeg not found in PATH (needed only by slideshow)"
+		c.fix = "sudo apt install ffmpeg"
+		if runtime.GOOS == "darwin" {
+			c.fix = "brew install ffmpeg"
+		}
+		return c
+	}
+	c.status = "ok"
+	c.detail = path
+	return c
+}
+
+func checkShotsDir() check {
+	c := check{name: "shots"}
+	dir, err := lib.PrepareShotsDir("")
+	if err == nil {
+		var f *os.File
+		f, err = os.CreateTemp(dir, ".doctor-*")
+		if err == nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		c.status = "fail"
+		c.detail = fmt.Sprintf("%s is not writable: %v", lib.DefaultShotsDir(), err)
+		c.fix = "fix its permissions, or pass -o/--output-dir to screenshot and step"
+		return c
+	}
+	c.status = "ok"
+	c.detail = dir
+	return c
+}
+
+func checkInstances(fix bool) check {
+	c := check{name: "instances"}
+	running, stale, err := scanInstances()
+	if err != nil {
+		c.status = "warn"
+		c.detail = fmt.Sprintf("cannot read %s: %v", lib.InstanceMetadataDir(), err)
+		return c
+	}
+	if len(stale) == 0 {
+		c.status = "ok"
+		c.detail = fmt.Sprintf("%d running, none stale", len(running))
+		return c
+	}
+	if fix {
+		for _, path := range stale {
+			if err := os.Remove(path); err != nil {
+				c.status = "warn"
+				c.detail = fmt.Sprintf("failed to remove %s: %v", path, err)
+				return c
+			}
+		}
+		c.status = "ok"
+		c.detail = fmt.Sprintf("%d running, removed %d stale", len(running), len(stale))
+		return c
+	}
+	c.status = "warn"
+	c.detail = fmt.Sprintf("%d running, %d stale: %s", len(running), len(stale), strings.Join(stale, ", "))
+	c.fix = "chrome doctor --fix"
+	return c
+}
+
+// scanInstances splits instance metadata into ports whose Chrome still
+// answers and files left behind by exited or unreadable instances. Unlike
+// lib.ListInstances it does not delete anything.
+func scanInstances() ([]int, []string, error) {
+	dir := lib.InstanceMetadataDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	var running []int
+	var stale []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		port, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			stale = append(stale, filepath.Join(dir, entry.Name()))
+			continue
+		}
+		if _, err := lib.ReadInstanceMetadata(port); err != nil || !lib.IsChromeRunningOnPort(port) {
+			stale = append(stale, lib.InstanceMetadataPath(port))
+			continue
+		}
+		running = append(running, port)
+	}
+	return running, stale, nil
+}
+
+func chromeInstallHint() string {
+	switch {
+	case runtime.GOOS == "darwin":
+		return "brew install --cask google-chrome"
+	case lib.IsWSL():
+		return "install Google Chrome on Windows: https://www.google.com/chrome/"
+	case runtime.GOOS == "linux":
+		return "install google-chrome-stable or chromium from your package manager"
+	default:
+		return "install Google Chrome: https://www.google.com/chrome/"
+	}
+}
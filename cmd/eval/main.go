@@ -41,7 +41,7 @@ func eval() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
@@ -50,7 +50,7 @@ func eval() {
 	err = chromedp.Run(targetCtx, chromedp.Evaluate(args.Script, &result))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	switch v := result.(type) {
@@ -62,7 +62,7 @@ func eval() {
 		jsonBytes, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+			lib.Exit(1)
 		}
 		fmt.Println(string(jsonBytes))
 	}
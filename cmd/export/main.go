@@ -0,0 +1,478 @@
+// export converts step history or a workflow into Playwright, Puppeteer, or chromedp code.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["export"] = export
+	lib.Args["export"] = exportArgs{}
+}
+
+type exportArgs struct {
+	Source string `arg:"positional" help:"shots directory of StepRecords or a workflow file (default: ~/chrome-shots)"`
+	Format string `arg:"-f,--format,required" help:"playwright, puppeteer, or chromedp"`
+	Output string `arg:"-o,--output" help:"file to write (default: stdout)"`
+	Name   string `arg:"-n,--name" help:"test or script name (default: workflow name or directory name)"`
+}
+
+func (exportArgs) Description() string {
+	return `export - Convert step history or a workflow into script code
+
+Reads the StepRecords in a shots directory (written by step, run, record,
+and serve) or a workflow file for 'chrome run', and prints a runnable
+script for Playwright Test, Puppeteer, or chromedp. Use it to graduate ad
+hoc CLI sessions into maintained test suites.
+
+Steps with no equivalent (e.g. click --index) are emitted as TODO comments.
+
+Example:
+  chrome export -f playwright > tests/login.spec.js
+  chrome export -f puppeteer /tmp/run -o login.js
+  chrome export -f chromedp login.yaml -o main.go`
+}
+
+// entry is one exported step, or a record that could not be converted.
+type entry struct {
+	step lib.WorkflowStep
+	todo string
+}
+
+type generator interface {
+	header(name string) []string
+	step(step lib.WorkflowStep) []string
+	footer() []string
+	comment(text string) string
+}
+
+func export() {
+	var args exportArgs
+	arg.MustParse(&args)
+
+	gen, ok := map[string]generator{
+		"playwright": playwright{},
+		"puppeteer":  puppeteer{},
+		"chromedp":   &chromedpGen{uses: map[string]bool{}},
+	}[args.Format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown format %q, expected playwright, puppeteer, or chromedp\n", args.Format)
+		lib.Exit(1)
+	}
+
+	name, entries, err := load(args.Source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if args.Name != "" {
+		name = args.Name
+	}
+
+	// chromedp declares helpers and imports based on the steps it sees
+	if g, ok := gen.(*chromedpGen); ok {
+		for _, e := range entries {
+			if e.todo == "" {
+				g.uses[e.step.Action] = true
+			}
+		}
+	}
+
+	var lines []string
+	lines = append(lines, gen.header(name)...)
+	for _, e := range entries {
+		if e.todo != "" {
+			lines = append(lines, gen.comment("TODO: "+e.todo))
+			continue
+		}
+		lines = append(lines, gen.step(e.step)...)
+	}
+	lines = append(lines, gen.footer()...)
+	code := strings.Join(lines, "\n") + "\n"
+
+	if args.Output == "" || args.Output == "-" {
+		fmt.Print(code)
+		return
+	}
+	if err := os.WriteFile(args.Output, []byte(code), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", args.Output)
+}
+
+func load(source string) (string, []entry, error) {
+	if source == "" {
+		source = lib.DefaultShotsDir()
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", nil, err
+	}
+	if !info.IsDir() {
+		wf, err := lib.LoadWorkflow(source)
+		if err != nil {
+			return "", nil, err
+		}
+		var entries []entry
+		for _, step := range wf.Steps {
+			if step.Action == "if" || step.Action == "loop" {
+				entries = append(entries, entry{todo: fmt.Sprintf("%s %s (control flow is not exported)", step.Action, strings.Join(step.Describe(), " "))})
+				continue
+			}
+			entries = append(entries, entry{step: step})
+		}
+		return wf.Name, entries, nil
+	}
+
+	records, err := lib.LoadStepRecordsFromDir(source)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(records) == 0 {
+		return "", nil, fmt.Errorf("no step records found in %s", source)
+	}
+	var entries []entry
+	for _, record := range records {
+		step, err := lib.StepFromRecord(record)
+		if err != nil {
+			entries = append(entries, entry{todo: fmt.Sprintf("%s %s (%v)", record.Action, strings.Join(record.Args, " "), err)})
+			continue
+		}
+		entries = append(entries, entry{step: step})
+	}
+	return "steps", entries, nil
+}
+
+// responseBudget describes a response assertion's latency budget, if any.
+func responseBudget(step lib.WorkflowStep) string {
+	if step.MaxMs > 0 {
+		return " within " + strconv.Itoa(step.MaxMs) + "ms"
+	}
+	return ""
+}
+
+// js quotes s as a JavaScript string literal.
+func js(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func screenshotPath(step lib.WorkflowStep) string {
+	label := step.Label
+	if label == "" {
+		label = "screenshot"
+	}
+	return label + ".png"
+}
+
+type playwright struct{}
+
+func (playwright) comment(text string) string { return "  // " + text }
+
+func (playwright) header(name string) []string {
+	return []string{
+		"const { test, expect } = require('@playwright/test');",
+		"",
+		"test(" + js(name) + ", async ({ page }) => {",
+	}
+}
+
+func (playwright) footer() []string { return []string{"});"} }
+
+func (playwright) step(step lib.WorkflowStep) []string {
+	switch step.Action {
+	case "navigate":
+		return []string{"  await page.goto(" + js(step.URL) + ");"}
+	case "click":
+		return []string{"  await page.locator(" + js(step.Selector) + ").first().click();"}
+	case "clicktext":
+		selector := step.Selector
+		if selector == "" {
+			selector = "button, a, [role='button']"
+		}
+		return []string{"  await page.locator(" + js(selector) + ").filter({ hasText: " + js(step.Text) + " }).first().click();"}
+	case "fill":
+		return []string{"  await page.locator(" + js(step.Selector) + ").fill(" + js(step.Value) + ");"}
+	case "type":
+		return []string{
+			"  await page.locator(" + js(step.Selector) + ").clear();",
+			"  await page.locator(" + js(step.Selector) + ").pressSequentially(" + js(step.Text) + ");",
+		}
+	case "waitfor":
+		return []string{"  await page.locator(" + js(step.Selector) + ").first().waitFor();"}
+	case "wait":
+		return []string{"  await page.getByText(" + js(step.Text) + ").first().waitFor();"}
+	case "eval":
+		return []string{"  await page.evaluate(" + js(step.Script) + ");"}
+	case "assert":
+		var lines []string
+		scope := "page.locator('body')"
+		if step.Selector != "" {
+			scope = "page.locator(" + js(step.Selector) + ").first()"
+			lines = append(lines, "  await expect("+scope+").toBeAttached();")
+		}
+		if step.Text != "" {
+			lines = append(lines, "  await expect("+scope+").toContainText("+js(step.Text)+");")
+		}
+		if step.URL != "" {
+			lines = append(lines, "  expect(page.url().startsWith("+js(step.URL)+")).toBeTruthy();")
+		}
+		if step.Title != "" {
+			lines = append(lines, "  await expect(page).toHaveTitle("+js(step.Title)+");")
+		}
+		if step.Script != "" {
+			lines = append(lines, "  expect(await page.evaluate("+js(step.Script)+")).toBeTruthy();")
+		}
+		if step.Response != "" {
+			lines = append(lines, "  // TODO: assert response "+step.Response+responseBudget(step))
+		}
+		return lines
+	case "screenshot":
+		if step.Selector != "" {
+			return []string{"  await page.locator(" + js(step.Selector) + ").first().screenshot({ path: " + js(screenshotPath(step)) + " });"}
+		}
+		return []string{"  await page.screenshot({ path: " + js(screenshotPath(step)) + " });"}
+	case "sleep":
+		return []string{"  await page.waitForTimeout(" + strconv.Itoa(step.Ms) + ");"}
+	}
+	return []string{"  // TODO: " + step.Action}
+}
+
+type puppeteer struct{}
+
+func (puppeteer) comment(text string) string { return "  // " + text }
+
+func (puppeteer) header(name string) []string {
+	return []string{
+		"// " + name,
+		"const assert = require('assert');",
+		"const puppeteer = require('puppeteer');",
+		"",
+		"(async () => {",
+		"  const browser = await puppeteer.launch();",
+		"  const page = await browser.newPage();",
+	}
+}
+
+func (puppeteer) footer() []string {
+	return []string{"  await browser.close();", "})();"}
+}
+
+func (puppeteer) step(step lib.WorkflowStep) []string {
+	switch step.Action {
+	case "navigate":
+		return []string{"  await page.goto(" + js(step.URL) + ");"}
+	case "click":
+		return []string{"  await page.click(" + js(step.Selector) + ");"}
+	case "clicktext":
+		selector := step.Selector
+		if selector == "" {
+			selector = "button, a, [role='button']"
+		}
+		return []string{
+			"  await page.$$eval(" + js(selector) + ", (els, text) => {",
+			"    const el = els.find((n) => (n.textContent || '').trim() === text);",
+			"    if (!el) throw new Error('no element with text ' + text);",
+			"    el.click();",
+			"  }, " + js(step.Text) + ");",
+		}
+	case "fill":
+		return []string{"  await page.locator(" + js(step.Selector) + ").fill(" + js(step.Value) + ");"}
+	case "type":
+		return []string{
+			"  await page.click(" + js(step.Selector) + ", { count: 3 });",
+			"  await page.type(" + js(step.Selector) + ", " + js(step.Text) + ");",
+		}
+	case "waitfor":
+		return []string{"  await page.waitForSelector(" + js(step.Selector) + ", { visible: true });"}
+	case "wait":
+		return []string{"  await page.waitForFunction((text) => document.body && document.body.innerText.includes(text), {}, " + js(step.Text) + ");"}
+	case "eval":
+		return []string{"  await page.evaluate(" + js(step.Script) + ");"}
+	case "assert":
+		var lines []string
+		if step.Selector != "" || step.Text != "" {
+			selector := step.Selector
+			if selector == "" {
+				selector = "body"
+			}
+			lines = append(lines, "  {",
+				"    const text = await page.$eval("+js(selector)+", (el) => el.innerText || el.textContent || '');")
+			if step.Text != "" {
+				lines = append(lines, "    assert.ok(text.includes("+js(step.Text)+"), 'text not found');")
+			}
+			lines = append(lines, "  }")
+		}
+		if step.URL != "" {
+			lines = append(lines, "  assert.ok(page.url().startsWith("+js(step.URL)+"), 'unexpected url ' + page.url());")
+		}
+		if step.Title != "" {
+			lines = append(lines, "  assert.strictEqual(await page.title(), "+js(step.Title)+");")
+		}
+		if step.Script != "" {
+			lines = append(lines, "  assert.ok(await page.evaluate("+js(step.Script)+"));")
+		}
+		if step.Response != "" {
+			lines = append(lines, "  // TODO: assert response "+step.Response+responseBudget(step))
+		}
+		return lines
+	case "screenshot":
+		if step.Selector != "" {
+			return []string{"  await (await page.waitForSelector(" + js(step.Selector) + ")).screenshot({ path: " + js(screenshotPath(step)) + " });"}
+		}
+		return []string{"  await page.screenshot({ path: " + js(screenshotPath(step)) + " });"}
+	case "sleep":
+		return []string{"  await new Promise((resolve) => setTimeout(resolve, " + strconv.Itoa(step.Ms) + "));"}
+	}
+	return []string{"  // TODO: " + step.Action}
+}
+
+// chromedpGen emits a Go program. uses records which actions appear so only
+// the needed helpers and imports are declared.
+type chromedpGen struct {
+	uses map[string]bool
+}
+
+func (g *chromedpGen) comment(text string) string { return "\t\t// " + text }
+
+func (g *chromedpGen) header(name string) []string {
+	imports := []string{`"context"`}
+	if g.uses["assert"] || g.uses["clicktext"] {
+		imports = append(imports, `"errors"`)
+	}
+	imports = append(imports, `"log"`)
+	if g.uses["screenshot"] {
+		imports = append(imports, `"os"`)
+	}
+	imports = append(imports, `"time"`, "", `"github.com/chromedp/chromedp"`)
+	lines := []string{"// " + name, "package main", "", "import ("}
+	for _, imp := range imports {
+		if imp == "" {
+			lines = append(lines, "")
+		} else {
+			lines = append(lines, "\t"+imp)
+		}
+	}
+	return append(lines, ")", "",
+		"func main() {",
+		"\tctx, cancel := chromedp.NewContext(context.Background())",
+		"\tdefer cancel()",
+		"\tctx, cancel = context.WithTimeout(ctx, 2*time.Minute)",
+		"\tdefer cancel()",
+		"",
+		"\terr := chromedp.Run(ctx,",
+	)
+}
+
+func (g *chromedpGen) footer() []string {
+	lines := []string{"\t)", "\tif err != nil {", "\t\tlog.Fatal(err)", "\t}", "}"}
+	if g.uses["clicktext"] || g.uses["assert"] {
+		lines = append(lines, "",
+			"// check evaluates script and fails with msg unless it returns true.",
+			"func check(script string, msg string) chromedp.Action {",
+			"\treturn chromedp.ActionFunc(func(ctx context.Context) error {",
+			"\t\tvar ok bool",
+			"\t\tif err := chromedp.Evaluate(script, &ok).Do(ctx); err != nil {",
+			"\t\t\treturn err",
+			"\t\t}",
+			"\t\tif !ok {",
+			"\t\t\treturn errors.New(msg)",
+			"\t\t}",
+			"\t\treturn nil",
+			"\t})",
+			"}",
+		)
+	}
+	if g.uses["screenshot"] {
+		lines = append(lines, "",
+			"// screenshot saves the viewport, or only the element matching selector.",
+			"func screenshot(path string, selector string) chromedp.Action {",
+			"\treturn chromedp.ActionFunc(func(ctx context.Context) error {",
+			"\t\tvar buf []byte",
+			"\t\tcapture := chromedp.CaptureScreenshot(&buf)",
+			"\t\tif selector != \"\" {",
+			"\t\t\tcapture = chromedp.Screenshot(selector, &buf, chromedp.ByQuery)",
+			"\t\t}",
+			"\t\tif err := capture.Do(ctx); err != nil {",
+			"\t\t\treturn err",
+			"\t\t}",
+			"\t\treturn os.WriteFile(path, buf, 0644)",
+			"\t})",
+			"}",
+		)
+	}
+	return lines
+}
+
+func (g *chromedpGen) step(step lib.WorkflowStep) []string {
+	q := strconv.Quote
+	switch step.Action {
+	case "navigate":
+		return []string{"\t\tchromedp.Navigate(" + q(step.URL) + "),"}
+	case "click":
+		return []string{"\t\tchromedp.Click(" + q(step.Selector) + ", chromedp.ByQuery),"}
+	case "clicktext":
+		selector := step.Selector
+		if selector == "" {
+			selector = "button, a, [role='button']"
+		}
+		script := "(() => { const el = Array.from(document.querySelectorAll(" + js(selector) + ")).find(n => (n.textContent || '').trim() === " + js(step.Text) + "); if (!el) return false; el.click(); return true; })()"
+		return []string{"\t\tcheck(" + q(script) + ", " + q("no element with text "+step.Text) + "),"}
+	case "fill":
+		return []string{
+			"\t\tchromedp.Clear(" + q(step.Selector) + ", chromedp.ByQuery),",
+			"\t\tchromedp.SendKeys(" + q(step.Selector) + ", " + q(step.Value) + ", chromedp.ByQuery),",
+		}
+	case "type":
+		return []string{
+			"\t\tchromedp.Clear(" + q(step.Selector) + ", chromedp.ByQuery),",
+			"\t\tchromedp.SendKeys(" + q(step.Selector) + ", " + q(step.Text) + ", chromedp.ByQuery),",
+		}
+	case "waitfor":
+		return []string{"\t\tchromedp.WaitVisible(" + q(step.Selector) + ", chromedp.ByQuery),"}
+	case "wait":
+		return []string{"\t\tchromedp.Poll(" + q("document.body && document.body.innerText.includes("+js(step.Text)+")") + ", nil),"}
+	case "eval":
+		return []string{"\t\tchromedp.Evaluate(" + q(step.Script) + ", nil),"}
+	case "assert":
+		var lines []string
+		scope := "document.body"
+		if step.Selector != "" {
+			scope = "document.querySelector(" + js(step.Selector) + ")"
+			lines = append(lines, "\t\tcheck("+q("!!"+scope)+", "+q("no element matches "+step.Selector)+"),")
+		}
+		if step.Text != "" {
+			lines = append(lines, "\t\tcheck("+q("("+scope+".innerText || '').includes("+js(step.Text)+")")+", "+q("text not found: "+step.Text)+"),")
+		}
+		if step.URL != "" {
+			lines = append(lines, "\t\tcheck("+q("location.href.startsWith("+js(step.URL)+")")+", "+q("url does not start with "+step.URL)+"),")
+		}
+		if step.Title != "" {
+			lines = append(lines, "\t\tcheck("+q("document.title === "+js(step.Title))+", "+q("title is not "+step.Title)+"),")
+		}
+		if step.Script != "" {
+			lines = append(lines, "\t\tcheck("+q("!!("+step.Script+")")+", "+q("script is falsy")+"),")
+		}
+		if step.Response != "" {
+			lines = append(lines, "\t\t// TODO: assert response "+step.Response+responseBudget(step))
+		}
+		return lines
+	case "screenshot":
+		return []string{"\t\tscreenshot(" + q(screenshotPath(step)) + ", " + q(step.Selector) + "),"}
+	case "sleep":
+		return []string{"\t\tchromedp.Sleep(" + strconv.Itoa(step.Ms) + "*time.Millisecond),"}
+	}
+	return []string{"\t\t// TODO: " + step.Action}
+}
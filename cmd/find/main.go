@@ -0,0 +1,239 @@
+// find locates text in the rendered page and reports where each occurrence is.
+package find
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["find"] = find
+	lib.Args["find"] = findArgs{}
+}
+
+type findArgs struct {
+	lib.TargetArgs
+	Text       string `arg:"positional,required" help:"text to find, or a JavaScript regular expression with --regex"`
+	Regex      bool   `arg:"-r,--regex" help:"treat TEXT as a JavaScript regular expression"`
+	IgnoreCase bool   `arg:"-i,--ignore-case" help:"match case-insensitively"`
+	Selector   string `arg:"--selector" default:"body" help:"CSS selector to limit the search to"`
+	All        bool   `arg:"-a,--all" help:"include text that is not rendered (display:none, visibility:hidden, zero size)"`
+	Index      int    `arg:"--index" default:"0" help:"which occurrence --scroll and --highlight act on (0-based)"`
+	Scroll     bool   `arg:"--scroll" help:"scroll the occurrence to the center of the viewport"`
+	Highlight  bool   `arg:"--highlight" help:"draw a box around the occurrence, replacing any earlier one, until the page navigates"`
+	Limit      int    `arg:"--limit" default:"100" help:"report at most this many occurrences, 0 for all"`
+	JSON       bool   `arg:"--json" help:"print occurrences as JSON"`
+}
+
+func (findArgs) Description() string {
+	return `find - Locate text in the rendered page
+
+Searches the text the page renders (not its HTML) and prints every
+occurrence: its index, the selector of the element that contains it, its
+rect in viewport coordinates, and the surrounding text. Occurrences
+outside the viewport are marked offscreen; text that is not rendered is
+skipped unless --all. Exits 1 when nothing matches.
+
+A match must lie within one text node, so text split across elements,
+like "Sign <b>in</b>", is found by its parts, not as a whole.
+
+--scroll and --highlight act on occurrence --index: scroll it into view
+and box it, so a following screenshot shows what was found. Coordinates
+printed with --scroll are after scrolling, ready for clickxy.
+
+Examples:
+  chrome find "Out of stock"
+  chrome find "order #\d+" --regex --json
+  chrome find total -i --selector "#cart"
+  chrome find "Terms of Service" --index 1 --scroll --highlight
+  chrome find "Delete" --scroll --json | jq '.matches[0].rect'`
+}
+
+type rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+type match struct {
+	Index     int    `json:"index"`
+	Text      string `json:"text"`
+	Context   string `json:"context"`
+	Selector  string `json:"selector"`
+	Tag       string `json:"tag"`
+	Visible   bool   `json:"visible"`
+	Offscreen bool   `json:"offscreen"`
+	Rect      rect   `json:"rect"`
+}
+
+type result struct {
+	Error   string  `json:"error"`
+	Count   int     `json:"count"`
+	Matches []match `json:"matches"`
+}
+
+func find() {
+	var args findArgs
+	arg.MustParse(&args)
+
+	flags := "g"
+	if args.IgnoreCase {
+		flags += "i"
+	}
+	pattern := args.Text
+	if !args.Regex {
+		pattern = regexpQuote(pattern)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	script := `(() => {
+	  ` + lib.CSSPathJS + `
+	  let re;
+	  try {
+	    re = new RegExp(` + strconv.Quote(pattern) + `, ` + strconv.Quote(flags) + `);
+	  } catch (e) {
+	    return { error: 'invalid regex: ' + e.message, count: 0, matches: [] };
+	  }
+	  const scope = document.querySelector(` + strconv.Quote(args.Selector) + `);
+	  if (!scope) return { error: 'no element matches selector ' + ` + strconv.Quote(strconv.Quote(args.Selector)) + `, count: 0, matches: [] };
+	  const includeAll = ` + strconv.FormatBool(args.All) + `;
+	  const want = ` + strconv.Itoa(args.Index) + `;
+	  const scroll = ` + strconv.FormatBool(args.Scroll) + `;
+	  const highlight = ` + strconv.FormatBool(args.Highlight) + `;
+	  const limit = ` + strconv.Itoa(args.Limit) + `;
+	  const skip = new Set(['SCRIPT', 'STYLE', 'NOSCRIPT', 'TEMPLATE']);
+	  const walker = document.createTreeWalker(scope, NodeFilter.SHOW_TEXT, {
+	    acceptNode: n => n.parentElement && !skip.has(n.parentElement.tagName) && n.parentElement.id !== '__chrome_find_highlight'
+	      ? NodeFilter.FILTER_ACCEPT : NodeFilter.FILTER_REJECT,
+	  });
+	  const found = [];
+	  let count = 0;
+	  for (let node = walker.nextNode(); node; node = walker.nextNode()) {
+	    const text = node.nodeValue;
+	    re.lastIndex = 0;
+	    let m;
+	    while ((m = re.exec(text)) !== null) {
+	      if (m[0].length === 0) { re.lastIndex++; continue; }
+	      const range = document.createRange();
+	      range.setStart(node, m.index);
+	      range.setEnd(node, m.index + m[0].length);
+	      const r = range.getBoundingClientRect();
+	      const style = getComputedStyle(node.parentElement);
+	      const visible = r.width > 0 && r.height > 0 && style.visibility !== 'hidden';
+	      if (!visible && !includeAll) continue;
+	      const index = count++;
+	      if (limit > 0 && found.length >= limit && index !== want) continue;
+	      found.push({ index, range, visible, text: m[0],
+	        context: (text.slice(Math.max(0, m.index - 30), m.index + m[0].length + 30)).replace(/\s+/g, ' ').trim() });
+	    }
+	  }
+	  const target = found.find(f => f.index === want);
+	  if (target && scroll) {
+	    const el = target.range.startContainer.parentElement;
+	    el.scrollIntoView({ block: 'center', inline: 'center' });
+	    const r = target.range.getBoundingClientRect();
+	    window.scrollBy(r.left + r.width / 2 - innerWidth / 2, r.top + r.height / 2 - innerHeight / 2);
+	  }
+	  if (target && highlight) {
+	    document.getElementById('__chrome_find_highlight')?.remove();
+	    const r = target.range.getBoundingClientRect();
+	    const box = document.createElement('div');
+	    box.id = '__chrome_find_highlight';
+	    Object.assign(box.style, {
+	      position: 'absolute', left: (r.left + scrollX - 3) + 'px', top: (r.top + scrollY - 3) + 'px',
+	      width: (r.width + 6) + 'px', height: (r.height + 6) + 'px', border: '3px solid #e3008c',
+	      borderRadius: '3px', background: 'rgba(255, 230, 0, 0.35)', pointerEvents: 'none',
+	      zIndex: '2147483647', boxSizing: 'border-box',
+	    });
+	    document.documentElement.appendChild(box);
+	  }
+	  const matches = found.map(f => {
+	    const r = f.range.getBoundingClientRect();
+	    const el = f.range.startContainer.parentElement;
+	    return {
+	      index: f.index,
+	      text: f.text,
+	      context: f.context,
+	      selector: cssPath(el),
+	      tag: el.tagName.toLowerCase(),
+	      visible: f.visible,
+	      offscreen: r.bottom < 0 || r.right < 0 || r.top > innerHeight || r.left > innerWidth,
+	      rect: { x: r.x, y: r.y, width: r.width, height: r.height },
+	    };
+	  });
+	  return { error: '', count, matches };
+	})()`
+
+	var res result
+	if err := chromedp.Run(targetCtx, chromedp.Evaluate(script, &res)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if res.Error != "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", res.Error)
+		lib.Exit(1)
+	}
+	if res.Matches == nil {
+		res.Matches = []match{}
+	}
+
+	if args.JSON {
+		data, err := json.MarshalIndent(map[string]any{"text": args.Text, "count": res.Count, "matches": res.Matches}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, m := range res.Matches {
+			var notes string
+			if !m.Visible {
+				notes += " (hidden)"
+			} else if m.Offscreen {
+				notes += " (offscreen)"
+			}
+			fmt.Printf("[%d] %s at %.0f,%.0f %.0fx%.0f%s %q\n", m.Index, m.Selector, m.Rect.X, m.Rect.Y, m.Rect.Width, m.Rect.Height, notes, m.Context)
+		}
+		if len(res.Matches) < res.Count {
+			fmt.Printf("%d more, use --limit 0 to show all\n", res.Count-len(res.Matches))
+		}
+	}
+
+	if res.Count == 0 {
+		fmt.Fprintf(os.Stderr, "error: no occurrence of %q\n", args.Text)
+		lib.Exit(1)
+	}
+	if (args.Scroll || args.Highlight) && args.Index >= res.Count {
+		fmt.Fprintf(os.Stderr, "error: --index %d out of range, found %d occurrences\n", args.Index, res.Count)
+		lib.Exit(1)
+	}
+}
+
+// regexpQuote escapes the characters special in JavaScript regular expressions.
+func regexpQuote(s string) string {
+	var out []rune
+	for _, r := range s {
+		switch r {
+		case '\\', '^', '$', '.', '*', '+', '?', '(', ')', '[', ']', '{', '}', '|', '/':
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,87 @@
+// fixture serves a local directory of static pages for examples and tests.
+package fixture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["fixture"] = fixture
+	lib.Args["fixture"] = fixtureArgs{}
+}
+
+type fixtureArgs struct {
+	Action string `arg:"positional,required" help:"serve"`
+	Dir    string `arg:"positional" help:"directory to serve (default: .)"`
+	Port   int    `arg:"--port" help:"port to listen on (default: a free port)"`
+	Host   string `arg:"--host" default:"127.0.0.1" help:"address to listen on"`
+	SPA    bool   `arg:"--spa" help:"serve index.html for paths without a file, for client-side routing"`
+	Quiet  bool   `arg:"-q,--quiet" help:"don't log requests to stderr"`
+}
+
+func (fixtureArgs) Description() string {
+	return `fixture - Serve a local directory of static pages
+
+  fixture serve [DIR] [--port PORT] [--spa]
+
+Serves DIR over HTTP and prints its URL, so examples, workflows, and
+integration tests can navigate to a known page without the network.
+Files are served uncached, so edits show on the next load. With --spa,
+paths with no file and no extension get DIR/index.html, as single-page
+apps with client-side routing expect; a missing /app.js still 404s.
+
+The URL is the only line on stdout; requests are logged to stderr unless
+--quiet. Serves until Ctrl+C.
+
+Example:
+  chrome fixture serve ./testdata/pages --port 8123
+  chrome fixture serve ./dist --spa -q &`
+}
+
+func fixture() {
+	var args fixtureArgs
+	arg.MustParse(&args)
+
+	if args.Action != "serve" {
+		fmt.Fprintf(os.Stderr, "error: unknown action %q, expected serve\n", args.Action)
+		lib.Exit(1)
+	}
+	dir := args.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if args.Port < 0 || args.Port > 65535 {
+		fmt.Fprintf(os.Stderr, "error: --port must be between 0 and 65535\n")
+		lib.Exit(1)
+	}
+	var log io.Writer = os.Stderr
+	if args.Quiet {
+		log = nil
+	}
+
+	server, err := lib.StartFixtureServer(dir, lib.FixtureOptions{
+		Addr: net.JoinHostPort(args.Host, fmt.Sprint(args.Port)),
+		SPA:  args.SPA,
+		Log:  log,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer func() { _ = server.Close() }()
+
+	fmt.Println(server.URL)
+	if !args.Quiet {
+		fmt.Fprintf(os.Stderr, "serving %s, Ctrl+C to stop\n", server.Dir)
+	}
+	ctx, stop := lib.InterruptContext(context.Background())
+	defer stop()
+	<-ctx.Done()
+}
@@ -0,0 +1,170 @@
+// har records the tab's network traffic to a HAR file.
+package har
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["har"] = har
+	lib.Args["har"] = harArgs{}
+}
+
+type harArgs struct {
+	lib.TargetArgs
+	Output      string   `arg:"-o,--output" help:"output path (default: ~/chrome-shots/<timestamp>-har.har)"`
+	Duration    int      `arg:"-d,--duration" help:"seconds to record (default: until Ctrl+C, COMMAND exits, or --navigate settles)"`
+	Navigate    string   `arg:"-u,--navigate" help:"load this URL and record until the network is idle after the load event"`
+	Idle        int      `arg:"--idle" default:"500" help:"with --navigate, milliseconds without requests that count as idle"`
+	NoBodies    bool     `arg:"--no-bodies" help:"leave response bodies out"`
+	MaxBodySize int64    `arg:"--max-body-size" default:"10485760" help:"leave out response bodies larger than this many bytes, 0 for no limit"`
+	Command     []string `arg:"positional" help:"chrome command and args to record (after --)"`
+}
+
+func (harArgs) Description() string {
+	return `har - Record network traffic to a HAR file
+
+Records every request the tab makes, with request and response headers,
+cookies, query strings, POST bodies, response bodies, redirects, failures,
+and DNS/connect/TLS/send/wait/receive timings, and writes an HTTP Archive
+1.2 file that DevTools, HAR viewers, and chrome perfdiff read.
+
+Recording stops after --duration seconds, on Ctrl+C, when COMMAND (after
+--) exits, or with --navigate when the page has loaded and the network
+has been idle for --idle ms. COMMAND runs against the same tab; har exits
+1 if it fails, after saving the HAR. Requests still in flight when it
+stops are left out.
+
+The file holds cookies and auth headers, so it is written readable only
+by you. Binary bodies are base64.
+
+Example:
+  chrome har --navigate https://example.com -o example.har
+  chrome har -o checkout.har -- run checkout.yaml
+  chrome har -d 30 --no-bodies
+  chrome perfdiff main.har branch.har`
+}
+
+func har() {
+	var args harArgs
+	arg.MustParse(&args)
+
+	if args.Navigate != "" && len(args.Command) > 0 {
+		fmt.Fprintf(os.Stderr, "error: use --navigate or COMMAND, not both\n")
+		lib.Exit(1)
+	}
+	output, err := lib.PrepareOutputPath(args.Output, "", "har", ".har")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error preparing output path: %v\n", err)
+		lib.Exit(1)
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	rec, err := lib.RecordHAR(ctx, lib.HAROptions{Bodies: !args.NoBodies, MaxBodySize: args.MaxBodySize})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	var timeout <-chan time.Time
+	if args.Duration > 0 {
+		timeout = time.After(time.Duration(args.Duration) * time.Second)
+	}
+	finished := make(chan struct{})
+	result := make(chan error, 1)
+	switch {
+	case args.Navigate != "":
+		go func() {
+			defer close(finished)
+			if err := lib.PoliteWait(ctx, args.Navigate); err != nil {
+				result <- err
+				return
+			}
+			navCtx, navCancel := context.WithTimeout(ctx, lib.DefaultTimeout)
+			defer navCancel()
+			if err := chromedp.Run(navCtx, chromedp.Navigate(args.Navigate)); err != nil {
+				result <- err
+				return
+			}
+			rec.WaitIdle(ctx, time.Duration(args.Idle)*time.Millisecond, lib.DefaultTimeout)
+			result <- nil
+		}()
+	case len(args.Command) > 0:
+		go func() {
+			defer close(finished)
+			output, err := lib.RunCommand(tabID, args.Command)
+			_, _ = os.Stdout.Write(output)
+			result <- err
+		}()
+	default:
+		fmt.Fprintf(os.Stderr, "recording, Ctrl+C to stop\n")
+	}
+	interrupted := false
+	select {
+	case <-sig:
+		interrupted = true
+	case <-timeout:
+	case <-finished:
+	}
+
+	data, inFlight := rec.HAR(ctx)
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if err := os.WriteFile(output, append(jsonBytes, '\n'), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	var bytes int64
+	for _, e := range data.Log.Entries {
+		bytes += e.Bytes()
+	}
+	fmt.Printf("har saved: %s (%d requests, %s)", output, len(data.Log.Entries), lib.FormatBytes(bytes))
+	if inFlight > 0 {
+		fmt.Printf(", %d still in flight left out", inFlight)
+	}
+	fmt.Println()
+
+	if interrupted || (args.Navigate == "" && len(args.Command) == 0) {
+		return
+	}
+	// --duration may stop recording before COMMAND finishes
+	if err := <-result; err != nil {
+		label := "navigate " + args.Navigate
+		if len(args.Command) > 0 {
+			label = strings.Join(args.Command, " ")
+		}
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", label, err)
+		lib.Exit(1)
+	}
+}
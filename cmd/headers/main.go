@@ -0,0 +1,162 @@
+// headers sets extra HTTP headers on every request from a tab.
+package headers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["headers"] = headers
+	lib.Args["headers"] = headersArgs{}
+}
+
+type headersArgs struct {
+	lib.TargetArgs
+	Headers []string `arg:"-H,--header,separate" help:"header as \"Name: value\" (repeatable)"`
+	Release bool     `arg:"--release" help:"remove the --header names, or every header when none are given (daemon only)"`
+	Hold    bool     `arg:"--hold" help:"set headers from this process until Ctrl+C even when the daemon is running"`
+	Command []string `arg:"positional" help:"chrome command and args to run with the headers set (after --)"`
+}
+
+func (headersArgs) Description() string {
+	return `headers - Send extra HTTP headers with every request
+
+Sets headers such as Authorization or X-Feature-Flag on every request the
+tab makes, documents and subresources alike, via
+Network.setExtraHTTPHeaders. They replace a header of the same name the
+browser would send.
+
+Chrome drops extra headers when the DevTools connection closes. With
+COMMAND (after --), headers are set while it runs against the same tab and
+headers exits 1 if it fails. Otherwise, when the daemon is running (chrome
+serve), headers are handed to it and stay on the tab for later navigations
+until 'chrome headers --release' or 'chrome state clear'; without it, this
+command holds them until Ctrl+C. For a single load, use 'chrome navigate
+--header'.
+
+Example:
+  chrome headers -H "Authorization: Bearer $TOKEN" -H "X-Feature-Flag: new-nav"
+  chrome headers -H "X-Debug: 1" -- run checkout.yaml
+  chrome headers --release -H X-Debug
+  chrome headers --release`
+}
+
+func headers() {
+	var args headersArgs
+	arg.MustParse(&args)
+
+	if args.Release {
+		release(args)
+		return
+	}
+
+	parsed, err := lib.ParseHeaders(args.Headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if len(parsed) == 0 {
+		fmt.Fprintf(os.Stderr, "error: usage: chrome headers -H \"Name: value\"... [-- COMMAND...]\n")
+		lib.Exit(1)
+	}
+
+	if len(args.Command) == 0 && !args.Hold && lib.DaemonRunning() {
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs), "headers": parsed}
+		if err := lib.DaemonCall("/headers", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Printf("set %d header(s) (held by daemon)\n", len(parsed))
+		return
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	extra := network.Headers{}
+	for name, value := range parsed {
+		extra[name] = value
+	}
+	setCtx, setCancel := context.WithTimeout(ctx, lib.DefaultTimeout)
+	err = chromedp.Run(setCtx, network.Enable(), network.SetExtraHTTPHeaders(extra))
+	setCancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if len(args.Command) > 0 {
+		output, err := lib.RunCommand(tabID, args.Command)
+		_, _ = os.Stdout.Write(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("set %d header(s)\n", len(parsed))
+	fmt.Fprintf(os.Stderr, "holding headers, Ctrl+C to release\n")
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
+
+func release(args headersArgs) {
+	if !lib.DaemonRunning() {
+		fmt.Fprintf(os.Stderr, "error: nothing to release, headers without the daemon (chrome serve) end with the command that set them\n")
+		lib.Exit(1)
+	}
+	target := lib.DaemonTarget(args.TargetArgs)
+	remove := map[string]string{}
+	for _, name := range args.Headers {
+		// Accept a bare name as well as "Name: value"
+		if parsed, err := lib.ParseHeaders([]string{name}); err == nil {
+			for n := range parsed {
+				name = n
+			}
+		}
+		remove[name] = ""
+	}
+	if len(remove) == 0 {
+		var current lib.Overrides
+		if err := lib.DaemonCall("/state", map[string]string{"target": target}, &current); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		for name := range current.Headers {
+			remove[name] = ""
+		}
+	}
+	if len(remove) == 0 {
+		fmt.Println("released 0 header(s)")
+		return
+	}
+	body := map[string]any{"target": target, "headers": remove}
+	if err := lib.DaemonCall("/headers", body, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("released %d header(s)\n", len(remove))
+}
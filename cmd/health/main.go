@@ -0,0 +1,160 @@
+// health reports whether a tab's renderer is alive and how much memory it uses.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/performance"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["health"] = health
+	lib.Args["health"] = healthArgs{}
+}
+
+type healthArgs struct {
+	lib.TargetArgs
+	Timeout int `arg:"--timeout" default:"5" help:"seconds to wait for the renderer to answer"`
+}
+
+func (healthArgs) Description() string {
+	return `health - Report a tab's renderer status and memory
+
+Prints JSON for the targeted tab:
+
+  status      ok, crashed (renderer gone, e.g. out of memory), or
+              unresponsive (no answer within --timeout: hung on a busy
+              main thread, or crashed before this command attached)
+  latency_ms  round trip of a trivial evaluation, a rough measure of how
+              busy the main thread is
+  memory      JS heap used and total (bytes), and live documents, DOM
+              nodes, and event listeners, from Performance.getMetrics
+
+Exits 0 when ok and 3 otherwise. Every command exits 3 when its tab
+crashes while it runs, so scripts can tell a dead tab from a failure.
+
+Example:
+  chrome health
+  chrome -t localhost:3000 health | jq .memory.js_heap_used`
+}
+
+type healthMemory struct {
+	JSHeapUsed  int64 `json:"js_heap_used"`
+	JSHeapTotal int64 `json:"js_heap_total"`
+	Documents   int64 `json:"documents"`
+	Nodes       int64 `json:"nodes"`
+	Listeners   int64 `json:"listeners"`
+}
+
+type healthReport struct {
+	Target    string        `json:"target"`
+	URL       string        `json:"url"`
+	Title     string        `json:"title"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	LatencyMs int64         `json:"latency_ms,omitempty"`
+	Memory    *healthMemory `json:"memory,omitempty"`
+}
+
+func health() {
+	var args healthArgs
+	arg.MustParse(&args)
+
+	id, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if id == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+	report := healthReport{Target: id}
+	if targets, err := lib.FetchTargets(); err == nil {
+		for _, t := range targets {
+			if t.ID == id {
+				report.URL, report.Title = t.URL, t.Title
+			}
+		}
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	defer cancel()
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	checkCtx, checkCancel := context.WithTimeout(targetCtx, time.Duration(args.Timeout)*time.Second)
+	defer checkCancel()
+	err = check(checkCtx, &report)
+	switch {
+	case err == nil:
+		report.Status = "ok"
+	case errors.Is(context.Cause(checkCtx), lib.ErrTabCrashed):
+		report.Status = "crashed"
+		report.Error = lib.CrashMessage
+	case errors.Is(err, context.DeadlineExceeded):
+		report.Status = "unresponsive"
+		report.Error = fmt.Sprintf("no answer within %ds", args.Timeout)
+	default:
+		report.Status = "unresponsive"
+		report.Error = err.Error()
+	}
+	lib.PrintJSONLine(report)
+	if report.Status != "ok" {
+		lib.Exit(lib.ExitCrashed)
+	}
+}
+
+// check attaches, times a trivial evaluation, and reads memory metrics.
+func check(ctx context.Context, report *healthReport) error {
+	if err := chromedp.Run(ctx); err != nil {
+		return err
+	}
+	start := time.Now()
+	var one int
+	if err := chromedp.Run(ctx, chromedp.Evaluate(`1`, &one)); err != nil {
+		return err
+	}
+	report.LatencyMs = time.Since(start).Milliseconds()
+
+	var metrics []*performance.Metric
+	err := chromedp.Run(ctx,
+		performance.Enable(),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			metrics, err = performance.GetMetrics().Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	memory := &healthMemory{}
+	for _, m := range metrics {
+		switch m.Name {
+		case "JSHeapUsedSize":
+			memory.JSHeapUsed = int64(m.Value)
+		case "JSHeapTotalSize":
+			memory.JSHeapTotal = int64(m.Value)
+		case "Documents":
+			memory.Documents = int64(m.Value)
+		case "Nodes":
+			memory.Nodes = int64(m.Value)
+		case "JSEventListeners":
+			memory.Listeners = int64(m.Value)
+		}
+	}
+	report.Memory = memory
+	return nil
+}
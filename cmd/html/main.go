@@ -40,7 +40,7 @@ func html() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
@@ -52,7 +52,7 @@ func html() {
 	var html string
 	if err := chromedp.Run(targetCtx, chromedp.Evaluate(script, &html)); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	fmt.Println(html)
@@ -38,7 +38,7 @@ func listInstances() {
 	instances, err := lib.ListInstances()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	if len(instances) == 0 {
@@ -0,0 +1,98 @@
+// keys sends raw key events to whatever has focus in a tab.
+package keys
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["keys"] = keys
+	lib.Args["keys"] = keysArgs{}
+}
+
+type keysArgs struct {
+	lib.TargetArgs
+	Keys   []string `arg:"positional,required" help:"key sequence tokens, see below"`
+	Delay  int      `arg:"--delay" default:"0" help:"milliseconds to wait after each key press or character"`
+	Hold   int      `arg:"--hold" default:"0" help:"milliseconds between each key's down and up events"`
+	DryRun bool     `arg:"--dry-run" help:"print the parsed sequence as JSON without sending it"`
+}
+
+func (keysArgs) Description() string {
+	return `keys - Send raw key events
+
+Sends Input.dispatchKeyEvent keydown/keypress/keyup events with explicit
+key, code, and key codes to whatever has focus in the tab. No selector is
+looked up and focus is not changed, so it works on canvas-based editors
+(Google Docs-like apps, terminals, games) that listen for key events
+directly and ignore 'chrome type'. Click the canvas first (chrome clickxy)
+if it does not have focus yet.
+
+Tokens:
+  KEY             press a key: a, A, 7, Enter, Escape, ArrowLeft, KeyA, F5
+  MOD+...+KEY     press with modifiers: Ctrl+Shift+z, Meta+Enter, Alt+F4
+  down:KEY        key down only; a held modifier applies to later keys
+  up:KEY          key up only
+  text:STRING     press each character of STRING in turn
+  wait:DURATION   pause, e.g. wait:250ms
+
+Modifiers are Shift, Ctrl, Alt, and Meta (Cmd). Shortcuts with Ctrl, Alt,
+or Meta send no text, as on a real keyboard. Keys still held at the end are
+released. Characters off the US layout in text: are inserted as text.
+
+Example:
+  chrome clickxy 400 300 && chrome keys text:hello Enter
+  chrome keys Ctrl+a Backspace text:"new text"
+  chrome keys down:Shift ArrowRight ArrowRight up:Shift Ctrl+c
+  chrome keys --delay 50 --hold 20 text:ls Enter
+  chrome keys ArrowUp wait:500ms ArrowUp
+  chrome keys --dry-run Ctrl+Shift+z`
+}
+
+func keys() {
+	var args keysArgs
+	arg.MustParse(&args)
+
+	steps, err := lib.ParseKeys(args.Keys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if args.DryRun {
+		jsonBytes, err := json.MarshalIndent(steps, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(jsonBytes))
+		return
+	}
+
+	opts := lib.KeyOptions{
+		Delay: time.Duration(args.Delay) * time.Millisecond,
+		Hold:  time.Duration(args.Hold) * time.Millisecond,
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	sent, err := lib.DispatchKeys(targetCtx, steps, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("sent %d key event(s)\n", sent)
+}
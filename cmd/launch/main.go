@@ -6,7 +6,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 
@@ -24,6 +23,7 @@ func init() {
 type launchArgs struct {
 	Port        int    `arg:"-p,--port" default:"9222" help:"Debug port (default: 9222)"`
 	UserDataDir string `arg:"--user-data-dir" help:"Chrome user data dir (default: ~/.chrome on Unix, C:\\temp\\chrome on WSL)"`
+	NoThrottle  bool   `arg:"--no-throttle" help:"disable background tab timer throttling and renderer backgrounding"`
 }
 
 func (launchArgs) Description() string {
@@ -58,6 +58,7 @@ WSL Note: On WSL, Chrome runs on Windows, so --user-data-dir must be a Windows p
 Example:
   chrome launch
   chrome launch --port 9223 --user-data-dir ~/.chrome-twitter
+  chrome launch --no-throttle                                 # Background tabs run at full speed
   chrome launch --user-data-dir ~/.chrome-myprofile           # Linux/macOS
   chrome launch --user-data-dir 'C:\temp\chrome-myprofile'    # WSL`
 }
@@ -72,18 +73,18 @@ func launchChrome() {
 		fmt.Fprintf(os.Stderr, "  chrome -p %d list              # See open tabs\n", port)
 		fmt.Fprintf(os.Stderr, "  chrome -p %d newtab <url>      # Open new tab\n", port)
 		fmt.Fprintf(os.Stderr, "  chrome -p %d -t <url> <cmd>    # Target existing tab\n", port)
-		os.Exit(0)
+		lib.Exit(0)
 	}
 
-	chromePath := findChrome()
+	chromePath := lib.FindChrome()
 	if chromePath == "" {
 		fmt.Fprintln(os.Stderr, "error: Chrome not found. Install Google Chrome or Chromium.")
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	userDataDir := strings.TrimSpace(args.UserDataDir)
 	if userDataDir == "" {
-		userDataDir = getUserDataDir()
+		userDataDir = lib.DefaultUserDataDir()
 	}
 
 	// Log file is derived from user data dir name
@@ -102,7 +103,7 @@ func launchChrome() {
 	lf, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error opening log file: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer func() { _ = lf.Close() }()
 
@@ -113,6 +114,13 @@ func launchChrome() {
 		"--no-first-run",
 		"--no-default-browser-check",
 	}
+	if args.NoThrottle {
+		chromeArgs = append(chromeArgs,
+			"--disable-background-timer-throttling",
+			"--disable-backgrounding-occluded-windows",
+			"--disable-renderer-backgrounding",
+		)
+	}
 
 	cmd := exec.Command(chromePath, chromeArgs...)
 	cmd.Stdout = lf
@@ -120,7 +128,7 @@ func launchChrome() {
 
 	if err := cmd.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "error launching Chrome: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	pid := 0
@@ -154,93 +162,3 @@ func launchChrome() {
 	fmt.Println()
 	fmt.Fprintf(os.Stderr, "warning: Chrome may still be starting. Check %s for details.\n", logFile)
 }
-
-// findChrome locates the Chrome executable based on the current platform.
-func findChrome() string {
-	// Check CHROME_PATH env var first.
-	if p := strings.TrimSpace(os.Getenv("CHROME_PATH")); p != "" {
-		if _, err := os.Stat(p); err == nil {
-			return p
-		}
-	}
-
-	var candidates []string
-
-	switch runtime.GOOS {
-	case "darwin":
-		candidates = []string{
-			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
-			"/Applications/Chromium.app/Contents/MacOS/Chromium",
-			"/Applications/Google Chrome Canary.app/Contents/MacOS/Google Chrome Canary",
-		}
-	case "linux":
-		if isWSL() {
-			candidates = []string{
-				"/mnt/c/Program Files/Google/Chrome/Application/chrome.exe",
-				"/mnt/c/Program Files (x86)/Google/Chrome/Application/chrome.exe",
-			}
-		}
-		candidates = append(candidates,
-			"/usr/bin/google-chrome",
-			"/usr/bin/google-chrome-stable",
-			"/usr/bin/chromium",
-			"/usr/bin/chromium-browser",
-			"/snap/bin/chromium",
-		)
-	case "windows":
-		candidates = []string{
-			filepath.Join(os.Getenv("PROGRAMFILES"), "Google", "Chrome", "Application", "chrome.exe"),
-			filepath.Join(os.Getenv("PROGRAMFILES(X86)"), "Google", "Chrome", "Application", "chrome.exe"),
-			filepath.Join(os.Getenv("LOCALAPPDATA"), "Google", "Chrome", "Application", "chrome.exe"),
-		}
-	}
-
-	for _, p := range candidates {
-		if p == "" {
-			continue
-		}
-		if _, err := os.Stat(p); err == nil {
-			return p
-		}
-	}
-
-	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome"} {
-		if p, err := exec.LookPath(name); err == nil {
-			return p
-		}
-	}
-
-	return ""
-}
-
-// getUserDataDir returns the user data directory for Chrome.
-func getUserDataDir() string {
-	if isWSL() {
-		return "C:\\temp\\chrome"
-	}
-
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return filepath.Join(os.TempDir(), "chrome")
-	}
-	return filepath.Join(home, ".chrome")
-}
-
-// isWSL detects if running under Windows Subsystem for Linux.
-func isWSL() bool {
-	if runtime.GOOS != "linux" {
-		return false
-	}
-
-	if _, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop"); err == nil {
-		return true
-	}
-
-	data, err := os.ReadFile("/proc/version")
-	if err != nil {
-		return false
-	}
-
-	s := string(data)
-	return strings.Contains(s, "Microsoft") || strings.Contains(s, "WSL")
-}
@@ -15,6 +15,7 @@ func init() {
 }
 
 type listArgs struct {
+	IncludeExtensions bool `arg:"-e,--include-extensions" help:"also list extension background pages and service workers"`
 }
 
 func (listArgs) Description() string {
@@ -22,17 +23,24 @@ func (listArgs) Description() string {
 
 Lists all open tabs in Chrome (external mode only).
 
+With --include-extensions (or the global flag, or CHROME_INCLUDE_EXTENSIONS=1)
+extension background pages and service workers are listed after the tabs,
+marked with their type. Select one with -t chrome-extension://<id> or its ID
+prefix, given the global --include-extensions, to eval or read its console.
+
 Example:
-  chrome list`
+  chrome list
+  chrome list --include-extensions
+  chrome --include-extensions -t chrome-extension://abcdef eval "chrome.runtime.id"`
 }
 
 func list() {
 	var args listArgs
 	arg.MustParse(&args)
 
-	err := lib.ListTabs()
+	err := lib.ListTabs(args.IncludeExtensions || lib.IncludeExtensions())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 }
\ No newline at end of file
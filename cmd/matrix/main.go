@@ -0,0 +1,333 @@
+// matrix runs a command or workflow once per emulated device.
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["matrix"] = matrix
+	lib.Args["matrix"] = matrixArgs{}
+}
+
+type matrixArgs struct {
+	lib.TargetArgs
+	Devices   string   `arg:"-d,--devices,required" help:"comma-separated preset names to run under"`
+	OutputDir string   `arg:"-o,--output-dir" help:"directory for per-device artifacts (default: ~/chrome-shots/matrix/<timestamp>)"`
+	Parallel  int      `arg:"-j,--parallel" default:"1" help:"devices to run at once, each in its own tab"`
+	Config    string   `arg:"-c,--config" help:"presets file (default: $CHROME_PRESETS or ~/.config/chrome-cli/presets.yaml)"`
+	Command   []string `arg:"positional,required" help:"chrome command and args, or a workflow file, to run per device (after --)"`
+}
+
+func (matrixArgs) Description() string {
+	return `matrix - Run a command or workflow under each device preset
+
+For every preset in --devices (see 'chrome session --help'), applies it to
+the tab, runs COMMAND against that tab, and releases the emulation before
+the next device. COMMAND is any chrome command, or a workflow file, which
+is run with 'chrome run -t <tab> --preset <device>', so the workflow's own
+target: and preset: do not apply.
+
+Each device gets its own directory, <output-dir>/<device>, set as the
+shots directory for COMMAND: step screenshots, run records and reports,
+and default screenshot paths land there, next to output.log (COMMAND's
+stdout and stderr). matrix.json in <output-dir> summarizes every device.
+
+Devices run one after another in the selected tab. With --parallel N, up
+to N run at once, each in a new tab opened at the selected tab's URL and
+closed afterwards. Exits 1 if COMMAND failed on any device.
+
+Example:
+  chrome matrix --devices iphone,pixel7,desktop -- checkout.yaml
+  chrome matrix -d "iPhone 14,Pixel 7,Desktop 1440" -- step screenshot
+  chrome matrix -d iphone,ipad,desktop -j 3 -o /tmp/sweep -- run -q smoke.yaml
+  chrome matrix -d iphone,desktop -- screenshot --label home`
+}
+
+// deviceResult is one device's entry in matrix.json.
+type deviceResult struct {
+	Device    string `json:"device"`
+	Dir       string `json:"dir"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+var slugCleanup = regexp.MustCompile(`[^a-z0-9]+`)
+
+func matrix() {
+	var args matrixArgs
+	arg.MustParse(&args)
+
+	var devices []string
+	for _, device := range strings.Split(args.Devices, ",") {
+		if device = strings.TrimSpace(device); device != "" {
+			devices = append(devices, device)
+		}
+	}
+	if len(devices) == 0 {
+		fmt.Fprintf(os.Stderr, "error: --devices needs at least one preset name\n")
+		lib.Exit(1)
+	}
+	if args.Parallel < 1 {
+		fmt.Fprintf(os.Stderr, "error: --parallel must be >= 1\n")
+		lib.Exit(1)
+	}
+	presets := map[string]lib.Preset{}
+	for _, device := range devices {
+		preset, err := lib.LoadPreset(args.Config, device)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		presets[device] = preset
+	}
+
+	command := args.Command
+	if isWorkflow(command[0]) {
+		command = append([]string{"run"}, command...)
+	}
+	if _, ok := lib.Commands[command[0]]; !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown command %q, and not a workflow file\n", command[0])
+		lib.Exit(1)
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+
+	out := args.OutputDir
+	if out == "" {
+		out = filepath.Join(lib.DefaultShotsDir(), "matrix", lib.NewRunID(""))
+	}
+	out, err = filepath.Abs(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if args.Config != "" {
+		// run --preset reads presets from here
+		if err := os.Setenv("CHROME_PRESETS", args.Config); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	results := make([]deviceResult, len(devices))
+	sem := make(chan struct{}, args.Parallel)
+	var wg sync.WaitGroup
+	seen := map[string]int{}
+	for i, device := range devices {
+		slug := deviceSlug(device)
+		if seen[slug]++; seen[slug] > 1 {
+			slug = fmt.Sprintf("%s-%d", slug, seen[slug])
+		}
+		results[i] = deviceResult{Device: device, Dir: filepath.Join(out, slug)}
+		if args.Parallel == 1 {
+			fmt.Printf("== %s\n", device)
+			runDevice(&results[i], presets[device], tabID, false, command, os.Stdout)
+			printResult(results[i])
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			runDevice(&results[i], presets[device], tabID, true, command, nil)
+			printResult(results[i])
+		}()
+	}
+	wg.Wait()
+
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(out, "matrix.json"), append(data, '\n'), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to write matrix.json: %v\n", err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status != "ok" {
+			failed++
+		}
+	}
+	fmt.Printf("%d/%d devices passed, artifacts in %s\n", len(devices)-failed, len(devices), out)
+	if failed > 0 {
+		lib.Exit(1)
+	}
+}
+
+// runDevice applies preset to the tab, or to a new tab opened at its URL
+// when fresh, runs command against it, and records the outcome. Output goes
+// to the device's output.log, and to echo if set.
+func runDevice(result *deviceResult, preset lib.Preset, tabID string, fresh bool, command []string, echo io.Writer) {
+	start := time.Now()
+	err := func() error {
+		if err := os.MkdirAll(result.Dir, 0755); err != nil {
+			return err
+		}
+		id, release, err := emulate(tabID, fresh, preset)
+		if err != nil {
+			return fmt.Errorf("applying preset: %w", err)
+		}
+		defer release()
+		log, err := os.Create(filepath.Join(result.Dir, "output.log"))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = log.Close() }()
+		var w io.Writer = log
+		if echo != nil {
+			w = io.MultiWriter(log, echo)
+		}
+		execPath, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command(execPath, deviceCommand(command, id, result.Device)...)
+		cmd.Env = lib.ChildEnv("CHROME_TARGET="+id, "CHROME_SHOTS_DIR="+result.Dir)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(command, " "), err)
+		}
+		return nil
+	}()
+	result.ElapsedMs = time.Since(start).Milliseconds()
+	result.Status = "ok"
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+	}
+}
+
+// emulate holds a connection with preset applied, to tabID or to a new tab
+// at its URL when fresh, and returns the tab's ID and a func that releases
+// the emulation (and closes the new tab).
+func emulate(tabID string, fresh bool, preset lib.Preset) (string, func(), error) {
+	if !fresh {
+		ctx, cancel, err := lib.Attach(tabID)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := runTimeout(ctx, preset.Actions()...); err != nil {
+			cancel()
+			return "", nil, err
+		}
+		return tabID, cancel, nil
+	}
+
+	url := "about:blank"
+	if pages, err := lib.PageTargets(); err == nil {
+		for _, page := range pages {
+			if page.ID == tabID {
+				url = page.URL
+			}
+		}
+	}
+	if err := lib.PoliteWait(context.Background(), url); err != nil {
+		return "", nil, err
+	}
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(context.Background(), lib.ChromeURL())
+	// Cancelling a context that created its tab closes the tab
+	tabCtx, tabCancel := chromedp.NewContext(allocCtx)
+	release := func() {
+		tabCancel()
+		allocCancel()
+	}
+	if err := chromedp.Run(tabCtx); err != nil {
+		release()
+		return "", nil, err
+	}
+	id := chromedp.FromContext(tabCtx).Target.TargetID
+	// Emulate before loading, so the page renders as the device from the start
+	actions := append(preset.Actions(), chromedp.Navigate(url))
+	if err := runTimeout(tabCtx, actions...); err != nil {
+		release()
+		return "", nil, err
+	}
+	return id.String(), release, nil
+}
+
+func runTimeout(ctx context.Context, actions ...chromedp.Action) error {
+	ctx, cancel := context.WithTimeout(ctx, lib.DefaultTimeout)
+	defer cancel()
+	return chromedp.Run(ctx, actions...)
+}
+
+// deviceCommand returns command as run against tab id. A workflow's target:
+// and preset: would pick another tab or emulation, so run gets -t and
+// --preset unless they were given.
+func deviceCommand(command []string, id string, device string) []string {
+	if command[0] != "run" {
+		return command
+	}
+	hasTarget, hasPreset := false, false
+	for _, a := range command[1:] {
+		switch {
+		case a == "-t" || a == "--target" || strings.HasPrefix(a, "-t=") || strings.HasPrefix(a, "--target="):
+			hasTarget = true
+		case a == "-p" || a == "--preset" || strings.HasPrefix(a, "-p=") || strings.HasPrefix(a, "--preset="):
+			hasPreset = true
+		}
+	}
+	out := []string{"run"}
+	if !hasTarget {
+		out = append(out, "-t", id)
+	}
+	if !hasPreset {
+		out = append(out, "--preset", device)
+	}
+	return append(out, command[1:]...)
+}
+
+// isWorkflow reports whether arg names a workflow file rather than a command.
+func isWorkflow(arg string) bool {
+	switch strings.ToLower(filepath.Ext(arg)) {
+	case ".yaml", ".yml", ".json":
+		info, err := os.Stat(arg)
+		return err == nil && !info.IsDir()
+	}
+	return false
+}
+
+// deviceSlug names a device's directory: "iPhone 14" becomes iphone-14.
+func deviceSlug(device string) string {
+	slug := strings.Trim(slugCleanup.ReplaceAllString(strings.ToLower(device), "-"), "-")
+	if slug == "" {
+		slug = "device"
+	}
+	return slug
+}
+
+func printResult(result deviceResult) {
+	line := fmt.Sprintf("%-6s %s (%.1fs) %s", result.Status, result.Device, float64(result.ElapsedMs)/1000, result.Dir)
+	if result.Error != "" {
+		line += ": " + result.Error
+	}
+	fmt.Println(line)
+}
@@ -0,0 +1,618 @@
+// mcp serves chrome commands as Model Context Protocol tools over stdio.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+const protocolVersion = "2024-11-05"
+
+func init() {
+	lib.Commands["mcp"] = serve
+	lib.Args["mcp"] = mcpArgs{}
+}
+
+type mcpArgs struct {
+	Timeout int `arg:"--timeout" default:"120" help:"per tool call timeout in seconds"`
+}
+
+func (mcpArgs) Description() string {
+	return `mcp - Serve chrome commands as MCP tools over stdio
+
+Runs a Model Context Protocol server on stdin/stdout (newline-delimited
+JSON-RPC). Tools run in-process against the current port, keeping one
+attached connection per tab across calls; each tool's target argument
+selects the tab like -t, falling back to CHROME_TARGET and the CLI's
+default tab.
+
+Tools: click, clicktext, fill, type, navigate, eval, screenshot, console,
+network, list.
+
+Global -p/--port applies to every tool call. Logs go to stderr.
+
+Example:
+  chrome mcp
+  chrome -p 9223 mcp
+
+Client config (e.g. mcpServers in an agent's settings):
+  {"chrome": {"command": "chrome", "args": ["mcp"]}}`
+}
+
+// param describes one tool argument.
+type param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+type tool struct {
+	Name        string
+	Description string
+	Params      []param
+	Call        func(s *server, a toolArgs) ([]content, error)
+}
+
+var targetParam = param{Name: "target", Type: "string", Description: "tab ID prefix or URL prefix to select tab"}
+
+var tools = []tool{
+	{
+		Name:        "click",
+		Description: "Click an element by CSS selector using a real mouse event.",
+		Params: []param{
+			{Name: "selector", Type: "string", Description: "CSS selector of element to click", Required: true},
+			{Name: "index", Type: "integer", Description: "which match to click (0-based)"},
+			{Name: "within", Type: "string", Description: "CSS selector of a container to search within"},
+			targetParam,
+		},
+		Call: click,
+	},
+	{
+		Name:        "clicktext",
+		Description: "Click a button or link by its exact visible text.",
+		Params: []param{
+			{Name: "text", Type: "string", Description: "exact text to match", Required: true},
+			{Name: "selector", Type: "string", Description: "CSS selector limiting the search"},
+			{Name: "index", Type: "integer", Description: "which match to click (0-based)"},
+			targetParam,
+		},
+		Call: clickText,
+	},
+	{
+		Name:        "fill",
+		Description: "Set the value of an input, textarea, select, or contenteditable element (React friendly).",
+		Params: []param{
+			{Name: "selector", Type: "string", Description: "CSS selector of input element", Required: true},
+			{Name: "value", Type: "string", Description: "value to set", Required: true},
+			{Name: "verify", Type: "boolean", Description: "read the value back and retry once on mismatch"},
+			targetParam,
+		},
+		Call: fill,
+	},
+	{
+		Name:        "type",
+		Description: "Type text into an element with real key events, replacing existing text.",
+		Params: []param{
+			{Name: "selector", Type: "string", Description: "CSS selector of element to type into", Required: true},
+			{Name: "text", Type: "string", Description: "text to type", Required: true},
+			{Name: "append", Type: "boolean", Description: "append instead of replacing"},
+			targetParam,
+		},
+		Call: typeText,
+	},
+	{
+		Name:        "navigate",
+		Description: "Navigate the tab to a URL.",
+		Params: []param{
+			{Name: "url", Type: "string", Description: "URL to navigate to", Required: true},
+			targetParam,
+		},
+		Call: navigate,
+	},
+	{
+		Name:        "eval",
+		Description: "Evaluate JavaScript in the page and return the result.",
+		Params: []param{
+			{Name: "script", Type: "string", Description: "JavaScript expression to evaluate", Required: true},
+			targetParam,
+		},
+		Call: eval,
+	},
+	{
+		Name:        "screenshot",
+		Description: "Capture a screenshot of the tab. Saved to the shots directory and returned as an image.",
+		Params: []param{
+			{Name: "label", Type: "string", Description: "label embedded in filename"},
+			{Name: "note", Type: "string", Description: "note saved in metadata"},
+			{Name: "selector", Type: "string", Description: "CSS selector of an element to clip the screenshot to"},
+			{Name: "format", Type: "string", Description: "png (default), jpeg, or webp"},
+			{Name: "quality", Type: "integer", Description: "jpeg/webp quality 1-100"},
+			targetParam,
+		},
+		Call: screenshot,
+	},
+	{
+		Name:        "console",
+		Description: "Capture console logs and exceptions for a duration. Returns NDJSON.",
+		Params: []param{
+			{Name: "duration", Type: "integer", Description: "seconds to capture (default 5)"},
+			{Name: "eval", Type: "string", Description: "JavaScript to run after capture starts"},
+			targetParam,
+		},
+		Call: console,
+	},
+	{
+		Name:        "network",
+		Description: "Capture network requests and responses for a duration. Returns NDJSON.",
+		Params: []param{
+			{Name: "duration", Type: "integer", Description: "seconds to capture (default 5)"},
+			{Name: "eval", Type: "string", Description: "JavaScript to run after capture starts"},
+			targetParam,
+		},
+		Call: networkTool,
+	},
+	{
+		Name:        "list",
+		Description: "List open tabs with their IDs and URLs.",
+		Call:        list,
+	},
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+type callResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError"`
+}
+
+func serve() {
+	var args mcpArgs
+	arg.MustParse(&args)
+
+	timeout := time.Duration(args.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+
+	srv := &server{timeout: timeout}
+	out := bufio.NewWriter(os.Stdout)
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := srv.handle(line); resp != nil {
+				data, marshalErr := json.Marshal(resp)
+				if marshalErr != nil {
+					fmt.Fprintf(os.Stderr, "error: %v\n", marshalErr)
+					continue
+				}
+				_, _ = out.Write(data)
+				_ = out.WriteByte('\n')
+				_ = out.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// server holds the Session shared by every tool call, created on the first
+// call that needs a tab so the server can start before Chrome does.
+type server struct {
+	timeout time.Duration
+	session *lib.Session
+}
+
+func (s *server) handle(line []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: "parse error"}}
+	}
+
+	// Notifications carry no id and get no response
+	if len(req.ID) == 0 {
+		return nil
+	}
+
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = protocolVersion
+		}
+		resp.Result = map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "chrome", "version": "1.0.0"},
+		}
+	case "ping":
+		resp.Result = map[string]any{}
+	case "tools/list":
+		var defs []map[string]any
+		for _, t := range tools {
+			defs = append(defs, map[string]any{
+				"name":        t.Name,
+				"description": t.Description,
+				"inputSchema": inputSchema(t),
+			})
+		}
+		resp.Result = map[string]any{"tools": defs}
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &rpcError{Code: -32602, Message: "invalid params"}
+			return resp
+		}
+		t, ok := findTool(params.Name)
+		if !ok {
+			resp.Error = &rpcError{Code: -32602, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+			return resp
+		}
+		resp.Result = s.call(t, params.Arguments)
+	default:
+		resp.Error = &rpcError{Code: -32601, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	return resp
+}
+
+func findTool(name string) (tool, bool) {
+	for _, t := range tools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return tool{}, false
+}
+
+func inputSchema(t tool) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for _, p := range t.Params {
+		properties[p.Name] = map[string]any{"type": p.Type, "description": p.Description}
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// toolArgs are a call's arguments, checked against the tool's params.
+type toolArgs map[string]any
+
+func (a toolArgs) str(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+func (a toolArgs) integer(name string) int {
+	v, _ := a[name].(float64)
+	return int(v)
+}
+
+func (a toolArgs) boolean(name string) bool {
+	v, _ := a[name].(bool)
+	return v
+}
+
+func checkArgs(t tool, arguments map[string]any) (toolArgs, error) {
+	args := toolArgs{}
+	for _, p := range t.Params {
+		value, ok := arguments[p.Name]
+		if !ok || value == nil {
+			if p.Required {
+				return nil, fmt.Errorf("missing required argument: %s", p.Name)
+			}
+			continue
+		}
+		valid := false
+		switch p.Type {
+		case "string":
+			_, valid = value.(string)
+		case "boolean":
+			_, valid = value.(bool)
+		case "integer":
+			n, isNumber := value.(float64)
+			valid = isNumber && n == math.Trunc(n)
+		}
+		if !valid {
+			return nil, fmt.Errorf("argument %s must be of type %s", p.Name, p.Type)
+		}
+		args[p.Name] = value
+	}
+	return args, nil
+}
+
+func (s *server) call(t tool, arguments map[string]any) callResult {
+	args, err := checkArgs(t, arguments)
+	if err != nil {
+		return errorResult(err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "mcp: %s\n", t.Name)
+	result, err := t.Call(s, args)
+	if err != nil {
+		return errorResult(err.Error())
+	}
+	return callResult{Content: result}
+}
+
+// run runs fn against the tab selected by target, on the tab's attached
+// context kept by the Session.
+func (s *server) run(target string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if s.session == nil {
+		session, err := lib.NewSession()
+		if err != nil {
+			return err
+		}
+		s.session = session
+	}
+	return s.session.Run(target, timeout, chromedp.ActionFunc(fn))
+}
+
+func click(s *server, a toolArgs) ([]content, error) {
+	err := s.run(a.str("target"), s.timeout, func(ctx context.Context) error {
+		return lib.ClickNth(ctx, a.str("selector"), a.str("within"), a.integer("index"))
+	})
+	return textContent("ok"), err
+}
+
+func clickText(s *server, a toolArgs) ([]content, error) {
+	err := s.run(a.str("target"), s.timeout, func(ctx context.Context) error {
+		return lib.ClickTextNth(ctx, a.str("text"), a.str("selector"), a.integer("index"))
+	})
+	return textContent("ok"), err
+}
+
+func fill(s *server, a toolArgs) ([]content, error) {
+	selector, want := a.str("selector"), a.str("value")
+	var notes []string
+	err := s.run(a.str("target"), s.timeout, func(ctx context.Context) error {
+		if a.boolean("verify") {
+			return lib.FillVerified(ctx, selector, want, func(warning string) {
+				notes = append(notes, warning)
+			})
+		}
+		value, err := lib.Fill(ctx, selector, want)
+		if err != nil {
+			return err
+		}
+		if value != want {
+			notes = append(notes, fmt.Sprintf("warning: value mismatch - requested %q but got %q", want, value))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return textContent("ok"), nil
+	}
+	return textContent(strings.Join(notes, "\n")), nil
+}
+
+func typeText(s *server, a toolArgs) ([]content, error) {
+	err := s.run(a.str("target"), s.timeout, func(ctx context.Context) error {
+		return lib.Type(ctx, a.str("selector"), a.str("text"), a.boolean("append"))
+	})
+	return textContent("ok"), err
+}
+
+func navigate(s *server, a toolArgs) ([]content, error) {
+	err := s.run(a.str("target"), s.timeout, func(ctx context.Context) error {
+		return lib.Navigate(ctx, a.str("url"))
+	})
+	return textContent("ok"), err
+}
+
+func eval(s *server, a toolArgs) ([]content, error) {
+	var result any
+	err := s.run(a.str("target"), s.timeout, func(ctx context.Context) error {
+		return lib.Eval(ctx, a.str("script"), &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch v := result.(type) {
+	case string:
+		return textContent(v), nil
+	case nil:
+		return textContent("ok"), nil
+	default:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return textContent(string(data)), nil
+	}
+}
+
+func screenshot(s *server, a toolArgs) ([]content, error) {
+	label := strings.TrimSpace(a.str("label"))
+	if label == "" {
+		label = "shot"
+	}
+	opts, err := lib.ScreenshotOptions{Element: a.str("selector"), Format: a.str("format"), Quality: a.integer("quality")}.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	name := lib.OutputName{Label: label, Target: a.str("target")}
+	path, err := lib.PrepareNamedOutputPath("", "", name, lib.ScreenshotExtension(opts.Format))
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	err = s.run(a.str("target"), s.timeout, func(ctx context.Context) error {
+		var err error
+		buf, err = lib.ScreenshotWithOptions(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		return nil, err
+	}
+	record := lib.StepRecord{
+		Action:     "screenshot",
+		Target:     a.str("target"),
+		Label:      label,
+		Note:       a.str("note"),
+		Screenshot: path,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := lib.RememberStep(record); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to persist metadata: %v\n", err)
+	}
+	text := "saved " + path
+	if record.Note != "" {
+		text += "\nnote: " + record.Note
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = "image/png"
+	}
+	return []content{
+		{Type: "text", Text: text},
+		{Type: "image", Data: base64.StdEncoding.EncodeToString(buf), MimeType: mimeType},
+	}, nil
+}
+
+func console(s *server, a toolArgs) ([]content, error) {
+	return capture(s, a, func(ctx context.Context, emit func(any)) error {
+		return lib.ListenConsole(ctx, func(msg lib.ConsoleMessage) { emit(msg) })
+	})
+}
+
+func networkTool(s *server, a toolArgs) ([]content, error) {
+	return capture(s, a, func(ctx context.Context, emit func(any)) error {
+		return lib.ListenNetwork(ctx, func(ev lib.NetworkEvent) { emit(ev) })
+	})
+}
+
+// capture listens for the tool's duration on the tab's attached context,
+// running the optional eval once listening, and returns what was seen as
+// NDJSON.
+func capture(s *server, a toolArgs, listen func(ctx context.Context, emit func(any)) error) ([]content, error) {
+	duration := 5 * time.Second
+	if _, ok := a["duration"]; ok {
+		duration = time.Duration(a.integer("duration")) * time.Second
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("duration must be > 0")
+	}
+	var mu sync.Mutex
+	var lines []string
+	emit := func(v any) {
+		// Listeners must never block, so only encode and append here
+		data, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		lines = append(lines, string(data))
+		mu.Unlock()
+	}
+	err := s.run(a.str("target"), duration+s.timeout, func(ctx context.Context) error {
+		captureCtx, cancel := context.WithTimeout(ctx, duration)
+		defer cancel()
+		if err := listen(captureCtx, emit); err != nil {
+			return err
+		}
+		if script := strings.TrimSpace(a.str("eval")); script != "" {
+			if err := lib.Eval(captureCtx, script, nil); err != nil {
+				return err
+			}
+		}
+		<-captureCtx.Done()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lines) == 0 {
+		return textContent("no events"), nil
+	}
+	return textContent(strings.Join(lines, "\n")), nil
+}
+
+func list(s *server, a toolArgs) ([]content, error) {
+	if !lib.IsChromeRunning() {
+		return nil, fmt.Errorf("Chrome not running on port %d", lib.GetPort())
+	}
+	pages, err := lib.PageTargets()
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return textContent("no page tabs"), nil
+	}
+	// Mark the tab calls without a target run against, as 'chrome list' does
+	preferred, _, _ := lib.ResolveTarget("", nil)
+	var b strings.Builder
+	for _, page := range pages {
+		marker := " "
+		if page.ID == preferred {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, "%s[%s] %s\n  %s\n", marker, page.ID, page.Title, page.URL)
+	}
+	return textContent(strings.TrimSuffix(b.String(), "\n")), nil
+}
+
+func textContent(text string) []content {
+	return []content{{Type: "text", Text: text}}
+}
+
+func errorResult(text string) callResult {
+	return callResult{Content: textContent(text), IsError: true}
+}
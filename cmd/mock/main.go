@@ -0,0 +1,145 @@
+// mock fulfills matching requests in a tab from fixture files.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["mock"] = mock
+	lib.Args["mock"] = mockArgs{}
+}
+
+type mockArgs struct {
+	lib.TargetArgs
+	Rules   []string `arg:"-r,--rule,separate" help:"rules file, JSON or YAML (repeatable; later rules win)"`
+	Release bool     `arg:"--release" help:"remove every mock rule from the tab (daemon only)"`
+	Hold    bool     `arg:"--hold" help:"intercept from this process until Ctrl+C even when the daemon is running"`
+	Command []string `arg:"positional" help:"chrome command and args to run with the mocks active (after --)"`
+}
+
+func (mockArgs) Description() string {
+	return `mock - Fulfill requests from fixture files
+
+Intercepts the tab's requests with the Fetch domain and answers those
+matching a rule with its status, headers, and body instead of going to the
+network, so the frontend can be tested against a fake backend from the
+CLI. Unmatched requests continue untouched.
+
+Rules file (JSON or YAML), a list or {"rules": [...]}:
+  [
+    {"url": "*/api/user", "body_file": "fixtures/user.json"},
+    {"url": "*/api/items?page=*", "method": "GET", "status": 200,
+     "headers": {"Cache-Control": "no-store"}, "body_file": "fixtures/items.json"},
+    {"url": "*/api/save", "method": "POST", "status": 500, "body": "{\"error\": \"down\"}"},
+    {"url": "*/analytics/*", "block": true},
+    {"url": "*/api/search*", "fail": "connection-refused"}
+  ]
+
+url is a glob ('*' any run of characters, '?' one). body_file is relative
+to the rules file and read on every match, so fixtures can be edited while
+mocks are active; Content-Type defaults from its extension. status defaults
+to 200. fail fails the request with a network error instead (see 'chrome
+abort --reasons'). When several rules match, the last one wins.
+
+Chrome drops interception when the DevTools connection closes. With
+COMMAND (after --), mocks are active while it runs against the same tab
+and mock exits 1 if it fails. Otherwise, when the daemon is running
+(chrome serve), rules are handed to it and stay until 'chrome mock
+--release' or 'chrome state clear'; without it, this command holds them
+until Ctrl+C.
+
+Example:
+  chrome mock --rule api.json -- run checkout.yaml
+  chrome mock --rule api.json --rule errors.yaml
+  chrome mock --release`
+}
+
+func mock() {
+	var args mockArgs
+	arg.MustParse(&args)
+
+	if args.Release {
+		if !lib.DaemonRunning() {
+			fmt.Fprintf(os.Stderr, "error: nothing to release, mocks without the daemon (chrome serve) end with the command that set them\n")
+			lib.Exit(1)
+		}
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs)}
+		if err := lib.DaemonCall("/unmock", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println("released")
+		return
+	}
+
+	if len(args.Rules) == 0 {
+		fmt.Fprintf(os.Stderr, "error: usage: chrome mock --rule FILE [-- COMMAND...]\n")
+		lib.Exit(1)
+	}
+	var rules []lib.MockRule
+	for _, path := range args.Rules {
+		loaded, err := lib.LoadMockRules(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		rules = append(rules, loaded...)
+	}
+
+	if len(args.Command) == 0 && !args.Hold && lib.DaemonRunning() {
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs), "rules": rules}
+		if err := lib.DaemonCall("/mock", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Printf("mocking %d rule(s) (held by daemon)\n", len(rules))
+		return
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	addCtx, addCancel := context.WithTimeout(ctx, lib.DefaultTimeout)
+	err = lib.NewInterceptor(ctx).Add(addCtx, rules...)
+	addCancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if len(args.Command) > 0 {
+		output, err := lib.RunCommand(tabID, args.Command)
+		_, _ = os.Stdout.Write(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("mocking %d rule(s)\n", len(rules))
+	fmt.Fprintf(os.Stderr, "holding mocks, Ctrl+C to release\n")
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
@@ -2,10 +2,16 @@
 package navigate
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 	"github.com/nathants/chrome/lib"
 )
@@ -17,7 +23,13 @@ func init() {
 
 type navigateArgs struct {
 	lib.TargetArgs
-	URL string `arg:"positional,required" help:"URL to navigate to"`
+	URL           string   `arg:"positional,required" help:"URL to navigate to"`
+	Deterministic bool     `arg:"--deterministic" help:"seed Math.random, freeze Date, use virtual time, and block external fonts/ads"`
+	Seed          int64    `arg:"--seed" default:"42" help:"Math.random seed for --deterministic"`
+	Time          string   `arg:"--time" help:"frozen clock for --deterministic, RFC3339 (default: 2024-01-01T00:00:00Z)"`
+	Budget        int      `arg:"--budget" default:"5000" help:"virtual time budget in ms for --deterministic"`
+	SeedStorage   string   `arg:"--seed-storage" help:"JSON file of localStorage/sessionStorage keys to set before page scripts run"`
+	Headers       []string `arg:"-H,--header,separate" help:"extra HTTP header as \"Name: value\" for this load (repeatable)"`
 }
 
 func (navigateArgs) Description() string {
@@ -25,27 +37,161 @@ func (navigateArgs) Description() string {
 
 Navigates the Chrome browser to the specified URL.
 
+Use --deterministic for reproducible visual baselines and scraping tests:
+  - Math.random is seeded (--seed) and Date is frozen (--time)
+  - virtual time starts at the frozen clock and only advances while no
+    network fetches are pending, until --budget ms of virtual time elapse
+  - external font hosts and common ad/analytics domains are blocked
+
+Use --seed-storage to set storage keys before the app boots, such as
+feature flags or markers that dismiss first-run onboarding. The file is:
+  {"localStorage": {"onboarded": "1", "flags": {"newNav": true}},
+   "sessionStorage": {"tour": "skipped"}}
+Strings are stored as is, other values as JSON text. Keys are written in
+the top frame of every document loaded during this navigation.
+
+Use --header to send extra headers, such as Authorization, with the page
+and the requests it makes while navigate is connected. To keep headers on
+the tab for later navigations, use 'chrome headers'.
+
 Example:
   chrome navigate http://localhost:8000
-  chrome navigate https://example.com`
+  chrome navigate https://example.com
+  chrome navigate --deterministic http://localhost:8000
+  chrome navigate --deterministic --seed 7 --time 2030-06-01T12:00:00Z https://example.com
+  chrome navigate --seed-storage flags.json http://localhost:8000
+  chrome navigate -H "Authorization: Bearer $TOKEN" https://staging.example.com`
 }
 
 func navigate() {
 	var args navigateArgs
 	arg.MustParse(&args)
 
+	if err := lib.PoliteWait(context.Background(), args.URL); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
 	ctx, cancel := lib.SetupContext()
 	defer cancel()
 
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
+	if len(args.Headers) > 0 {
+		headers, err := lib.ParseHeaders(args.Headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		extra := network.Headers{}
+		for name, value := range headers {
+			extra[name] = value
+		}
+		if err := chromedp.Run(targetCtx, network.Enable(), network.SetExtraHTTPHeaders(extra)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	if args.SeedStorage != "" {
+		if err := seedStorage(targetCtx, args.SeedStorage); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	if args.Deterministic {
+		if err := navigateDeterministic(targetCtx, args); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		return
+	}
+
 	if err := chromedp.Run(targetCtx, chromedp.Navigate(args.URL)); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 }
+
+// seedStorage installs the --seed-storage init script for the navigation.
+func seedStorage(ctx context.Context, path string) error {
+	seed, err := lib.LoadStorageSeed(path)
+	if err != nil {
+		return err
+	}
+	script, err := lib.StorageSeedScript(seed)
+	if err != nil {
+		return err
+	}
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
+		return err
+	}))
+}
+
+func navigateDeterministic(ctx context.Context, args navigateArgs) error {
+	now := lib.DefaultDeterministicTime
+	if args.Time != "" {
+		parsed, err := time.Parse(time.RFC3339, args.Time)
+		if err != nil {
+			return fmt.Errorf("invalid --time: %v", err)
+		}
+		now = parsed
+	}
+	budget := args.Budget
+	if budget <= 0 {
+		budget = 5000
+	}
+
+	expired := make(chan struct{}, 1)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if _, ok := ev.(*emulation.EventVirtualTimeBudgetExpired); ok {
+			select {
+			case expired <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	initial := cdp.TimeSinceEpoch(now)
+	err := chromedp.Run(ctx,
+		network.Enable(),
+		network.SetBlockedURLs(lib.DeterministicBlockedURLs),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(lib.DeterministicScript(args.Seed, now)).Do(ctx)
+			return err
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := emulation.SetVirtualTimePolicy(emulation.VirtualTimePolicyPauseIfNetworkFetchesPending).
+				WithBudget(float64(budget)).
+				WithInitialVirtualTime(&initial).
+				Do(ctx)
+			return err
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, _, _, _, err := page.Navigate(args.URL).Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-expired:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Hand the page back to real time so later commands are not stuck paused
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := emulation.SetVirtualTimePolicy(emulation.VirtualTimePolicyAdvance).Do(ctx)
+		return err
+	}))
+}
@@ -2,9 +2,11 @@
 package network
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alexflint/go-arg"
@@ -20,9 +22,17 @@ func init() {
 
 type networkArgs struct {
 	lib.TargetArgs
-	Duration int  `arg:"-d,--duration" default:"5" help:"duration in seconds to monitor"`
-	Follow   bool `arg:"-f,--follow" help:"follow mode, monitor continuously"`
-	Eval     string `arg:"--eval" help:"JavaScript to evaluate after enabling network capture"`
+	Duration     int      `arg:"-d,--duration" default:"5" help:"duration in seconds to monitor"`
+	Follow       bool     `arg:"-f,--follow" help:"follow mode, monitor continuously"`
+	Eval         string   `arg:"--eval" help:"JavaScript to evaluate after enabling network capture"`
+	Bodies       bool     `arg:"--bodies" help:"capture request post data and response bodies as \"body\" events"`
+	BodiesDir    string   `arg:"--bodies-dir" help:"write bodies to DIR as <requestId>.request and <requestId>.response instead of inlining them (implies --bodies)"`
+	MaxBodySize  int64    `arg:"--max-body-size" default:"65536" help:"cut inlined bodies to this many bytes, 0 for no limit"`
+	Block        []string `arg:"--block,separate" help:"block requests matching this URL pattern or @group while monitoring (repeatable, see chrome block)"`
+	WebSockets   bool     `arg:"--websockets" help:"capture WebSocket connections and frames as \"ws-*\" events"`
+	MaxFrameSize int64    `arg:"--max-frame-size" default:"4096" help:"cut WebSocket frame payloads to this many bytes, 0 for no limit"`
+	lib.OutArgs
+	Buffer int `arg:"--buffer" default:"10000" help:"events held while output catches up; beyond it the oldest are dropped"`
 }
 
 func (networkArgs) Description() string {
@@ -32,26 +42,62 @@ Captures HTTP requests and responses from the page.
 Output is JSON, one object per line (NDJSON).
 Use --eval to run JavaScript after capture starts (handy for triggering requests).
 
+"response" events carry a timing breakdown in ms: queued, dns, connect,
+tls (omitted on reused connections), send, and ttfb, the wait for the
+first byte, which is server time plus a round trip. A "finished" event
+follows once the body is in, adding download and total, with the bytes
+transferred in size.
+
+Events wait in a fixed buffer of --buffer events until written, so memory
+stays flat in multi-hour -f sessions. If output falls that far behind, the
+oldest are dropped and a {"type": "dropped", "count": N} line marks the
+gap. --out writes to a file instead of stdout, rotated to FILE.1, FILE.2,
+... at --rotate-size, or when it is --rotate-every old (rotation happens as
+events are written, so a quiet file rotates with its next event). --keep
+old files are kept.
+
+With --bodies, each finished request also prints a "body" event with its
+post data and response body. Text is inlined as is, binary as base64
+("encoding": "base64"), cut to --max-body-size with "truncated": true.
+--bodies-dir writes full bodies to files named by requestId instead.
+
+With --websockets, WebSocket traffic is printed too: "ws-open" and
+"ws-handshake" when a socket connects, "ws-sent" and "ws-received" for each
+frame, then "ws-error" or "ws-closed". Frames carry the socket's url, the
+opcode (1 text, 2 binary), the payload in "body" (binary as base64), and
+its full "size"; payloads over --max-frame-size are cut, with
+"truncated": true.
+
+--block blocks matching requests for as long as the command runs; they
+show as "failed" events with "blockedReason". See 'chrome block' for
+patterns, @groups, and blocking that outlives one command.
+
+Ctrl+C (or SIGTERM) stops capture cleanly: bodies being fetched get a
+moment to arrive, buffered events are written, --out is closed, and --block
+patterns are lifted before exiting. A second Ctrl+C exits at once.
+
 Example:
   chrome network                    # Monitor for 5 seconds
   chrome network -d 10              # Monitor for 10 seconds
-  chrome network -f                 # Follow mode (continuous, Ctrl+C to stop)`
-}
-
-type NetworkEvent struct {
-	Type       string    `json:"type"`
-	RequestID  string    `json:"requestId"`
-	URL        string    `json:"url,omitempty"`
-	Method     string    `json:"method,omitempty"`
-	Status     int64     `json:"status,omitempty"`
-	StatusText string    `json:"statusText,omitempty"`
-	Timestamp  time.Time `json:"timestamp"`
+  chrome network -f                 # Follow mode (continuous, Ctrl+C to stop)
+  chrome network --bodies --eval "fetch('/api/items')"
+  chrome network -f --bodies-dir ./bodies
+  chrome network -f --out network.ndjson --rotate-size 50MB --keep 3
+  chrome network -f --block @trackers --block "*.mp4"
+  chrome network -f --websockets | jq 'select(.type == "ws-received") | .body'`
 }
 
 func networkCmd() {
 	var args networkArgs
 	arg.MustParse(&args)
 
+	sink, err := args.OpenSink()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer func() { _ = sink.Close() }()
+
 	ctxTimeout := lib.DefaultTimeout
 	if args.Follow {
 		ctxTimeout = 0
@@ -68,78 +114,100 @@ func networkCmd() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
-	events := make(chan NetworkEvent, 100)
-
-	chromedp.ListenTarget(targetCtx, func(ev interface{}) {
-		switch ev := ev.(type) {
-		case *network.EventRequestWillBeSent:
-			evt := NetworkEvent{
-				Type:      "request",
-				RequestID: string(ev.RequestID),
-				URL:       ev.Request.URL,
-				Method:    ev.Request.Method,
-				Timestamp: time.Now(),
-			}
-			select {
-			case events <- evt:
-			default:
-			}
-		case *network.EventResponseReceived:
-			evt := NetworkEvent{
-				Type:       "response",
-				RequestID:  string(ev.RequestID),
-				URL:        ev.Response.URL,
-				Status:     ev.Response.Status,
-				StatusText: ev.Response.StatusText,
-				Timestamp:  time.Now(),
-			}
-			select {
-			case events <- evt:
-			default:
-			}
-		case *network.EventLoadingFailed:
-			evt := NetworkEvent{
-				Type:      "failed",
-				RequestID: string(ev.RequestID),
-				Timestamp: time.Now(),
-			}
-			select {
-			case events <- evt:
-			default:
-			}
+	events := lib.NewEventRing[lib.NetworkEvent](args.Buffer)
+	var pending sync.WaitGroup
+
+	if args.Bodies || args.BodiesDir != "" {
+		opts := lib.BodyOptions{Dir: args.BodiesDir, MaxInline: args.MaxBodySize, Pending: &pending}
+		err := lib.ListenNetworkBodies(targetCtx, opts, events.Push)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
 		}
-	})
+	}
 
-	if err := chromedp.Run(targetCtx, network.Enable()); err != nil {
+	if args.WebSockets {
+		opts := lib.WebSocketOptions{MaxFrameSize: args.MaxFrameSize}
+		err := lib.ListenWebSockets(targetCtx, opts, events.Push)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	err = lib.ListenNetwork(targetCtx, events.Push)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
+	}
+	if len(args.Block) > 0 {
+		patterns, err := lib.ExpandBlockPatterns(args.Block)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		if err := chromedp.Run(targetCtx, network.SetBlockedURLs(patterns)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		defer unblock(targetCtx)
 	}
 	if strings.TrimSpace(args.Eval) != "" {
 		err := chromedp.Run(targetCtx, chromedp.Evaluate(args.Eval, nil))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+			lib.Exit(1)
 		}
 	}
 
-	if args.Follow {
-		for {
-			evt := <-events
-			lib.PrintJSONLine(evt)
-		}
+	waitCtx, stop := lib.InterruptContext(context.Background())
+	defer stop()
+	if !args.Follow {
+		var waitCancel context.CancelFunc
+		waitCtx, waitCancel = context.WithTimeout(waitCtx, time.Duration(args.Duration)*time.Second)
+		defer waitCancel()
+	}
+	if err := drain(waitCtx, events, sink); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	// Bodies still being fetched get a moment to arrive, then what they
+	// queued is written too
+	if !lib.WaitTimeout(&pending, 2*time.Second) {
+		fmt.Fprintf(os.Stderr, "warning: stopped before every body was fetched\n")
 	}
+	if err := drain(waitCtx, events, sink); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if n := sink.Dropped(); n > 0 {
+		fmt.Fprintf(os.Stderr, "warning: dropped %d events, output fell behind\n", n)
+	}
+}
 
-	deadline := time.After(time.Duration(args.Duration) * time.Second)
+// drain writes events to sink until ctx ends and the ring is empty.
+func drain(ctx context.Context, events *lib.EventRing[lib.NetworkEvent], sink *lib.EventSink) error {
 	for {
-		select {
-		case evt := <-events:
-			lib.PrintJSONLine(evt)
-		case <-deadline:
-			return
+		evt, dropped, ok := events.Pop(ctx)
+		if !ok {
+			return nil
+		}
+		if err := sink.Write(evt, dropped); err != nil {
+			return err
 		}
 	}
 }
+
+// unblock lifts --block patterns before exiting, rather than leaving it to
+// Chrome to drop them when the connection closes.
+func unblock(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := chromedp.Run(ctx, network.SetBlockedURLs([]string{})); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to lift --block patterns: %v\n", err)
+	}
+}
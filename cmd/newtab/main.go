@@ -37,7 +37,13 @@ func newtab() {
 
 	if !lib.IsChromeRunning() {
 		fmt.Fprintf(os.Stderr, "Chrome not running on port %d\n", lib.GetPort())
-		os.Exit(1)
+		lib.Exit(1)
+	}
+	if args.URL != "about:blank" {
+		if err := lib.PoliteWait(context.Background(), args.URL); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), lib.DefaultTimeout)
@@ -66,7 +72,7 @@ func newtab() {
 
 	if err := chromedp.Run(tabCtx, actions...); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	fmt.Printf("Created tab: %s\n", targetID)
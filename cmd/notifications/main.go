@@ -0,0 +1,208 @@
+// notifications streams web notifications and push subscription changes.
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["notifications"] = notifications
+	lib.Args["notifications"] = notificationsArgs{}
+}
+
+type notificationsArgs struct {
+	lib.TargetArgs
+	Duration int    `arg:"-d,--duration" default:"5" help:"duration in seconds to capture notifications"`
+	Follow   bool   `arg:"-f,--follow" help:"follow mode, capture notifications until Ctrl+C"`
+	Eval     string `arg:"--eval" help:"JavaScript to evaluate after capture starts"`
+}
+
+func (notificationsArgs) Description() string {
+	return `notifications - Capture web notifications and push subscriptions
+
+Grants the notifications permission, then reports each notification the page
+shows and each push subscription it creates or removes, as JSON, one object
+per line (NDJSON):
+
+  {"type":"notification","source":"window","title":"New message",
+   "body":"Alice: hi","tag":"msg-1","url":"http://localhost:3000/inbox",
+   "timestamp":"2024-01-15T10:30:00Z"}
+
+type is notification, push-subscribe, or push-unsubscribe. source is
+window (new Notification) or service-worker (registration.showNotification
+called from the page). Push subscriptions include endpoint. Notifications a
+service worker shows from its own push handler are not seen.
+
+Hooks are installed in the current document and every document it
+navigates to while capturing.
+
+Example:
+  chrome notifications                          # capture for 5 seconds
+  chrome notifications -f                       # until Ctrl+C
+  chrome notifications --eval "document.querySelector('#notify').click()"`
+}
+
+const bindingName = "__chromeCliNotification"
+
+// hookScript wraps the Notification constructor, showNotification, and the
+// push subscription calls to report through the binding, then defers to the
+// originals so the page behaves as before.
+const hookScript = `(() => {
+  if (window.__chromeCliNotificationHooked) return;
+  window.__chromeCliNotificationHooked = true;
+  const report = (event) => {
+    try {
+      window.` + bindingName + `(JSON.stringify(Object.assign({ url: location.href }, event)));
+    } catch (e) {}
+  };
+  const fields = (title, options) => {
+    options = options || {};
+    let data;
+    try { data = JSON.parse(JSON.stringify(options.data)); } catch (e) {}
+    return { title: String(title), body: options.body, tag: options.tag, icon: options.icon, data };
+  };
+  const Original = window.Notification;
+  if (Original) {
+    const Hooked = function(title, options) {
+      const n = new Original(title, options);
+      report(Object.assign({ type: 'notification', source: 'window' }, fields(title, options)));
+      return n;
+    };
+    Hooked.prototype = Original.prototype;
+    Object.defineProperty(Hooked, 'permission', { get: () => Original.permission });
+    Hooked.requestPermission = (...args) => Original.requestPermission(...args);
+    Hooked.maxActions = Original.maxActions;
+    window.Notification = Hooked;
+  }
+  if (window.ServiceWorkerRegistration) {
+    const show = ServiceWorkerRegistration.prototype.showNotification;
+    ServiceWorkerRegistration.prototype.showNotification = function(title, options) {
+      report(Object.assign({ type: 'notification', source: 'service-worker' }, fields(title, options)));
+      return show.call(this, title, options);
+    };
+  }
+  if (window.PushManager) {
+    const subscribe = PushManager.prototype.subscribe;
+    PushManager.prototype.subscribe = async function(...args) {
+      const sub = await subscribe.apply(this, args);
+      report({ type: 'push-subscribe', endpoint: sub && sub.endpoint });
+      return sub;
+    };
+  }
+  if (window.PushSubscription) {
+    const unsubscribe = PushSubscription.prototype.unsubscribe;
+    PushSubscription.prototype.unsubscribe = async function(...args) {
+      const ok = await unsubscribe.apply(this, args);
+      report({ type: 'push-unsubscribe', endpoint: this.endpoint, ok });
+      return ok;
+    };
+  }
+})()`
+
+type notificationEvent struct {
+	Type      string          `json:"type"`
+	Source    string          `json:"source,omitempty"`
+	Title     string          `json:"title,omitempty"`
+	Body      string          `json:"body,omitempty"`
+	Tag       string          `json:"tag,omitempty"`
+	Icon      string          `json:"icon,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Endpoint  string          `json:"endpoint,omitempty"`
+	OK        *bool           `json:"ok,omitempty"`
+	URL       string          `json:"url"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+func notifications() {
+	var args notificationsArgs
+	arg.MustParse(&args)
+
+	ctxTimeout := lib.DefaultTimeout
+	if args.Follow {
+		ctxTimeout = 0
+	} else if d := time.Duration(args.Duration)*time.Second + 5*time.Second; d > ctxTimeout {
+		ctxTimeout = d
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(ctxTimeout)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	events := make(chan notificationEvent, 100)
+	chromedp.ListenTarget(targetCtx, func(ev interface{}) {
+		called, ok := ev.(*runtime.EventBindingCalled)
+		if !ok || called.Name != bindingName {
+			return
+		}
+		var e notificationEvent
+		if json.Unmarshal([]byte(called.Payload), &e) != nil {
+			return
+		}
+		e.Timestamp = time.Now().UTC()
+		select {
+		case events <- e:
+		default:
+		}
+	})
+
+	err = chromedp.Run(targetCtx,
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			return browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeNotifications}).Do(ctx)
+		}),
+		runtime.Enable(),
+		runtime.AddBinding(bindingName),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(hookScript).Do(ctx)
+			return err
+		}),
+		chromedp.Evaluate(hookScript, nil),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if strings.TrimSpace(args.Eval) != "" {
+		if err := chromedp.Run(targetCtx, chromedp.Evaluate(args.Eval, nil)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	var deadline <-chan time.Time
+	if !args.Follow {
+		deadline = time.After(time.Duration(args.Duration) * time.Second)
+	}
+	for {
+		select {
+		case e := <-events:
+			lib.PrintJSONLine(e)
+		case <-deadline:
+			return
+		case <-sig:
+			return
+		case <-targetCtx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// options provides select element option listing command
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["options"] = options
+	lib.Args["options"] = optionsArgs{}
+}
+
+type optionsArgs struct {
+	lib.TargetArgs
+	Selector string `arg:"positional,required" help:"CSS selector of select element"`
+}
+
+func (optionsArgs) Description() string {
+	return `options - List a select element's options
+
+Prints the options of a SELECT element as JSON, including value, label,
+selected, and disabled. Use this to pick a valid value before calling fill.
+
+Example:
+  chrome options "#country"
+  chrome options "select[name='size']"`
+}
+
+type option struct {
+	Index    int    `json:"index"`
+	Value    string `json:"value"`
+	Label    string `json:"label"`
+	Selected bool   `json:"selected"`
+	Disabled bool   `json:"disabled"`
+	Group    string `json:"group,omitempty"`
+}
+
+func options() {
+	var args optionsArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	script := fmt.Sprintf(`(() => {
+	  const el = document.querySelector(%q);
+	  if (!el) return { ok: false, error: "element not found" };
+	  if (el.tagName !== 'SELECT') return { ok: false, error: "element is not a SELECT" };
+	  return {
+	    ok: true,
+	    options: Array.from(el.options).map((o, i) => {
+	      const group = o.parentElement && o.parentElement.tagName === 'OPTGROUP' ? o.parentElement : null;
+	      return {
+	        index: i,
+	        value: o.value,
+	        label: (o.label || o.textContent || '').trim(),
+	        selected: o.selected,
+	        disabled: o.disabled || (group !== null && group.disabled),
+	        group: group ? group.label : '',
+	      };
+	    }),
+	  };
+	})()`, args.Selector)
+
+	var res struct {
+		Ok      bool     `json:"ok"`
+		Error   string   `json:"error"`
+		Options []option `json:"options"`
+	}
+	if err := chromedp.Run(targetCtx, chromedp.Evaluate(script, &res)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if !res.Ok {
+		fmt.Fprintf(os.Stderr, "error: %s (selector %q)\n", res.Error, args.Selector)
+		lib.Exit(1)
+	}
+
+	if res.Options == nil {
+		res.Options = []option{}
+	}
+	jsonBytes, err := json.MarshalIndent(res.Options, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
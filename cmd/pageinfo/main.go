@@ -0,0 +1,146 @@
+// pageinfo reports a page's language, encoding, size, resources, and SPA signals.
+package pageinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["pageinfo"] = pageinfo
+	lib.Args["pageinfo"] = pageinfoArgs{}
+}
+
+type pageinfoArgs struct {
+	lib.TargetArgs
+}
+
+func (pageinfoArgs) Description() string {
+	return `pageinfo - Describe the page for routing it to an extractor
+
+Prints JSON about the loaded page:
+
+  lang        declared language: <html lang>, else Content-Language meta
+  script      dominant writing system of the visible text (latin, cyrillic,
+              cjk, arabic, ...), a hint when lang is missing or wrong
+  charset     document encoding, and contentType (text/html, image/png, ...)
+  size        HTML bytes, visible text characters, element count
+  resources   loaded resources by type, with bytes transferred
+  spa         likely true when the page looks client-rendered: a framework
+              root, state blob, or global, history.pushState wrapped by a
+              router, or a noscript warning; signals lists what was seen
+
+Example:
+  chrome pageinfo
+  chrome pageinfo | jq -r '.spa.likely'
+  chrome pageinfo -t docs | jq '{lang, charset, size}'`
+}
+
+const script = `(() => {
+  const meta = document.querySelector('meta[http-equiv="content-language" i]');
+  const text = document.body ? document.body.innerText || '' : '';
+
+  const scripts = {
+    latin: /[A-Za-zÀ-ɏ]/, cyrillic: /[Ѐ-ӿ]/, greek: /[Ͱ-Ͽ]/,
+    arabic: /[؀-ۿ]/, hebrew: /[֐-׿]/, devanagari: /[ऀ-ॿ]/,
+    thai: /[฀-๿]/, hangul: /[가-힯]/, kana: /[぀-ヿ]/, cjk: /[一-鿿]/,
+  };
+  const counts = {};
+  for (const ch of text.slice(0, 20000)) {
+    for (const [name, re] of Object.entries(scripts)) {
+      if (re.test(ch)) { counts[name] = (counts[name] || 0) + 1; break; }
+    }
+  }
+  // Japanese mixes kana with kanji, report it as kana rather than cjk
+  if (counts.kana && counts.cjk) { counts.kana += counts.cjk; delete counts.cjk; }
+  let dominant = '';
+  for (const [name, n] of Object.entries(counts)) if (!dominant || n > counts[dominant]) dominant = name;
+
+  const html = document.documentElement ? document.documentElement.outerHTML : '';
+  const resources = {};
+  let transferred = 0;
+  for (const r of performance.getEntriesByType('resource')) {
+    const type = r.initiatorType || 'other';
+    resources[type] = resources[type] || { count: 0, bytes: 0 };
+    resources[type].count++;
+    resources[type].bytes += r.transferSize || 0;
+    transferred += r.transferSize || 0;
+  }
+  const nav = performance.getEntriesByType('navigation')[0];
+
+  const signals = [];
+  const roots = ['#root', '#app', '#__next', '#__nuxt', '#___gatsby', '[data-reactroot]', '[ng-version]', '[data-v-app]', '#svelte', '[data-sveltekit-hydrated]'];
+  for (const sel of roots) if (document.querySelector(sel)) signals.push('root ' + sel);
+  for (const name of ['__NEXT_DATA__', '__NUXT__', '__remixContext', '__APOLLO_STATE__', '__INITIAL_STATE__', '__PRELOADED_STATE__']) {
+    if (name in window || document.getElementById(name)) signals.push('data ' + name);
+  }
+  for (const name of ['React', 'Vue', 'angular', 'Ember', '__REACT_DEVTOOLS_GLOBAL_HOOK__', '__VUE__']) {
+    if (window[name] && !signals.includes('global ' + name)) signals.push('global ' + name);
+  }
+  if (!Function.prototype.toString.call(history.pushState).includes('[native code]')) signals.push('history.pushState wrapped');
+  const scriptCount = document.scripts.length;
+  const noscript = document.querySelector('noscript');
+  if (noscript && /enable javascript|requires javascript/i.test(noscript.textContent)) signals.push('noscript asks for JavaScript');
+
+  return {
+    url: location.href,
+    title: document.title,
+    lang: (document.documentElement && document.documentElement.lang) || (meta && meta.content) || '',
+    script: dominant,
+    charset: document.characterSet,
+    contentType: document.contentType,
+    size: {
+      htmlBytes: new TextEncoder().encode(html).length,
+      responseBytes: nav ? nav.transferSize : 0,
+      textChars: text.length,
+      elements: document.getElementsByTagName('*').length,
+    },
+    resources: {
+      total: Object.values(resources).reduce((n, r) => n + r.count, 0),
+      bytes: transferred,
+      byType: resources,
+      dom: {
+        scripts: scriptCount,
+        stylesheets: document.styleSheets.length,
+        images: document.images.length,
+        iframes: document.querySelectorAll('iframe').length,
+        links: document.links.length,
+        forms: document.forms.length,
+      },
+    },
+    spa: { likely: signals.length >= 2 || signals.some(s => s.startsWith('data ') || s === 'history.pushState wrapped'), signals },
+  };
+})()`
+
+func pageinfo() {
+	var args pageinfoArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	var result map[string]any
+	if err := chromedp.Run(targetCtx, chromedp.Evaluate(script, &result)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	jsonBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -0,0 +1,230 @@
+// paginate runs an extraction command on each page of a paginated listing.
+package paginate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["paginate"] = paginate
+	lib.Args["paginate"] = paginateArgs{}
+}
+
+type paginateArgs struct {
+	lib.TargetArgs
+	NextSelector string   `arg:"-s,--next-selector,required" help:"CSS selector of the next-page control"`
+	UntilMissing bool     `arg:"--until-missing" help:"stop cleanly when the next control is missing or disabled"`
+	MaxPages     int      `arg:"-m,--max-pages" help:"stop after this many pages (0: no limit)"`
+	Delay        int      `arg:"-d,--delay" default:"500" help:"milliseconds to let a new page settle before extracting"`
+	Timeout      int      `arg:"--timeout" default:"30" help:"seconds to wait for the page to change after clicking next"`
+	JSON         bool     `arg:"--json" help:"parse each page's output as JSON and print one combined array"`
+	Command      []string `arg:"positional,required" help:"chrome command and args to run on each page (after --)"`
+}
+
+func (paginateArgs) Description() string {
+	return `paginate - Run a command on every page of a paginated listing
+
+Runs COMMAND on the current page, clicks --next-selector, waits for the page
+to change, and repeats. Output from each run is written to stdout in order.
+
+Stops when the next control is missing, disabled (disabled attribute,
+aria-disabled="true", or a "disabled" class), or hidden; when --max-pages is
+reached; or when clicking next no longer changes the page. At least one of
+--until-missing or --max-pages is required. Without --until-missing, running
+out of pages before --max-pages is an error.
+
+With --json, each page's output must be JSON: arrays are flattened and other
+values appended, and one combined array is printed at the end.
+
+COMMAND runs with the same tab as its target, so any chrome command works.
+
+Example:
+  chrome paginate --next-selector ".next" --until-missing -- eval "[...document.querySelectorAll('.item')].map(e => e.textContent)"
+  chrome paginate -s "a[rel=next]" --until-missing --json -- eval "[...document.querySelectorAll('h2')].map(e => e.innerText)"
+  chrome paginate -s "button.load-more" --max-pages 5 -- html`
+}
+
+// nextStateScript reports whether the next control can be clicked.
+const nextStateScript = `(() => {
+  const el = document.querySelector(%s);
+  if (!el) return 'missing';
+  if (el.disabled || el.getAttribute('aria-disabled') === 'true' || el.classList.contains('disabled')) return 'disabled';
+  const r = el.getBoundingClientRect();
+  if (r.width === 0 && r.height === 0) return 'hidden';
+  return 'ok';
+})()`
+
+// signatureScript fingerprints the URL and visible text to detect a page change.
+const signatureScript = `(() => {
+  const text = document.body ? document.body.innerText : '';
+  let h = 0;
+  for (let i = 0; i < text.length; i++) h = (h * 31 + text.charCodeAt(i)) | 0;
+  return location.href + '#' + text.length + ':' + h;
+})()`
+
+func paginate() {
+	var args paginateArgs
+	arg.MustParse(&args)
+
+	if !args.UntilMissing && args.MaxPages <= 0 {
+		fmt.Fprintf(os.Stderr, "error: one of --until-missing or --max-pages is required\n")
+		lib.Exit(1)
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	timeout := time.Duration(args.Timeout) * time.Second
+	var combined []json.RawMessage
+	page := 1
+	stop := ""
+	for {
+		output, err := lib.RunCommand(tabID, args.Command)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: page %d: %v\n", page, err)
+			lib.Exit(1)
+		}
+		if args.JSON {
+			values, err := jsonValues(output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: page %d: %v\n", page, err)
+				lib.Exit(1)
+			}
+			combined = append(combined, values...)
+		} else {
+			_, _ = os.Stdout.Write(output)
+		}
+
+		if args.MaxPages > 0 && page >= args.MaxPages {
+			break
+		}
+		stop, err = nextPage(ctx, args.NextSelector, timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: page %d: %v\n", page, err)
+			lib.Exit(1)
+		}
+		if stop != "" {
+			break
+		}
+		time.Sleep(time.Duration(args.Delay) * time.Millisecond)
+		page++
+	}
+
+	if args.JSON {
+		if combined == nil {
+			combined = []json.RawMessage{}
+		}
+		data, err := json.MarshalIndent(combined, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(data))
+	}
+
+	switch {
+	case stop == "":
+		fmt.Fprintf(os.Stderr, "stopped after %d page(s): reached --max-pages\n", page)
+	case args.UntilMissing:
+		fmt.Fprintf(os.Stderr, "stopped after %d page(s): %s\n", page, stop)
+	default:
+		fmt.Fprintf(os.Stderr, "error: stopped after %d of %d pages: %s\n", page, args.MaxPages, stop)
+		lib.Exit(1)
+	}
+}
+
+// nextPage clicks the next control and waits for the page to change. It
+// returns a non-empty reason when there is no next page.
+func nextPage(tabCtx context.Context, selector string, timeout time.Duration) (string, error) {
+	checkCtx, checkCancel := context.WithTimeout(tabCtx, timeout)
+	defer checkCancel()
+
+	var state, before, next string
+	err := chromedp.Run(checkCtx,
+		chromedp.Evaluate(fmt.Sprintf(nextStateScript, strconv.Quote(selector)), &state),
+		chromedp.Evaluate(signatureScript, &before),
+		chromedp.Evaluate(fmt.Sprintf(`(document.querySelector(%s) || {}).href || location.href`, strconv.Quote(selector)), &next),
+	)
+	if err != nil {
+		return "", err
+	}
+	if state != "ok" {
+		return fmt.Sprintf("next control %s is %s", selector, state), nil
+	}
+	// A link's target, else the current page's host, for crawl etiquette
+	if err := lib.PoliteWait(tabCtx, next); err != nil {
+		return "", err
+	}
+	// The wait does not count against the page change timeout
+	ctx, cancel := context.WithTimeout(tabCtx, timeout)
+	defer cancel()
+	if err := lib.Click(ctx, selector); err != nil {
+		return "", err
+	}
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "clicking next did not change the page", nil
+			}
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+		// Evaluate fails while a navigation swaps the document; retry
+		var after, ready string
+		err := chromedp.Run(ctx,
+			chromedp.Evaluate(signatureScript, &after),
+			chromedp.Evaluate(`document.readyState`, &ready),
+		)
+		if err == nil && after != before && ready != "loading" {
+			return "", nil
+		}
+	}
+}
+
+// jsonValues decodes one or more JSON values, flattening top-level arrays.
+func jsonValues(output []byte) ([]json.RawMessage, error) {
+	var values []json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("output is not JSON: %w", err)
+		}
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) == nil {
+			values = append(values, items...)
+		} else {
+			values = append(values, raw)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,162 @@
+// pdf saves the current tab as a PDF document.
+package pdf
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["pdf"] = pdf
+	lib.Args["pdf"] = pdfArgs{}
+}
+
+type pdfArgs struct {
+	lib.TargetArgs
+	Path           string   `arg:"--path" help:"exact file path for the PDF (overrides output dir)"`
+	OutputDir      string   `arg:"-o,--output-dir" help:"directory to store the PDF (default: ~/chrome-shots)"`
+	Label          string   `arg:"-l,--label" default:"page" help:"label embedded in filename"`
+	Paper          string   `arg:"--paper" default:"letter" help:"letter, legal, tabloid, a3, a4, a5, or WIDTHxHEIGHT in inches"`
+	Landscape      bool     `arg:"--landscape" help:"landscape orientation"`
+	Margin         *float64 `arg:"--margin" help:"all margins in inches (default: ~0.4)"`
+	MarginTop      *float64 `arg:"--margin-top" help:"top margin in inches"`
+	MarginBottom   *float64 `arg:"--margin-bottom" help:"bottom margin in inches"`
+	MarginLeft     *float64 `arg:"--margin-left" help:"left margin in inches"`
+	MarginRight    *float64 `arg:"--margin-right" help:"right margin in inches"`
+	Background     bool     `arg:"--background" help:"print background colors and images"`
+	Scale          float64  `arg:"--scale" default:"1" help:"page rendering scale (0.1 to 2)"`
+	Pages          string   `arg:"--pages" help:"page ranges to print, e.g. 1-5, 8, 11-13"`
+	HeaderTemplate string   `arg:"--header-template" help:"HTML header (supports date, title, url, pageNumber, totalPages classes)"`
+	FooterTemplate string   `arg:"--footer-template" help:"HTML footer (same classes as --header-template)"`
+	CSSPageSize    bool     `arg:"--css-page-size" help:"prefer the page size from CSS @page over --paper"`
+}
+
+func (pdfArgs) Description() string {
+	return `pdf - Save the current tab as a PDF
+
+Uses Page.printToPDF. Writes to ~/chrome-shots/<timestamp>-<label>.pdf
+unless --path is given. Headless or not, Chrome prints with print media CSS.
+
+Header and footer templates are HTML; elements with class date, title, url,
+pageNumber, or totalPages get those values injected. Templates need an
+explicit font-size (e.g. style="font-size:10px") to be visible, and enough
+top/bottom margin to fit.
+
+Examples:
+  chrome pdf
+  chrome pdf --path /tmp/report.pdf --paper a4 --background
+  chrome pdf --landscape --margin 0.5 --pages 1-3
+  chrome pdf --footer-template '<div style="font-size:9px;margin:auto"><span class="pageNumber"></span>/<span class="totalPages"></span></div>' --margin-bottom 0.6`
+}
+
+// paperSizes are width and height in inches.
+var paperSizes = map[string][2]float64{
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+}
+
+func pdf() {
+	var args pdfArgs
+	arg.MustParse(&args)
+
+	width, height, err := parsePaper(args.Paper)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	params := page.PrintToPDF().
+		WithPaperWidth(width).
+		WithPaperHeight(height).
+		WithLandscape(args.Landscape).
+		WithPrintBackground(args.Background).
+		WithScale(args.Scale).
+		WithPageRanges(args.Pages).
+		WithPreferCSSPageSize(args.CSSPageSize)
+	// The With methods copy params, so each result must be kept in turn
+	if v := cmp.Or(args.MarginTop, args.Margin); v != nil {
+		params = params.WithMarginTop(*v)
+	}
+	if v := cmp.Or(args.MarginBottom, args.Margin); v != nil {
+		params = params.WithMarginBottom(*v)
+	}
+	if v := cmp.Or(args.MarginLeft, args.Margin); v != nil {
+		params = params.WithMarginLeft(*v)
+	}
+	if v := cmp.Or(args.MarginRight, args.Margin); v != nil {
+		params = params.WithMarginRight(*v)
+	}
+	if args.HeaderTemplate != "" || args.FooterTemplate != "" {
+		// Chrome fills in a default for whichever template is empty; use a blank one
+		header, footer := args.HeaderTemplate, args.FooterTemplate
+		if header == "" {
+			header = "<span></span>"
+		}
+		if footer == "" {
+			footer = "<span></span>"
+		}
+		params = params.WithDisplayHeaderFooter(true).WithHeaderTemplate(header).WithFooterTemplate(footer)
+	}
+
+	path, err := lib.PrepareOutputPath(args.Path, args.OutputDir, args.Label, ".pdf")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error preparing pdf path: %v\n", err)
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	var buf []byte
+	err = chromedp.Run(targetCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, _, err = params.Do(ctx)
+		return err
+	}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("saved %s\n", path)
+}
+
+func parsePaper(paper string) (float64, float64, error) {
+	name := strings.ToLower(strings.TrimSpace(paper))
+	if size, ok := paperSizes[name]; ok {
+		return size[0], size[1], nil
+	}
+	w, h, ok := strings.Cut(name, "x")
+	if ok {
+		width, errW := strconv.ParseFloat(w, 64)
+		height, errH := strconv.ParseFloat(h, 64)
+		if errW == nil && errH == nil && width > 0 && height > 0 {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("unknown paper %q, expected letter, legal, tabloid, a3, a4, a5, or WIDTHxHEIGHT in inches", paper)
+}
@@ -0,0 +1,157 @@
+// perfdiff compares two HAR captures and reports performance regressions.
+package perfdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["perfdiff"] = perfdiff
+	lib.Args["perfdiff"] = perfdiffArgs{}
+}
+
+type perfdiffArgs struct {
+	A         string  `arg:"positional,required" help:"baseline HAR file"`
+	B         string  `arg:"positional,required" help:"HAR file to compare against the baseline"`
+	Threshold float64 `arg:"-t,--threshold" default:"10" help:"percent increase that counts as a regression"`
+	MinMs     float64 `arg:"--min-ms" default:"50" help:"ignore timing increases smaller than this many ms"`
+	MinBytes  int64   `arg:"--min-bytes" default:"10240" help:"ignore size increases smaller than this many bytes"`
+	All       bool    `arg:"-a,--all" help:"show every type and domain, not only changed ones"`
+	JSON      bool    `arg:"--json" help:"print the comparison as JSON"`
+	Fail      bool    `arg:"--fail" help:"exit 1 if anything regressed"`
+}
+
+func (perfdiffArgs) Description() string {
+	return `perfdiff - Compare two HAR captures and report regressions
+
+Groups requests overall, by resource type, and by domain, and compares
+request count, failed requests, bytes transferred, and p50/p95/max request
+time between run A (baseline) and run B. A stat regresses when it grows by
+more than --threshold percent and by more than --min-ms or --min-bytes, so
+small groups do not flag on noise. Regressed rows are marked with "!".
+
+HAR files come from chrome har, DevTools (Network panel, "Save all as
+HAR"), proxies, or Playwright's recordHar. Bytes use Chrome's _transferSize when present.
+
+Example:
+  chrome perfdiff before.har after.har
+  chrome perfdiff main.har branch.har --threshold 5 --fail
+  chrome perfdiff a.har b.har --json | jq '.rows[] | select(.regressions)'`
+}
+
+func perfdiff() {
+	var args perfdiffArgs
+	arg.MustParse(&args)
+
+	a, err := lib.LoadHAR(args.A)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	b, err := lib.LoadHAR(args.B)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	diff := lib.ComparePerf(a, b, lib.PerfThresholds{
+		Percent:  args.Threshold,
+		MinMs:    args.MinMs,
+		MinBytes: args.MinBytes,
+	})
+
+	if args.JSON {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		report(diff, args.All)
+	}
+
+	if args.Fail && diff.Regressed {
+		lib.Exit(1)
+	}
+}
+
+func report(diff lib.PerfDiff, all bool) {
+	if diff.LoadA > 0 || diff.LoadB > 0 {
+		fmt.Printf("page load: %s -> %s (%s)\n\n", ms(diff.LoadA), ms(diff.LoadB), change(diff.LoadA, diff.LoadB))
+	}
+	group := ""
+	hidden := 0
+	for _, row := range diff.Rows {
+		if row.Group != "total" && !all && len(row.Regressions) == 0 && row.A == row.B {
+			hidden++
+			continue
+		}
+		if row.Group != group {
+			if group != "" {
+				fmt.Println()
+			}
+			group = row.Group
+			fmt.Printf("%-2s%-32s %15s %21s %21s %21s %8s\n", "", "by "+group, "requests", "bytes", "p50", "p95", "failed")
+		}
+		mark := ""
+		if len(row.Regressions) > 0 {
+			mark = "!"
+		}
+		fmt.Printf("%-2s%-32s %15s %21s %21s %21s %8s\n",
+			mark,
+			clip(row.Key, 32),
+			fmt.Sprintf("%d -> %d", row.A.Requests, row.B.Requests),
+			fmt.Sprintf("%s -> %s", lib.FormatBytes(row.A.Bytes), lib.FormatBytes(row.B.Bytes)),
+			fmt.Sprintf("%s -> %s", ms(row.A.P50), ms(row.B.P50)),
+			fmt.Sprintf("%s -> %s", ms(row.A.P95), ms(row.B.P95)),
+			fmt.Sprintf("%d -> %d", row.A.Failed, row.B.Failed),
+		)
+	}
+	if hidden > 0 {
+		fmt.Printf("\n%d unchanged groups hidden, use --all to show them\n", hidden)
+	}
+
+	var regressed []string
+	for _, row := range diff.Rows {
+		if len(row.Regressions) > 0 {
+			regressed = append(regressed, fmt.Sprintf("%s %s: %s", row.Group, row.Key, strings.Join(row.Regressions, ", ")))
+		}
+	}
+	fmt.Println()
+	if len(regressed) == 0 {
+		fmt.Println("no regressions")
+		return
+	}
+	fmt.Printf("%d regressions:\n", len(regressed))
+	for _, r := range regressed {
+		fmt.Println("  " + r)
+	}
+}
+
+func ms(v float64) string {
+	if v >= 1000 {
+		return fmt.Sprintf("%.2fs", v/1000)
+	}
+	return fmt.Sprintf("%.0fms", v)
+}
+
+func change(a float64, b float64) string {
+	if a == 0 {
+		return "new"
+	}
+	return fmt.Sprintf("%+.0f%%", (b-a)/a*100)
+}
+
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
@@ -0,0 +1,84 @@
+// pixel prints the rendered color at a viewport coordinate.
+package pixel
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["pixel"] = pixel
+	lib.Args["pixel"] = pixelArgs{}
+}
+
+type pixelArgs struct {
+	lib.TargetArgs
+	X          string `arg:"positional,required" help:"X coordinate in pixels"`
+	Y          string `arg:"positional,required" help:"Y coordinate in pixels"`
+	Screenshot bool   `arg:"-s,--screenshot" help:"coordinates are screenshot pixels: divide by devicePixelRatio"`
+}
+
+func (pixelArgs) Description() string {
+	return `pixel - Print the rendered color at a viewport coordinate
+
+Captures the single pixel at X, Y as the compositor drew it, after CSS,
+images, canvas, filters, and overlapping layers, and prints it as #rrggbb.
+Use it to verify theming and state changes that only show visually.
+
+Coordinates are viewport CSS pixels, as used by clickxy. Pass --screenshot
+when reading coordinates off a screenshot on a high-DPI display.
+
+See also 'chrome color SELECTOR' for an element's colors.
+
+Example:
+  chrome pixel 10 10
+  chrome pixel --screenshot 1200 80
+  test "$(chrome pixel 300 200)" = "#1a73e8"`
+}
+
+func pixel() {
+	var args pixelArgs
+	arg.MustParse(&args)
+
+	x, err := strconv.ParseFloat(args.X, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid x coordinate: %v\n", err)
+		lib.Exit(1)
+	}
+	y, err := strconv.ParseFloat(args.Y, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid y coordinate: %v\n", err)
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	if args.Screenshot {
+		var dpr float64
+		if err := chromedp.Run(targetCtx, chromedp.Evaluate(`window.devicePixelRatio || 1`, &dpr)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		x, y = x/dpr, y/dpr
+	}
+
+	c, err := lib.SamplePixel(targetCtx, x, y)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(lib.HexColor(c))
+}
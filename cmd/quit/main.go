@@ -45,7 +45,7 @@ func quitChrome() {
 
 	if !lib.IsChromeRunningOnPort(port) {
 		fmt.Printf("No Chrome instance running on port %d\n", port)
-		os.Exit(0)
+		lib.Exit(0)
 	}
 
 	fmt.Printf("Quitting Chrome on port %d...\n", port)
@@ -69,7 +69,7 @@ func quitChrome() {
 		time.Sleep(500 * time.Millisecond)
 		if lib.IsChromeRunningOnPort(port) {
 			fmt.Fprintf(os.Stderr, "error: failed to quit Chrome: %v\n", err)
-			os.Exit(1)
+			lib.Exit(1)
 		}
 	}
 
@@ -77,7 +77,7 @@ func quitChrome() {
 	time.Sleep(500 * time.Millisecond)
 	if lib.IsChromeRunningOnPort(port) {
 		fmt.Fprintf(os.Stderr, "warning: Chrome may still be running on port %d\n", port)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	// Clean up instance metadata
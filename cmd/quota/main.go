@@ -0,0 +1,167 @@
+// quota reports storage usage and quota per origin.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["quota"] = quota
+	lib.Args["quota"] = quotaArgs{}
+}
+
+type quotaArgs struct {
+	lib.TargetArgs
+	Origins []string `arg:"positional" help:"origins or URLs to report (default: the tab's origin)"`
+	All     bool     `arg:"-a,--all" help:"list every storage type, not just cache, IndexedDB, and service workers"`
+	JSON    bool     `arg:"--json" help:"print one JSON object per origin"`
+}
+
+func (quotaArgs) Description() string {
+	return `quota - Report storage usage and quota per origin
+
+Prints how much of its quota each origin uses, broken down into Cache
+Storage, IndexedDB, service workers, and everything else, for debugging
+QuotaExceededError in offline-capable apps. --all lists every storage type
+Chrome reports. Usage over 80% of quota is flagged.
+
+Example:
+  chrome quota
+  chrome quota https://app.example.com https://cdn.example.com
+  chrome quota --all --json`
+}
+
+// mainTypes are always shown; the rest are summed into "other".
+var mainTypes = []storage.Type{storage.TypeCacheStorage, storage.TypeIndexeddb, storage.TypeServiceWorkers}
+
+type report struct {
+	Origin         string           `json:"origin"`
+	Usage          int64            `json:"usage"`
+	Quota          int64            `json:"quota"`
+	Percent        float64          `json:"percent"`
+	OverrideActive bool             `json:"overrideActive,omitempty"`
+	Breakdown      map[string]int64 `json:"breakdown"`
+}
+
+func quota() {
+	var args quotaArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	origins := args.Origins
+	if len(origins) == 0 {
+		var current string
+		if err := chromedp.Run(targetCtx, chromedp.Evaluate("location.origin", &current)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		if !strings.HasPrefix(current, "http") {
+			fmt.Fprintf(os.Stderr, "error: the tab has no origin (%s), pass one\n", current)
+			lib.Exit(1)
+		}
+		origins = []string{current}
+	}
+
+	for i, raw := range origins {
+		origin, err := lib.NormalizeOrigin(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		r := report{Origin: origin, Breakdown: map[string]int64{}}
+		err = chromedp.Run(targetCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+			usage, quota, override, breakdown, err := storage.GetUsageAndQuota(origin).Do(ctx)
+			if err != nil {
+				return err
+			}
+			r.Usage, r.Quota, r.OverrideActive = int64(usage), int64(quota), override
+			for _, t := range mainTypes {
+				r.Breakdown[string(t)] = 0
+			}
+			if !args.All {
+				r.Breakdown["other"] = 0
+			}
+			for _, b := range breakdown {
+				name := string(b.StorageType)
+				if !args.All && !isMain(b.StorageType) {
+					name = "other"
+				}
+				r.Breakdown[name] += int64(b.Usage)
+			}
+			return nil
+		}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", origin, err)
+			lib.Exit(1)
+		}
+		if r.Quota > 0 {
+			r.Percent = float64(r.Usage) / float64(r.Quota) * 100
+		}
+
+		if args.JSON {
+			lib.PrintJSONLine(r)
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		flag := ""
+		if r.Percent >= 80 {
+			flag = "  near quota"
+		}
+		if r.OverrideActive {
+			flag += "  (quota overridden)"
+		}
+		fmt.Printf("%s  %s of %s (%.1f%%)%s\n", r.Origin, lib.FormatBytes(r.Usage), lib.FormatBytes(r.Quota), r.Percent, flag)
+		for _, name := range breakdownOrder(r.Breakdown) {
+			fmt.Printf("  %-20s %10s\n", name, lib.FormatBytes(r.Breakdown[name]))
+		}
+	}
+}
+
+func isMain(t storage.Type) bool {
+	for _, m := range mainTypes {
+		if t == m {
+			return true
+		}
+	}
+	return false
+}
+
+// breakdownOrder lists the main types first, then the rest by size.
+func breakdownOrder(breakdown map[string]int64) []string {
+	var names, rest []string
+	for _, t := range mainTypes {
+		names = append(names, string(t))
+	}
+	for name, usage := range breakdown {
+		if !isMain(storage.Type(name)) && (usage > 0 || name == "other") {
+			rest = append(rest, name)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		if breakdown[rest[i]] != breakdown[rest[j]] {
+			return breakdown[rest[i]] > breakdown[rest[j]]
+		}
+		return rest[i] < rest[j]
+	})
+	return append(names, rest...)
+}
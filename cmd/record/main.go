@@ -0,0 +1,282 @@
+// record captures user interactions in a tab as a workflow for `chrome run`.
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["record"] = record
+	lib.Args["record"] = recordArgs{}
+}
+
+type recordArgs struct {
+	lib.TargetArgs
+	Output      string `arg:"-o,--output" help:"workflow file to write (default: stdout)"`
+	Name        string `arg:"-n,--name" default:"recorded" help:"workflow name"`
+	Duration    int    `arg:"-d,--duration" help:"stop after N seconds (default: until Ctrl+C)"`
+	Screenshots bool   `arg:"-s,--screenshots" help:"save a screenshot and StepRecord after each recorded step"`
+	OutputDir   string `arg:"--output-dir" help:"directory for --screenshots (default: ~/chrome-shots)"`
+}
+
+func (recordArgs) Description() string {
+	return `record - Record clicks, typing, and navigations as a workflow
+
+Injects a listener into the tab (kept across navigations) and records what
+you do in the browser as steps for 'chrome run':
+  navigate   starting URL, and typed/reloaded/address bar navigations
+  click      clicks on anything except text fields
+  fill       final value of inputs, textareas, selects, and contenteditables
+
+Navigations caused by clicks and form submits are not recorded, since
+replaying the click reproduces them. Stop with Ctrl+C (or SIGTERM) or
+--duration: interactions already reported are recorded, the listener is
+removed from the tab, and the workflow is written as YAML. A second Ctrl+C
+exits at once.
+
+With --screenshots, each step is also saved as a StepRecord with a
+screenshot, so 'chrome slideshow' can replay the session.
+
+Example:
+  chrome record -o login.yaml
+  chrome record -d 60 -s > flow.yaml
+  chrome run login.yaml`
+}
+
+const bindingName = "__chromeRecord"
+
+// recorderScript reports interactions to the binding. Clicks into text fields
+// are skipped since the following fill covers them.
+const recorderScript = lib.CSSPathJS + `
+(() => {
+  if (window !== window.top || window.__chromeRecorder) return;
+  window.__chromeRecorder = true;
+  const send = (ev) => { try { window.` + bindingName + `(JSON.stringify(ev)); } catch (e) {} };
+  const isField = (el) => el.isContentEditable || el.tagName === 'TEXTAREA' || el.tagName === 'SELECT' ||
+    (el.tagName === 'INPUT' && !['checkbox', 'radio', 'button', 'submit', 'reset', 'image', 'file'].includes(el.type));
+  document.addEventListener('click', (e) => {
+    const el = e.target instanceof Element ? e.target : null;
+    if (!el || !e.isTrusted || isField(el)) return;
+    const text = (el.innerText || el.value || '').trim().replace(/\s+/g, ' ').slice(0, 60);
+    send({ type: 'click', selector: cssPath(el), text: text });
+  }, true);
+  document.addEventListener('change', (e) => {
+    const el = e.target;
+    if (!(el instanceof Element) || !isField(el) || el.isContentEditable) return;
+    send({ type: 'fill', selector: cssPath(el), value: String(el.value) });
+  }, true);
+  document.addEventListener('focusout', (e) => {
+    const el = e.target;
+    if (!(el instanceof Element) || !el.isContentEditable) return;
+    send({ type: 'fill', selector: cssPath(el), value: el.textContent || '' });
+  }, true);
+})()`
+
+// typedTransitions are navigations the user started directly, as opposed to
+// ones caused by a recorded click or submit.
+var typedTransitions = map[page.TransitionType]bool{
+	page.TransitionTypeTyped:            true,
+	page.TransitionTypeAddressBar:       true,
+	page.TransitionTypeAutoBookmark:     true,
+	page.TransitionTypeGenerated:        true,
+	page.TransitionTypeKeyword:          true,
+	page.TransitionTypeKeywordGenerated: true,
+	page.TransitionTypeReload:           true,
+}
+
+type event struct {
+	Type     string `json:"type"`
+	Selector string `json:"selector"`
+	Text     string `json:"text"`
+	Value    string `json:"value"`
+	URL      string `json:"url"`
+}
+
+func record() {
+	var args recordArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	// Interactions and navigations share one ring to keep their order; the
+	// loop below makes CDP calls, and the listener must not wait on it
+	events := lib.NewEventRing[event](1000)
+	chromedp.ListenTarget(targetCtx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventBindingCalled:
+			if ev.Name != bindingName {
+				return
+			}
+			var e event
+			if json.Unmarshal([]byte(ev.Payload), &e) == nil {
+				events.Push(e)
+			}
+		case *page.EventFrameNavigated:
+			if ev.Frame.ParentID == "" {
+				events.Push(event{Type: "navigate", URL: ev.Frame.URL})
+			}
+		}
+	})
+
+	var startURL string
+	var scriptID page.ScriptIdentifier
+	err = chromedp.Run(targetCtx,
+		runtime.Enable(),
+		page.Enable(),
+		runtime.AddBinding(bindingName),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			scriptID, err = page.AddScriptToEvaluateOnNewDocument(recorderScript).Do(ctx)
+			return err
+		}),
+		chromedp.Evaluate(recorderScript, nil),
+		chromedp.Location(&startURL),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	wf := lib.Workflow{Name: args.Name}
+	add := func(step lib.WorkflowStep) {
+		// Typing then tabbing away fires change once per edit; keep the last value
+		if n := len(wf.Steps); n > 0 && step.Action == "fill" && wf.Steps[n-1].Action == "fill" && wf.Steps[n-1].Selector == step.Selector {
+			wf.Steps[n-1] = step
+		} else {
+			wf.Steps = append(wf.Steps, step)
+		}
+		fmt.Fprintf(os.Stderr, "recorded %s %s\n", step.Action, strings.Join(step.Describe(), " "))
+		if args.Screenshots {
+			screenshot(targetCtx, args, len(wf.Steps), step)
+		}
+	}
+	add(lib.WorkflowStep{Action: "navigate", URL: startURL})
+	handle := func(e event) {
+		switch e.Type {
+		case "click":
+			add(lib.WorkflowStep{Action: "click", Selector: e.Selector, Note: e.Text})
+		case "fill":
+			add(lib.WorkflowStep{Action: "fill", Selector: e.Selector, Value: e.Value})
+		case "navigate":
+			if typed(targetCtx) {
+				add(lib.WorkflowStep{Action: "navigate", URL: e.URL})
+			}
+		}
+	}
+
+	stopCtx, stop := lib.InterruptContext(context.Background())
+	defer stop()
+	if args.Duration > 0 {
+		var stopCancel context.CancelFunc
+		stopCtx, stopCancel = context.WithTimeout(stopCtx, time.Duration(args.Duration)*time.Second)
+		defer stopCancel()
+	}
+	fmt.Fprintf(os.Stderr, "recording %s, Ctrl+C to stop\n", startURL)
+
+	// Pop returns what was queued before stopping too, so interactions
+	// reported just before Ctrl+C are kept
+	dropped := 0
+	for {
+		e, n, ok := events.Pop(stopCtx)
+		if !ok {
+			break
+		}
+		dropped += n
+		handle(e)
+	}
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "warning: dropped %d interactions, recording fell behind\n", dropped)
+	}
+	uninstall(targetCtx, scriptID)
+
+	if err := lib.SaveWorkflow(args.Output, wf); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if args.Output != "" && args.Output != "-" {
+		fmt.Fprintf(os.Stderr, "saved %d steps to %s\n", len(wf.Steps), args.Output)
+	}
+}
+
+// uninstall removes the recorder from the tab, so later navigations don't
+// inject it and the page stops reporting to a binding nobody reads.
+func uninstall(ctx context.Context, scriptID page.ScriptIdentifier) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := chromedp.Run(ctx,
+		page.RemoveScriptToEvaluateOnNewDocument(scriptID),
+		runtime.RemoveBinding(bindingName),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to remove the recorder from the tab: %v\n", err)
+	}
+}
+
+// typed reports whether the current history entry was a user-initiated navigation.
+func typed(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var transition page.TransitionType
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		index, entries, err := page.GetNavigationHistory().Do(ctx)
+		if err != nil {
+			return err
+		}
+		if index >= 0 && int(index) < len(entries) {
+			transition = entries[index].TransitionType
+		}
+		return nil
+	}))
+	return err == nil && typedTransitions[transition]
+}
+
+func screenshot(ctx context.Context, args recordArgs, index int, step lib.WorkflowStep) {
+	label := fmt.Sprintf("%02d-%s", index, step.Action)
+	path, err := lib.PrepareScreenshotPath("", args.OutputDir, label)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	shotCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var buf []byte
+	if err := chromedp.Run(shotCtx, chromedp.CaptureScreenshot(&buf)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: screenshot failed: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	record := lib.StepRecord{
+		Action:     step.Action,
+		Args:       step.Describe(),
+		Target:     args.TargetArgs.Selector(),
+		Label:      label,
+		Note:       step.Note,
+		Screenshot: path,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := lib.RememberStep(record); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to persist metadata: %v\n", err)
+	}
+}
@@ -42,7 +42,7 @@ func rect() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
@@ -69,18 +69,18 @@ func rect() {
 	err = chromedp.Run(targetCtx, chromedp.Evaluate(script, &result))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	if result == nil {
 		fmt.Fprintf(os.Stderr, "error: element not found: %s\n", args.Selector)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	jsonBytes, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	fmt.Println(string(jsonBytes))
 }
\ No newline at end of file
@@ -0,0 +1,497 @@
+// repl provides an interactive shell over a persistent Chrome connection.
+package repl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+	"golang.org/x/term"
+)
+
+const historyLimit = 1000
+
+func init() {
+	lib.Commands["repl"] = repl
+	lib.Args["repl"] = replArgs{}
+}
+
+type replArgs struct {
+	lib.TargetArgs
+}
+
+func (replArgs) Description() string {
+	return `repl - Interactive shell for exploring a tab
+
+Opens one persistent connection and reads commands interactively, so each
+action skips connection setup. Arguments are split like a shell (quotes work).
+
+Built-in (run over the persistent connection):
+  navigate URL          click SELECTOR          fill SELECTOR VALUE
+  type SELECTOR TEXT    eval JS (rest of line)  waitfor SELECTOR
+  screenshot [LABEL]    title                   url
+  target [PREFIX]       show or switch the targeted tab
+  help                  exit / quit / Ctrl+D
+
+Any other chrome command (e.g. clicktext, console -d 2, list) runs as a
+subprocess against the current target.
+
+Up/Down browse history (saved in the cache dir), Tab completes command names.
+
+Example:
+  chrome repl
+  chrome repl -t http://localhost:3000
+  echo 'title' | chrome repl              # non-interactive input works too`
+}
+
+type shell struct {
+	session *lib.Session
+	target  string
+	out     io.Writer
+	// cooked is the terminal's mode before going raw, restored on fd while
+	// a subcommand runs; nil when stdin is not a terminal
+	fd     int
+	cooked *term.State
+}
+
+func repl() {
+	var args replArgs
+	arg.MustParse(&args)
+
+	session, err := lib.NewSession()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	sh := &shell{session: session, target: args.TargetArgs.Selector(), out: os.Stdout}
+	if _, _, err := session.Tab(sh.target); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+		failed := false
+		for scanner.Scan() {
+			done, err := sh.exec(scanner.Text())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				failed = true
+			}
+			if done {
+				break
+			}
+		}
+		if failed {
+			lib.Exit(1)
+		}
+		return
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+	sh.fd, sh.cooked = fd, state
+
+	screen := struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}
+	terminal := term.NewTerminal(screen, "chrome> ")
+	terminal.History = loadHistory()
+	terminal.AutoCompleteCallback = complete
+	sh.out = terminal
+
+	fmt.Fprintf(terminal, "connected to port %d, type help for commands\r\n", lib.GetPort())
+	for {
+		line, err := terminal.ReadLine()
+		if err != nil {
+			// io.EOF is Ctrl+D, ErrPasteIndicator is harmless
+			if errors.Is(err, io.EOF) {
+				fmt.Fprint(terminal, "\r\n")
+				return
+			}
+			continue
+		}
+		done, err := sh.exec(line)
+		if err != nil {
+			fmt.Fprintf(terminal, "error: %v\r\n", err)
+		}
+		if done {
+			return
+		}
+	}
+}
+
+var builtins = []string{"navigate", "click", "fill", "type", "eval", "waitfor", "screenshot", "title", "url", "target", "help", "exit", "quit"}
+
+// complete handles Tab by completing the command name under the cursor.
+func complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	prefix := line[:pos]
+	if strings.ContainsAny(prefix, " \t") {
+		return "", 0, false
+	}
+	names := map[string]struct{}{}
+	for _, name := range builtins {
+		names[name] = struct{}{}
+	}
+	for name := range lib.Commands {
+		names[name] = struct{}{}
+	}
+	var matches []string
+	for name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+	sort.Strings(matches)
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(matches) == 1 {
+		common += " "
+	}
+	if common == prefix {
+		return "", 0, false
+	}
+	return common + line[pos:], len(common), true
+}
+
+// exec runs one input line and reports whether the shell should exit.
+func (sh *shell) exec(line string) (bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return false, nil
+	}
+
+	name, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	// eval takes the raw rest of the line so JavaScript quoting is untouched
+	if name == "eval" {
+		if rest == "" {
+			return false, errors.New("usage: eval JS")
+		}
+		var result any
+		if err := sh.run(chromedp.Evaluate(rest, &result)); err != nil {
+			return false, err
+		}
+		sh.printValue(result)
+		return false, nil
+	}
+
+	fields, err := splitArgs(rest)
+	if err != nil {
+		return false, err
+	}
+
+	switch name {
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		sh.println((replArgs{}).Description())
+	case "target":
+		if len(fields) > 0 {
+			if _, _, err := sh.session.Tab(fields[0]); err != nil {
+				return false, err
+			}
+			sh.target = fields[0]
+		}
+		_, id, err := sh.session.Tab(sh.target)
+		if err != nil {
+			return false, err
+		}
+		sh.println(fmt.Sprintf("target: %s (%s)", displayTarget(sh.target), id))
+	case "navigate":
+		if len(fields) != 1 {
+			return false, errors.New("usage: navigate URL")
+		}
+		if err := lib.PoliteWait(context.Background(), fields[0]); err != nil {
+			return false, err
+		}
+		return false, sh.run(chromedp.Navigate(fields[0]))
+	case "click":
+		if len(fields) != 1 {
+			return false, errors.New("usage: click SELECTOR")
+		}
+		return false, sh.run(chromedp.ActionFunc(func(ctx context.Context) error {
+			return lib.Click(ctx, fields[0])
+		}))
+	case "waitfor":
+		if len(fields) != 1 {
+			return false, errors.New("usage: waitfor SELECTOR")
+		}
+		return false, sh.run(chromedp.WaitVisible(fields[0], chromedp.ByQuery))
+	case "fill":
+		if len(fields) != 2 {
+			return false, errors.New("usage: fill SELECTOR VALUE")
+		}
+		return false, sh.run(chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := lib.Fill(ctx, fields[0], fields[1])
+			return err
+		}))
+	case "type":
+		if len(fields) != 2 {
+			return false, errors.New("usage: type SELECTOR TEXT")
+		}
+		return false, sh.run(chromedp.ActionFunc(func(ctx context.Context) error {
+			return lib.Type(ctx, fields[0], fields[1], false)
+		}))
+	case "title":
+		var title string
+		if err := sh.run(chromedp.Title(&title)); err != nil {
+			return false, err
+		}
+		sh.println(title)
+	case "url":
+		var url string
+		if err := sh.run(chromedp.Location(&url)); err != nil {
+			return false, err
+		}
+		sh.println(url)
+	case "screenshot":
+		label := "shot"
+		if len(fields) > 0 {
+			label = fields[0]
+		}
+		path, err := sh.screenshot(label)
+		if err != nil {
+			return false, err
+		}
+		sh.println("saved " + path)
+	default:
+		if _, ok := lib.Commands[name]; !ok {
+			return false, fmt.Errorf("unknown command: %s (try help)", name)
+		}
+		return false, sh.subcommand(name, fields)
+	}
+	return false, nil
+}
+
+func (sh *shell) run(actions ...chromedp.Action) error {
+	return sh.session.Run(sh.target, lib.DefaultTimeout, actions...)
+}
+
+func (sh *shell) screenshot(label string) (string, error) {
+	path, err := lib.PrepareScreenshotPath("", "", label)
+	if err != nil {
+		return "", err
+	}
+	var buf []byte
+	if err := sh.run(chromedp.CaptureScreenshot(&buf)); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		return "", err
+	}
+	record := lib.StepRecord{
+		Action:     "screenshot",
+		Args:       []string{label},
+		Target:     sh.target,
+		Label:      label,
+		Screenshot: path,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := lib.RememberStep(record); err != nil {
+		sh.println(fmt.Sprintf("warning: unable to persist metadata: %v", err))
+	}
+	return path, nil
+}
+
+// subcommand runs any other registered command as a child process, passing the
+// current target through the environment so it works for every command. Its
+// output streams to the terminal as it is written, with the terminal back in
+// cooked mode, so long-running commands (console -f) show output live and
+// Ctrl+C stops the child instead of the repl.
+func (sh *shell) subcommand(name string, args []string) error {
+	execPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(execPath, append([]string{name}, args...)...)
+	cmd.Env = lib.ChildEnv()
+	if sh.target != "" {
+		cmd.Env = append(cmd.Env, "CHROME_TARGET="+sh.target)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if sh.cooked == nil {
+		return cmd.Run()
+	}
+	if err := term.Restore(sh.fd, sh.cooked); err != nil {
+		return err
+	}
+	// Ctrl+C signals the whole foreground group; catching it keeps the repl
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	err = cmd.Run()
+	signal.Stop(interrupt)
+	if _, rawErr := term.MakeRaw(sh.fd); rawErr != nil && err == nil {
+		err = rawErr
+	}
+	return err
+}
+
+func (sh *shell) println(text string) {
+	// The raw-mode terminal needs explicit carriage returns
+	if _, ok := sh.out.(*term.Terminal); ok {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+		fmt.Fprint(sh.out, text+"\r\n")
+		return
+	}
+	fmt.Fprintln(sh.out, text)
+}
+
+func (sh *shell) printValue(value any) {
+	switch v := value.(type) {
+	case string:
+		sh.println(v)
+	case nil:
+	default:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			sh.println(fmt.Sprint(value))
+			return
+		}
+		sh.println(string(data))
+	}
+}
+
+func displayTarget(target string) string {
+	if target == "" {
+		return "(default)"
+	}
+	return target
+}
+
+// splitArgs splits a line into arguments, honoring single quotes, double
+// quotes, and backslash escapes the way a shell would.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// fileHistory is a term.History that appends entries to a file in the cache dir.
+type fileHistory struct {
+	entries []string
+	path    string
+}
+
+func loadHistory() *fileHistory {
+	h := &fileHistory{}
+	cache, err := lib.CacheDir()
+	if err != nil {
+		return h
+	}
+	h.path = filepath.Join(cache, "repl-history")
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		return h
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > historyLimit {
+		h.entries = h.entries[len(h.entries)-historyLimit:]
+	}
+	return h
+}
+
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > historyLimit {
+		h.entries = h.entries[len(h.entries)-historyLimit:]
+	}
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	_, _ = fmt.Fprintln(f, entry)
+}
+
+func (h *fileHistory) Len() int {
+	return len(h.entries)
+}
+
+// At returns entries newest first, as term.History requires.
+func (h *fileHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
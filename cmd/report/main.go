@@ -0,0 +1,81 @@
+// report writes a self-contained HTML report of a workflow run.
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["report"] = report
+	lib.Args["report"] = reportArgs{}
+}
+
+type reportArgs struct {
+	FromRun  string `arg:"-r,--from-run" default:"last" help:"run ID, a unique prefix of one, last, or current"`
+	ShotsDir string `arg:"-d,--shots-dir" help:"directory holding the run (default: ~/chrome-shots)"`
+	Output   string `arg:"-o,--output" help:"HTML path (default: report-<run ID>.html in the run's directory)"`
+}
+
+func (reportArgs) Description() string {
+	return `report - HTML report of a workflow run
+
+Combines the steps of a 'chrome run' with the console messages and network
+requests it logged into one HTML file: pass/fail status, per-step timing,
+errors, and screenshots inlined, so the file can be attached to a CI job
+and opened anywhere.
+
+Console messages and requests are listed under the step that was running
+when they happened; sections of failed steps start expanded. Exits 1 when
+the run failed, after writing the report.
+
+Example:
+  chrome run -o /tmp/run login.yaml; chrome report -d /tmp/run
+  chrome report --from-run 20240115-103000-login -o report.html`
+}
+
+func report() {
+	var args reportArgs
+	arg.MustParse(&args)
+
+	dir := strings.TrimSpace(args.ShotsDir)
+	if dir == "" {
+		dir = lib.DefaultShotsDir()
+	}
+	runID, records, err := lib.LoadRunRecords(dir, args.FromRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	output := strings.TrimSpace(args.Output)
+	if output == "" {
+		// Runs saved before they had their own directory keep reports at the top
+		outDir := dir
+		if runDir, err := lib.RunDir(dir, runID); err == nil {
+			if info, err := os.Stat(runDir); err == nil && info.IsDir() {
+				outDir = runDir
+			}
+		}
+		output = filepath.Join(outDir, fmt.Sprintf("report-%s.html", runID))
+	}
+	output, err = filepath.Abs(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if err := lib.WriteReport(dir, runID, records, output); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(output)
+	for _, record := range records {
+		if record.Status == "failed" {
+			lib.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// run executes a declarative workflow file of chrome steps.
+package run
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["run"] = run
+	lib.Args["run"] = runArgs{}
+}
+
+type runArgs struct {
+	lib.TargetArgs
+	Workflow  string   `arg:"positional,required" help:"workflow file (YAML or JSON)"`
+	OutputDir string   `arg:"-o,--output-dir" help:"directory for step screenshots and metadata (default: ~/chrome-shots)"`
+	Quiet     bool     `arg:"-q,--quiet" help:"only print failures and the final summary"`
+	Preset    string   `arg:"-p,--preset" help:"emulation preset applied before the first step (overrides preset: in the file)"`
+	Format    string   `arg:"-f,--format" default:"png" help:"step screenshot format: png, jpeg, or webp"`
+	Quality   int      `arg:"--quality" help:"jpeg/webp quality 1-100 (default: Chrome's)"`
+	RunID     string   `arg:"--run-id" help:"run ID for the steps, reusing an existing one adds to it (default: <timestamp>-<name>)"`
+	Var       []string `arg:"--var,separate" help:"set a workflow variable, NAME=VALUE (repeatable)"`
+	DryRun    bool     `arg:"-n,--dry-run" help:"check the workflow and print its plan without connecting to Chrome"`
+}
+
+func (runArgs) Description() string {
+	return `run - Execute a workflow file of steps
+
+Runs a YAML or JSON list of steps over one connection, with a per-step
+timeout. Stops at the first failing step (unless it sets continue_on_error)
+and exits 1. A screenshot and StepRecord metadata are saved after every
+step (including the failing one), so 'chrome slideshow' works on the
+results. Records are tagged with the run ID printed at the end and saved in
+<output-dir>/runs/<run ID>, and click and typing steps record where they
+acted, for 'chrome trail --from-run ID'.
+
+Actions and fields:
+  navigate   url
+  click      selector
+  clicktext  text [selector]
+  fill       selector value
+  type       selector text
+  waitfor    selector              wait until visible
+  wait       text                  wait until page text contains it
+  eval       script [var]            var stores the result
+  capture    var and one of: script, selector [attr], response [field]
+             stores a script's result, an element's text or attribute
+             (waiting for it), or a matching response's body or JSON
+             field (dotted, e.g. order.items.0.id) in var
+  assert     selector text url title script  (checked once, no waiting)
+             response (glob) max_ms   a matching request finished within
+                                      max_ms, waiting up to the step timeout
+  screenshot label selector        selector clips to one element
+  sleep      ms
+  emulate    preset and/or emulation (inline preset fields)
+  throttle   offline latency (ms) download upload (kbps); empty resets
+  offline    shortcut for throttle offline: true
+  online     clears network throttling
+  mock       url (glob) status body headers block
+  unmock     removes all mock rules
+  if         when (condition) then (steps) else (steps)
+  loop       max and steps, with while or until (condition); runs
+             steps up to max times, checking while before and until
+             after each iteration, and fails if it is still unmet
+
+Emulation, throttling, and mock rules stay active for the rest of the run,
+since every step shares one connection.
+
+Every step also accepts name, label, note, timeout (seconds), and
+continue_on_error, which records the step as failed and carries on.
+
+Variables come from vars: in the file, --var, and capture steps, and are
+substituted into later steps' fields as {{name}} or {{vars.name}}; using
+one that is not set fails the step. Non-string values are stored as JSON.
+
+Conditions take the assert fields selector, text, url, title, and script,
+checked once without waiting (a selector alone tests that an element
+exists), and not: true to negate them. Steps inside an if or loop are
+numbered from their parent: 3.1, or 4.2.1 for the second iteration.
+
+The tab's console messages and network events are saved in the run's
+directory as <run ID>-console.ndjson and <run ID>-network.ndjson;
+'chrome report' turns a run into a single HTML file for CI.
+
+--dry-run checks the file without a browser: fields, variables used before
+they are set, and selector syntax. It prints every step with its variables
+substituted (values captured at run time stay {{name}}), both branches of
+each if, and each loop's steps once, then exits 1 if it found problems.
+Top-level fields: name, target, timeout (default per step), output_dir,
+preset (see 'chrome session --help').
+
+Example workflow.yaml:
+  name: login
+  target: http://localhost:3000
+  timeout: 10
+  steps:
+    - action: navigate
+      url: http://localhost:3000/login
+    - action: fill
+      selector: "#email"
+      value: alice@example.com
+    - action: clicktext
+      text: Sign In
+    - action: waitfor
+      selector: "#dashboard"
+    - action: assert
+      selector: h1
+      text: Welcome
+    - action: mock
+      url: "*/api/notifications*"
+      body: '{"items": []}'
+      headers: {Content-Type: application/json}
+    - action: offline
+    - action: clicktext
+      text: Refresh
+    - action: wait
+      text: You are offline
+
+Conditions and retries:
+  steps:
+    - action: if                     # dismiss the cookie banner if present
+      when: {selector: "#cookie-banner"}
+      then:
+        - action: click
+          selector: "#cookie-banner .accept"
+    - action: loop                   # retry until the row appears
+      max: 5
+      until: {selector: "tr[data-id='42']"}
+      steps:
+        - action: clicktext
+          text: Refresh
+        - action: sleep
+          ms: 1000
+    - action: click                  # optional, may be hidden
+      selector: "#tour-close"
+      continue_on_error: true
+
+Example:
+  chrome run workflow.yaml
+  chrome run -t http://localhost:3000 -o /tmp/run workflow.json
+  chrome run --preset iphone workflow.yaml
+  chrome run --var user=bob --var base=http://localhost:3000 workflow.yaml
+  chrome run --dry-run --var user=bob workflow.yaml   # check before a long run
+  chrome run --format jpeg --quality 60 workflow.yaml   # smaller shots for long runs`
+}
+
+func run() {
+	var args runArgs
+	arg.MustParse(&args)
+
+	wf, err := lib.LoadWorkflow(args.Workflow)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	vars, err := lib.ParseVars(args.Var)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if args.DryRun {
+		dryRun(wf, vars)
+		return
+	}
+
+	shot, err := lib.ScreenshotOptions{Format: args.Format, Quality: args.Quality}.Normalize()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	target := args.TargetArgs.Selector()
+	if target == "" {
+		target = strings.TrimSpace(wf.Target)
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	// Attach without a deadline; steps derive their own timeouts
+	if err := chromedp.Run(targetCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	presetName := args.Preset
+	if presetName == "" {
+		presetName = wf.Preset
+	}
+	if presetName != "" {
+		preset, err := lib.LoadPreset("", presetName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		presetCtx, presetCancel := context.WithTimeout(targetCtx, lib.DefaultTimeout)
+		err = chromedp.Run(presetCtx, preset.Actions()...)
+		presetCancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: applying preset %s: %v\n", presetName, err)
+			lib.Exit(1)
+		}
+	}
+
+	// Only top-level steps count toward the summary; nested steps are
+	// numbered like 3.1 and counted through their if or loop
+	total := len(wf.Steps)
+	passed := 0
+	runID := args.RunID
+	if runID == "" {
+		runID = lib.NewRunID(wf.Name)
+	}
+	_, err = lib.RunWorkflow(targetCtx, wf, lib.RunOptions{
+		OutputDir:  args.OutputDir,
+		Target:     target,
+		Screenshot: shot,
+		RunID:      runID,
+		Vars:       vars,
+		OnStep: func(number string, record lib.StepRecord) {
+			if !strings.Contains(number, ".") && record.Status == "ok" {
+				passed++
+			}
+			if args.Quiet && record.Status == "ok" {
+				return
+			}
+			line := fmt.Sprintf("%-6s [%s/%d] %s %s", record.Status, number, total, record.Action, strings.Join(record.Args, " "))
+			if record.Error != "" {
+				line += ": " + record.Error
+			}
+			fmt.Println(strings.TrimSpace(line))
+		},
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		fmt.Printf("%s: failed, %d/%d steps passed (run %s)\n", wf.Name, passed, total, runID)
+		lib.Exit(1)
+	}
+	fmt.Printf("%s: passed, %d/%d steps (run %s)\n", wf.Name, passed, total, runID)
+}
+
+// dryRun prints the workflow's plan and exits 1 if it has problems.
+func dryRun(wf lib.Workflow, vars map[string]string) {
+	plan, err := lib.PlanWorkflow(wf, vars)
+	top := 0
+	for _, step := range plan {
+		if step.Depth == 0 {
+			top++
+		}
+		action := step.Action
+		if step.Branch != "" {
+			action = step.Branch + ": " + action
+		}
+		line := fmt.Sprintf("%s%-6s %s %s", strings.Repeat("  ", step.Depth), step.Number, action, strings.Join(step.Args, " "))
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	if err != nil {
+		problems := strings.Split(err.Error(), "\n")
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "error: %s\n", problem)
+		}
+		fmt.Printf("%s: %d steps, %d problems\n", wf.Name, top, len(problems))
+		lib.Exit(1)
+	}
+	fmt.Printf("%s: %d steps, ok\n", wf.Name, top)
+}
@@ -0,0 +1,56 @@
+// schema provides the command registry as machine-readable JSON
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["schema"] = schema
+	lib.Args["schema"] = schemaArgs{}
+}
+
+type schemaArgs struct {
+	Command string `arg:"positional" help:"only print this command"`
+}
+
+func (schemaArgs) Description() string {
+	return `schema - Print the command registry as JSON
+
+Dumps every registered command with its summary, full description, and
+arguments (name, flags, type, default, help, positional, required), plus a
+JSON Schema "inputSchema" object per command. Agent frameworks and tool
+servers can generate tool definitions from it instead of parsing --help.
+
+Example:
+  chrome schema
+  chrome schema click
+  chrome schema | jq '.[].name'`
+}
+
+func schema() {
+	var args schemaArgs
+	arg.MustParse(&args)
+
+	var value any = lib.Schemas()
+	if args.Command != "" {
+		argsStruct, ok := lib.Args[args.Command]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "error: unknown command: %s\n", args.Command)
+			lib.Exit(1)
+		}
+		value = lib.Schema(args.Command, argsStruct)
+	}
+
+	jsonBytes, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -4,6 +4,7 @@ package screenshot
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -18,10 +19,16 @@ func init() {
 
 type screenshotArgs struct {
 	lib.TargetArgs
-	Path      string `arg:"--path" help:"exact file path for screenshot (overrides output dir)"`
-	OutputDir string `arg:"-o,--output-dir" help:"directory to store screenshots (default: ~/chrome-shots)"`
-	Label     string `arg:"-l,--label" help:"label embedded in filename"`
-	Note      string `arg:"-n,--note" help:"note saved in metadata"`
+	Path         string `arg:"--path" help:"exact file path for screenshot (overrides output dir)"`
+	OutputDir    string `arg:"-o,--output-dir" help:"directory to store screenshots (default: ~/chrome-shots)"`
+	Label        string `arg:"-l,--label" help:"label embedded in filename"`
+	Note         string `arg:"-n,--note" help:"note saved in metadata"`
+	Selector     string `arg:"-s,--selector" help:"CSS selector: clip to this element's bounding box"`
+	Freeze       bool   `arg:"--freeze-animations" help:"disable transitions and pause animations during capture"`
+	Format       string `arg:"-f,--format" help:"png, jpeg, or webp (default: from --path extension, else png)"`
+	Quality      int    `arg:"--quality" help:"jpeg/webp quality 1-100 (default: Chrome's)"`
+	Media        string `arg:"--media" help:"emulate CSS media type for the capture: print or screen"`
+	NameTemplate string `arg:"--name-template" help:"file name template, e.g. \"{date}-{label}-{seq}\" (default: $CHROME_NAME_TEMPLATE or {timestamp}-{label})"`
 }
 
 func (screenshotArgs) Description() string {
@@ -31,23 +38,65 @@ Examples:
   chrome screenshot                                  # writes to ~/chrome-shots/<timestamp>-shot.png
   chrome screenshot --label after-login             # include label in metadata
   chrome screenshot --path /tmp/latest.png           # explicit path
-  chrome screenshot -t http://localhost --note "after submit"        # annotate metadata`
+  chrome screenshot -t http://localhost --note "after submit"        # annotate metadata
+  chrome screenshot --selector "#chart"              # just one element
+  chrome screenshot --freeze-animations              # stable capture of spinners/carousels
+  chrome screenshot --format jpeg --quality 70       # ~10x smaller than png
+  chrome screenshot --path /tmp/page.webp            # format from extension
+  chrome screenshot --media print                    # apply @media print styles
+  chrome screenshot --name-template "{host}-{seq}"   # localhost-3000-0001.png, -0002.png, ...
+
+--selector scrolls the first matching element into view and clips the
+capture to its bounding box, including any part outside the viewport.
+
+--freeze-animations injects CSS that disables transitions, pauses running
+animations where they are, and pauses the Animation domain clock for the
+capture. Afterwards the CSS is removed and each animation resumes from where
+it was paused; none is finished or cancelled, so no animation events fire.
+
+--media print applies print stylesheets (@media print, <link media=print>)
+while capturing, so they can be checked visually without generating a PDF.
+The page keeps its screen viewport and is restored to screen media after.
+
+--name-template names the file in the output directory (ignored with
+--path). Tokens: {timestamp} (20240115-103000_123), {date}, {time}, {label},
+{seq} (one more than the highest in the directory, 4 digits), and {host}
+(the tab's host). It needs {seq} or {timestamp}. Set $CHROME_NAME_TEMPLATE
+to apply a template to every command that saves files.`
 }
 
 func screenshot() {
 	var args screenshotArgs
 	arg.MustParse(&args)
 
-	path, err := lib.PrepareScreenshotPath(args.Path, args.OutputDir, effectiveLabel(args.Label))
+	format := args.Format
+	if format == "" && args.Path != "" {
+		// An unrecognized extension keeps the png default
+		format, _ = lib.ParseScreenshotFormat(strings.TrimPrefix(filepath.Ext(args.Path), "."))
+	}
+	opts, err := lib.ScreenshotOptions{
+		FreezeAnimations: args.Freeze,
+		Element:          args.Selector,
+		Format:           format,
+		Quality:          args.Quality,
+		Media:            args.Media,
+	}.Normalize()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	name := lib.OutputName{Template: args.NameTemplate, Label: effectiveLabel(args.Label), Target: args.TargetArgs.Selector()}
+	path, err := lib.PrepareNamedOutputPath(args.Path, args.OutputDir, name, lib.ScreenshotExtension(opts.Format))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error preparing screenshot path: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
-	err = lib.CaptureScreenshot(args.TargetArgs.Selector(), path)
+	err = lib.CaptureScreenshotWithOptions(args.TargetArgs.Selector(), path, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error capturing screenshot: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	record := lib.StepRecord{
@@ -94,6 +143,24 @@ func buildScreenshotArgs(args screenshotArgs) []string {
 	if args.Note != "" {
 		collected = append(collected, fmt.Sprintf("--note=%s", args.Note))
 	}
+	if args.Selector != "" {
+		collected = append(collected, fmt.Sprintf("--selector=%s", args.Selector))
+	}
+	if args.Freeze {
+		collected = append(collected, "--freeze-animations")
+	}
+	if args.Format != "" {
+		collected = append(collected, fmt.Sprintf("--format=%s", args.Format))
+	}
+	if args.Quality > 0 {
+		collected = append(collected, fmt.Sprintf("--quality=%d", args.Quality))
+	}
+	if args.Media != "" {
+		collected = append(collected, fmt.Sprintf("--media=%s", args.Media))
+	}
+	if args.NameTemplate != "" {
+		collected = append(collected, fmt.Sprintf("--name-template=%s", args.NameTemplate))
+	}
 	target := args.TargetArgs.Selector()
 	if target != "" {
 		collected = append(collected, fmt.Sprintf("--target=%s", target))
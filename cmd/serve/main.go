@@ -0,0 +1,412 @@
+// serve provides an HTTP JSON API backed by a persistent Chrome connection.
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["serve"] = serve
+	lib.Args["serve"] = serveArgs{}
+}
+
+type serveArgs struct {
+	Listen string `arg:"-l,--listen" help:"address to listen on (default: $CHROME_DAEMON or 127.0.0.1:9333)"`
+}
+
+func (serveArgs) Description() string {
+	return `serve - Run an HTTP JSON API daemon
+
+Keeps one CDP connection to Chrome open and exposes actions over HTTP, so
+tooling in any language can drive Chrome without a process per action.
+Tab contexts are attached once and reused across requests.
+
+Every POST takes a JSON body. "target" selects the tab like -t and "timeout"
+is in seconds (default 30). Responses are {"ok": true, "result": ...} or
+{"ok": false, "error": "..."}.
+
+Endpoints:
+  GET  /health                                      daemon and Chrome status
+  GET  /tabs                                        open page tabs
+  POST /navigate   {"url"}
+  POST /click      {"selector"}
+  POST /fill       {"selector", "value"}
+  POST /type       {"selector", "text", "append"}
+  POST /eval       {"script"}
+  POST /waitfor    {"selector"}
+  POST /title      {}
+  POST /html       {"outer"}
+  POST /screenshot {"path", "output_dir", "label", "note", "selector", "format", "quality"}
+
+Overrides (kept per tab for the life of the daemon):
+  POST /emulate     {"preset", "emulation"}                 preset name and/or inline fields
+  POST /throttle    {"offline", "latency", "download", "upload"}  empty resets
+  POST /block       {"patterns"}                            URL patterns, '*' wildcards, @group names
+  POST /unblock     {"patterns"}                            remove patterns, empty removes all
+  POST /headers     {"headers"}                             "" value removes a header
+  POST /mock        {"rules"}                               [{"url", "method", "status", "body", "body_file", "headers", "block", "fail"}]
+  POST /unmock      {}                                      remove all mock rules
+  POST /state       {}                                      current overrides
+  POST /state/clear {}                                      reset all overrides
+
+Chrome drops emulation and interception when a DevTools connection closes,
+so one-shot commands lose them on exit. While the daemon runs, 'session
+apply' sends presets here and 'state show/clear' inspect and reset them.
+
+Security: the API grants full control of Chrome. It binds to 127.0.0.1 by
+default; do not expose it on untrusted networks.
+
+Example:
+  chrome serve &
+  curl -s localhost:9333/navigate -d '{"url": "https://example.com"}'
+  curl -s localhost:9333/click -d '{"target": "https://example", "selector": "a"}'
+  curl -s localhost:9333/eval -d '{"script": "document.title"}'`
+}
+
+// request is the union of all endpoint bodies.
+type request struct {
+	Target    string `json:"target"`
+	Timeout   int    `json:"timeout"`
+	URL       string `json:"url"`
+	Selector  string `json:"selector"`
+	Value     string `json:"value"`
+	Text      string `json:"text"`
+	Append    bool   `json:"append"`
+	Script    string `json:"script"`
+	Outer     bool   `json:"outer"`
+	Path      string `json:"path"`
+	OutputDir string `json:"output_dir"`
+	Label     string `json:"label"`
+	Note      string `json:"note"`
+	Format    string `json:"format"`
+	Quality   int    `json:"quality"`
+
+	Preset    string            `json:"preset"`
+	Emulation *lib.Preset       `json:"emulation"`
+	Offline   bool              `json:"offline"`
+	Latency   int               `json:"latency"`
+	Download  int               `json:"download"`
+	Upload    int               `json:"upload"`
+	Patterns  []string          `json:"patterns"`
+	Headers   map[string]string `json:"headers"`
+	Rules     []lib.MockRule    `json:"rules"`
+
+	// ctx is the HTTP request's context, done when the client goes away
+	ctx context.Context
+}
+
+func (r request) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return time.Duration(r.Timeout) * time.Second
+	}
+	return lib.DefaultTimeout
+}
+
+type response struct {
+	Ok     bool   `json:"ok"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type handler func(session *lib.Session, req request) (any, error)
+
+func serve() {
+	var args serveArgs
+	arg.MustParse(&args)
+
+	session, err := lib.NewSession()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	handlers := map[string]handler{
+		"/navigate":   navigate,
+		"/click":      click,
+		"/fill":       fill,
+		"/type":       typeText,
+		"/eval":       eval,
+		"/waitfor":    waitfor,
+		"/title":      title,
+		"/html":       html,
+		"/screenshot": screenshot,
+
+		"/emulate":     emulate,
+		"/throttle":    throttle,
+		"/block":       block,
+		"/unblock":     unblock,
+		"/headers":     headers,
+		"/mock":        mock,
+		"/unmock":      unmock,
+		"/state":       stateShow,
+		"/state/clear": stateClear,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, response{Ok: true, Result: map[string]any{
+			"port":    lib.GetPort(),
+			"chrome":  lib.IsChromeRunning(),
+			"version": 1,
+		}})
+	})
+	mux.HandleFunc("/tabs", func(w http.ResponseWriter, r *http.Request) {
+		pages, err := lib.PageTargets()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, response{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, response{Ok: true, Result: pages})
+	})
+	for path, fn := range handlers {
+		mux.HandleFunc(path, wrap(session, path, fn))
+	}
+
+	listen := args.Listen
+	if listen == "" {
+		listen = lib.DaemonAddr()
+	}
+	fmt.Fprintf(os.Stderr, "serving on http://%s (chrome port %d)\n", listen, lib.GetPort())
+	if err := http.ListenAndServe(listen, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+}
+
+func wrap(session *lib.Session, path string, fn handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, response{Error: "use POST"})
+			return
+		}
+		var req request
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, response{Error: fmt.Sprintf("invalid JSON body: %v", err)})
+				return
+			}
+		}
+		req.ctx = r.Context()
+		start := time.Now()
+		result, err := fn(session, req)
+		fmt.Fprintf(os.Stderr, "%s %s %s\n", path, time.Since(start).Round(time.Millisecond), errText(err))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, response{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, response{Ok: true, Result: result})
+	}
+}
+
+func errText(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return err.Error()
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func required(name string, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+	return nil
+}
+
+func navigate(session *lib.Session, req request) (any, error) {
+	if err := required("url", req.URL); err != nil {
+		return nil, err
+	}
+	waitCtx, cancel := context.WithTimeout(req.ctx, req.timeout())
+	defer cancel()
+	if err := lib.PoliteWait(waitCtx, req.URL); err != nil {
+		return nil, err
+	}
+	return nil, session.Run(req.Target, req.timeout(), chromedp.Navigate(req.URL))
+}
+
+func click(session *lib.Session, req request) (any, error) {
+	if err := required("selector", req.Selector); err != nil {
+		return nil, err
+	}
+	return nil, session.Run(req.Target, req.timeout(), chromedp.ActionFunc(func(ctx context.Context) error {
+		return lib.Click(ctx, req.Selector)
+	}))
+}
+
+func fill(session *lib.Session, req request) (any, error) {
+	if err := required("selector", req.Selector); err != nil {
+		return nil, err
+	}
+	var value string
+	err := session.Run(req.Target, req.timeout(), chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		value, err = lib.Fill(ctx, req.Selector, req.Value)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"value": value}, nil
+}
+
+func typeText(session *lib.Session, req request) (any, error) {
+	if err := required("selector", req.Selector); err != nil {
+		return nil, err
+	}
+	return nil, session.Run(req.Target, req.timeout(), chromedp.ActionFunc(func(ctx context.Context) error {
+		return lib.Type(ctx, req.Selector, req.Text, req.Append)
+	}))
+}
+
+func eval(session *lib.Session, req request) (any, error) {
+	if err := required("script", req.Script); err != nil {
+		return nil, err
+	}
+	var result any
+	err := session.Run(req.Target, req.timeout(), chromedp.Evaluate(req.Script, &result))
+	return result, err
+}
+
+func waitfor(session *lib.Session, req request) (any, error) {
+	if err := required("selector", req.Selector); err != nil {
+		return nil, err
+	}
+	return nil, session.Run(req.Target, req.timeout(), chromedp.WaitVisible(req.Selector, chromedp.ByQuery))
+}
+
+func title(session *lib.Session, req request) (any, error) {
+	var value string
+	err := session.Run(req.Target, req.timeout(), chromedp.Title(&value))
+	return value, err
+}
+
+func html(session *lib.Session, req request) (any, error) {
+	script := "document.documentElement.innerHTML"
+	if req.Outer {
+		script = "document.documentElement.outerHTML"
+	}
+	var value string
+	err := session.Run(req.Target, req.timeout(), chromedp.Evaluate(script, &value))
+	return value, err
+}
+
+func screenshot(session *lib.Session, req request) (any, error) {
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		label = "shot"
+	}
+	opts, err := lib.ScreenshotOptions{Element: req.Selector, Format: req.Format, Quality: req.Quality}.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	path, err := lib.PrepareScreenshotPathWithFormat(req.Path, req.OutputDir, label, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	err = session.Run(req.Target, req.timeout(), chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, err = lib.ScreenshotWithOptions(ctx, opts)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		return nil, err
+	}
+	record := lib.StepRecord{
+		Action:     "screenshot",
+		Target:     req.Target,
+		Label:      label,
+		Note:       req.Note,
+		Screenshot: path,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := lib.RememberStep(record); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to persist metadata: %v\n", err)
+	}
+	return map[string]any{"path": path, "metadata": record.MetadataPath()}, nil
+}
+
+func emulate(session *lib.Session, req request) (any, error) {
+	preset := lib.Preset{}
+	if req.Preset != "" {
+		var err error
+		preset, err = lib.LoadPreset("", req.Preset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.Emulation != nil {
+		preset = lib.MergePreset(preset, *req.Emulation)
+	}
+	return nil, session.Emulate(req.Target, preset, req.timeout())
+}
+
+func throttle(session *lib.Session, req request) (any, error) {
+	conditions := lib.NetworkConditions{
+		Offline:  req.Offline,
+		Latency:  req.Latency,
+		Download: req.Download,
+		Upload:   req.Upload,
+	}
+	return nil, session.Throttle(req.Target, conditions, req.timeout())
+}
+
+func block(session *lib.Session, req request) (any, error) {
+	if len(req.Patterns) == 0 {
+		return nil, fmt.Errorf("patterns is required")
+	}
+	return nil, session.Block(req.Target, req.Patterns, req.timeout())
+}
+
+func unblock(session *lib.Session, req request) (any, error) {
+	blocked, err := session.Unblock(req.Target, req.Patterns, req.timeout())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"blocked": blocked}, nil
+}
+
+func headers(session *lib.Session, req request) (any, error) {
+	if len(req.Headers) == 0 {
+		return nil, fmt.Errorf("headers is required")
+	}
+	return nil, session.SetHeaders(req.Target, req.Headers, req.timeout())
+}
+
+func mock(session *lib.Session, req request) (any, error) {
+	if len(req.Rules) == 0 {
+		return nil, fmt.Errorf("rules is required")
+	}
+	return nil, session.Mock(req.Target, req.Rules, req.timeout())
+}
+
+func unmock(session *lib.Session, req request) (any, error) {
+	return nil, session.Unmock(req.Target, req.timeout())
+}
+
+func stateShow(session *lib.Session, req request) (any, error) {
+	return session.Overrides(req.Target)
+}
+
+func stateClear(session *lib.Session, req request) (any, error) {
+	return nil, session.ClearOverrides(req.Target, req.timeout())
+}
@@ -0,0 +1,138 @@
+// session applies named emulation presets from the presets config.
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["session"] = session
+	lib.Args["session"] = sessionArgs{}
+}
+
+type sessionArgs struct {
+	lib.TargetArgs
+	Action string `arg:"positional,required" help:"apply, show, or list"`
+	Preset string `arg:"positional" help:"preset name"`
+	Config string `arg:"-c,--config" help:"presets file (default: $CHROME_PRESETS or ~/.config/chrome-cli/presets.yaml)"`
+	Hold   bool   `arg:"--hold" help:"keep the connection open (and overrides active) until Ctrl+C"`
+}
+
+func (sessionArgs) Description() string {
+	return `session - Apply emulation presets to a tab
+
+Presets bundle viewport, user agent, locale, timezone, geolocation,
+permissions, and extra headers under a name, applied in one shot.
+
+Chrome clears emulation overrides when the DevTools connection closes. When
+the daemon is running (chrome serve), 'session apply' hands the preset to it
+and the overrides persist across commands until 'chrome state clear'.
+Otherwise they last only while this command is connected: use --hold, or
+'chrome run --preset NAME' / 'preset:' in a workflow. Granted permissions
+outlive the connection either way.
+
+Presets file (YAML or JSON):
+  iphone:
+    viewport: {width: 390, height: 844, scale: 3, mobile: true}
+    user_agent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) ..."
+    locale: en-US
+    timezone: America/New_York
+    geolocation: {latitude: 40.7128, longitude: -74.0060}
+    permissions: [geolocation]
+    headers:
+      X-Debug: "1"
+
+Example:
+  chrome session list
+  chrome session show iphone
+  chrome session apply iphone --hold`
+}
+
+func session() {
+	var args sessionArgs
+	arg.MustParse(&args)
+
+	switch args.Action {
+	case "list":
+		presets, err := lib.LoadPresets(args.Config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		for _, name := range lib.PresetNames(presets) {
+			fmt.Println(name)
+		}
+	case "show":
+		preset := loadPreset(args)
+		data, err := json.MarshalIndent(preset, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(data))
+	case "apply":
+		apply(args, loadPreset(args))
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown action %q, expected apply, show, or list\n", args.Action)
+		lib.Exit(1)
+	}
+}
+
+func loadPreset(args sessionArgs) lib.Preset {
+	if args.Preset == "" {
+		fmt.Fprintf(os.Stderr, "error: preset name is required\n")
+		lib.Exit(1)
+	}
+	preset, err := lib.LoadPreset(args.Config, args.Preset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	return preset
+}
+
+func apply(args sessionArgs, preset lib.Preset) {
+	if !args.Hold && lib.DaemonRunning() {
+		body := map[string]any{"target": lib.DaemonTarget(args.TargetArgs), "emulation": preset}
+		if err := lib.DaemonCall("/emulate", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Printf("applied preset %s (held by daemon)\n", args.Preset)
+		return
+	}
+
+	timeout := lib.DefaultTimeout
+	if args.Hold {
+		timeout = 0
+	}
+	ctx, cancel := lib.SetupContextWithTimeout(timeout)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	if err := chromedp.Run(targetCtx, preset.Actions()...); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("applied preset %s\n", args.Preset)
+
+	if args.Hold {
+		fmt.Fprintf(os.Stderr, "holding overrides, Ctrl+C to release\n")
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+	}
+}
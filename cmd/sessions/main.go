@@ -0,0 +1,103 @@
+// sessions maps named logins to the Chrome instances that hold them.
+package sessions
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["sessions"] = sessions
+	lib.Args["sessions"] = sessionsArgs{}
+}
+
+type sessionsArgs struct {
+	Action  string   `arg:"positional" default:"list" help:"list, show, or launch"`
+	Account []string `arg:"positional" help:"account names (launch: default all)"`
+	Config  string   `arg:"-c,--config" help:"accounts file (default: $CHROME_ACCOUNTS or ~/.config/chrome-cli/accounts.yaml)"`
+}
+
+func (sessionsArgs) Description() string {
+	return `sessions - Named logins mapped to Chrome instances
+
+Each account names the port and profile directory of a Chrome instance, so
+commands can say which login they act as instead of which port holds it:
+
+  chrome --as admin navigate http://localhost:3000/admin
+  chrome --as user screenshot
+
+--as is a global option, like --port, and must appear before the command.
+Log in once per account in its own instance ('chrome sessions launch admin',
+then sign in); the profile keeps the cookies for later runs.
+
+Accounts file (YAML or JSON):
+  admin:
+    port: 9222
+    user_data_dir: ~/.chrome-admin
+  user:
+    port: 9223
+
+user_data_dir defaults to the launch profile with the account name appended
+(~/.chrome-user above).
+
+Example:
+  chrome sessions
+  chrome sessions show admin
+  chrome sessions launch
+  chrome sessions launch admin`
+}
+
+func sessions() {
+	var args sessionsArgs
+	arg.MustParse(&args)
+
+	accounts, err := lib.LoadAccounts(args.Config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	names := args.Account
+	for _, name := range names {
+		if _, ok := accounts[name]; !ok {
+			fmt.Fprintf(os.Stderr, "error: unknown account %q\n", name)
+			lib.Exit(1)
+		}
+	}
+	if len(names) == 0 {
+		names = lib.AccountNames(accounts)
+	}
+
+	switch args.Action {
+	case "list", "show":
+		fmt.Printf("%-16s  %-6s  %-8s  %s\n", "ACCOUNT", "PORT", "STATUS", "USER_DATA_DIR")
+		for _, name := range names {
+			account := accounts[name]
+			status := "stopped"
+			if lib.IsChromeRunningOnPort(account.Port) {
+				status = "running"
+			}
+			fmt.Printf("%-16s  %-6d  %-8s  %s\n", name, account.Port, status, account.UserDataDir)
+		}
+	case "launch":
+		for _, name := range names {
+			account := accounts[name]
+			if lib.IsChromeRunningOnPort(account.Port) {
+				fmt.Printf("%s: already running on port %d\n", name, account.Port)
+				continue
+			}
+			out, err := lib.RunCommand("", []string{"launch", "--port", strconv.Itoa(account.Port), "--user-data-dir", account.UserDataDir})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+				lib.Exit(1)
+			}
+			_, _ = os.Stdout.Write(out)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown action %q (want list, show, or launch)\n", args.Action)
+		lib.Exit(1)
+	}
+}
@@ -0,0 +1,166 @@
+// shots manages the screenshots directory.
+package shots
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["shots"] = shots
+	lib.Args["shots"] = shotsArgs{}
+}
+
+type shotsArgs struct {
+	Action       string `arg:"positional,required" help:"index, runs, or prune"`
+	ShotsDir     string `arg:"-d,--shots-dir" help:"directory containing screenshots and metadata (default: ~/chrome-shots)"`
+	Output       string `arg:"-o,--output" help:"index: output html path (default: <shots-dir>/index.html)"`
+	Run          string `arg:"-r,--run" help:"index: only this run's steps, by run ID, unique prefix, last, or current"`
+	ThumbWidth   int    `arg:"--thumb-width" default:"320" help:"index: thumbnail width in pixels"`
+	KeepDays     int    `arg:"--keep-days" help:"prune: keep screenshots newer than this many days"`
+	KeepLast     int    `arg:"--keep-last" help:"prune: keep this many of the newest screenshots"`
+	DryRun       bool   `arg:"-n,--dry-run" help:"prune: list what would be removed without deleting"`
+	CompactCache bool   `arg:"--compact-cache" help:"prune: also drop the last-step cache if its screenshot is gone, and expired target caches"`
+}
+
+func (shotsArgs) Description() string {
+	return `shots - Manage the screenshots directory
+
+  shots index   write index.html, a gallery of every screenshot with a
+                StepRecord sidecar, newest first
+  shots runs    list runs with their step counts, failures, and times,
+                most recent first
+  shots prune   delete screenshots outside a retention policy
+
+The gallery shows a thumbnail, label, command, target, note, status, and
+timestamp for each screenshot, linked to the full image, with a filter box.
+Thumbnails are cached as jpegs in <shots-dir>/thumbs and only regenerated
+when the screenshot changes, so re-running index is cheap. With --run the
+gallery shows one run and is written to index.html in its directory.
+
+Steps are grouped into runs under <shots-dir>/runs/<run ID> ('chrome step'
+and 'chrome run' both record one); 'current' is the run 'chrome step' is
+adding to.
+
+prune keeps screenshots newer than --keep-days or among the --keep-last
+newest (at least one is required), across every run, and deletes the rest
+with their metadata JSON, thumbnail, and vr diff image, plus the logs and
+report of runs left empty. Screenshots without metadata and subdirectories
+such as baselines are never touched.
+
+Example:
+  chrome shots index
+  chrome shots index --shots-dir /tmp/run
+  chrome shots index -o /tmp/gallery.html --thumb-width 480
+  chrome shots index --run last
+  chrome shots runs
+  chrome shots prune --keep-days 7 --keep-last 50
+  chrome shots prune --keep-last 100 --dry-run
+  chrome shots prune --keep-days 30 --compact-cache`
+}
+
+func shots() {
+	var args shotsArgs
+	arg.MustParse(&args)
+
+	switch args.Action {
+	case "index":
+		index(args)
+	case "runs":
+		runs(args)
+	case "prune":
+		prune(args)
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown action %q, expected index, runs, or prune\n", args.Action)
+		lib.Exit(1)
+	}
+}
+
+func shotsDir(args shotsArgs) string {
+	if dir := strings.TrimSpace(args.ShotsDir); dir != "" {
+		return dir
+	}
+	return lib.DefaultShotsDir()
+}
+
+func index(args shotsArgs) {
+	dir := shotsDir(args)
+	path, count, err := lib.WriteGallery(dir, lib.GalleryOptions{
+		Output:     args.Output,
+		Run:        args.Run,
+		ThumbWidth: args.ThumbWidth,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if count == 0 {
+		fmt.Fprintf(os.Stderr, "warning: no screenshots with metadata found in %s\n", dir)
+	}
+	fmt.Printf("wrote %s (%d screenshots)\n", path, count)
+}
+
+func runs(args shotsArgs) {
+	dir := shotsDir(args)
+	runs, err := lib.ListRuns(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if len(runs) == 0 {
+		fmt.Fprintf(os.Stderr, "warning: no runs found in %s\n", dir)
+		return
+	}
+	current, _ := lib.LoadCurrentRunID()
+	for _, run := range runs {
+		status := "ok"
+		if run.Failed > 0 {
+			status = fmt.Sprintf("%d failed", run.Failed)
+		}
+		line := fmt.Sprintf("%-32s %3d steps  %-9s %s  %s", run.ID, run.Steps, status,
+			run.Started.Local().Format("2006-01-02 15:04:05"), run.Ended.Sub(run.Started).Round(time.Second))
+		if run.ID == current {
+			line += "  (current)"
+		}
+		fmt.Println(line)
+	}
+}
+
+func prune(args shotsArgs) {
+	if args.KeepDays <= 0 && args.KeepLast <= 0 {
+		fmt.Fprintf(os.Stderr, "error: one of --keep-days or --keep-last is required\n")
+		lib.Exit(1)
+	}
+	res, err := lib.PruneShots(shotsDir(args), lib.PruneOptions{
+		KeepDays: args.KeepDays,
+		KeepLast: args.KeepLast,
+		DryRun:   args.DryRun,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	removed := res.Removed
+	if args.CompactCache {
+		stale, err := lib.CompactCache(args.DryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		removed = append(removed, stale...)
+	}
+
+	verb := "removed"
+	if args.DryRun {
+		verb = "would remove"
+	}
+	for _, path := range removed {
+		fmt.Printf("%s %s\n", verb, path)
+	}
+	fmt.Printf("%s %d file(s), %.1f MB; kept %d screenshot(s)\n", verb, len(removed), float64(res.Bytes)/(1<<20), res.Kept)
+}
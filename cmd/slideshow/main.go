@@ -18,17 +18,88 @@ func init() {
 }
 
 type args struct {
-	ShotsDir string `arg:"-d,--shots-dir" help:"directory containing screenshots and metadata (default: ~/chrome-shots)"`
-	Output   string `arg:"-o,--output" help:"output mp4 path (default: <shots-dir>/slideshow-<timestamp>.mp4)"`
-	FPS      int    `arg:"-f,--fps" help:"frames per second for output video (default: 30)"`
-	Verbose  bool   `arg:"--verbose" help:"show ffmpeg banner and progress output"`
+	ShotsDir string  `arg:"-d,--shots-dir" help:"directory containing screenshots and metadata (default: ~/chrome-shots)"`
+	Run      string  `arg:"-r,--run" help:"only this run's steps: a run ID, a unique prefix of one, last, or current (default: every step)"`
+	Output   string  `arg:"-o,--output" help:"output path (default: <shots-dir>/slideshow-<timestamp>.<format>)"`
+	Format   string  `arg:"--format" help:"mp4, webm, or gif (default: from --output extension, else mp4)"`
+	FPS      int     `arg:"-f,--fps" help:"frames per second for output video (default: 30, gif: 10, or 2 without transitions)"`
+	Duration float64 `arg:"--duration" default:"5" help:"seconds per screenshot, unless its step set --duration"`
+	RealTime bool    `arg:"--real-time" help:"show each screenshot for as long as its step's action took (at least 1s)"`
+	Speed    float64 `arg:"--speed" default:"1" help:"with --real-time, play the session this many times faster"`
+
+	Transition         string  `arg:"--transition" default:"fade" help:"transition between screenshots: none (hard cuts), fade, dissolve, fadeblack, fadewhite, slideleft|right|up|down, wipeleft|right|up|down"`
+	TransitionDuration float64 `arg:"--transition-duration" default:"0.5" help:"seconds each transition takes, centered on the cut"`
+	CaptionFont        string  `arg:"--caption-font" help:"caption font name (default: DejaVu Sans)"`
+	CaptionSize        int     `arg:"--caption-size" help:"caption size, where the frame is 288 units tall (default: 32)"`
+	CaptionColor       string  `arg:"--caption-color" help:"caption text color as hex RRGGBB (default: ffffff)"`
+	CaptionPosition    string  `arg:"--caption-position" help:"bottom, top, or below (a band under the screenshots) (default: bottom)"`
+	NoCaptions         bool    `arg:"--no-captions" help:"leave out step captions"`
+	Stamp              string  `arg:"--stamp" help:"overlay step number and capture time in a corner: top-left, top-right, bottom-left, bottom-right"`
+	Narrate            bool    `arg:"--narrate" help:"speak each step's note on an audio track (mp4/webm)"`
+	TTSCommand         string  `arg:"--tts-command" help:"shell command reading text on stdin and writing audio to {out} (default: $CHROME_TTS, espeak-ng, espeak, or say)"`
+	Title              string  `arg:"--title" help:"text for a title card shown before the screenshots"`
+	Outro              string  `arg:"--outro" help:"text for an end card shown after the screenshots"`
+	Verbose            bool    `arg:"--verbose" help:"show ffmpeg banner and progress output"`
 }
 
 func (args) Description() string {
-	return `slideshow - build mp4 slideshow from captured steps
+	return `slideshow - build mp4, webm, or gif slideshow from captured steps
+
+Steps from every run in --shots-dir are included in the order they were
+captured; --run picks one run, such as 'last' or 'current' (the run
+'chrome step' is adding to), so interleaved sessions stay apart.
+
+Each screenshot is shown for --duration seconds, or for the duration its
+step recorded ('chrome step --duration 10 ...'), so important steps can
+stay on screen longer than trivial ones.
+
+GIFs use a palette generated from the whole slideshow and loop forever,
+which makes them easy to drop into pull request descriptions.
+
+Screenshots crossfade into each other by default. --transition picks
+another ffmpeg xfade effect, or none for hard cuts. Transitions overlap
+the cut they replace, so the slideshow keeps the same length.
+
+Each screenshot is captioned with its step's note, or its action and
+arguments. On small screenshots captions can cover the page: shrink them
+with --caption-size, move them with --caption-position top, or use
+--caption-position below to add a band for them under the screenshots.
+
+--stamp burns "3/12  2024-01-15T10:30:01.234Z" (step number and the time
+the step was captured, UTC) into a corner of each frame, so frames can be
+matched against console and network logs from the same run.
+
+--narrate speaks each step's note (and the title and end cards) with a
+text to speech command and adds it as an audio track, starting as the
+step's frame appears. Frames stay up until their note has been spoken.
+--tts-command sets the command: it reads the text on stdin and must write
+an audio file to {out}, e.g. 'piper -m en_US-amy.onnx -f {out}'.
+
+--real-time paces the slideshow like the session itself: each screenshot
+stays up for as long as its step's action took to run (recorded by step
+and run), divided by --speed, and at least a second. Screenshots without
+a recorded time use --duration.
+
+--title and --outro add generated cards before and after the screenshots,
+with the text centered on a dark frame, for demo videos that explain
+themselves. Cards are shown for --duration seconds.
 
 Examples:
   chrome slideshow
+  chrome slideshow --format gif
+  chrome slideshow --run current
+  chrome slideshow --output /tmp/steps.webm
+  chrome slideshow --duration 2
+  chrome slideshow --real-time --speed 2
+  chrome slideshow --transition slideleft --transition-duration 0.8
+  chrome slideshow --transition none
+  chrome slideshow --title "Checkout flow" --outro "Shipped in v2.3"
+  chrome slideshow --caption-position below --caption-size 20
+  chrome slideshow --caption-font "Noto Sans" --caption-color ffd700
+  chrome slideshow --no-captions
+  chrome slideshow --stamp top-right
+  chrome slideshow --narrate --title "Signing up"
+  chrome slideshow --narrate --tts-command 'espeak-ng -v en-us -s 150 --stdin -w {out}'
   chrome slideshow --shots-dir /tmp/run
   chrome slideshow --output /tmp/slideshow.mp4
   chrome slideshow --fps 30
@@ -38,33 +109,64 @@ Examples:
 func run() {
 	var parsed args
 	arg.MustParse(&parsed)
+	if parsed.Speed <= 0 {
+		fmt.Fprintf(os.Stderr, "error: --speed must be positive, got %g\n", parsed.Speed)
+		lib.Exit(1)
+	}
 
 	dir := strings.TrimSpace(parsed.ShotsDir)
 	if dir == "" {
 		dir = lib.DefaultShotsDir()
 	}
 
-	records, err := lib.LoadStepRecordsFromDir(dir)
+	var records []lib.StepRecord
+	var err error
+	if run := strings.TrimSpace(parsed.Run); run != "" {
+		_, records, err = lib.LoadRunRecords(dir, run)
+	} else {
+		records, err = lib.LoadStepRecordsFromDir(dir)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error loading step records: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	if len(records) == 0 {
 		fmt.Fprintf(os.Stderr, "error: no screenshots found in %s\n", dir)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
+	format, err := lib.ParseSlideshowFormat(parsed.Format, parsed.Output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
 	output := strings.TrimSpace(parsed.Output)
 	if output == "" {
-		output = filepath.Join(dir, fmt.Sprintf("slideshow-%s.mp4", time.Now().UTC().Format("20060102-150405")))
-	}
-
-	fps := parsed.FPS
-	if fps <= 0 {
-		fps = 30
+		output = filepath.Join(dir, fmt.Sprintf("slideshow-%s.%s", time.Now().UTC().Format("20060102-150405"), format))
 	}
 
-	err = lib.GenerateSlideshow(records, output, fps, parsed.Verbose)
+	err = lib.GenerateSlideshow(records, output, lib.SlideshowOptions{
+		Format:             format,
+		FPS:                parsed.FPS,
+		Duration:           time.Duration(parsed.Duration * float64(time.Second)),
+		RealTime:           parsed.RealTime,
+		Speed:              parsed.Speed,
+		Transition:         parsed.Transition,
+		TransitionDuration: time.Duration(parsed.TransitionDuration * float64(time.Second)),
+		Title:              strings.TrimSpace(parsed.Title),
+		Outro:              strings.TrimSpace(parsed.Outro),
+		Stamp:              parsed.Stamp,
+		Narrate:            parsed.Narrate,
+		TTSCommand:         parsed.TTSCommand,
+		Captions: lib.CaptionStyle{
+			Font:     parsed.CaptionFont,
+			Size:     parsed.CaptionSize,
+			Color:    parsed.CaptionColor,
+			Position: parsed.CaptionPosition,
+			Disabled: parsed.NoCaptions,
+		},
+		Verbose: parsed.Verbose,
+	})
 	if err != nil {
 		var pathErr *os.PathError
 		if errors.As(err, &pathErr) {
@@ -72,7 +174,7 @@ func run() {
 		} else {
 			fmt.Fprintf(os.Stderr, "error generating slideshow: %v\n", err)
 		}
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	fmt.Printf("slideshow created: %s\n", output)
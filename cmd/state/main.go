@@ -0,0 +1,264 @@
+// state provides element interaction state query command
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["state"] = state
+	lib.Args["state"] = stateArgs{}
+}
+
+type stateArgs struct {
+	lib.TargetArgs
+	Element *elementArgs `arg:"subcommand:element" help:"print an element's interaction state"`
+	Show    *showArgs    `arg:"subcommand:show" help:"print the tab's daemon overrides (chrome serve)"`
+	Clear   *clearArgs   `arg:"subcommand:clear" help:"reset the tab's daemon overrides (chrome serve)"`
+	Save    *saveArgs    `arg:"subcommand:save" help:"save cookies and storage to a storage state file"`
+	Load    *loadArgs    `arg:"subcommand:load" help:"restore a storage state file into the tab"`
+}
+
+type elementArgs struct {
+	Selector string `arg:"positional,required" help:"CSS selector of element"`
+}
+
+type showArgs struct{}
+
+type clearArgs struct{}
+
+type saveArgs struct {
+	File    string   `arg:"positional,required" help:"storage state JSON file"`
+	Origins []string `arg:"--origin,separate" help:"also save this origin's localStorage (repeatable; default: the tab's origin only)"`
+}
+
+type loadArgs struct {
+	File   string `arg:"positional,required" help:"storage state JSON file"`
+	Reload bool   `arg:"--reload" help:"reload the tab afterwards so the page reads the restored state"`
+}
+
+func (stateArgs) Description() string {
+	return `state - Get element interaction state, daemon overrides, or storage state
+
+'state element SELECTOR' prints JSON describing whether an element can be
+interacted with right now: visible, enabled, focused, checked, readonly,
+inViewport, obscured (another element covers its center), and ARIA state
+(role, aria-* attributes).
+
+"interactable" is true when the element is visible, enabled, in the viewport,
+and not obscured.
+
+With the daemon running (chrome serve), 'state show' prints the tab's
+emulation, throttling, blocked URLs, headers, and mock rules, and 'state
+clear' resets them.
+
+'state save FILE' writes a Playwright storage state: every cookie in the
+tab's browser context plus the localStorage and sessionStorage of the tab's
+origin, and the localStorage of each --origin. 'state load FILE' restores
+one into another tab or instance, from chrome or Playwright. sessionStorage
+lives in a single tab, so it is restored only for the tab's own origin.
+The file holds login sessions and is written readable only by you.
+
+Example:
+  chrome state element "#submit"
+  chrome state element "input[name='agree']"
+  chrome state show -t localhost
+  chrome state clear
+  chrome state save auth.json
+  chrome state save auth.json --origin https://app.example.com --origin https://sso.example.com
+  chrome -p 9223 state load auth.json --reload`
+}
+
+func state() {
+	var args stateArgs
+	p := arg.MustParse(&args)
+
+	switch {
+	case args.Element != nil:
+		elementState(args.TargetArgs, args.Element.Selector)
+	case args.Show != nil:
+		overrides(args.TargetArgs, false)
+	case args.Clear != nil:
+		overrides(args.TargetArgs, true)
+	case args.Save != nil:
+		saveState(args.TargetArgs, *args.Save)
+	case args.Load != nil:
+		loadState(args.TargetArgs, *args.Load)
+	default:
+		p.Fail("missing subcommand: element, show, clear, save, or load")
+	}
+}
+
+func elementState(target lib.TargetArgs, selector string) {
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, target.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	script := fmt.Sprintf(`(() => {
+	  const el = document.querySelector(%q);
+	  if (!el) return null;
+	  const rect = el.getBoundingClientRect();
+	  const style = getComputedStyle(el);
+	  const visible = rect.width > 0 && rect.height > 0 && style.display !== 'none' && style.visibility !== 'hidden' && parseFloat(style.opacity || '1') > 0;
+	  const inViewport = rect.bottom > 0 && rect.right > 0 && rect.top < window.innerHeight && rect.left < window.innerWidth;
+	  const disabled = el.disabled === true || el.closest('fieldset:disabled') !== null || el.getAttribute('aria-disabled') === 'true';
+	  let obscured = false;
+	  if (visible && inViewport) {
+	    const cx = Math.min(Math.max(rect.left + rect.width / 2, 0), window.innerWidth - 1);
+	    const cy = Math.min(Math.max(rect.top + rect.height / 2, 0), window.innerHeight - 1);
+	    const hit = document.elementFromPoint(cx, cy);
+	    obscured = hit !== null && hit !== el && !el.contains(hit);
+	  }
+	  const aria = {};
+	  for (const attr of Array.from(el.attributes)) {
+	    if (attr.name.startsWith('aria-')) aria[attr.name] = attr.value;
+	  }
+	  return {
+	    tag: el.tagName.toLowerCase(),
+	    role: el.getAttribute('role') || '',
+	    visible: visible,
+	    enabled: !disabled,
+	    focused: document.activeElement === el,
+	    checked: el.checked === true || el.getAttribute('aria-checked') === 'true',
+	    readonly: el.readOnly === true || el.getAttribute('aria-readonly') === 'true',
+	    editable: el.isContentEditable || ((el.tagName === 'INPUT' || el.tagName === 'TEXTAREA') && !el.readOnly && !disabled),
+	    inViewport: inViewport,
+	    obscured: obscured,
+	    interactable: visible && !disabled && inViewport && !obscured,
+	    aria: aria,
+	    rect: { x: rect.x, y: rect.y, width: rect.width, height: rect.height },
+	  };
+	})()`, selector)
+
+	var result map[string]interface{}
+	if err := chromedp.Run(targetCtx, chromedp.Evaluate(script, &result)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if result == nil {
+		fmt.Fprintf(os.Stderr, "error: element not found: %s\n", selector)
+		lib.Exit(1)
+	}
+
+	jsonBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
+
+func overrides(target lib.TargetArgs, clear bool) {
+	if !lib.DaemonRunning() {
+		mode := "show"
+		if clear {
+			mode = "clear"
+		}
+		fmt.Fprintf(os.Stderr, "error: state %s requires the daemon (chrome serve) on %s\n", mode, lib.DaemonAddr())
+		lib.Exit(1)
+	}
+	body := map[string]string{"target": lib.DaemonTarget(target)}
+	if clear {
+		if err := lib.DaemonCall("/state/clear", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println("cleared")
+		return
+	}
+	var result lib.Overrides
+	if err := lib.DaemonCall("/state", body, &result); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	jsonBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
+
+// tabContext attaches to the tab for save and load.
+func tabContext(target lib.TargetArgs) (context.Context, func()) {
+	ctx, cancel := lib.SetupContext()
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, target.Selector())
+	if err != nil {
+		cancel()
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	return targetCtx, func() {
+		targetCancel()
+		cancel()
+	}
+}
+
+func loadState(target lib.TargetArgs, args loadArgs) {
+	targetCtx, cancel := tabContext(target)
+	defer cancel()
+
+	state, err := lib.LoadStorageState(args.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	res, err := lib.LoadState(targetCtx, state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if args.Reload {
+		if err := chromedp.Run(targetCtx, chromedp.Reload()); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+	fmt.Printf("loaded %d cookie(s), %d key(s) in %d origin(s)", res.Cookies, res.Keys, res.Origins)
+	if res.ExpiredCookies > 0 {
+		fmt.Printf(", skipped %d expired cookie(s)", res.ExpiredCookies)
+	}
+	fmt.Println()
+	for _, origin := range res.SkippedSession {
+		fmt.Fprintf(os.Stderr, "warning: skipped sessionStorage for %s, the tab is not on that origin\n", origin)
+	}
+}
+
+func saveState(target lib.TargetArgs, args saveArgs) {
+	targetCtx, cancel := tabContext(target)
+	defer cancel()
+
+	state, err := lib.SaveState(targetCtx, args.Origins)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	jsonBytes, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if err := os.WriteFile(args.File, append(jsonBytes, '\n'), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	keys := 0
+	for _, o := range state.Origins {
+		keys += len(o.LocalStorage) + len(o.SessionStorage)
+	}
+	fmt.Printf("saved %d cookie(s), %d key(s) in %d origin(s) to %s\n", len(state.Cookies), keys, len(state.Origins), args.File)
+}
@@ -2,14 +2,21 @@
 package step
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/alexflint/go-arg"
 	"github.com/nathants/chrome/lib"
 )
 
@@ -22,16 +29,62 @@ func init() {
 
 type stepArgs struct {
 	lib.TargetArgs
-	OutputDir string `arg:"-o,--output-dir" help:"directory to store screenshots (default: ~/chrome-shots)"`
-	Label     string `arg:"-l,--label" help:"label embedded in filename (default: action name)"`
-	Note      string `arg:"-n,--note" help:"note stored with metadata"`
-	Action    string `arg:"positional,required" help:"chrome command to execute (e.g. click, type, waitfor)"`
+	OutputDir  string  `arg:"-o,--output-dir" help:"shots directory; screenshots go in its runs/<run ID> (default: ~/chrome-shots)"`
+	Label      string  `arg:"-l,--label" help:"label embedded in filename (default: action name)"`
+	Name       string  `arg:"--name-template" help:"screenshot file name template, e.g. \"{seq}-{label}\" (see chrome screenshot --help)"`
+	Note       string  `arg:"-n,--note" help:"note stored with metadata"`
+	Freeze     bool    `arg:"--freeze-animations" help:"disable transitions and pause animations during capture"`
+	Duration   float64 `arg:"-d,--duration" help:"seconds to show this step in a slideshow (default: slideshow's)"`
+	Before     bool    `arg:"--before" help:"also capture a screenshot before running the action"`
+	Diff       bool    `arg:"--diff" help:"write a pixel diff of the before and after screenshots (implies --before)"`
+	Capture    string  `arg:"--capture" help:"comma-separated events to save during the action: console, network"`
+	AfterMs    int     `arg:"--after-ms" help:"wait this many ms after the action before the screenshot"`
+	When       string  `arg:"--when-selector" help:"CSS selector: take the screenshot once it is visible"`
+	RunID      string  `arg:"--run-id" help:"run to add this step to, or new (default: the current run)"`
+	Retries    int     `arg:"--retries" help:"retry the action this many times when it fails"`
+	RetryDelay string  `arg:"--retry-delay" help:"wait between retries, e.g. 500ms or 2 (seconds) (default: 1s)"`
+	DryRun     bool    `arg:"--dry-run" help:"check the action and its args and print what would run, without running it"`
+	KeepGoing  bool    `arg:"--keep-going" help:"still screenshot and record the step when the action fails"`
+	JSON       bool    `arg:"--json" help:"print the StepRecord as JSON instead of a summary"`
+	Action     string  `arg:"positional,required" help:"chrome command to execute (e.g. click, type, waitfor)"`
 }
 
 func (stepArgs) Description() string {
 	return `step - Run an action and capture the result
 
 Step is a wrapper that: (1) runs any chrome action, then (2) takes a screenshot.
+With --before it also screenshots the tab before the action, and --diff writes
+<shot>-diff.png highlighting the pixels the action changed in red.
+--retries N runs the action again, after --retry-delay, each time it exits
+non-zero (an element not rendered yet, a click racing a re-render), up to
+N more times. Every attempt is listed in the StepRecord's attempts.
+A failed assertion (asserttext, assertselector, asserturl, asserttitle) is
+still screenshotted and recorded, as failed with its expected and actual
+values, and step exits 4.
+When the action fails, step exits with the action's exit status. With
+--keep-going it first screenshots the tab and records the step as failed,
+with the action's exit_code and the end of its stderr in the StepRecord:
+failure evidence is when screenshots matter most. --json prints the
+StepRecord (or, for a failed action without --keep-going, its action,
+status, exit_code, and stderr) instead of the summary.
+--dry-run resolves the target, checks the action exists and its args parse
+as that command's, prints the command it would run, and exits 1 if not,
+without running the action, taking screenshots, or touching the run.
+
+Steps are grouped into runs, saved under <output-dir>/runs/<run ID>. A step
+joins the current run (cached, and kept for 30 minutes after its last step)
+unless --run-id names one; --run-id new starts a fresh run. Select runs with
+'chrome slideshow --run', 'chrome report --from-run', and 'chrome shots runs'.
+
+The screenshot is taken as soon as the action exits, which can catch the
+frame before a transition or a late render. --when-selector waits until an
+element is visible first (up to 30s; if it never shows, the screenshot is
+still saved and the step recorded as failed), and --after-ms waits that
+many ms more, after the element appears when both are set.
+
+With --capture console,network it records the tab's console messages and
+network events while the action runs, as NDJSON next to the screenshot
+(<shot>-console.ndjson, <shot>-network.ndjson).
 The --output-dir flag controls where step saves its screenshot, NOT the action.
 Actions like clicktext, type, etc. don't take screenshots themselves.
 Pass the action and its args as separate tokens (ACTION [ARGS...]).
@@ -41,14 +94,40 @@ Examples:
   chrome step navigate https://localhost:3000
   chrome step -t http://localhost:3000 click "button.submit"
   chrome step type "#name" "Alice"
-  chrome step --output-dir /tmp/shots clicktext "Login"   # screenshot saved to /tmp/shots/`
+  chrome step --output-dir /tmp/shots clicktext "Login"   # screenshot saved to /tmp/shots/runs/<run ID>/
+  chrome step --freeze-animations click "#open-modal"     # stable capture mid-animation
+  chrome step --duration 10 -n "totals match" waitfor ".summary"   # longer in slideshow
+  chrome step --diff click "#toggle-theme"                # before, after, and what changed
+  chrome step --capture console,network click "#save"     # what the page logged and fetched
+  chrome step --after-ms 300 click "#open-drawer"         # after the slide-in transition
+  chrome step --when-selector ".toast" click "#save"      # once the confirmation shows
+  chrome step --retries 3 --retry-delay 500ms click "#submit"   # ride out re-renders
+  chrome step --dry-run type "#name" "Alice"              # validate a planned step
+  chrome step --keep-going --json click "#checkout"       # screenshot even if the click fails
+  chrome step --name-template "{seq}-{label}" click "#next"   # 0001-click.png, 0002-click.png, ...
+  chrome step --run-id new navigate https://localhost:3000  # start a new run
+  chrome step --run-id checkout click "#pay"              # interleave runs by naming them`
 }
 
 type parsedStep struct {
 	target     string
 	outputDir  string
 	label      string
+	name       string
 	note       string
+	freeze     bool
+	duration   float64
+	before     bool
+	diff       bool
+	capture    []string
+	afterMs    int
+	when       string
+	runID      string
+	retries    int
+	retryDelay time.Duration
+	dryRun     bool
+	keepGoing  bool
+	json       bool
 	action     string
 	actionArgs []string
 }
@@ -61,14 +140,28 @@ func step() {
 			fmt.Println("\nUsage: step [OPTIONS] ACTION [ACTION_ARGS...]")
 			fmt.Println("\nOptions:")
 			fmt.Println("  -t, --target URL       URL prefix to select tab")
-			fmt.Println("  -o, --output-dir DIR   directory to store screenshots (default: ~/chrome-shots)")
+			fmt.Println("  -o, --output-dir DIR   shots directory; screenshots go in its runs/<run ID> (default: ~/chrome-shots)")
 			fmt.Println("  -l, --label LABEL      label embedded in filename")
+			fmt.Println("  --name-template T      screenshot file name template, e.g. \"{seq}-{label}\"")
 			fmt.Println("  -n, --note NOTE        note stored with metadata")
+			fmt.Println("  --freeze-animations    disable transitions and pause animations during capture")
+			fmt.Println("  -d, --duration SECS    seconds to show this step in a slideshow")
+			fmt.Println("  --before               also capture a screenshot before the action")
+			fmt.Println("  --diff                 write a pixel diff of before and after (implies --before)")
+			fmt.Println("  --capture KINDS        save console and/or network events during the action")
+			fmt.Println("  --after-ms MS          wait this many ms after the action before the screenshot")
+			fmt.Println("  --when-selector SEL    take the screenshot once SEL is visible")
+			fmt.Println("  --run-id ID            run to add this step to, or new (default: the current run)")
+			fmt.Println("  --retries N            retry the action this many times when it fails")
+			fmt.Println("  --retry-delay D        wait between retries, e.g. 500ms or 2 (seconds) (default: 1s)")
+			fmt.Println("  --dry-run              check the action and print what would run, without running it")
+			fmt.Println("  --keep-going           still screenshot and record the step when the action fails")
+			fmt.Println("  --json                 print the StepRecord as JSON instead of a summary")
 			fmt.Println("  -h, --help             display this help")
-			os.Exit(0)
+			lib.Exit(0)
 		}
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	action := parsed.action
@@ -79,25 +172,133 @@ func step() {
 		actionArgs = applyTarget(actionArgs, target)
 	}
 
-	if err := runSubcommand(action, actionArgs); err != nil {
-		fmt.Fprintf(os.Stderr, "error executing action: %v\n", err)
-		os.Exit(1)
-	}
-
 	label := parsed.label
 	if label == "" {
 		label = action
 	}
 
-	path, err := lib.PrepareScreenshotPath("", parsed.outputDir, label)
+	if parsed.dryRun {
+		dryRun(parsed, actionArgs, supportsTarget)
+		return
+	}
+
+	runID, err := lib.CurrentRunID(parsed.runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	runDir, err := lib.RunDir(parsed.outputDir, runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	path, err := lib.PrepareNamedOutputPath("", runDir, lib.OutputName{Template: parsed.name, Label: label, Target: target}, ".png")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error preparing screenshot path: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
+	}
+	opts := lib.ScreenshotOptions{FreezeAnimations: parsed.freeze}
+	base := strings.TrimSuffix(path, ".png")
+
+	// The before shot is named after the step's screenshot so it is pruned
+	// along with it.
+	var beforePath string
+	if parsed.before {
+		beforePath = base + "-before.png"
+		if err := lib.CaptureScreenshotWithOptions(target, beforePath, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "error capturing before screenshot: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	var logPaths map[string]string
+	stopCapture := func() {}
+	if len(parsed.capture) > 0 {
+		logPaths = map[string]string{}
+		for _, kind := range parsed.capture {
+			logPaths[kind] = lib.StepLogPath(path, kind)
+		}
+		stopCapture, err = startCapture(target, logPaths)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error starting capture: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	// Assert commands save their expected and actual values here
+	assertionPath := filepath.Join(os.TempDir(), fmt.Sprintf("chrome-assertion-%d.json", os.Getpid()))
+	_ = os.Remove(assertionPath)
+	if err := os.Setenv(lib.AssertionFileEnv, assertionPath); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	start := time.Now()
+	attempts, err := runWithRetries(action, actionArgs, parsed.retries, parsed.retryDelay)
+	assertion, readErr := lib.ReadAssertion(assertionPath)
+	_ = os.Remove(assertionPath)
+	if readErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to read assertion: %v\n", readErr)
+	}
+	// A failed assertion is a result, not a broken step: it is screenshotted
+	// and recorded like any other, then step exits with ExitAssertion
+	assertErr := err
+	if err != nil && assertion != nil && !assertion.Passed {
+		err = nil
+	} else {
+		assertErr = nil
 	}
+	actionErr := err
+	if err != nil {
+		if len(attempts) > 1 {
+			fmt.Fprintf(os.Stderr, "error executing action after %d attempts: %v\n", len(attempts), err)
+		} else {
+			fmt.Fprintf(os.Stderr, "error executing action: %v\n", err)
+		}
+	}
+	if err != nil && !parsed.keepGoing {
+		// Keep what was captured, it's most useful when the action fails
+		stopCapture()
+		for _, kind := range parsed.capture {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", kind, logPaths[kind])
+		}
+		if parsed.json {
+			record := lib.StepRecord{
+				Action:     action,
+				Args:       append([]string{}, actionArgs...),
+				Target:     target,
+				Label:      label,
+				ConsoleLog: logPaths["console"],
+				NetworkLog: logPaths["network"],
+				RunID:      runID,
+				ElapsedMs:  time.Since(start).Milliseconds(),
+				Attempts:   attempts,
+				CreatedAt:  time.Now().UTC(),
+			}
+			setFailure(&record, err)
+			printJSON(record)
+		}
+		lib.Exit(exitCode(err))
+	}
+	elapsed := time.Since(start)
 
-	if err := lib.CaptureScreenshot(target, path); err != nil {
+	waitErr := waitForCapture(target, parsed.when, parsed.afterMs)
+	err = lib.CaptureScreenshotWithOptions(target, path, opts)
+	stopCapture()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error capturing screenshot: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
+	}
+
+	var diffPath, diffSummary string
+	if parsed.diff {
+		diffPath = base + "-diff.png"
+		diffSummary, err = writeDiff(beforePath, path, diffPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing diff: %v\n", err)
+			lib.Exit(1)
+		}
 	}
 
 	record := lib.StepRecord{
@@ -107,22 +308,101 @@ func step() {
 		Label:      label,
 		Note:       parsed.note,
 		Screenshot: path,
+		Before:     beforePath,
+		Diff:       diffPath,
+		ConsoleLog: logPaths["console"],
+		NetworkLog: logPaths["network"],
+		RunID:      runID,
+		Duration:   parsed.duration,
+		ElapsedMs:  elapsed.Milliseconds(),
+		Attempts:   attempts,
+		Assertion:  assertion,
 		CreatedAt:  time.Now().UTC(),
 	}
+	if assertErr != nil {
+		setFailure(&record, assertErr)
+	} else if actionErr != nil {
+		setFailure(&record, actionErr)
+	} else if waitErr != nil {
+		record.Status = "failed"
+		record.Error = waitErr.Error()
+	}
 
 	if err := lib.RememberStep(record); err != nil {
 		fmt.Fprintf(os.Stderr, "warning: unable to persist metadata: %v\n", err)
 	}
 
+	if parsed.json {
+		printJSON(record)
+	} else {
+		printSummary(record, parsed, logPaths, diffSummary)
+	}
+	if assertErr != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", assertErr)
+		lib.Exit(lib.ExitAssertion)
+	}
+	if actionErr != nil {
+		lib.Exit(exitCode(actionErr))
+	}
+	if waitErr != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", waitErr)
+		lib.Exit(1)
+	}
+}
+
+func printSummary(record lib.StepRecord, parsed parsedStep, logPaths map[string]string, diffSummary string) {
 	fmt.Println(lib.StepSummary(record))
+	if record.Before != "" {
+		fmt.Printf("before: %s\n", record.Before)
+	}
+	if record.Diff != "" {
+		fmt.Printf("diff: %s (%s)\n", record.Diff, diffSummary)
+	}
+	for _, kind := range parsed.capture {
+		fmt.Printf("%s: %s\n", kind, logPaths[kind])
+	}
 	fmt.Printf("metadata: %s\n", record.MetadataPath())
+	fmt.Printf("run: %s\n", record.RunID)
 	if record.Note != "" {
 		fmt.Printf("note: %s\n", record.Note)
 	}
+	if record.Status == "failed" {
+		fmt.Printf("status: failed (exit %d)\n", max(record.ExitCode, 1))
+	}
+}
+
+func printJSON(record lib.StepRecord) {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+// setFailure marks record failed with err, and the exit status and stderr
+// of the action when err came from it.
+func setFailure(record *lib.StepRecord, err error) {
+	record.Status = "failed"
+	record.Error = err.Error()
+	var failed *actionError
+	if errors.As(err, &failed) {
+		record.ExitCode = failed.code
+		record.Stderr = failed.stderr
+	}
+}
+
+// exitCode returns the action's exit status for err, or 1.
+func exitCode(err error) int {
+	var failed *actionError
+	if errors.As(err, &failed) && failed.code > 0 {
+		return failed.code
+	}
+	return 1
 }
 
 func parseStep(args []string) (parsedStep, error) {
-	var parsed parsedStep
+	parsed := parsedStep{retryDelay: -1}
 	pos := 0
 	for pos < len(args) {
 		tok := args[pos]
@@ -163,6 +443,15 @@ func parseStep(args []string) (parsedStep, error) {
 			pos++
 			continue
 		}
+		if strings.HasPrefix(tok, "--name-template=") {
+			value := strings.TrimPrefix(tok, "--name-template=")
+			if err := lib.ValidateNameTemplate(value); err != nil {
+				return parsedStep{}, err
+			}
+			parsed.name = value
+			pos++
+			continue
+		}
 		if strings.HasPrefix(tok, "--note=") {
 			value := strings.TrimPrefix(tok, "--note=")
 			if value == "" {
@@ -172,7 +461,83 @@ func parseStep(args []string) (parsedStep, error) {
 			pos++
 			continue
 		}
+		if strings.HasPrefix(tok, "--run-id=") {
+			value := strings.TrimPrefix(tok, "--run-id=")
+			if value == "" {
+				return parsedStep{}, errors.New("--run-id requires a value")
+			}
+			parsed.runID = value
+			pos++
+			continue
+		}
+		if strings.HasPrefix(tok, "--retries=") {
+			value, err := parseRetries(strings.TrimPrefix(tok, "--retries="))
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.retries = value
+			pos++
+			continue
+		}
+		if strings.HasPrefix(tok, "--retry-delay=") {
+			value, err := parseRetryDelay(strings.TrimPrefix(tok, "--retry-delay="))
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.retryDelay = value
+			pos++
+			continue
+		}
+		if strings.HasPrefix(tok, "--capture=") {
+			kinds, err := parseCapture(strings.TrimPrefix(tok, "--capture="))
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.capture = kinds
+			pos++
+			continue
+		}
+		if strings.HasPrefix(tok, "--after-ms=") {
+			value, err := parseAfterMs(strings.TrimPrefix(tok, "--after-ms="))
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.afterMs = value
+			pos++
+			continue
+		}
+		if strings.HasPrefix(tok, "--when-selector=") {
+			value := strings.TrimPrefix(tok, "--when-selector=")
+			if value == "" {
+				return parsedStep{}, errors.New("--when-selector requires a value")
+			}
+			parsed.when = value
+			pos++
+			continue
+		}
+		if strings.HasPrefix(tok, "--duration=") {
+			value, err := parseDuration(strings.TrimPrefix(tok, "--duration="))
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.duration = value
+			pos++
+			continue
+		}
 		switch tok {
+		case "--freeze-animations":
+			parsed.freeze = true
+		case "--dry-run":
+			parsed.dryRun = true
+		case "--keep-going":
+			parsed.keepGoing = true
+		case "--json":
+			parsed.json = true
+		case "--before":
+			parsed.before = true
+		case "--diff":
+			parsed.before = true
+			parsed.diff = true
 		case "-t", "--target":
 			pos++
 			if pos >= len(args) {
@@ -191,12 +556,83 @@ func parseStep(args []string) (parsedStep, error) {
 				return parsedStep{}, errors.New("--label requires a value")
 			}
 			parsed.label = args[pos]
+		case "--name-template":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--name-template requires a value")
+			}
+			if err := lib.ValidateNameTemplate(args[pos]); err != nil {
+				return parsedStep{}, err
+			}
+			parsed.name = args[pos]
 		case "-n", "--note":
 			pos++
 			if pos >= len(args) {
 				return parsedStep{}, errors.New("--note requires a value")
 			}
 			parsed.note = args[pos]
+		case "--run-id":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--run-id requires a value")
+			}
+			parsed.runID = args[pos]
+		case "--retries":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--retries requires a value")
+			}
+			value, err := parseRetries(args[pos])
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.retries = value
+		case "--retry-delay":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--retry-delay requires a value")
+			}
+			value, err := parseRetryDelay(args[pos])
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.retryDelay = value
+		case "--capture":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--capture requires a value")
+			}
+			kinds, err := parseCapture(args[pos])
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.capture = kinds
+		case "--after-ms":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--after-ms requires a value")
+			}
+			value, err := parseAfterMs(args[pos])
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.afterMs = value
+		case "--when-selector":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--when-selector requires a value")
+			}
+			parsed.when = args[pos]
+		case "-d", "--duration":
+			pos++
+			if pos >= len(args) {
+				return parsedStep{}, errors.New("--duration requires a value")
+			}
+			value, err := parseDuration(args[pos])
+			if err != nil {
+				return parsedStep{}, err
+			}
+			parsed.duration = value
 		default:
 			return parsedStep{}, fmt.Errorf("unknown step option %q", tok)
 		}
@@ -206,6 +642,9 @@ func parseStep(args []string) (parsedStep, error) {
 	if pos >= len(args) {
 		return parsedStep{}, errors.New("action is required")
 	}
+	if parsed.retryDelay < 0 {
+		parsed.retryDelay = time.Second
+	}
 
 	parsed.action = args[pos]
 	pos++
@@ -231,16 +670,244 @@ func parseStep(args []string) (parsedStep, error) {
 	return parsed, nil
 }
 
+// startCapture attaches to the target and logs the events in paths until
+// the returned stop function flushes them. The action runs in a separate
+// process, so events are recorded from this connection.
+func startCapture(target string, paths map[string]string) (func(), error) {
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, target)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	log, err := lib.StartEventLog(targetCtx, paths)
+	if err != nil {
+		targetCancel()
+		cancel()
+		return nil, err
+	}
+	return func() {
+		if err := log.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: unable to save captured events: %v\n", err)
+		}
+		targetCancel()
+		cancel()
+	}, nil
+}
+
+// waitForCapture holds the screenshot until selector is visible, when set,
+// then for afterMs more. It returns an error when the selector does not
+// show in time, and the screenshot is taken anyway.
+func waitForCapture(target string, selector string, afterMs int) error {
+	if selector != "" {
+		ctx, cancel := lib.SetupContextWithTimeout(lib.DefaultTimeout)
+		defer cancel()
+		targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, target)
+		if err != nil {
+			return err
+		}
+		defer targetCancel()
+		if err := lib.WaitVisible(targetCtx, selector); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("--when-selector %s not visible after %s", selector, lib.DefaultTimeout)
+			}
+			return fmt.Errorf("--when-selector %s: %w", selector, err)
+		}
+	}
+	if afterMs > 0 {
+		time.Sleep(time.Duration(afterMs) * time.Millisecond)
+	}
+	return nil
+}
+
+// writeDiff saves a pixel diff of the before and after screenshots to
+// diffPath and returns a one-line summary of how much changed.
+func writeDiff(beforePath, afterPath, diffPath string) (string, error) {
+	before, err := lib.LoadImage(beforePath)
+	if err != nil {
+		return "", err
+	}
+	after, err := lib.LoadImage(afterPath)
+	if err != nil {
+		return "", err
+	}
+	res, diff := lib.DiffImages(before, after, lib.DiffOptions{Tolerance: 8, AntiAlias: true})
+	if err := lib.SavePNG(diffPath, diff); err != nil {
+		return "", err
+	}
+	summary := fmt.Sprintf("%d of %d pixels changed, %.2f%%", res.Different, res.Pixels, res.Ratio*100)
+	if res.SizeMismatch {
+		b, a := before.Bounds(), after.Bounds()
+		summary += fmt.Sprintf(", size %dx%d -> %dx%d", b.Dx(), b.Dy(), a.Dx(), a.Dy())
+	}
+	return summary, nil
+}
+
+// runWithRetries runs the action, and again after delay each time it fails,
+// up to retries more times. It returns every attempt when retrying is on.
+func runWithRetries(name string, args []string, retries int, delay time.Duration) ([]lib.Attempt, error) {
+	var attempts []lib.Attempt
+	for i := 0; ; i++ {
+		start := time.Now()
+		err := runSubcommand(name, args)
+		attempt := lib.Attempt{ElapsedMs: time.Since(start).Milliseconds()}
+		if err != nil {
+			attempt.Error = err.Error()
+			attempt.ExitCode = exitCode(err)
+		}
+		attempts = append(attempts, attempt)
+		if err == nil || i >= retries {
+			if retries == 0 {
+				return nil, err
+			}
+			return attempts, err
+		}
+		fmt.Fprintf(os.Stderr, "attempt %d/%d failed: %s; retrying in %s\n", i+1, retries+1, attempt.Error, delay)
+		time.Sleep(delay)
+	}
+}
+
+// maxStderr bounds the stderr kept in a StepRecord; the end is kept, where
+// the error usually is.
+const maxStderr = 4096
+
+// actionError is a failed action: its exit status, the end of its stderr,
+// and its last line of stderr as the message.
+type actionError struct {
+	code   int
+	stderr string
+	msg    string
+}
+
+func (e *actionError) Error() string {
+	return e.msg
+}
+
+// runSubcommand runs a chrome command, passing its output through. A
+// non-zero exit is an *actionError whose message is the command's last
+// line of stderr, falling back to the exit status.
 func runSubcommand(name string, args []string) error {
 	execPath, err := os.Executable()
 	if err != nil {
 		return err
 	}
+	var stderr bytes.Buffer
 	cmd := exec.Command(execPath, append([]string{name}, args...)...)
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = os.Environ()
-	return cmd.Run()
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	cmd.Env = lib.ChildEnv()
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		output := strings.TrimSpace(stderr.String())
+		failed := &actionError{code: exitErr.ExitCode(), stderr: output, msg: err.Error()}
+		if len(output) > maxStderr {
+			failed.stderr = "..." + output[len(output)-maxStderr:]
+		}
+		lines := strings.Split(output, "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+			failed.msg = strings.TrimPrefix(last, "error: ")
+		}
+		return failed
+	}
+	return nil
+}
+
+// dryRun checks the action is a command whose args parse, resolves the
+// target, and prints what step would do. It exits 1 if the step would
+// fail before running.
+func dryRun(parsed parsedStep, actionArgs []string, supportsTarget bool) {
+	if err := validateAction(parsed.action, actionArgs); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("would run: chrome %s\n", quoteArgs(append([]string{parsed.action}, actionArgs...)))
+	if supportsTarget {
+		switch {
+		case !lib.IsChromeRunning():
+			fmt.Println("target: not resolved, Chrome is not running")
+		default:
+			id, reason, err := lib.ResolveTarget(parsed.target, nil)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: resolving target: %v\n", err)
+				lib.Exit(1)
+			}
+			if id == "" {
+				fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+				lib.Exit(1)
+			}
+			fmt.Printf("target: %s (%s)\n", id, reason)
+		}
+	}
+	runID := parsed.runID
+	if runID == "" {
+		runID = "current"
+	}
+	fmt.Printf("run: %s\n", runID)
+	var shots []string
+	if parsed.before {
+		shots = append(shots, "before")
+	}
+	shots = append(shots, "after")
+	if parsed.diff {
+		shots = append(shots, "diff")
+	}
+	fmt.Printf("screenshots: %s\n", strings.Join(shots, ", "))
+	if parsed.when != "" {
+		fmt.Printf("screenshot when visible: %s\n", parsed.when)
+	}
+	if parsed.afterMs > 0 {
+		fmt.Printf("screenshot delay: %dms\n", parsed.afterMs)
+	}
+	if len(parsed.capture) > 0 {
+		fmt.Printf("capture: %s\n", strings.Join(parsed.capture, ", "))
+	}
+	if parsed.retries > 0 {
+		fmt.Printf("retries: %d, %s apart\n", parsed.retries, parsed.retryDelay)
+	}
+}
+
+// validateAction checks that name is a command step can run and that args
+// parse against its registered Args struct.
+func validateAction(name string, args []string) error {
+	if _, ok := lib.Commands[name]; !ok {
+		return fmt.Errorf("unknown action %q, see 'chrome --help'", name)
+	}
+	if name == "step" {
+		return errors.New("step cannot run step")
+	}
+	argsStruct, ok := lib.Args[name]
+	if !ok {
+		return nil
+	}
+	val := reflect.ValueOf(argsStruct)
+	dest := reflect.New(val.Type())
+	dest.Elem().Set(val)
+	p, err := arg.NewParser(arg.Config{Program: "chrome " + name}, dest.Interface())
+	if err != nil {
+		return err
+	}
+	if err := p.Parse(args); err != nil {
+		if err == arg.ErrHelp {
+			return fmt.Errorf("%s args ask for help, see 'chrome %s --help'", name, name)
+		}
+		return fmt.Errorf("%s: %v, see 'chrome %s --help'", name, err, name)
+	}
+	return nil
+}
+
+// quoteArgs joins args for display, quoting those a shell would split.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$`#&|;<>()*?[]{}!~") {
+			a = strconv.Quote(a)
+		}
+		quoted[i] = a
+	}
+	return strings.Join(quoted, " ")
 }
 
 func applyTarget(args []string, target string) []string {
@@ -276,3 +943,59 @@ func commandSupportsTarget(name string) bool {
 	_, found := t.FieldByName("TargetArgs")
 	return found
 }
+
+func parseCapture(value string) ([]string, error) {
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" {
+			continue
+		}
+		if !slices.Contains(lib.RunLogKinds, kind) {
+			return nil, fmt.Errorf("unknown --capture kind %q, expected console or network", kind)
+		}
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return nil, errors.New("--capture requires console, network, or both")
+	}
+	return kinds, nil
+}
+
+func parseRetries(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("--retries must be a non-negative integer, got %q", value)
+	}
+	return n, nil
+}
+
+// parseRetryDelay accepts a Go duration (500ms, 2s) or plain seconds.
+func parseRetryDelay(value string) (time.Duration, error) {
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("--retry-delay must be a duration like 500ms or seconds, got %q", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func parseAfterMs(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("--after-ms must be a non-negative integer, got %q", value)
+	}
+	return n, nil
+}
+
+func parseDuration(value string) (float64, error) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("--duration must be a positive number of seconds, got %q", value)
+	}
+	return seconds, nil
+}
@@ -0,0 +1,154 @@
+// storage gets, sets, removes, clears, and dumps localStorage and sessionStorage.
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["storage"] = storage
+	lib.Args["storage"] = storageArgs{}
+}
+
+type storageArgs struct {
+	lib.TargetArgs
+	Action string   `arg:"positional,required" help:"get, set, remove, clear, or dump"`
+	Args   []string `arg:"positional" help:"get/remove: KEY; set: KEY VALUE"`
+	Area   string   `arg:"-a,--area" help:"local or session (default: local; dump: both)"`
+	Output string   `arg:"-o,--output" help:"dump to this file instead of stdout"`
+}
+
+func (storageArgs) Description() string {
+	return `storage - Read and write the page's localStorage and sessionStorage
+
+Works on the top frame of the tab, so the keys belong to the page's
+origin. Values are strings; set stores VALUE as given, so JSON values
+are passed as JSON text.
+
+Actions:
+  get KEY           print the value, error if the key is not set
+  set KEY VALUE     set a key
+  remove KEY        remove a key
+  clear             remove every key in the area
+  dump              print every key as JSON, {"localStorage": {...},
+                    "sessionStorage": {...}}, the same shape navigate
+                    --seed-storage reads
+
+Example:
+  chrome storage get authToken
+  chrome storage set onboarded 1
+  chrome storage set flags '{"newNav": true}' --area session
+  chrome storage remove cart
+  chrome storage clear --area session
+  chrome storage dump -o state.json
+  chrome navigate https://example.com --seed-storage state.json`
+}
+
+func storage() {
+	var args storageArgs
+	arg.MustParse(&args)
+
+	want := map[string]int{"get": 1, "set": 2, "remove": 1, "clear": 0, "dump": 0}
+	n, ok := want[args.Action]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown action %q, expected get, set, remove, clear, or dump\n", args.Action)
+		lib.Exit(1)
+	}
+	if len(args.Args) != n {
+		usage := map[string]string{"get": "get KEY", "set": "set KEY VALUE", "remove": "remove KEY"}[args.Action]
+		if usage == "" {
+			usage = args.Action + " (no arguments)"
+		}
+		fmt.Fprintf(os.Stderr, "error: usage: chrome storage %s\n", usage)
+		lib.Exit(1)
+	}
+	area, err := lib.StorageArea(args.Area)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fail(err)
+	}
+	defer targetCancel()
+
+	switch args.Action {
+	case "get":
+		var value *string
+		if err := evaluate(targetCtx, fmt.Sprintf("%s.getItem(%s)", area, strconv.Quote(args.Args[0])), &value); err != nil {
+			fail(err)
+		}
+		if value == nil {
+			fail(fmt.Errorf("%s has no key %q", area, args.Args[0]))
+		}
+		fmt.Println(*value)
+	case "set":
+		if err := evaluate(targetCtx, fmt.Sprintf("%s.setItem(%s, %s)", area, strconv.Quote(args.Args[0]), strconv.Quote(args.Args[1])), nil); err != nil {
+			fail(err)
+		}
+	case "remove":
+		if err := evaluate(targetCtx, fmt.Sprintf("%s.removeItem(%s)", area, strconv.Quote(args.Args[0])), nil); err != nil {
+			fail(err)
+		}
+	case "clear":
+		if err := evaluate(targetCtx, area+".clear()", nil); err != nil {
+			fail(err)
+		}
+	case "dump":
+		areas := lib.StorageAreas
+		if args.Area != "" {
+			areas = []string{area}
+		}
+		dump, err := lib.DumpStorage(targetCtx, areas)
+		if err != nil {
+			fail(err)
+		}
+		data, err := json.MarshalIndent(dump, "", "  ")
+		if err != nil {
+			fail(err)
+		}
+		data = append(data, '\n')
+		if args.Output == "" {
+			_, _ = os.Stdout.Write(data)
+			return
+		}
+		// Storage often holds auth tokens, keep the file private
+		if err := os.WriteFile(args.Output, data, 0600); err != nil {
+			fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "wrote %d keys to %s\n", countKeys(dump), args.Output)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	lib.Exit(1)
+}
+
+// evaluate runs a storage expression, discarding its result when res is nil.
+func evaluate(ctx context.Context, expr string, res any) error {
+	if err := chromedp.Run(ctx, chromedp.Evaluate(expr, res)); err != nil {
+		return lib.StorageError(err)
+	}
+	return nil
+}
+
+func countKeys(dump map[string]map[string]string) int {
+	n := 0
+	for _, keys := range dump {
+		n += len(keys)
+	}
+	return n
+}
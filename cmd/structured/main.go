@@ -0,0 +1,130 @@
+// structured extracts and validates JSON-LD, microdata, and RDFa.
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["structured"] = structured
+	lib.Args["structured"] = structuredArgs{}
+}
+
+type structuredArgs struct {
+	lib.TargetArgs
+	Format string `arg:"-f,--format" help:"only json-ld, microdata, or rdfa (comma-separated)"`
+	Type   string `arg:"--type" help:"only items of this schema.org type, like Product"`
+	Strict bool   `arg:"--strict" help:"exit 1 if any item has errors, or no item is found"`
+}
+
+func (structuredArgs) Description() string {
+	return `structured - Extract and validate schema.org structured data
+
+Collects JSON-LD scripts, microdata (itemscope/itemprop), and RDFa
+(typeof/property) from the page and prints them as normalized JSON: each
+item in compact JSON-LD form, with "@type" and property names stripped
+of their schema.org prefix, whatever format it came from. JSON-LD @graph
+arrays become separate items.
+
+Each item is validated, nested items included: invalid JSON, a missing
+@context or @type, and missing properties Google requires for rich
+results of common types (Product, Offer, Article, BreadcrumbList, Event,
+FAQPage, Recipe, ...). Problems are listed in the item's errors and
+counted on stderr; --strict makes them fail the command.
+
+Example:
+  chrome structured
+  chrome structured --type Product | jq '.items[].item.offers'
+  chrome structured -f json-ld --strict
+  diff <(chrome structured -t staging) <(chrome structured -t prod)`
+}
+
+func structured() {
+	var args structuredArgs
+	arg.MustParse(&args)
+
+	var formats []string
+	for _, f := range strings.Split(args.Format, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch f {
+		case "":
+			continue
+		case "jsonld":
+			f = "json-ld"
+		case "json-ld", "microdata", "rdfa":
+		default:
+			fmt.Fprintf(os.Stderr, "error: unknown format %q, expected json-ld, microdata, or rdfa\n", f)
+			lib.Exit(1)
+		}
+		formats = append(formats, f)
+	}
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	var raw lib.RawStructured
+	var url string
+	if err := chromedp.Run(targetCtx, chromedp.Evaluate(lib.StructuredDataJS, &raw), chromedp.Location(&url)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	items := []lib.StructuredItem{}
+	problems := 0
+	for _, item := range lib.NormalizeStructured(raw) {
+		if len(formats) > 0 && !slices.Contains(formats, item.Format) {
+			continue
+		}
+		if args.Type != "" && !hasType(item.Item, args.Type) {
+			continue
+		}
+		problems += len(item.Errors)
+		items = append(items, item)
+	}
+
+	jsonBytes, err := json.MarshalIndent(map[string]any{"url": url, "items": items, "errors": problems}, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+
+	if problems > 0 {
+		fmt.Fprintf(os.Stderr, "%d items, %d problems\n", len(items), problems)
+	}
+	if args.Strict && (problems > 0 || len(items) == 0) {
+		if len(items) == 0 {
+			fmt.Fprintf(os.Stderr, "error: no structured data found\n")
+		}
+		lib.Exit(1)
+	}
+}
+
+func hasType(item map[string]any, want string) bool {
+	switch v := item["@type"].(type) {
+	case string:
+		return strings.EqualFold(v, want)
+	case []any:
+		for _, t := range v {
+			if s, ok := t.(string); ok && strings.EqualFold(s, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
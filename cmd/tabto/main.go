@@ -0,0 +1,159 @@
+// tabto provides keyboard focus navigation command
+package tabto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["tabto"] = tabto
+	lib.Args["tabto"] = tabtoArgs{}
+}
+
+type tabtoArgs struct {
+	lib.TargetArgs
+	Want    string `arg:"positional,required" help:"CSS selector or visible text of the element to focus"`
+	Text    bool   `arg:"--text" help:"treat WANT as text even if it parses as a CSS selector"`
+	Reverse bool   `arg:"--reverse" help:"press Shift+Tab instead of Tab"`
+	Max     int    `arg:"--max" default:"50" help:"maximum key presses before giving up"`
+	Verbose bool   `arg:"-v,--verbose" help:"print every focus stop along the way"`
+	JSON    bool   `arg:"--json" help:"print the result and focus stops as JSON"`
+}
+
+func (tabtoArgs) Description() string {
+	return `tabto - Move keyboard focus with Tab until an element is focused
+
+Presses Tab (or Shift+Tab with --reverse) until document.activeElement
+matches WANT, verifying focus after every key press. Exits 1 if the element
+is not reached within --max presses.
+
+WANT is a CSS selector, or visible text when it is not a valid selector or
+matches nothing (use --text to force text matching). Text matches when the
+focused element's innerText, aria-label, value, or title equals WANT.
+
+Useful for accessibility testing of keyboard flows and tab order.
+
+Example:
+  chrome tabto "#submit"
+  chrome tabto "Sign In"
+  chrome tabto --reverse "#email"
+  chrome tabto --verbose --max 20 "button.save"   # show tab order along the way`
+}
+
+type stop struct {
+	Selector string `json:"selector"`
+	Tag      string `json:"tag"`
+	Text     string `json:"text"`
+}
+
+type checkResult struct {
+	Matched bool `json:"matched"`
+	Stop    stop `json:"stop"`
+}
+
+func tabto() {
+	var args tabtoArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContext()
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	check := `(() => {
+	  ` + lib.CSSPathJS + `
+	  const want = ` + strconv.Quote(args.Want) + `;
+	  let bySelector = !` + strconv.FormatBool(args.Text) + `;
+	  if (bySelector) {
+	    try { bySelector = document.querySelector(want) !== null; } catch (e) { bySelector = false; }
+	  }
+	  const el = document.activeElement;
+	  if (!el || el === document.body) return { matched: false, stop: { selector: '', tag: el ? 'body' : '', text: '' } };
+	  const text = ((el.innerText || '').trim() || el.getAttribute('aria-label') || el.value || el.title || '').toString().trim();
+	  let matched;
+	  if (bySelector) {
+	    matched = el.matches(want) || Array.from(document.querySelectorAll(want)).some(m => m.contains(el));
+	  } else {
+	    matched = text === want || (el.getAttribute('aria-label') || '') === want;
+	  }
+	  return { matched: matched, stop: { selector: cssPath(el), tag: el.tagName.toLowerCase(), text: text.slice(0, 80) } };
+	})()`
+
+	var opts []chromedp.KeyOption
+	if args.Reverse {
+		opts = append(opts, chromedp.KeyModifiers(input.ModifierShift))
+	}
+
+	var stops []stop
+	presses := 0
+	for {
+		var res checkResult
+		if err := chromedp.Run(targetCtx, chromedp.Evaluate(check, &res)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		if presses > 0 {
+			stops = append(stops, res.Stop)
+			if args.Verbose && !args.JSON {
+				fmt.Printf("%d: %s %q\n", presses, res.Stop.Selector, res.Stop.Text)
+			}
+		}
+		if res.Matched {
+			report(args, true, presses, stops, res.Stop)
+			return
+		}
+		if presses >= args.Max {
+			break
+		}
+		if err := chromedp.Run(targetCtx, chromedp.KeyEvent(kb.Tab, opts...)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		presses++
+	}
+
+	report(args, false, presses, stops, stop{})
+	fmt.Fprintf(os.Stderr, "error: %q not focused after %d key presses\n", args.Want, presses)
+	lib.Exit(1)
+}
+
+func report(args tabtoArgs, found bool, presses int, stops []stop, focused stop) {
+	if args.JSON {
+		if stops == nil {
+			stops = []stop{}
+		}
+		direction := "forward"
+		if args.Reverse {
+			direction = "reverse"
+		}
+		jsonBytes, err := json.MarshalIndent(map[string]any{
+			"found":     found,
+			"presses":   presses,
+			"direction": direction,
+			"stops":     stops,
+		}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		fmt.Println(string(jsonBytes))
+		return
+	}
+	if found {
+		fmt.Printf("focused %s after %d key presses\n", focused.Selector, presses)
+	}
+}
@@ -0,0 +1,183 @@
+// throttle emulates slow or offline networking in a tab.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["throttle"] = throttle
+	lib.Args["throttle"] = throttleArgs{}
+}
+
+type throttleArgs struct {
+	lib.TargetArgs
+	Kind    string   `arg:"positional" help:"what to throttle: network"`
+	Profile string   `arg:"positional" help:"slow-3g, fast-3g, slow-4g, fast-4g, offline, off, or custom"`
+	Latency int      `arg:"--latency" help:"added round-trip latency in ms (overrides the profile)"`
+	Down    int      `arg:"--down" help:"download throughput in kbps (overrides the profile)"`
+	Up      int      `arg:"--up" help:"upload throughput in kbps (overrides the profile)"`
+	List    bool     `arg:"--list" help:"list the network profiles"`
+	Hold    bool     `arg:"--hold" help:"throttle from this process until Ctrl+C even when the daemon is running"`
+	Command []string `arg:"positional" help:"chrome command and args to run while throttled (after --)"`
+}
+
+func (throttleArgs) Description() string {
+	return `throttle - Emulate slow or offline networking
+
+  throttle network PROFILE [--latency MS] [--down KBPS] [--up KBPS]
+
+Applies network conditions to the tab with
+Network.emulateNetworkConditions, to debug performance and see loading
+states, spinners, and timeouts as users on slow connections do. Profiles
+match DevTools' throttling presets (see --list); "custom" starts from no
+throttling, and --latency, --down, and --up override any profile. "off"
+restores normal networking.
+
+Chrome drops throttling when the DevTools connection closes. With COMMAND
+(after --), the tab is throttled while it runs and throttle exits 1 if it
+fails. Otherwise, when the daemon is running (chrome serve), the
+conditions are handed to it and stay until 'chrome throttle network off'
+or 'chrome state clear'; without it, this command holds them until Ctrl+C.
+
+Example:
+  chrome throttle network slow-3g
+  chrome throttle network fast-3g -- run checkout.yaml
+  chrome throttle network custom --latency 300 --down 1000 --up 500
+  chrome throttle network offline -- screenshot --label offline
+  chrome throttle network off
+  chrome throttle --list`
+}
+
+func throttle() {
+	var args throttleArgs
+	arg.MustParse(&args)
+
+	if args.List {
+		for _, name := range lib.NetworkProfileNames() {
+			fmt.Printf("%-8s %s\n", name, describe(lib.NetworkProfiles[name]))
+		}
+		return
+	}
+	if args.Kind != "network" || args.Profile == "" {
+		fmt.Fprintf(os.Stderr, "error: usage: chrome throttle network PROFILE [--latency MS] [--down KBPS] [--up KBPS] [-- COMMAND...]\n")
+		lib.Exit(1)
+	}
+	if args.Latency < 0 || args.Down < 0 || args.Up < 0 {
+		fmt.Fprintf(os.Stderr, "error: --latency, --down, and --up must be >= 0\n")
+		lib.Exit(1)
+	}
+
+	profile := strings.ToLower(args.Profile)
+	conditions, ok := lib.NetworkProfiles[profile]
+	if !ok && profile != "custom" {
+		fmt.Fprintf(os.Stderr, "error: unknown profile %q, expected custom or one of: %s\n", args.Profile, strings.Join(lib.NetworkProfileNames(), ", "))
+		lib.Exit(1)
+	}
+	if profile == "custom" && args.Latency == 0 && args.Down == 0 && args.Up == 0 {
+		fmt.Fprintf(os.Stderr, "error: custom needs --latency, --down, or --up\n")
+		lib.Exit(1)
+	}
+	if args.Latency > 0 {
+		conditions.Latency = args.Latency
+	}
+	if args.Down > 0 {
+		conditions.Download = args.Down
+	}
+	if args.Up > 0 {
+		conditions.Upload = args.Up
+	}
+	reset := conditions == (lib.NetworkConditions{})
+
+	if len(args.Command) == 0 && !args.Hold && lib.DaemonRunning() {
+		body := map[string]any{
+			"target":   lib.DaemonTarget(args.TargetArgs),
+			"offline":  conditions.Offline,
+			"latency":  conditions.Latency,
+			"download": conditions.Download,
+			"upload":   conditions.Upload,
+		}
+		if err := lib.DaemonCall("/throttle", body, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		if reset {
+			fmt.Println("network throttling off")
+		} else {
+			fmt.Printf("network throttled: %s (held by daemon)\n", describe(conditions))
+		}
+		return
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	setCtx, setCancel := context.WithTimeout(ctx, lib.DefaultTimeout)
+	err = chromedp.Run(setCtx, conditions.Action())
+	setCancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if len(args.Command) > 0 {
+		output, err := lib.RunCommand(tabID, args.Command)
+		_, _ = os.Stdout.Write(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		return
+	}
+	if reset {
+		fmt.Println("network throttling off")
+		return
+	}
+
+	fmt.Printf("network throttled: %s\n", describe(conditions))
+	fmt.Fprintf(os.Stderr, "holding throttling, Ctrl+C to release\n")
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
+
+func describe(nc lib.NetworkConditions) string {
+	if nc.Offline {
+		return "offline"
+	}
+	if nc == (lib.NetworkConditions{}) {
+		return "no throttling"
+	}
+	var parts []string
+	if nc.Latency > 0 {
+		parts = append(parts, fmt.Sprintf("%dms latency", nc.Latency))
+	}
+	if nc.Download > 0 {
+		parts = append(parts, fmt.Sprintf("%d kbps down", nc.Download))
+	}
+	if nc.Upload > 0 {
+		parts = append(parts, fmt.Sprintf("%d kbps up", nc.Upload))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,83 @@
+// trail draws a run's clicks and typing onto its screenshots as a journey map.
+package trail
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["trail"] = trail
+	lib.Args["trail"] = trailArgs{}
+}
+
+type trailArgs struct {
+	FromRun  string `arg:"-r,--from-run,required" help:"run ID printed by 'chrome run' or 'chrome step', a unique prefix, last, or current"`
+	PerStep  bool   `arg:"--per-step" help:"one frame per step on the page it acted on, tiled into one image"`
+	ShotsDir string `arg:"-d,--shots-dir" help:"directory containing the run's screenshots and metadata (default: ~/chrome-shots)"`
+	Output   string `arg:"-o,--output" help:"output png path (default: <shots-dir>/trail-<run>.png)"`
+	Columns  int    `arg:"--columns" default:"3" help:"--per-step: frames per row"`
+	Width    int    `arg:"--width" default:"640" help:"--per-step: frame width in pixels"`
+}
+
+func (trailArgs) Description() string {
+	return `trail - Draw a run's click path as a journey map
+
+Overlays numbered markers for each click, clicktext, fill, and type step of
+a 'chrome run' onto its screenshots, joined by a line in step order: red for
+clicks, blue for typing. Numbers are step numbers in the workflow.
+
+By default every marker is drawn on the final screenshot. Positions are
+measured on the page each step acted on, so after navigation or scrolling
+they show where on the screen the action happened rather than on the final
+page. --per-step instead draws each marker on the screenshot taken just
+before its step and tiles the frames into one image.
+
+Example:
+  chrome run login.yaml         # prints: login: passed, 6/6 steps (run 20240115-103000-login)
+  chrome trail --from-run 20240115-103000-login
+  chrome trail --from-run last --per-step
+  chrome trail -r 20240115 -d /tmp/run -o /tmp/journey.png`
+}
+
+func trail() {
+	var args trailArgs
+	arg.MustParse(&args)
+
+	dir := strings.TrimSpace(args.ShotsDir)
+	if dir == "" {
+		dir = lib.DefaultShotsDir()
+	}
+	runID, records, err := lib.LoadRunRecords(dir, args.FromRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	var journey *image.RGBA
+	if args.PerStep {
+		journey, err = lib.JourneySheet(records, args.Columns, args.Width)
+	} else {
+		journey, err = lib.JourneyMap(records)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: run %s: %v\n", runID, err)
+		lib.Exit(1)
+	}
+
+	output := strings.TrimSpace(args.Output)
+	if output == "" {
+		output = filepath.Join(dir, "trail-"+runID+".png")
+	}
+	if err := lib.SavePNG(output, journey); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("wrote %s (%d of %d steps marked)\n", output, len(lib.TrailMarks(records)), len(records))
+}
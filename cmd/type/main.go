@@ -4,7 +4,6 @@ package typecommand
 import (
 	"fmt"
 	"os"
-	"strconv"
 
 	"github.com/alexflint/go-arg"
 	"github.com/chromedp/chromedp"
@@ -21,6 +20,7 @@ type typeArgs struct {
 	Selector string `arg:"positional,required" help:"CSS selector of element to type into"`
 	Text     string `arg:"positional,required" help:"Text to type"`
 	Append   bool   `arg:"--append,-a" help:"Append to existing text instead of replacing"`
+	Verify   bool   `arg:"--verify" help:"read the value back after the next frame, retry once on mismatch, exit 1 if still wrong"`
 }
 
 func (typeArgs) Description() string {
@@ -31,10 +31,14 @@ This ensures predictable behavior with React controlled inputs.
 
 Use --append to add to existing text instead of replacing.
 
+Use --verify to read the value back after React's state settles (next frame).
+On mismatch the field is cleared and retyped once; if still wrong, exits 1.
+
 Example:
   chrome type "#nameInput" "Alice"
   chrome type "input[name='email']" "alice@test.com"
-  chrome type --append "textarea" " more text"`
+  chrome type --append "textarea" " more text"
+  chrome type --verify "#nameInput" "Alice"`
 }
 
 func typeText() {
@@ -47,29 +51,29 @@ func typeText() {
 	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	defer targetCancel()
 
+	// Capture the existing value so --verify knows what an append should produce
+	expected := args.Text
+	if args.Verify && args.Append {
+		before, found, err := lib.ReadElementValue(targetCtx, args.Selector)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		if found {
+			expected = before + args.Text
+		}
+	}
+
+	// Select all existing text within the element so new text replaces it
+	selectScript := lib.SelectContentsScript(args.Selector)
+
 	var actions []chromedp.Action
 	actions = append(actions, chromedp.Focus(args.Selector, chromedp.ByQuery))
 	if !args.Append {
-		// Select all existing text within the element so new text replaces it
-		// For INPUT/TEXTAREA: use element.select()
-		// For contenteditable: use Selection API to select all content
-		selectScript := `(() => {
-		  const el = document.querySelector(` + strconv.Quote(args.Selector) + `);
-		  if (!el) return;
-		  if (typeof el.select === 'function') {
-		    el.select();
-		  } else if (el.isContentEditable) {
-		    const range = document.createRange();
-		    range.selectNodeContents(el);
-		    const sel = window.getSelection();
-		    sel.removeAllRanges();
-		    sel.addRange(range);
-		  }
-		})()`
 		actions = append(actions, chromedp.Evaluate(selectScript, nil))
 	}
 	actions = append(actions, chromedp.SendKeys(args.Selector, args.Text, chromedp.ByQuery))
@@ -77,6 +81,41 @@ func typeText() {
 	err = chromedp.Run(targetCtx, actions...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		lib.Exit(1)
+	}
+
+	if !args.Verify {
+		return
 	}
-}
\ No newline at end of file
+
+	for attempt := 1; ; attempt++ {
+		value, found, err := lib.ReadElementValue(targetCtx, args.Selector)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "error: element not found after typing (selector %q)\n", args.Selector)
+			lib.Exit(1)
+		}
+		if value == expected {
+			return
+		}
+		if attempt > 1 {
+			fmt.Fprintf(os.Stderr, "error: value mismatch after retry - expected %q but got %q\n", expected, value)
+			lib.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "warning: value mismatch - expected %q but got %q, retrying\n", expected, value)
+
+		// Retry always replaces the whole value with the expected text
+		err = chromedp.Run(targetCtx,
+			chromedp.Focus(args.Selector, chromedp.ByQuery),
+			chromedp.Evaluate(selectScript, nil),
+			chromedp.SendKeys(args.Selector, expected, chromedp.ByQuery),
+		)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+}
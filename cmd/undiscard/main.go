@@ -0,0 +1,77 @@
+// undiscard provides the tab unfreeze command
+package undiscard
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alexflint/go-arg"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["undiscard"] = undiscard
+	lib.Args["undiscard"] = undiscardArgs{}
+}
+
+type undiscardArgs struct {
+	lib.TargetArgs
+	NoThrottle bool `arg:"--no-throttle" help:"also emulate focus so background timers are not throttled, holding the connection until Ctrl+C"`
+}
+
+func (undiscardArgs) Description() string {
+	return `undiscard - Resume a frozen tab
+
+Moves the tab back to the "active" web lifecycle state.
+
+Chrome also throttles timers in background tabs even when they are not
+frozen, which breaks long-running monitoring. --no-throttle keeps the tab
+active and emulates focus so it runs as if in the foreground; focus
+emulation lasts only while this command stays connected, so it holds until
+Ctrl+C. For a durable fix start Chrome with 'chrome launch --no-throttle'.
+
+Example:
+  chrome undiscard -t dashboard
+  chrome undiscard -t dashboard --no-throttle &`
+}
+
+func undiscard() {
+	var args undiscardArgs
+	arg.MustParse(&args)
+
+	timeout := lib.DefaultTimeout
+	if args.NoThrottle {
+		timeout = 0
+	}
+	ctx, cancel := lib.SetupContextWithTimeout(timeout)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	actions := []chromedp.Action{page.SetWebLifecycleState(page.SetWebLifecycleStateStateActive)}
+	if args.NoThrottle {
+		actions = append(actions, emulation.SetFocusEmulationEnabled(true))
+	}
+	if err := chromedp.Run(targetCtx, actions...); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	fmt.Println("active")
+
+	if args.NoThrottle {
+		fmt.Fprintf(os.Stderr, "emulating focus, Ctrl+C to release\n")
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+	}
+}
@@ -0,0 +1,142 @@
+// video records the tab with the screencast API and encodes it to mp4 or webm.
+package video
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["video"] = video
+	lib.Args["video"] = videoArgs{}
+}
+
+type videoArgs struct {
+	lib.TargetArgs
+	Output    string   `arg:"-o,--output" help:"output path, .mp4 or .webm (default: ~/chrome-shots/<timestamp>-video.mp4)"`
+	Duration  int      `arg:"-d,--duration" help:"seconds to record (default: until Ctrl+C or COMMAND exits)"`
+	FPS       int      `arg:"-f,--fps" default:"25" help:"output frames per second"`
+	Quality   int      `arg:"--quality" default:"80" help:"screencast jpeg quality 1-100"`
+	MaxWidth  int      `arg:"--max-width" help:"scale frames down to at most this width"`
+	MaxHeight int      `arg:"--max-height" help:"scale frames down to at most this height"`
+	Verbose   bool     `arg:"--verbose" help:"show ffmpeg banner and progress output"`
+	Command   []string `arg:"positional" help:"chrome command and args to record (after --)"`
+}
+
+func (videoArgs) Description() string {
+	return `video - Record the tab to mp4 or webm
+
+Streams frames with the DevTools screencast API and encodes them with
+ffmpeg, so animations and transitions are captured, unlike slideshow.
+Chrome only sends frames when the page repaints, so a still page records
+as a held frame.
+
+Recording stops after --duration seconds, on Ctrl+C, or, if COMMAND is given
+(after --), when COMMAND exits. COMMAND runs against the same tab and its
+output is written to stdout; video waits for it to finish and exits 1 if it
+fails, after saving the recording.
+
+The codec follows the output extension: .webm is VP9, otherwise H.264.
+
+Example:
+  chrome video -d 10
+  chrome video -o /tmp/login.webm -- run login.yaml
+  chrome video --max-width 1280 --fps 30 -- autoscroll --max-rounds 5
+  chrome -t localhost:3000 video                  # Ctrl+C to stop`
+}
+
+func video() {
+	var args videoArgs
+	arg.MustParse(&args)
+
+	ext := ".mp4"
+	if args.Output != "" {
+		ext = filepath.Ext(args.Output)
+	}
+	output, err := lib.PrepareOutputPath(args.Output, "", "video", ext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error preparing output path: %v\n", err)
+		lib.Exit(1)
+	}
+
+	tabID, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if tabID == "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+		lib.Exit(1)
+	}
+	ctx, cancel, err := lib.Attach(tabID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "chrome-video-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	stop := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		defer close(stop)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		defer signal.Stop(sig)
+		var timeout <-chan time.Time
+		if args.Duration > 0 {
+			timeout = time.After(time.Duration(args.Duration) * time.Second)
+		}
+		finished := make(chan struct{})
+		if len(args.Command) > 0 {
+			go func() {
+				defer close(finished)
+				output, err := lib.RunCommand(tabID, args.Command)
+				_, _ = os.Stdout.Write(output)
+				result <- err
+			}()
+		} else {
+			fmt.Fprintf(os.Stderr, "recording, Ctrl+C to stop\n")
+		}
+		select {
+		case <-sig:
+		case <-timeout:
+		case <-finished:
+		}
+	}()
+
+	frames, end, err := lib.RecordScreencast(ctx, dir, lib.ScreencastOptions{
+		Quality:   args.Quality,
+		MaxWidth:  args.MaxWidth,
+		MaxHeight: args.MaxHeight,
+	}, stop)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if err := lib.EncodeVideo(frames, end, output, args.FPS, args.Verbose); err != nil {
+		fmt.Fprintf(os.Stderr, "error encoding video: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("video created: %s (%d frames)\n", output, len(frames))
+	if len(args.Command) > 0 {
+		// --duration may stop recording before COMMAND finishes
+		if err := <-result; err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", strings.Join(args.Command, " "), err)
+			lib.Exit(1)
+		}
+	}
+}
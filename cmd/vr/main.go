@@ -0,0 +1,243 @@
+// vr compares screenshots against stored baselines for visual regression checks.
+package vr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["vr"] = vr
+	lib.Args["vr"] = vrArgs{}
+}
+
+type vrArgs struct {
+	lib.TargetArgs
+	Action    string  `arg:"positional,required" help:"baseline, check, or list"`
+	Label     string  `arg:"positional" help:"baseline name"`
+	From      string  `arg:"--from" help:"use an existing png/jpeg screenshot instead of capturing"`
+	Selector  string  `arg:"-s,--selector" help:"CSS selector: capture only this element"`
+	Freeze    bool    `arg:"--freeze-animations" help:"disable transitions and pause animations during capture"`
+	Dir       string  `arg:"-d,--dir" help:"baseline directory (default: ~/chrome-shots/baselines)"`
+	OutputDir string  `arg:"-o,--output-dir" help:"directory for check captures and diffs (default: ~/chrome-shots)"`
+	Threshold float64 `arg:"--threshold" default:"0.001" help:"fraction of pixels allowed to differ"`
+	Tolerance int     `arg:"--tolerance" default:"8" help:"per-channel color difference (0-255) treated as equal"`
+	NoAA      bool    `arg:"--no-anti-alias" help:"count anti-aliasing differences instead of ignoring them"`
+}
+
+func (vrArgs) Description() string {
+	return `vr - Visual regression baselines and checks
+
+  vr baseline LABEL   capture the tab and store it as the baseline for LABEL
+  vr check LABEL      capture again and compare pixel by pixel with the baseline
+  vr list             list baselines
+
+Baselines are PNGs (with StepRecord sidecars) in ~/chrome-shots/baselines,
+or --dir. Use the same viewport for baseline and check, e.g. with
+'chrome session apply' or a workflow preset.
+
+check saves the capture and a diff image (red: different, yellow: ignored
+anti-aliasing) to the shots directory with a StepRecord whose status is ok
+or failed, then exits 1 if more than --threshold of the pixels differ or the
+sizes differ. A pixel counts as different when any channel differs by more
+than --tolerance. Unless --no-anti-alias, a differing pixel is ignored when
+each image has the other's color within one pixel, as happens when edges
+and text shift by a pixel.
+
+--from compares or stores an existing screenshot, such as one from step or
+run, instead of capturing.
+
+Example:
+  chrome vr baseline header --selector "header"
+  chrome vr check header --selector "header"
+  chrome vr check home --threshold 0.01 --tolerance 16
+  chrome vr baseline login --from ~/chrome-shots/20240115-103000-login.png
+  chrome vr list`
+}
+
+func vr() {
+	var args vrArgs
+	arg.MustParse(&args)
+
+	switch args.Action {
+	case "baseline":
+		baseline(args)
+	case "check":
+		check(args)
+	case "list":
+		list(args)
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown action %q, expected baseline, check, or list\n", args.Action)
+		lib.Exit(1)
+	}
+}
+
+func baselinePath(args vrArgs) string {
+	path, err := lib.BaselinePath(args.Dir, args.Label)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	return path
+}
+
+// capture writes the current tab (or --from) to path as png.
+func capture(args vrArgs, path string) error {
+	if args.From != "" {
+		img, err := lib.LoadImage(args.From)
+		if err != nil {
+			return err
+		}
+		return lib.SavePNG(path, img)
+	}
+	return lib.CaptureScreenshotWithOptions(args.TargetArgs.Selector(), path, lib.ScreenshotOptions{
+		FreezeAnimations: args.Freeze,
+		Element:          args.Selector,
+	})
+}
+
+func baseline(args vrArgs) {
+	path := baselinePath(args)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if err := capture(args, path); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	record := lib.StepRecord{
+		Action:     "vr",
+		Args:       buildArgs(args),
+		Target:     args.TargetArgs.Selector(),
+		Label:      args.Label,
+		Screenshot: path,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := lib.SaveStepRecord(record); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to persist metadata: %v\n", err)
+	}
+	fmt.Printf("saved baseline %s\n", path)
+}
+
+func check(args vrArgs) {
+	basePath := baselinePath(args)
+	want, err := lib.LoadImage(basePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "error: no baseline for %s, create one with: chrome vr baseline %s\n", args.Label, args.Label)
+		} else {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		lib.Exit(1)
+	}
+
+	label := "vr-" + args.Label
+	path, err := lib.PrepareScreenshotPath("", args.OutputDir, label)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error preparing screenshot path: %v\n", err)
+		lib.Exit(1)
+	}
+	if err := capture(args, path); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	got, err := lib.LoadImage(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	res, diff := lib.DiffImages(want, got, lib.DiffOptions{Tolerance: args.Tolerance, AntiAlias: !args.NoAA})
+	diffPath := strings.TrimSuffix(path, ".png") + "-diff.png"
+	if err := lib.SavePNG(diffPath, diff); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	summary := fmt.Sprintf("%d of %d pixels differ (%.4f%%, threshold %.4f%%)", res.Different, res.Pixels, res.Ratio*100, args.Threshold*100)
+	if res.AntiAliased > 0 {
+		summary += fmt.Sprintf(", %d anti-aliased ignored", res.AntiAliased)
+	}
+	if res.SizeMismatch {
+		b, g := want.Bounds(), got.Bounds()
+		summary += fmt.Sprintf(", size %dx%d vs baseline %dx%d", g.Dx(), g.Dy(), b.Dx(), b.Dy())
+	}
+	failed := res.SizeMismatch || res.Ratio > args.Threshold
+
+	record := lib.StepRecord{
+		Action:     "vr",
+		Args:       buildArgs(args),
+		Target:     args.TargetArgs.Selector(),
+		Label:      label,
+		Note:       "diff: " + diffPath,
+		Screenshot: path,
+		Status:     "ok",
+		CreatedAt:  time.Now().UTC(),
+	}
+	if failed {
+		record.Status = "failed"
+		record.Error = summary
+	}
+	if err := lib.RememberStep(record); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to persist metadata: %v\n", err)
+	}
+
+	status := "pass"
+	if failed {
+		status = "fail"
+	}
+	fmt.Printf("%s %s: %s\n", status, args.Label, summary)
+	fmt.Printf("capture: %s\n", path)
+	fmt.Printf("diff: %s\n", diffPath)
+	if failed {
+		lib.Exit(1)
+	}
+}
+
+func list(args vrArgs) {
+	dir := lib.BaselineDir(args.Dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".png") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		record, err := lib.LoadStepMetadata(path)
+		created := ""
+		if err == nil && !record.CreatedAt.IsZero() {
+			created = record.CreatedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%-30s %-20s %s\n", strings.TrimSuffix(name, ".png"), created, path)
+	}
+}
+
+func buildArgs(args vrArgs) []string {
+	collected := []string{args.Action, args.Label}
+	if args.Selector != "" {
+		collected = append(collected, fmt.Sprintf("--selector=%s", args.Selector))
+	}
+	if args.From != "" {
+		collected = append(collected, fmt.Sprintf("--from=%s", args.From))
+	}
+	if args.Freeze {
+		collected = append(collected, "--freeze-animations")
+	}
+	return collected
+}
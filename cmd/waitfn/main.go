@@ -0,0 +1,81 @@
+// waitfn waits for a JavaScript expression to become truthy.
+package waitfn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["waitfn"] = waitfn
+	lib.Args["waitfn"] = waitfnArgs{}
+}
+
+type waitfnArgs struct {
+	lib.TargetArgs
+	Expression string `arg:"positional,required" help:"JavaScript expression to wait for, may return a promise"`
+	Timeout    int    `arg:"--timeout" default:"30" help:"timeout in seconds"`
+	PollMs     int    `arg:"--poll-ms" default:"100" help:"milliseconds between checks, besides one on every DOM change"`
+}
+
+func (waitfnArgs) Description() string {
+	return `waitfn - Wait for a JavaScript expression to be truthy
+
+Evaluates the expression in the page on every DOM change and every
+--poll-ms until it is truthy, then prints its value as JSON. For app
+state that wait and waitfor can't see: a flag on window, a store's
+loading state, a canvas that has drawn.
+
+The expression may return a promise, which is awaited. Errors it throws
+count as false, so "window.app.ready" waits for window.app to exist; on
+timeout the last error is printed. A syntax error fails at once. A
+navigation during the wait restarts it on the new page.
+
+Example:
+  chrome waitfn "window.app && window.app.ready"
+  chrome waitfn "window.__store.getState().cart.loaded" --timeout 60
+  chrome waitfn "document.querySelectorAll('.row').length >= 20"
+  chrome waitfn "fetch('/api/health').then(r => r.ok)" --poll-ms 1000`
+}
+
+func waitfn() {
+	var args waitfnArgs
+	arg.MustParse(&args)
+
+	if strings.TrimSpace(args.Expression) == "" {
+		fmt.Fprintf(os.Stderr, "error: usage: chrome waitfn EXPRESSION [--timeout SECONDS]\n")
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	waitCtx, waitCancel := context.WithTimeout(targetCtx, time.Duration(args.Timeout)*time.Second)
+	defer waitCancel()
+
+	value, err := lib.WaitFn(waitCtx, args.Expression, time.Duration(args.PollMs)*time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Println(string(data))
+}
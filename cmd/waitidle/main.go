@@ -0,0 +1,89 @@
+// waitidle waits until the tab's network goes quiet.
+package waitidle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["waitidle"] = waitidle
+	lib.Args["waitidle"] = waitidleArgs{}
+}
+
+type waitidleArgs struct {
+	lib.TargetArgs
+	IdleMs      int      `arg:"-q,--idle-ms" default:"500" help:"milliseconds with no more than --max-inflight requests in flight"`
+	MaxInflight int      `arg:"-m,--max-inflight" default:"0" help:"requests that may stay in flight, e.g. 1 for an open long poll"`
+	Ignore      []string `arg:"-i,--ignore,separate" help:"URL pattern ('*' any run of characters, '?' one) or @group of requests not counted (repeatable)"`
+	Timeout     int      `arg:"--timeout" default:"30" help:"timeout in seconds"`
+}
+
+func (waitidleArgs) Description() string {
+	return `waitidle - Wait until the network goes quiet
+
+Tracks the tab's requests with Network events and waits until no more
+than --max-inflight of them have been in flight for --idle-ms. Single-page
+apps fetch their data and render long after the load event; this waits
+for that fetching to finish when there is no one element or text to wait
+for.
+
+Requests already in flight when waitidle starts aren't seen, so start it
+right after the action, or run 'chrome waitnav --until networkidle' for a
+navigation. Pages that keep a long poll or stream open never reach zero:
+allow them with --max-inflight, or leave them out with --ignore patterns
+(globs matched against the whole URL, as in 'chrome mock'; @groups from
+'chrome block --groups', e.g. @trackers, work too). WebSockets aren't
+requests and never count. On timeout, the requests still in flight are
+listed.
+
+Example:
+  chrome waitidle
+  chrome clicktext "Load more" && chrome waitidle --idle-ms 1000
+  chrome waitidle --ignore @trackers --ignore "*/api/events*"
+  chrome waitidle --max-inflight 1 --timeout 60`
+}
+
+func waitidle() {
+	var args waitidleArgs
+	arg.MustParse(&args)
+
+	if args.IdleMs < 0 || args.MaxInflight < 0 {
+		fmt.Fprintf(os.Stderr, "error: --idle-ms and --max-inflight must not be negative\n")
+		lib.Exit(1)
+	}
+	ignore, err := lib.ExpandBlockPatterns(args.Ignore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	waitCtx, waitCancel := context.WithTimeout(targetCtx, time.Duration(args.Timeout)*time.Second)
+	defer waitCancel()
+
+	res, err := lib.WaitNetworkIdle(waitCtx, lib.IdleOptions{
+		Quiet:       time.Duration(args.IdleMs) * time.Millisecond,
+		MaxInflight: args.MaxInflight,
+		Ignore:      ignore,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("idle after %s (%d requests, %d in flight)\n", res.Elapsed.Round(time.Millisecond), res.Requests, res.Inflight)
+}
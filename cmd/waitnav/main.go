@@ -0,0 +1,125 @@
+// waitnav waits for the tab's next navigation to complete.
+package waitnav
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["waitnav"] = waitnav
+	lib.Args["waitnav"] = waitnavArgs{}
+}
+
+type waitnavArgs struct {
+	lib.TargetArgs
+	Until   string   `arg:"--until" default:"load" help:"load, domcontentloaded, or networkidle"`
+	URL     string   `arg:"-u,--url" help:"only a navigation to a URL matching this pattern ('*' any run of characters, '?' one) counts"`
+	Timeout int      `arg:"--timeout" default:"30" help:"timeout in seconds"`
+	Command []string `arg:"positional" help:"chrome command and args that navigate, run once the wait has started (after --)"`
+}
+
+func (waitnavArgs) Description() string {
+	return `waitnav - Wait for a navigation to complete
+
+  waitnav [--until STATE] [--url PATTERN] [-- COMMAND...]
+
+Waits for the tab's main frame to navigate and for the new document to
+reach --until:
+  domcontentloaded  the HTML is parsed
+  load              the page and its images, styles, and scripts loaded (default)
+  networkidle       no network requests for 500ms after loading
+
+--url makes only a navigation to a matching URL count, e.g. the page
+after a login's redirects. Patterns are globs matched against the whole
+URL, as in 'chrome mock'. history.pushState and #fragment navigations
+load nothing, so they complete as soon as they happen.
+
+A click and a separate waitnav race: the page may have navigated before
+waitnav starts watching. Pass the click as COMMAND (after --) and it runs
+against the same tab once the wait has begun; waitnav exits 1 if it
+fails. Without COMMAND, a page already on a --url match counts and only
+has to reach --until; without either, only a navigation that starts
+after waitnav does counts.
+
+Prints the URL the tab navigated to.
+
+Example:
+  chrome waitnav -- click "button[type=submit]"
+  chrome waitnav --url "*/dashboard*" --until networkidle -- clicktext "Sign in"
+  chrome click "#login" && chrome waitnav --url "*/dashboard*"
+  chrome waitnav --until domcontentloaded --timeout 60`
+}
+
+func waitnav() {
+	var args waitnavArgs
+	arg.MustParse(&args)
+
+	if _, ok := lib.NavStates[args.Until]; !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown --until %q, expected load, domcontentloaded, or networkidle\n", args.Until)
+		lib.Exit(1)
+	}
+
+	var targetCtx context.Context
+	var tabID string
+	if len(args.Command) > 0 {
+		// The command needs the tab's ID to run against the same tab
+		id, reason, err := lib.ResolveTarget(args.TargetArgs.Selector(), nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		if id == "" {
+			fmt.Fprintf(os.Stderr, "error: %s\n", reason)
+			lib.Exit(1)
+		}
+		ctx, cancel, err := lib.Attach(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		defer cancel()
+		targetCtx, tabID = ctx, id
+	} else {
+		ctx, cancel := lib.SetupContextWithTimeout(0)
+		defer cancel()
+		ctx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		defer targetCancel()
+		targetCtx = ctx
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(targetCtx, time.Duration(args.Timeout)*time.Second)
+	defer waitCancel()
+
+	current := len(args.Command) == 0 && args.URL != ""
+	watcher, err := lib.WatchNavigation(waitCtx, args.Until, args.URL, current)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+
+	if len(args.Command) > 0 {
+		output, err := lib.RunCommand(tabID, args.Command)
+		_, _ = os.Stdout.Write(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+	}
+
+	res, err := watcher.Wait(waitCtx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("%s (%s after %s)\n", res.URL, res.Until, res.Elapsed.Round(time.Millisecond))
+}
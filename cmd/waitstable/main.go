@@ -0,0 +1,65 @@
+// waitstable waits until the page stops changing.
+package waitstable
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/nathants/chrome/lib"
+)
+
+func init() {
+	lib.Commands["waitstable"] = waitstable
+	lib.Args["waitstable"] = waitstableArgs{}
+}
+
+type waitstableArgs struct {
+	lib.TargetArgs
+	QuietMs int `arg:"-q,--quiet-ms" default:"500" help:"milliseconds without DOM mutations or layout shifts"`
+	Timeout int `arg:"--timeout" default:"30" help:"timeout in seconds"`
+}
+
+func (waitstableArgs) Description() string {
+	return `waitstable - Wait until the page stops changing
+
+Waits until the document has finished parsing and neither the DOM (nodes,
+attributes, text) nor the layout (layout shifts) has changed for --quiet-ms.
+A generic "page is ready" signal when you don't know which element to wait
+for. A navigation during the wait restarts it on the new page.
+
+Pages with constant animation, tickers, or clocks never go quiet; use
+waitfor or wait for those.
+
+Example:
+  chrome waitstable
+  chrome waitstable --quiet-ms 1000 --timeout 60
+  chrome clicktext "Load more" && chrome waitstable && chrome html`
+}
+
+func waitstable() {
+	var args waitstableArgs
+	arg.MustParse(&args)
+
+	ctx, cancel := lib.SetupContextWithTimeout(0)
+	defer cancel()
+
+	targetCtx, targetCancel, err := lib.EnsureTargetContext(ctx, args.TargetArgs.Selector())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	defer targetCancel()
+
+	waitCtx, waitCancel := context.WithTimeout(targetCtx, time.Duration(args.Timeout)*time.Second)
+	defer waitCancel()
+
+	res, err := lib.WaitStable(waitCtx, time.Duration(args.QuietMs)*time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	fmt.Printf("stable after %s (%d mutations, %d layout shifts)\n", res.Elapsed.Round(time.Millisecond), res.Mutations, res.Shifts)
+}
@@ -0,0 +1,106 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Account maps a login name to the Chrome instance that holds it: a debug
+// port and the profile directory whose cookies carry the session.
+type Account struct {
+	Port        int    `json:"port" yaml:"port"`
+	UserDataDir string `json:"user_data_dir,omitempty" yaml:"user_data_dir,omitempty"`
+}
+
+// AccountsPath returns the accounts config file ($CHROME_ACCOUNTS, else
+// $XDG_CONFIG_HOME/chrome-cli/accounts.yaml or ~/.config/chrome-cli/accounts.yaml).
+func AccountsPath() (string, error) {
+	if path := strings.TrimSpace(os.Getenv("CHROME_ACCOUNTS")); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chrome-cli", "accounts.yaml"), nil
+}
+
+// LoadAccounts reads a YAML or JSON map of account name to Account and
+// fills in defaults. An empty path means AccountsPath().
+func LoadAccounts(path string) (map[string]Account, error) {
+	if path == "" {
+		var err error
+		path, err = AccountsPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no accounts config at %s", path)
+		}
+		return nil, err
+	}
+	accounts := map[string]Account{}
+	if err := yaml.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	ports := map[int]string{}
+	for name, account := range accounts {
+		if account.Port <= 0 || account.Port >= 65536 {
+			return nil, fmt.Errorf("account %s: invalid port %d", name, account.Port)
+		}
+		if other, ok := ports[account.Port]; ok {
+			return nil, fmt.Errorf("accounts %s and %s share port %d", other, name, account.Port)
+		}
+		ports[account.Port] = name
+		account.UserDataDir = accountUserDataDir(name, account.UserDataDir)
+		accounts[name] = account
+	}
+	return accounts, nil
+}
+
+// LoadAccount returns one named account from the config at path.
+func LoadAccount(path string, name string) (Account, error) {
+	accounts, err := LoadAccounts(path)
+	if err != nil {
+		return Account{}, err
+	}
+	account, ok := accounts[name]
+	if !ok {
+		return Account{}, fmt.Errorf("unknown account %q, available: %s", name, strings.Join(AccountNames(accounts), ", "))
+	}
+	return account, nil
+}
+
+// AccountNames returns the account names sorted.
+func AccountNames(accounts map[string]Account) []string {
+	names := make([]string, 0, len(accounts))
+	for name := range accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// accountUserDataDir expands ~ in dir, defaulting to the launch profile
+// directory suffixed with the account name (~/.chrome-admin).
+func accountUserDataDir(name, dir string) string {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return DefaultUserDataDir() + "-" + name
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(dir, "~"))
+		}
+	}
+	return dir
+}
@@ -0,0 +1,806 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/cdproto/animation"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// The functions in this file are the embedding API: each takes a tab context
+// from Attach (or EnsureTargetContext) and returns an error instead of exiting.
+// Derive per-call deadlines with context.WithTimeout on the tab context.
+//
+//	ctx, cancel, err := lib.Attach("https://example.com")
+//	if err != nil { ... }
+//	defer cancel()
+//	stepCtx, stepCancel := context.WithTimeout(ctx, 10*time.Second)
+//	defer stepCancel()
+//	err = lib.Click(stepCtx, "button.submit")
+
+// Attach connects to the tab matched by selector (see ResolveTarget; empty
+// means the preferred tab) and returns a long-lived context bound to it.
+// cancel releases the connection; with remote Chrome the tab stays open.
+// If the tab crashes, the context is cancelled with cause ErrTabCrashed.
+func Attach(selector string) (context.Context, context.CancelFunc, error) {
+	ctx, cancel := SetupContextWithTimeout(0)
+	tabCtx, tabCancel, err := EnsureTargetContext(ctx, selector)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	release := func() {
+		tabCancel()
+		cancel()
+	}
+	// The first Run binds the connection to the context it is given, so
+	// attach here without a deadline rather than inside a caller's timeout
+	if err := chromedp.Run(tabCtx); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return tabCtx, release, nil
+}
+
+// Navigate loads url and waits for the load event. Crawl etiquette
+// (PoliteWait) applies first, and its wait counts against ctx's deadline.
+func Navigate(ctx context.Context, url string) error {
+	if err := PoliteWait(ctx, url); err != nil {
+		return err
+	}
+	return chromedp.Run(ctx, chromedp.Navigate(url))
+}
+
+// Click clicks the first element matching a CSS selector, waiting for it to be visible.
+func Click(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx, chromedp.Click(selector, chromedp.ByQuery))
+}
+
+// ClickNth clicks the index'th match (0-based) of selector with a real
+// mouse event, among the descendants of within's first match when within
+// is set. Index 0 without within is Click; otherwise it doesn't wait for
+// matches and fails at once when there are too few.
+func ClickNth(ctx context.Context, selector string, within string, index int) error {
+	if index < 0 {
+		return fmt.Errorf("index must be >= 0")
+	}
+	if index == 0 && within == "" {
+		return Click(ctx, selector)
+	}
+	// AtLeast(0) returns no matches at once instead of waiting for one
+	opts := []chromedp.QueryOption{chromedp.ByQueryAll, chromedp.AtLeast(0)}
+	if within != "" {
+		var parents []*cdp.Node
+		if err := chromedp.Run(ctx, chromedp.Nodes(within, &parents, chromedp.ByQueryAll, chromedp.AtLeast(0))); err != nil {
+			return fmt.Errorf("container %q: %w", within, err)
+		}
+		if len(parents) == 0 {
+			return fmt.Errorf("container %q: 0 matches", within)
+		}
+		opts = append(opts, chromedp.FromNode(parents[0]))
+	}
+	var nodes []*cdp.Node
+	if err := chromedp.Run(ctx, chromedp.Nodes(selector, &nodes, opts...)); err != nil {
+		return err
+	}
+	if index >= len(nodes) {
+		return fmt.Errorf("index %d out of range for selector %q, matches=%d", index, selector, len(nodes))
+	}
+	return chromedp.Run(ctx, chromedp.MouseClickNode(nodes[index]))
+}
+
+// ClickText clicks the first element under selector (default buttons, links,
+// and role=button) whose trimmed text equals text.
+func ClickText(ctx context.Context, text string, selector string) error {
+	return ClickTextNth(ctx, text, selector, 0)
+}
+
+// ClickTextNth is ClickText for the index'th match (0-based).
+func ClickTextNth(ctx context.Context, text string, selector string, index int) error {
+	if selector == "" {
+		selector = "button, a, [role='button']"
+	}
+	script := `(() => {
+	  const want = ` + strconv.Quote(text) + `;
+	  const matches = Array.from(document.querySelectorAll(` + strconv.Quote(selector) + `)).filter(n => (n.textContent || '').trim() === want);
+	  const el = matches[` + strconv.Itoa(index) + `];
+	  if (!el) return matches.length;
+	  el.scrollIntoView({block:'center', inline:'center'});
+	  el.click();
+	  return -1;
+	})()`
+	var count int
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &count)); err != nil {
+		return err
+	}
+	if count >= 0 {
+		return fmt.Errorf("no element with text %q (selector %q), matches=%d", text, selector, count)
+	}
+	return nil
+}
+
+// Fill sets the value of a form field the way React and similar frameworks
+// expect (see FillScript) and returns the value the element reports.
+func Fill(ctx context.Context, selector string, value string) (string, error) {
+	var res struct {
+		Ok    bool   `json:"ok"`
+		Value string `json:"value"`
+		Error string `json:"error"`
+	}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(FillScript(selector, value), &res)); err != nil {
+		return "", err
+	}
+	if !res.Ok {
+		return "", fmt.Errorf("%s (selector %q)", res.Error, selector)
+	}
+	return res.Value, nil
+}
+
+// FillVerified fills like Fill, then reads the value back after the next
+// frame, so controlled inputs have re-rendered, and fills once more on a
+// mismatch, passing the mismatch to warn. It fails if the element is gone
+// or the value is still wrong after the retry.
+func FillVerified(ctx context.Context, selector string, value string, warn func(string)) error {
+	if _, err := Fill(ctx, selector, value); err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		got, found, err := ReadElementValue(ctx, selector)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("element not found after fill (selector %q)", selector)
+		}
+		if got == value {
+			return nil
+		}
+		if attempt > 1 {
+			return fmt.Errorf("value mismatch after retry - requested %q but got %q", value, got)
+		}
+		warn(fmt.Sprintf("warning: value mismatch - requested %q but got %q, retrying", value, got))
+		if _, err := Fill(ctx, selector, value); err != nil {
+			return err
+		}
+	}
+}
+
+// Type focuses an element and sends keystrokes, replacing its contents
+// unless appendText is set.
+func Type(ctx context.Context, selector string, text string, appendText bool) error {
+	actions := []chromedp.Action{chromedp.Focus(selector, chromedp.ByQuery)}
+	if !appendText {
+		actions = append(actions, chromedp.Evaluate(SelectContentsScript(selector), nil))
+	}
+	actions = append(actions, chromedp.SendKeys(selector, text, chromedp.ByQuery))
+	return chromedp.Run(ctx, actions...)
+}
+
+// WaitVisible blocks until an element matching selector is visible.
+func WaitVisible(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx, chromedp.WaitVisible(selector, chromedp.ByQuery))
+}
+
+// StableResult reports what WaitStable saw before the page went quiet.
+type StableResult struct {
+	Mutations int           `json:"mutations"`
+	Shifts    int           `json:"shifts"`
+	Elapsed   time.Duration `json:"elapsed"`
+}
+
+// stableScript resolves once the document has finished parsing and no DOM
+// mutation or layout shift has happened for quiet ms, or after limit ms with
+// timedOut set. The page-side limit keeps observers from outliving the caller.
+const stableScript = `new Promise(resolve => {
+  const quiet = %d, limit = %d, start = performance.now();
+  let last = start, mutations = 0, shifts = 0;
+  const mo = new MutationObserver(records => { mutations += records.length; last = performance.now(); });
+  mo.observe(document, {subtree: true, childList: true, attributes: true, characterData: true});
+  let po = null;
+  try {
+    po = new PerformanceObserver(list => {
+      for (const e of list.getEntries()) {
+        if (!e.hadRecentInput) { shifts++; last = performance.now(); }
+      }
+    });
+    po.observe({type: 'layout-shift'});
+  } catch (e) {}
+  const tick = () => {
+    const now = performance.now();
+    const stable = document.readyState !== 'loading' && now - last >= quiet;
+    if (stable || now - start >= limit) {
+      mo.disconnect();
+      if (po) po.disconnect();
+      resolve({mutations, shifts, timedOut: !stable});
+      return;
+    }
+    setTimeout(tick, 50);
+  };
+  setTimeout(tick, 50);
+})`
+
+// WaitStable blocks until the DOM stops mutating and layout stops shifting
+// for quiet, a generic "page is ready" signal. A navigation restarts the wait.
+// It fails when ctx ends first.
+func WaitStable(ctx context.Context, quiet time.Duration) (StableResult, error) {
+	start := time.Now()
+	for {
+		limit := 30 * time.Second
+		if deadline, ok := ctx.Deadline(); ok {
+			limit = time.Until(deadline)
+		}
+		var res struct {
+			Mutations int  `json:"mutations"`
+			Shifts    int  `json:"shifts"`
+			TimedOut  bool `json:"timedOut"`
+		}
+		script := fmt.Sprintf(stableScript, quiet.Milliseconds(), limit.Milliseconds())
+		err := chromedp.Run(ctx, chromedp.Evaluate(script, &res, awaitPromise))
+		if ctx.Err() != nil {
+			return StableResult{}, fmt.Errorf("page not stable for %s before timeout", quiet)
+		}
+		if err != nil {
+			// The document was replaced mid-wait; start over on the new one
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if res.TimedOut {
+			continue
+		}
+		return StableResult{Mutations: res.Mutations, Shifts: res.Shifts, Elapsed: time.Since(start)}, nil
+	}
+}
+
+// waitFnScript resolves once the expression is truthy, checking it on every
+// DOM mutation and every poll ms, or after limit ms with timedOut set and
+// the last error the expression threw. The expression is inlined rather
+// than passed to new Function, which a page's CSP may forbid.
+const waitFnScript = `new Promise(resolve => {
+  const poll = %d, limit = %d, start = performance.now();
+  const check = async () => (
+%s
+  );
+  let done = false, running = false, lastError = '', mo = null, timer = null;
+  const finish = res => {
+    if (done) return;
+    done = true;
+    if (mo) mo.disconnect();
+    clearTimeout(timer);
+    resolve(res);
+  };
+  const run = async () => {
+    if (done || running) return;
+    running = true;
+    try {
+      const value = await check();
+      if (value) {
+        let v;
+        try { v = JSON.parse(JSON.stringify(value)); } catch (e) {}
+        finish({ok: true, value: v === undefined ? String(value) : v});
+      }
+    } catch (e) {
+      lastError = String((e && e.message) || e);
+    }
+    running = false;
+  };
+  const tick = () => {
+    if (done) return;
+    if (performance.now() - start >= limit) {
+      finish({ok: false, timedOut: true, error: lastError});
+      return;
+    }
+    run();
+    timer = setTimeout(tick, poll);
+  };
+  mo = new MutationObserver(() => run());
+  mo.observe(document, {subtree: true, childList: true, attributes: true, characterData: true});
+  tick();
+})`
+
+// WaitFn blocks until the JavaScript expression is truthy in the page, and
+// returns its value. The expression may return a promise. It is checked on
+// every DOM mutation and every poll; errors it throws count as false, so
+// "window.app.ready" can wait for window.app to exist. A navigation restarts
+// the wait on the new page. A syntax error fails at once; otherwise it fails,
+// with the expression's last error, when ctx ends first.
+func WaitFn(ctx context.Context, expression string, poll time.Duration) (any, error) {
+	if poll <= 0 {
+		poll = 100 * time.Millisecond
+	}
+	var lastError string
+	for {
+		limit := 30 * time.Second
+		if deadline, ok := ctx.Deadline(); ok {
+			limit = time.Until(deadline)
+		}
+		var res struct {
+			Value    any    `json:"value"`
+			TimedOut bool   `json:"timedOut"`
+			Error    string `json:"error"`
+		}
+		script := fmt.Sprintf(waitFnScript, poll.Milliseconds(), limit.Milliseconds(), expression)
+		err := chromedp.Run(ctx, chromedp.Evaluate(script, &res, awaitPromise))
+		var exception *runtime.ExceptionDetails
+		if errors.As(err, &exception) {
+			return nil, fmt.Errorf("invalid expression: %v", err)
+		}
+		if res.Error != "" {
+			lastError = res.Error
+		}
+		if ctx.Err() != nil {
+			if lastError != "" {
+				return nil, fmt.Errorf("expression not truthy before timeout, last error: %s", lastError)
+			}
+			return nil, fmt.Errorf("expression not truthy before timeout")
+		}
+		if err != nil {
+			// The document was replaced mid-wait; start over on the new one
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if res.TimedOut {
+			continue
+		}
+		return res.Value, nil
+	}
+}
+
+// Eval evaluates script in the page and decodes its result into res (may be nil).
+func Eval(ctx context.Context, script string, res any) error {
+	return chromedp.Run(ctx, chromedp.Evaluate(script, res))
+}
+
+// Screenshot captures the viewport as PNG.
+func Screenshot(ctx context.Context) ([]byte, error) {
+	var buf []byte
+	if err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ScreenshotElement captures the first element matching a CSS selector as
+// PNG, scrolling it into view first.
+func ScreenshotElement(ctx context.Context, selector string) ([]byte, error) {
+	var buf []byte
+	if err := chromedp.Run(ctx, chromedp.Screenshot(selector, &buf, chromedp.ByQuery)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ScreenshotWithOptions captures the tab as opts describes: optionally with
+// animations frozen, clipped to an element, and encoded as png, jpeg, or webp.
+func ScreenshotWithOptions(ctx context.Context, opts ScreenshotOptions) ([]byte, error) {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	params := page.CaptureScreenshot().
+		WithFormat(page.CaptureScreenshotFormat(opts.Format)).
+		WithFromSurface(true)
+	if opts.Quality > 0 {
+		params = params.WithQuality(int64(opts.Quality))
+	}
+
+	// A missing element fails here, before the page is touched
+	if opts.Element != "" {
+		var found bool
+		if err := chromedp.Run(ctx, chromedp.Evaluate("!!document.querySelector("+strconv.Quote(opts.Element)+")", &found)); err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", errElementNotFound, opts.Element)
+		}
+	}
+	// Emulation and frozen animations outlive this capture on long-lived
+	// connections, so they are undone even when ctx is done or expired
+	restore := func(actions ...chromedp.Action) {
+		restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = chromedp.Run(restoreCtx, actions...)
+	}
+	if opts.Media != "" {
+		if err := chromedp.Run(ctx, emulation.SetEmulatedMedia().WithMedia(opts.Media)); err != nil {
+			return nil, err
+		}
+		defer restore(emulation.SetEmulatedMedia().WithMedia(""))
+	}
+	if opts.FreezeAnimations {
+		defer restore(animation.SetPlaybackRate(1), chromedp.Evaluate(unfreezeAnimationsScript, nil))
+		err := chromedp.Run(ctx,
+			animation.Enable(),
+			chromedp.Evaluate(freezeAnimationsScript, nil, awaitPromise),
+			animation.SetPlaybackRate(0),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var actions []chromedp.Action
+	if opts.Element != "" {
+		actions = append(actions,
+			chromedp.WaitVisible(opts.Element, chromedp.ByQuery),
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				var box *page.Viewport
+				if err := chromedp.Evaluate(elementClipScript(opts.Element), &box, awaitPromise).Do(ctx); err != nil {
+					return err
+				}
+				if box == nil {
+					return fmt.Errorf("%w: %s", errElementNotFound, opts.Element)
+				}
+				if box.Width <= 0 || box.Height <= 0 {
+					return fmt.Errorf("element %s has zero size", opts.Element)
+				}
+				box.Scale = 1
+				params = params.WithClip(box).WithCaptureBeyondViewport(true)
+				return nil
+			}),
+		)
+	}
+	var buf []byte
+	actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, err = params.Do(ctx)
+		return err
+	}))
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ConsoleMessage is a console call, exception, or browser log entry.
+type ConsoleMessage struct {
+	Type      string      `json:"type"`
+	Message   string      `json:"message,omitempty"`
+	Args      interface{} `json:"args,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Level     string      `json:"level,omitempty"`
+
+	// Where the message was logged or the exception thrown, when known.
+	// Line and Column are 1-based.
+	URL    string `json:"url,omitempty"`
+	Line   int64  `json:"line,omitempty"`
+	Column int64  `json:"column,omitempty"`
+	// Set with ConsoleOptions.Stack, innermost frame first
+	Stack []StackFrame `json:"stack,omitempty"`
+}
+
+// StackFrame is one JavaScript call frame. Line and Column are 1-based.
+type StackFrame struct {
+	Function string `json:"function,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Line     int64  `json:"line"`
+	Column   int64  `json:"column"`
+	// Async names the async call (e.g. "Promise.then") that scheduled the
+	// frames from this one on. Chrome only reports async stacks while a
+	// debugger asks for them, as DevTools does when open.
+	Async string `json:"async,omitempty"`
+}
+
+// ConsoleOptions control ListenConsoleWithOptions.
+type ConsoleOptions struct {
+	// Stack adds each message's full stack trace
+	Stack bool
+	// Depth resolves object arguments to this many levels of properties
+	// (see ResolveObject) instead of their type, e.g. "object"
+	Depth int
+}
+
+// ListenConsole calls fn for console.* calls, uncaught exceptions, and Log
+// domain entries (CSP violations, security errors, deprecations) until the
+// tab context ends. fn runs on the event goroutine and must not block.
+func ListenConsole(ctx context.Context, fn func(ConsoleMessage)) error {
+	return ListenConsoleWithOptions(ctx, ConsoleOptions{}, fn)
+}
+
+// ListenConsoleWithOptions is ListenConsole with source locations and,
+// with opts.Stack, stack traces. With opts.Depth, object arguments are
+// resolved off the event goroutine, and fn is called from another
+// goroutine, still one message at a time and in order.
+func ListenConsoleWithOptions(ctx context.Context, opts ConsoleOptions, fn func(ConsoleMessage)) error {
+	var queue serialQueue
+	emit := fn
+	if opts.Depth > 0 {
+		emit = func(msg ConsoleMessage) { queue.do(func() { fn(msg) }) }
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			msg := ConsoleMessage{
+				Type:      string(ev.Type),
+				Timestamp: time.Now(),
+			}
+			msg.locate("", -1, -1, ev.StackTrace, opts.Stack)
+			c := chromedp.FromContext(ctx)
+			if opts.Depth <= 0 || c == nil || c.Target == nil {
+				msg.setArgs(ev.Args, func(arg *runtime.RemoteObject) any { return arg.Type.String() })
+				emit(msg)
+				return
+			}
+			args := ev.Args
+			queue.do(func() {
+				resolveCtx, cancel := context.WithTimeout(cdp.WithExecutor(ctx, c.Target), resolveTimeout)
+				defer cancel()
+				msg.setArgs(args, func(arg *runtime.RemoteObject) any { return ResolveObject(resolveCtx, arg, opts.Depth) })
+				fn(msg)
+			})
+		case *runtime.EventExceptionThrown:
+			msg := ConsoleMessage{
+				Type:      "exception",
+				Level:     "error",
+				Timestamp: time.Now(),
+			}
+			if ev.ExceptionDetails.Exception != nil {
+				msg.Message = ev.ExceptionDetails.Exception.Description
+			} else {
+				msg.Message = ev.ExceptionDetails.Text
+			}
+			details := ev.ExceptionDetails
+			msg.locate(details.URL, details.LineNumber, details.ColumnNumber, details.StackTrace, opts.Stack)
+			emit(msg)
+		case *cdplog.EventEntryAdded:
+			msg := ConsoleMessage{
+				Type:      string(ev.Entry.Source),
+				Level:     string(ev.Entry.Level),
+				Message:   ev.Entry.Text,
+				Timestamp: time.Now(),
+			}
+			line := int64(-1)
+			if ev.Entry.URL != "" {
+				line = ev.Entry.LineNumber
+			}
+			msg.locate(ev.Entry.URL, line, -1, ev.Entry.StackTrace, opts.Stack)
+			emit(msg)
+		}
+	})
+	return chromedp.Run(ctx, runtime.Enable(), cdplog.Enable())
+}
+
+// setArgs sets a console call's arguments: a lone string as Message, else
+// Args. Primitives are decoded; other arguments are passed to object.
+func (msg *ConsoleMessage) setArgs(remote []*runtime.RemoteObject, object func(*runtime.RemoteObject) any) {
+	if len(remote) == 0 {
+		return
+	}
+	var args []interface{}
+	for _, arg := range remote {
+		var val interface{}
+		if arg.Value != nil {
+			err := json.Unmarshal(arg.Value, &val)
+			if err != nil {
+				val = string(arg.Value)
+			}
+			args = append(args, val)
+		} else {
+			args = append(args, object(arg))
+		}
+	}
+	if len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			msg.Message = s
+		} else {
+			msg.Args = args[0]
+		}
+	} else {
+		msg.Args = args
+	}
+}
+
+// locate sets where msg came from: url and the 0-based line and column
+// when given (-1 when not), else the stack's top frame, and with
+// withStack, the whole stack.
+func (msg *ConsoleMessage) locate(url string, line int64, column int64, stack *runtime.StackTrace, withStack bool) {
+	frames := stackFrames(stack)
+	if url == "" && len(frames) > 0 {
+		msg.URL, msg.Line, msg.Column = frames[0].URL, frames[0].Line, frames[0].Column
+	} else {
+		msg.URL = url
+		if line >= 0 {
+			msg.Line = line + 1
+		}
+		if column >= 0 {
+			msg.Column = column + 1
+		}
+	}
+	if withStack {
+		msg.Stack = frames
+	}
+}
+
+// stackFrames flattens a stack trace and any async parents.
+func stackFrames(stack *runtime.StackTrace) []StackFrame {
+	var frames []StackFrame
+	for depth := 0; stack != nil; stack, depth = stack.Parent, depth+1 {
+		// A parent's description names the async call that scheduled it
+		async := ""
+		if depth > 0 {
+			async = stack.Description
+			if async == "" {
+				async = "async"
+			}
+		}
+		for i, f := range stack.CallFrames {
+			frame := StackFrame{Function: f.FunctionName, URL: f.URL, Line: f.LineNumber + 1, Column: f.ColumnNumber + 1}
+			if i == 0 {
+				frame.Async = async
+			}
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// NetworkEvent is a request, response, completion, failure, or captured
+// body seen by the tab.
+type NetworkEvent struct {
+	Type       string    `json:"type"`
+	RequestID  string    `json:"requestId"`
+	URL        string    `json:"url,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	Status     int64     `json:"status,omitempty"`
+	StatusText string    `json:"statusText,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+
+	// Set on "failed" events blocked by the browser, e.g. by --block
+	BlockedReason string `json:"blockedReason,omitempty"`
+
+	// Set on "response" events (through TTFB) and "finished" events
+	// (every phase and the total)
+	Timing *RequestPhases `json:"timing,omitempty"`
+
+	// Set on "ws-sent" and "ws-received" frames, see ListenWebSockets
+	Opcode int64 `json:"opcode,omitempty"`
+
+	// Set on "body" events, see ListenNetworkBodies
+	MimeType         string `json:"mimeType,omitempty"`
+	PostData         string `json:"postData,omitempty"`
+	PostDataEncoding string `json:"postDataEncoding,omitempty"`
+	PostDataFile     string `json:"postDataFile,omitempty"`
+	Body             string `json:"body,omitempty"`
+	Encoding         string `json:"encoding,omitempty"`
+	BodyFile         string `json:"bodyFile,omitempty"`
+	Size             int64  `json:"size,omitempty"`
+	Truncated        bool   `json:"truncated,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// RequestPhases splits a request's time into phases, in ms, to tell server
+// time (TTFB) from network time. DNS, Connect, and TLS are omitted when the
+// request reused a connection; Connect includes TLS.
+type RequestPhases struct {
+	// Queued is time before the request started: queueing behind other
+	// requests, stalls, and proxy negotiation.
+	Queued  float64 `json:"queued"`
+	DNS     float64 `json:"dns,omitempty"`
+	Connect float64 `json:"connect,omitempty"`
+	TLS     float64 `json:"tls,omitempty"`
+	Send    float64 `json:"send"`
+	// TTFB is the wait from sending the request to the first response
+	// byte: server processing plus one round trip.
+	TTFB     float64 `json:"ttfb"`
+	Download float64 `json:"download,omitempty"`
+	Total    float64 `json:"total,omitempty"`
+}
+
+// requestPhases computes phases from Chrome's ResourceTiming, whose offsets
+// are ms after its requestTime. start and end are monotonic seconds; end is
+// 0 before the request finishes, leaving Download and Total unset. It
+// returns nil for requests without network timing (cache, data: URLs).
+func requestPhases(start float64, rt *network.ResourceTiming, end float64) *RequestPhases {
+	if rt == nil || rt.RequestTime == 0 {
+		if end == 0 {
+			return nil
+		}
+		return &RequestPhases{Total: round2(max((end-start)*1000, 0))}
+	}
+	firstPhase := rt.SendStart
+	for _, v := range []float64{rt.ConnectStart, rt.DNSStart} {
+		if v >= 0 && v < firstPhase {
+			firstPhase = v
+		}
+	}
+	t := &RequestPhases{
+		Queued: round2(max((rt.RequestTime-start)*1000, 0) + max(firstPhase, 0)),
+		Send:   round2(max(rt.SendEnd-rt.SendStart, 0)),
+		TTFB:   round2(max(rt.ReceiveHeadersEnd-rt.SendEnd, 0)),
+	}
+	if rt.DNSStart >= 0 {
+		t.DNS = round2(rt.DNSEnd - rt.DNSStart)
+	}
+	if rt.ConnectStart >= 0 {
+		t.Connect = round2(rt.ConnectEnd - rt.ConnectStart)
+	}
+	if rt.SslStart >= 0 {
+		t.TLS = round2(rt.SslEnd - rt.SslStart)
+	}
+	if end > 0 {
+		t.Download = round2(max((end-rt.RequestTime)*1000-rt.ReceiveHeadersEnd, 0))
+		t.Total = round2(max((end-start)*1000, 0))
+	}
+	return t
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ListenNetwork calls fn for every request, response, completed load, and
+// loading failure until the tab context ends. Responses and "finished"
+// events carry a RequestPhases breakdown; "finished" events also carry the
+// bytes transferred in Size. fn runs on the event goroutine
+// and must not block.
+func ListenNetwork(ctx context.Context, fn func(NetworkEvent)) error {
+	type inflight struct {
+		url    string
+		start  float64 // monotonic seconds
+		timing *network.ResourceTiming
+	}
+	requests := map[network.RequestID]*inflight{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			// A redirect reuses the request ID; time the new hop
+			requests[ev.RequestID] = &inflight{url: ev.Request.URL, start: monotonic(ev.Timestamp)}
+			fn(NetworkEvent{
+				Type:      "request",
+				RequestID: string(ev.RequestID),
+				URL:       ev.Request.URL,
+				Method:    ev.Request.Method,
+				Timestamp: time.Now(),
+			})
+		case *network.EventResponseReceived:
+			evt := NetworkEvent{
+				Type:       "response",
+				RequestID:  string(ev.RequestID),
+				URL:        ev.Response.URL,
+				Status:     ev.Response.Status,
+				StatusText: ev.Response.StatusText,
+				Timestamp:  time.Now(),
+			}
+			if req := requests[ev.RequestID]; req != nil {
+				req.timing = ev.Response.Timing
+				evt.Timing = requestPhases(req.start, req.timing, 0)
+			}
+			fn(evt)
+		case *network.EventLoadingFinished:
+			req := requests[ev.RequestID]
+			if req == nil {
+				return
+			}
+			delete(requests, ev.RequestID)
+			fn(NetworkEvent{
+				Type:      "finished",
+				RequestID: string(ev.RequestID),
+				URL:       req.url,
+				Size:      int64(ev.EncodedDataLength),
+				Timing:    requestPhases(req.start, req.timing, monotonic(ev.Timestamp)),
+				Timestamp: time.Now(),
+			})
+		case *network.EventLoadingFailed:
+			delete(requests, ev.RequestID)
+			fn(NetworkEvent{
+				Type:          "failed",
+				RequestID:     string(ev.RequestID),
+				Timestamp:     time.Now(),
+				Error:         ev.ErrorText,
+				BlockedReason: string(ev.BlockedReason),
+			})
+		}
+	})
+	return chromedp.Run(ctx, network.Enable())
+}
@@ -0,0 +1,190 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ExitAssertion is the exit code of the assert commands when the page does
+// not match, so scripts can tell a wrong page apart from a command that could
+// not run (exit 1) or a crashed tab (ExitCrashed).
+const ExitAssertion = 4
+
+// AssertionFileEnv names the file the assert commands write their Assertion
+// to, set by 'chrome step' so the result lands in the StepRecord.
+const AssertionFileEnv = "CHROME_ASSERTION_FILE"
+
+// assertionActualMax caps how much page text an Assertion keeps.
+const assertionActualMax = 200
+
+// Assertion is the outcome of an assert command, checked once without
+// waiting.
+type Assertion struct {
+	Kind     string `json:"kind"` // text, selector, url, or title
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Passed   bool   `json:"passed"`
+}
+
+// Message describes the assertion in one line.
+func (a Assertion) Message() string {
+	if a.Passed {
+		return fmt.Sprintf("%s: %s", a.Kind, a.Expected)
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", a.Kind, a.Expected, a.Actual)
+}
+
+// AssertText checks that the page's text, or the text of the first element
+// matching selector, contains text.
+func AssertText(ctx context.Context, text string, selector string) (Assertion, error) {
+	a := Assertion{Kind: "text", Expected: "to contain " + strconv.Quote(text)}
+	if selector != "" {
+		if err := CheckSelector(selector); err != nil {
+			return a, fmt.Errorf("selector %q: %w", selector, err)
+		}
+		a.Expected += " in " + strconv.Quote(selector)
+	}
+	script := `(() => {
+	  const sel = ` + strconv.Quote(selector) + `;
+	  const el = sel ? document.querySelector(sel) : document.body;
+	  if (!el) return { found: false, text: '' };
+	  return { found: true, text: el.innerText || el.textContent || '' };
+	})()`
+	var res struct {
+		Found bool   `json:"found"`
+		Text  string `json:"text"`
+	}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &res)); err != nil {
+		return a, err
+	}
+	switch {
+	case !res.Found:
+		a.Actual = "no element matches " + strconv.Quote(selector)
+	default:
+		a.Actual = strconv.Quote(truncate(res.Text, assertionActualMax))
+		a.Passed = strings.Contains(res.Text, text)
+	}
+	return a, nil
+}
+
+// AssertSelector checks that selector matches an element, with count >= 0
+// exactly that many, or with not none.
+func AssertSelector(ctx context.Context, selector string, count int, not bool) (Assertion, error) {
+	a := Assertion{Kind: "selector"}
+	switch {
+	case not:
+		a.Expected = "no match for " + strconv.Quote(selector)
+	case count >= 0:
+		a.Expected = fmt.Sprintf("%d matches for %q", count, selector)
+	default:
+		a.Expected = "a match for " + strconv.Quote(selector)
+	}
+	if err := CheckSelector(selector); err != nil {
+		return a, fmt.Errorf("selector %q: %w", selector, err)
+	}
+	var n int
+	if err := chromedp.Run(ctx, chromedp.Evaluate("document.querySelectorAll("+strconv.Quote(selector)+").length", &n)); err != nil {
+		return a, err
+	}
+	a.Actual = fmt.Sprintf("%d matches", n)
+	switch {
+	case not:
+		a.Passed = n == 0
+	case count >= 0:
+		a.Passed = n == count
+	default:
+		a.Passed = n > 0
+	}
+	return a, nil
+}
+
+// AssertURL checks that the page's URL starts with url, or equals it with
+// exact.
+func AssertURL(ctx context.Context, url string, exact bool) (Assertion, error) {
+	a := Assertion{Kind: "url", Expected: "to start with " + strconv.Quote(url)}
+	if exact {
+		a.Expected = strconv.Quote(url)
+	}
+	var href string
+	if err := chromedp.Run(ctx, chromedp.Evaluate("location.href", &href)); err != nil {
+		return a, err
+	}
+	a.Actual = strconv.Quote(href)
+	if exact {
+		a.Passed = href == url
+	} else {
+		a.Passed = strings.HasPrefix(href, url)
+	}
+	return a, nil
+}
+
+// AssertTitle checks that the page's title equals title, or contains it
+// with contains.
+func AssertTitle(ctx context.Context, title string, contains bool) (Assertion, error) {
+	a := Assertion{Kind: "title", Expected: strconv.Quote(title)}
+	if contains {
+		a.Expected = "to contain " + strconv.Quote(title)
+	}
+	var actual string
+	if err := chromedp.Run(ctx, chromedp.Title(&actual)); err != nil {
+		return a, err
+	}
+	a.Actual = strconv.Quote(actual)
+	if contains {
+		a.Passed = strings.Contains(actual, title)
+	} else {
+		a.Passed = actual == title
+	}
+	return a, nil
+}
+
+// FinishAssertion reports an assertion and ends the command: it saves the
+// result for 'chrome step' (see AssertionFileEnv), prints it, and exits
+// ExitAssertion if it failed.
+func FinishAssertion(a Assertion) {
+	if path := os.Getenv(AssertionFileEnv); path != "" {
+		if data, err := json.Marshal(a); err == nil {
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: unable to save assertion: %v\n", err)
+			}
+		}
+	}
+	if !a.Passed {
+		fmt.Fprintf(os.Stderr, "error: assertion failed: %s\n", a.Message())
+		Exit(ExitAssertion)
+	}
+	fmt.Printf("ok: %s\n", a.Message())
+}
+
+// ReadAssertion loads the result an assert command saved to path, or nil if
+// the action did not save one.
+func ReadAssertion(path string) (*Assertion, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var a Assertion
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &a, nil
+}
+
+// truncate shortens s to at most n runes, marking the cut with "...".
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
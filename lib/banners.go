@@ -0,0 +1,244 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ConsentPlatform describes a consent-management platform's cookie banner:
+// the element that holds it and the buttons that accept or reject.
+type ConsentPlatform struct {
+	Name   string   `json:"name"`
+	Banner string   `json:"banner"`
+	Accept []string `json:"accept"`
+	Reject []string `json:"reject"`
+}
+
+// ConsentPlatforms are the banners DismissBanners recognizes by selector,
+// tried in order before the text heuristics.
+var ConsentPlatforms = []ConsentPlatform{
+	{
+		Name:   "onetrust",
+		Banner: "#onetrust-banner-sdk, #onetrust-consent-sdk",
+		Accept: []string{"#onetrust-accept-btn-handler", "#accept-recommended-btn-handler"},
+		Reject: []string{"#onetrust-reject-all-handler", ".ot-pc-refuse-all-handler"},
+	},
+	{
+		Name:   "cookiebot",
+		Banner: "#CybotCookiebotDialog",
+		Accept: []string{"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll", "#CybotCookiebotDialogBodyButtonAccept"},
+		Reject: []string{"#CybotCookiebotDialogBodyButtonDecline"},
+	},
+	{
+		Name:   "didomi",
+		Banner: "#didomi-host, #didomi-notice",
+		Accept: []string{"#didomi-notice-agree-button"},
+		Reject: []string{"#didomi-notice-disagree-button", ".didomi-continue-without-agreeing"},
+	},
+	{
+		Name:   "quantcast",
+		Banner: "#qc-cmp2-container, .qc-cmp2-container",
+		Accept: []string{".qc-cmp2-summary-buttons button[mode='primary']"},
+		Reject: []string{".qc-cmp2-summary-buttons button[mode='secondary']"},
+	},
+	{
+		Name:   "usercentrics",
+		Banner: "#usercentrics-root, #usercentrics-cmp-ui, #uc-center-container",
+		Accept: []string{"[data-testid='uc-accept-all-button']", "#accept"},
+		Reject: []string{"[data-testid='uc-deny-all-button']", "#deny"},
+	},
+	{
+		Name:   "trustarc",
+		Banner: "#truste-consent-track, #consent_blackbar",
+		Accept: []string{"#truste-consent-button"},
+		Reject: []string{"#truste-consent-required"},
+	},
+	{
+		Name:   "osano",
+		Banner: ".osano-cm-window",
+		Accept: []string{".osano-cm-accept-all", ".osano-cm-accept"},
+		Reject: []string{".osano-cm-denyAll", ".osano-cm-deny"},
+	},
+	{
+		Name:   "cookieyes",
+		Banner: ".cky-consent-container",
+		Accept: []string{".cky-btn-accept"},
+		Reject: []string{".cky-btn-reject"},
+	},
+	{
+		Name:   "complianz",
+		Banner: "#cmplz-cookiebanner-container, .cmplz-cookiebanner",
+		Accept: []string{".cmplz-accept"},
+		Reject: []string{".cmplz-deny"},
+	},
+	{
+		Name:   "iubenda",
+		Banner: "#iubenda-cs-banner",
+		Accept: []string{".iubenda-cs-accept-btn"},
+		Reject: []string{".iubenda-cs-reject-btn"},
+	},
+	{
+		Name:   "termly",
+		Banner: "#termly-code-snippet-support, [class*='termly-styles-root']",
+		Accept: []string{"[data-tid='banner-accept']"},
+		Reject: []string{"[data-tid='banner-decline']"},
+	},
+	{
+		Name:   "axeptio",
+		Banner: "#axeptio_overlay",
+		Accept: []string{"#axeptio_btn_acceptAll"},
+		Reject: []string{"#axeptio_btn_dismiss"},
+	},
+	{
+		Name:   "klaro",
+		Banner: ".klaro .cookie-notice, .klaro .cookie-modal",
+		Accept: []string{".klaro .cm-btn-accept-all", ".klaro .cm-btn-success"},
+		Reject: []string{".klaro .cm-btn-decline"},
+	},
+	{
+		Name:   "borlabs",
+		Banner: "#BorlabsCookieBox",
+		Accept: []string{"#BorlabsCookieBox [data-cookie-accept-all]", "#BorlabsCookieBox [data-cookie-accept]"},
+		Reject: []string{"#BorlabsCookieBox [data-cookie-refuse]"},
+	},
+	{
+		Name:   "cookieconsent",
+		Banner: ".cc-window, #cc-main",
+		Accept: []string{".cc-window .cc-allow", ".cc-window .cc-dismiss", "#cc-main [data-role='all']"},
+		Reject: []string{".cc-window .cc-deny", "#cc-main [data-role='necessary']"},
+	},
+	{
+		Name:   "google-funding-choices",
+		Banner: ".fc-consent-root",
+		Accept: []string{".fc-cta-consent"},
+		Reject: []string{".fc-cta-do-not-consent"},
+	},
+	{
+		Name:   "shopify",
+		Banner: "#shopify-pc__banner",
+		Accept: []string{"#shopify-pc__banner__btn-accept"},
+		Reject: []string{"#shopify-pc__banner__btn-decline"},
+	},
+	{
+		Name:   "amazon",
+		Banner: "#sp-cc",
+		Accept: []string{"#sp-cc-accept"},
+		Reject: []string{"#sp-cc-rejectall-link"},
+	},
+}
+
+// BannerResult describes what DismissBanners found and clicked.
+type BannerResult struct {
+	Found     bool   `json:"found"`
+	Dismissed bool   `json:"dismissed"`
+	Platform  string `json:"platform,omitempty"`
+	Action    string `json:"action"`
+	Selector  string `json:"selector,omitempty"`
+	Text      string `json:"text,omitempty"`
+	// Hidden reports whether the banner was gone shortly after the click.
+	Hidden bool `json:"hidden"`
+}
+
+// bannerScript finds a cookie banner in the document, its open shadow
+// roots, and same-origin iframes, and clicks its accept or reject button.
+// Known platforms go first, then buttons with consent wording inside
+// elements that look like cookie banners.
+const bannerScript = `(async (platforms, reject) => {
+  ` + CSSPathJS + `
+  const roots = [];
+  const collect = (root, depth) => {
+    roots.push(root);
+    if (depth > 3) return;
+    for (const el of root.querySelectorAll('*')) {
+      if (el.shadowRoot) collect(el.shadowRoot, depth + 1);
+      if (el.tagName === 'IFRAME') {
+        try { if (el.contentDocument) collect(el.contentDocument, depth + 1); } catch (e) {}
+      }
+    }
+  };
+  collect(document, 0);
+  const visible = (el) => {
+    if (!el || !el.isConnected) return false;
+    const r = el.getBoundingClientRect();
+    const style = getComputedStyle(el);
+    return r.width > 0 && r.height > 0 && style.display !== 'none' && style.visibility !== 'hidden' && parseFloat(style.opacity || '1') > 0;
+  };
+  const query = (sel) => {
+    const out = [];
+    for (const root of roots) {
+      try { out.push(...Array.from(root.querySelectorAll(sel)).filter(visible)); } catch (e) { return []; }
+    }
+    return out;
+  };
+  const text = (el) => (el.innerText || el.textContent || el.value || el.getAttribute('aria-label') || '').replace(/\s+/g, ' ').trim();
+  const settle = () => new Promise(resolve => setTimeout(resolve, 500));
+  const click = async (el, platform, banner) => {
+    const res = { found: true, dismissed: true, platform: platform, action: reject ? 'reject' : 'accept', selector: cssPath(el), text: text(el).slice(0, 80) };
+    el.click();
+    await settle();
+    res.hidden = banner ? !visible(banner) : !visible(el);
+    return res;
+  };
+
+  for (const p of platforms) {
+    const banner = query(p.banner)[0];
+    if (!banner) continue;
+    for (const sel of (reject ? p.reject : p.accept)) {
+      const btn = query(sel)[0];
+      if (btn) return await click(btn, p.name, banner);
+    }
+    return { found: true, dismissed: false, platform: p.name, action: reject ? 'reject' : 'accept', hidden: false };
+  }
+
+  const acceptRe = /^(accept|accept all|accept all cookies|accept cookies|accept and close|accept & close|accept and continue|allow|allow all|allow all cookies|allow cookies|agree|i agree|agree and close|agree and continue|yes, i agree|i accept|got it|ok|okay|alle akzeptieren|akzeptieren|alle cookies akzeptieren|tout accepter|accepter|accepter et fermer|aceptar|aceptar todo|aceptar todas|accetta|accetta tutti|accetto|alles accepteren|accepteren|aceitar|aceitar todos)$/i;
+  const rejectRe = /^(reject|reject all|reject all cookies|reject cookies|reject non-essential|reject optional cookies|decline|decline all|deny|deny all|refuse|refuse all|only necessary|necessary only|only essential|essential only|use necessary cookies only|only allow essential cookies|continue without accepting|alle ablehnen|ablehnen|nur notwendige|tout refuser|refuser|continuer sans accepter|rechazar|rechazar todo|rechazar todas|rifiuta|rifiuta tutto|alles weigeren|weigeren|rejeitar|recusar)$/i;
+  const want = reject ? rejectRe : acceptRe;
+  const containers = query([
+    '[id*="cookie" i]', '[class*="cookie" i]', '[id*="consent" i]', '[class*="consent" i]',
+    '[id*="gdpr" i]', '[class*="gdpr" i]', '[id*="privacy" i]', '[class*="privacy" i]',
+    '[aria-label*="cookie" i]', '[aria-label*="consent" i]', '[role="dialog"]', '[role="alertdialog"]', 'dialog[open]',
+  ].join(', '))
+    .filter(el => el !== el.ownerDocument.body && el !== el.ownerDocument.documentElement)
+    .map(el => ({ el: el, words: text(el) }))
+    .sort((a, b) => a.words.length - b.words.length);
+  let found = false;
+  for (const { el: box, words } of containers) {
+    if (!/cookie|consent|gdpr|privacy|datenschutz|confidentialit|privacidad/i.test(words)) continue;
+    found = true;
+    const buttons = Array.from(box.querySelectorAll('button, a, [role="button"], input[type="button"], input[type="submit"]')).filter(visible);
+    const btn = buttons.find(b => want.test(text(b).replace(/[.!]+$/, '')));
+    if (btn) return await click(btn, '', box);
+  }
+  return { found: found, dismissed: false, action: reject ? 'reject' : 'accept', hidden: false };
+})`
+
+// DismissBanners looks for a cookie consent banner until wait passes and
+// clicks its accept button, or its reject button when reject is set. A
+// banner without the wanted button is reported as found but not
+// dismissed; DismissBanners never accepts when asked to reject.
+func DismissBanners(ctx context.Context, reject bool, wait time.Duration) (BannerResult, error) {
+	platforms, err := json.Marshal(ConsentPlatforms)
+	if err != nil {
+		return BannerResult{}, err
+	}
+	script := bannerScript + `(` + string(platforms) + `, ` + strconv.FormatBool(reject) + `)`
+	deadline := time.Now().Add(wait)
+	for {
+		var res BannerResult
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &res, awaitPromise)); err != nil {
+			return res, err
+		}
+		if res.Dismissed || time.Now().After(deadline) {
+			return res, nil
+		}
+		select {
+		case <-time.After(250 * time.Millisecond):
+		case <-ctx.Done():
+			return res, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BlockGroups are named sets of Network.setBlockedURLs patterns, selected
+// with @name wherever block patterns are accepted.
+var BlockGroups = map[string][]string{
+	"fonts": {
+		"*fonts.googleapis.com*",
+		"*fonts.gstatic.com*",
+		"*use.typekit.net*",
+		"*p.typekit.net*",
+		"*fonts.adobe.com*",
+		"*fast.fonts.net*",
+		"*cloud.typography.com*",
+		"*use.fontawesome.com*",
+		"*kit.fontawesome.com*",
+	},
+	"trackers": {
+		"*doubleclick.net*",
+		"*googlesyndication.com*",
+		"*googleadservices.com*",
+		"*adservice.google.com*",
+		"*googletagmanager.com*",
+		"*google-analytics.com*",
+		"*connect.facebook.net*",
+		"*amazon-adsystem.com*",
+		"*adnxs.com*",
+		"*criteo.com*",
+		"*criteo.net*",
+		"*taboola.com*",
+		"*outbrain.com*",
+		"*scorecardresearch.com*",
+		"*quantserve.com*",
+		"*hotjar.com*",
+		"*segment.io*",
+		"*cdn.segment.com*",
+		"*mixpanel.com*",
+		"*clarity.ms*",
+		"*adsrvr.org*",
+		"*rubiconproject.com*",
+		"*pubmatic.com*",
+		"*moatads.com*",
+	},
+	"images": {
+		"*.png", "*.png?*",
+		"*.jpg", "*.jpg?*",
+		"*.jpeg", "*.jpeg?*",
+		"*.gif", "*.gif?*",
+		"*.webp", "*.webp?*",
+		"*.avif", "*.avif?*",
+		"*.svg", "*.svg?*",
+		"*.ico", "*.ico?*",
+	},
+	"media": {
+		"*.mp4", "*.mp4?*",
+		"*.webm", "*.webm?*",
+		"*.m3u8", "*.m3u8?*",
+		"*.ts?*",
+		"*.mp3", "*.mp3?*",
+		"*.ogg", "*.ogg?*",
+		"*.wav", "*.wav?*",
+	},
+}
+
+// BlockGroupNames returns the names of BlockGroups, sorted.
+func BlockGroupNames() []string {
+	var names []string
+	for name := range BlockGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandBlockPatterns replaces @group names with their patterns and drops
+// duplicates, keeping the first occurrence's order.
+func ExpandBlockPatterns(patterns []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(p, "@"); ok {
+			group, ok := BlockGroups[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown block group %q, expected one of @%s", p, strings.Join(BlockGroupNames(), ", @"))
+			}
+			for _, g := range group {
+				add(g)
+			}
+			continue
+		}
+		add(p)
+	}
+	return out, nil
+}
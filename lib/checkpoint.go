@@ -0,0 +1,193 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// Checkpoint is a named snapshot of a tab: its URL and scroll position,
+// every cookie, and the web storage of the tab's origin.
+type Checkpoint struct {
+	Name    string       `json:"name"`
+	URL     string       `json:"url"`
+	Title   string       `json:"title,omitempty"`
+	ScrollX float64      `json:"scrollX"`
+	ScrollY float64      `json:"scrollY"`
+	State   StorageState `json:"state"`
+	Created time.Time    `json:"created"`
+}
+
+// CheckpointResult describes what RestoreCheckpoint applied.
+type CheckpointResult struct {
+	StateResult
+	// URL is where the tab ended up, which differs from the checkpoint's
+	// when the site redirects.
+	URL string
+}
+
+// checkpointDir returns the checkpoints directory in the cache directory.
+func checkpointDir() (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "checkpoints")
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+// checkpointPath returns the file for name.
+func checkpointPath(name string) (string, error) {
+	label := sanitizeLabel(name)
+	if label == "" {
+		return "", fmt.Errorf("invalid checkpoint name %q", name)
+	}
+	dir, err := checkpointDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, label+".json"), nil
+}
+
+// SaveCheckpoint snapshots the tab under name, replacing any checkpoint of
+// that name. The file holds login sessions and is readable only by you.
+func SaveCheckpoint(ctx context.Context, name string) (Checkpoint, error) {
+	cp := Checkpoint{Name: name, Created: time.Now().UTC()}
+	path, err := checkpointPath(name)
+	if err != nil {
+		return cp, err
+	}
+	var page struct {
+		URL     string  `json:"url"`
+		Title   string  `json:"title"`
+		ScrollX float64 `json:"scrollX"`
+		ScrollY float64 `json:"scrollY"`
+	}
+	script := `({url: location.href, title: document.title, scrollX: window.scrollX, scrollY: window.scrollY})`
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &page)); err != nil {
+		return cp, err
+	}
+	cp.URL, cp.Title, cp.ScrollX, cp.ScrollY = page.URL, page.Title, page.ScrollX, page.ScrollY
+	cp.State, err = SaveState(ctx, nil)
+	if err != nil {
+		return cp, err
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return cp, err
+	}
+	return cp, os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// LoadCheckpoint reads the checkpoint saved under name.
+func LoadCheckpoint(name string) (Checkpoint, error) {
+	var cp Checkpoint
+	path, err := checkpointPath(name)
+	if err != nil {
+		return cp, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, fmt.Errorf("no checkpoint named %q, see 'chrome checkpoint list'", name)
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// ListCheckpoints returns the saved checkpoints, newest first.
+func ListCheckpoints() ([]Checkpoint, error) {
+	dir, err := checkpointDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Checkpoint
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var cp Checkpoint
+		if json.Unmarshal(data, &cp) == nil {
+			out = append(out, cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out, nil
+}
+
+// DeleteCheckpoint removes the checkpoint saved under name.
+func DeleteCheckpoint(name string) error {
+	path, err := checkpointPath(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no checkpoint named %q", name)
+	}
+	return err
+}
+
+// RestoreCheckpoint rolls the tab back to cp: it replaces every cookie with
+// the checkpoint's, navigates to its URL, replaces the origin's web
+// storage, reloads so the page reads it, and scrolls back. The first load
+// runs with the current storage; only the reload sees the checkpoint's.
+func RestoreCheckpoint(ctx context.Context, cp Checkpoint) (CheckpointResult, error) {
+	var res CheckpointResult
+	err := browserCall(ctx, func(ctx context.Context, id cdp.BrowserContextID) error {
+		return storage.ClearCookies().WithBrowserContextID(id).Do(ctx)
+	})
+	if err != nil {
+		return res, err
+	}
+	// Cookies first, so the navigation is made with them
+	cookies, err := LoadState(ctx, StorageState{Cookies: cp.State.Cookies})
+	if err != nil {
+		return res, err
+	}
+	if err := Navigate(ctx, cp.URL); err != nil {
+		return res, err
+	}
+	current, err := tabOrigin(ctx)
+	if err != nil {
+		return res, err
+	}
+	// A redirect elsewhere leaves that origin's storage alone
+	if saved, err := NormalizeOrigin(cp.URL); err == nil && current == saved {
+		clear := `(() => { try { localStorage.clear(); sessionStorage.clear() } catch (e) {} })()`
+		if err := chromedp.Run(ctx, chromedp.Evaluate(clear, nil)); err != nil {
+			return res, err
+		}
+	}
+	res.StateResult, err = LoadState(ctx, StorageState{Origins: cp.State.Origins})
+	if err != nil {
+		return res, err
+	}
+	res.Cookies, res.ExpiredCookies = cookies.Cookies, cookies.ExpiredCookies
+
+	scroll := fmt.Sprintf(`window.scrollTo(%g, %g)`, cp.ScrollX, cp.ScrollY)
+	err = chromedp.Run(ctx,
+		chromedp.Reload(),
+		chromedp.Evaluate(scroll, nil),
+		chromedp.Location(&res.URL),
+	)
+	return res, err
+}
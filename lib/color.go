@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// HexColor formats c as #rrggbb, or #rrggbbaa when not opaque.
+func HexColor(c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if rgba.A == 255 {
+		return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", rgba.R, rgba.G, rgba.B, rgba.A)
+}
+
+// SamplePixel returns the rendered color at a viewport coordinate in CSS
+// pixels, captured from the compositor at scale 1.
+func SamplePixel(ctx context.Context, x, y float64) (color.Color, error) {
+	var img image.Image
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var viewport struct {
+			ScrollX float64 `json:"scrollX"`
+			ScrollY float64 `json:"scrollY"`
+			Width   float64 `json:"width"`
+			Height  float64 `json:"height"`
+		}
+		script := `({scrollX: window.scrollX, scrollY: window.scrollY, width: window.innerWidth, height: window.innerHeight})`
+		if err := chromedp.Evaluate(script, &viewport).Do(ctx); err != nil {
+			return err
+		}
+		if x < 0 || y < 0 || x >= viewport.Width || y >= viewport.Height {
+			return fmt.Errorf("%.0f, %.0f is outside the %.0fx%.0f viewport", x, y, viewport.Width, viewport.Height)
+		}
+		buf, err := page.CaptureScreenshot().
+			WithFormat(page.CaptureScreenshotFormatPng).
+			WithFromSurface(true).
+			WithClip(&page.Viewport{X: x + viewport.ScrollX, Y: y + viewport.ScrollY, Width: 1, Height: 1, Scale: 1}).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+		img, _, err = image.Decode(bytes.NewReader(buf))
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	return img.At(b.Min.X, b.Min.Y), nil
+}
+
+// ColorShare is a color and the fraction of pixels that have it.
+type ColorShare struct {
+	Color string  `json:"color"`
+	Share float64 `json:"share"`
+}
+
+// DominantColors returns the n most common exact colors in img, most common
+// first.
+func DominantColors(img image.Image, n int) []ColorShare {
+	b := img.Bounds()
+	counts := map[color.NRGBA]int{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			counts[color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)]++
+		}
+	}
+	type entry struct {
+		color color.NRGBA
+		count int
+	}
+	entries := make([]entry, 0, len(counts))
+	for c, count := range counts {
+		entries = append(entries, entry{c, count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return HexColor(entries[i].color) < HexColor(entries[j].color)
+	})
+	total := float64(b.Dx() * b.Dy())
+	var shares []ColorShare
+	for i := 0; i < len(entries) && i < n; i++ {
+		shares = append(shares, ColorShare{Color: HexColor(entries[i].color), Share: float64(entries[i].count) / total})
+	}
+	return shares
+}
+
+// ForegroundColor returns the most common color in img that is clearly
+// distinct from background (text, icons, borders), or "" if none is.
+func ForegroundColor(img image.Image, background string) string {
+	var bg color.NRGBA
+	if _, err := fmt.Sscanf(background, "#%02x%02x%02x", &bg.R, &bg.G, &bg.B); err != nil {
+		return ""
+	}
+	for _, share := range DominantColors(img, 64) {
+		var c color.NRGBA
+		if _, err := fmt.Sscanf(share.Color, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+			continue
+		}
+		dr, dg, db := int(c.R)-int(bg.R), int(c.G)-int(bg.G), int(c.B)-int(bg.B)
+		// Anti-aliased edges blend toward the background; skip near matches
+		if dr*dr+dg*dg+db*db > 96*96 {
+			return share.Color
+		}
+	}
+	return ""
+}
@@ -0,0 +1,261 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+
+	"github.com/chromedp/chromedp"
+)
+
+// PageSnapshot is the comparable view of a loaded page: its visible text
+// and outline line by line, and optionally a screenshot.
+type PageSnapshot struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Text is the visible text, one non-empty trimmed line per entry.
+	Text []string `json:"text"`
+	// Outline lists headings (indented by level) and landmarks such as
+	// nav, main, and forms in document order.
+	Outline []string `json:"outline"`
+	// Screenshot is a PNG of the viewport, or of the compared element.
+	Screenshot []byte `json:"-"`
+}
+
+// snapshotScript extracts the text and outline of the element matching the
+// selector argument, or of the whole body when it is empty.
+const snapshotScript = `((sel) => {
+  const scope = sel ? document.querySelector(sel) : document.body;
+  if (!scope) return null;
+  const clean = (s) => (s || '').replace(/\s+/g, ' ').trim();
+  const text = (scope.innerText || scope.textContent || '').split('\n').map(clean).filter(Boolean);
+  const outline = [];
+  const landmarks = { HEADER: 'header', NAV: 'nav', MAIN: 'main', ASIDE: 'aside', FOOTER: 'footer', FORM: 'form' };
+  const visible = (el) => el.getClientRects().length > 0 && getComputedStyle(el).visibility !== 'hidden';
+  for (const el of scope.querySelectorAll('h1, h2, h3, h4, h5, h6, header, nav, main, aside, footer, form, [role="navigation"], [role="main"], [role="banner"], [role="contentinfo"], [role="search"]')) {
+    if (!visible(el)) continue;
+    const m = /^H([1-6])$/.exec(el.tagName);
+    if (m) {
+      outline.push('  '.repeat(Number(m[1]) - 1) + 'h' + m[1] + ' ' + clean(el.innerText).slice(0, 120));
+      continue;
+    }
+    const kind = landmarks[el.tagName] || el.getAttribute('role');
+    const name = clean(el.getAttribute('aria-label') || el.id || el.getAttribute('name') || '');
+    let line = '[' + kind + (name ? ' ' + name : '') + ']';
+    if (kind === 'nav' || kind === 'navigation') line += ' ' + el.querySelectorAll('a[href]').length + ' links';
+    if (kind === 'form' || kind === 'search') line += ' ' + el.querySelectorAll('input:not([type=hidden]), select, textarea').length + ' fields';
+    outline.push(line);
+  }
+  return { url: location.href, title: document.title, text: text, outline: outline };
+})`
+
+// SnapshotPage captures the tab's text and outline, limited to the first
+// element matching selector when set, and a screenshot of the same area
+// when screenshot is set.
+func SnapshotPage(ctx context.Context, selector string, screenshot bool) (PageSnapshot, error) {
+	var snap *PageSnapshot
+	script := snapshotScript + `(` + strconv.Quote(selector) + `)`
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &snap)); err != nil {
+		return PageSnapshot{}, err
+	}
+	if snap == nil {
+		return PageSnapshot{}, fmt.Errorf("%w: %s", errElementNotFound, selector)
+	}
+	if screenshot {
+		buf, err := ScreenshotWithOptions(ctx, ScreenshotOptions{FreezeAnimations: true, Element: selector})
+		if err != nil {
+			return *snap, err
+		}
+		snap.Screenshot = buf
+	}
+	return *snap, nil
+}
+
+// Mask replaces every match of the patterns in the snapshot's title, text,
+// and outline with "*", so timestamps, build hashes, and other expected
+// differences between environments do not show up in a comparison.
+func (s *PageSnapshot) Mask(patterns []*regexp.Regexp) {
+	if len(patterns) == 0 {
+		return
+	}
+	mask := func(line string) string {
+		for _, re := range patterns {
+			line = re.ReplaceAllString(line, "*")
+		}
+		return line
+	}
+	s.Title = mask(s.Title)
+	for i := range s.Text {
+		s.Text[i] = mask(s.Text[i])
+	}
+	for i := range s.Outline {
+		s.Outline[i] = mask(s.Outline[i])
+	}
+}
+
+// DiffLine is one line of a line diff. Op is " " for a line in both sides,
+// "-" for one only in A, and "+" for one only in B. A and B are 1-based line
+// numbers on each side, 0 when the line is absent there.
+type DiffLine struct {
+	Op   string `json:"op"`
+	A    int    `json:"a,omitempty"`
+	B    int    `json:"b,omitempty"`
+	Text string `json:"text"`
+}
+
+// LineDiff summarizes a line diff: counts of removed and added lines and the
+// changed lines with up to the requested context around them.
+type LineDiff struct {
+	Removed int        `json:"removed"`
+	Added   int        `json:"added"`
+	Lines   []DiffLine `json:"lines,omitempty"`
+}
+
+// Changed reports whether the two sides differ.
+func (d LineDiff) Changed() bool {
+	return d.Removed > 0 || d.Added > 0
+}
+
+// maxDiffCells bounds the LCS table; larger changed regions are reported as
+// removed then added rather than aligned line by line.
+const maxDiffCells = 4_000_000
+
+// DiffLines compares a and b line by line on their longest common
+// subsequence, keeping context unchanged lines around each change.
+func DiffLines(a []string, b []string, context int) LineDiff {
+	var script []DiffLine
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		script = append(script, DiffLine{Op: " ", A: prefix + 1, B: prefix + 1, Text: a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if len(midA)*len(midB) > maxDiffCells {
+		for i, line := range midA {
+			script = append(script, DiffLine{Op: "-", A: prefix + i + 1, Text: line})
+		}
+		for j, line := range midB {
+			script = append(script, DiffLine{Op: "+", B: prefix + j + 1, Text: line})
+		}
+	} else {
+		// lcs[i][j] is the LCS length of midA[i:] and midB[j:]
+		n, m := len(midA), len(midB)
+		lcs := make([][]int32, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && midA[i] == midB[j]:
+				script = append(script, DiffLine{Op: " ", A: prefix + i + 1, B: prefix + j + 1, Text: midA[i]})
+				i++
+				j++
+			case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+				script = append(script, DiffLine{Op: "+", B: prefix + j + 1, Text: midB[j]})
+				j++
+			default:
+				script = append(script, DiffLine{Op: "-", A: prefix + i + 1, Text: midA[i]})
+				i++
+			}
+		}
+	}
+	for k := 0; k < suffix; k++ {
+		script = append(script, DiffLine{Op: " ", A: len(a) - suffix + k + 1, B: len(b) - suffix + k + 1, Text: a[len(a)-suffix+k]})
+	}
+
+	var diff LineDiff
+	keep := make([]bool, len(script))
+	for k, line := range script {
+		if line.Op == " " {
+			continue
+		}
+		if line.Op == "-" {
+			diff.Removed++
+		} else {
+			diff.Added++
+		}
+		for c := max(0, k-context); c <= min(len(script)-1, k+context); c++ {
+			keep[c] = true
+		}
+	}
+	for k, line := range script {
+		if keep[k] {
+			diff.Lines = append(diff.Lines, line)
+		}
+	}
+	return diff
+}
+
+// CompareOptions controls ComparePages.
+type CompareOptions struct {
+	// Context is the number of unchanged lines kept around each change.
+	Context int
+	// Threshold is the fraction of differing pixels above which the
+	// screenshots count as different.
+	Threshold float64
+	Diff      DiffOptions
+}
+
+// PageComparison is the result of ComparePages. Screenshot is nil when
+// either snapshot has none.
+type PageComparison struct {
+	URLA         string      `json:"url_a"`
+	URLB         string      `json:"url_b"`
+	TitleA       string      `json:"title_a"`
+	TitleB       string      `json:"title_b"`
+	Outline      LineDiff    `json:"outline"`
+	Text         LineDiff    `json:"text"`
+	Screenshot   *DiffResult `json:"screenshot,omitempty"`
+	ScreenshotOK bool        `json:"screenshot_ok"`
+	Different    bool        `json:"different"`
+}
+
+// ComparePages diffs two snapshots' titles, outlines, text, and
+// screenshots. The returned image is the pixel diff, nil without
+// screenshots.
+func ComparePages(a PageSnapshot, b PageSnapshot, opts CompareOptions) (PageComparison, *image.RGBA, error) {
+	cmp := PageComparison{
+		URLA:         a.URL,
+		URLB:         b.URL,
+		TitleA:       a.Title,
+		TitleB:       b.Title,
+		Outline:      DiffLines(a.Outline, b.Outline, opts.Context),
+		Text:         DiffLines(a.Text, b.Text, opts.Context),
+		ScreenshotOK: true,
+	}
+	var diffImage *image.RGBA
+	if len(a.Screenshot) > 0 && len(b.Screenshot) > 0 {
+		imgA, _, err := image.Decode(bytes.NewReader(a.Screenshot))
+		if err != nil {
+			return cmp, nil, fmt.Errorf("decoding screenshot of %s: %w", a.URL, err)
+		}
+		imgB, _, err := image.Decode(bytes.NewReader(b.Screenshot))
+		if err != nil {
+			return cmp, nil, fmt.Errorf("decoding screenshot of %s: %w", b.URL, err)
+		}
+		res, img := DiffImages(imgA, imgB, opts.Diff)
+		cmp.Screenshot = &res
+		cmp.ScreenshotOK = !res.SizeMismatch && res.Ratio <= opts.Threshold
+		diffImage = img
+	}
+	cmp.Different = cmp.TitleA != cmp.TitleB || cmp.Outline.Changed() || cmp.Text.Changed() || !cmp.ScreenshotOK
+	return cmp, diffImage, nil
+}
@@ -0,0 +1,282 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookie is a browser cookie as 'chrome cookies' lists, exports, and
+// imports it. The JSON form matches the cookies of a Playwright storage
+// state, so files move between the two.
+type Cookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"` // seconds since the epoch, -1 for a session cookie
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"sameSite,omitempty"` // Strict, Lax, or None
+}
+
+// Session reports whether the cookie ends with the browser session.
+func (c Cookie) Session() bool {
+	return c.Expires <= 0
+}
+
+// param converts the cookie for Storage.setCookies. A cookie without a
+// domain is set for url. Host-only cookies (a domain without a leading dot)
+// are set through a URL on their host, since a domain makes a domain cookie.
+func (c Cookie) param(url string) *network.CookieParam {
+	p := &network.CookieParam{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HTTPOnly: c.HTTPOnly,
+		SameSite: network.CookieSameSite(c.SameSite),
+	}
+	switch {
+	case c.Domain == "":
+		p.URL = url
+	case !strings.HasPrefix(c.Domain, "."):
+		scheme := "http://"
+		if c.Secure {
+			scheme = "https://"
+		}
+		p.URL = scheme + c.Domain + c.Path
+		p.Domain = ""
+	}
+	if !c.Session() {
+		sec, frac := math.Modf(c.Expires)
+		t := cdp.TimeSinceEpoch(time.Unix(int64(sec), int64(frac*1e9)))
+		p.Expires = &t
+	}
+	return p
+}
+
+// browserCall runs fn with the tab's browser context ID against the browser
+// connection, where the Storage cookie methods live.
+func browserCall(ctx context.Context, fn func(ctx context.Context, id cdp.BrowserContextID) error) error {
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		c := chromedp.FromContext(ctx)
+		if c == nil || c.Target == nil {
+			return errors.New("not attached to a tab")
+		}
+		browserCtx := cdp.WithExecutor(ctx, c.Browser)
+		info, err := target.GetTargetInfo().WithTargetID(c.Target.TargetID).Do(browserCtx)
+		if err != nil {
+			return err
+		}
+		return fn(browserCtx, info.BrowserContextID)
+	}))
+}
+
+// GetCookies returns every cookie in the tab's browser context.
+func GetCookies(ctx context.Context) ([]Cookie, error) {
+	var found []*network.Cookie
+	err := browserCall(ctx, func(ctx context.Context, id cdp.BrowserContextID) error {
+		var err error
+		found, err = storage.GetCookies().WithBrowserContextID(id).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	cookies := make([]Cookie, 0, len(found))
+	for _, c := range found {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: string(c.SameSite),
+		}
+		if c.Session {
+			cookie.Expires = -1
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// SetCookies adds or replaces cookies in the tab's browser context; those
+// without a domain are set for url.
+func SetCookies(ctx context.Context, cookies []Cookie, url string) error {
+	params := make([]*network.CookieParam, len(cookies))
+	for i, c := range cookies {
+		params[i] = c.param(url)
+	}
+	return browserCall(ctx, func(ctx context.Context, id cdp.BrowserContextID) error {
+		return storage.SetCookies(params).WithBrowserContextID(id).Do(ctx)
+	})
+}
+
+// DeleteCookies deletes the given cookies from the tab's browser context.
+func DeleteCookies(ctx context.Context, cookies []Cookie) error {
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, c := range cookies {
+			if err := network.DeleteCookies(c.Name).WithDomain(c.Domain).WithPath(c.Path).Do(ctx); err != nil {
+				return fmt.Errorf("deleting %s on %s: %w", c.Name, c.Domain, err)
+			}
+		}
+		return nil
+	}))
+}
+
+// FilterCookies returns the cookies with name (any if "") whose domain
+// matches domain (any if ""), ignoring a leading dot.
+func FilterCookies(cookies []Cookie, name string, domain string) []Cookie {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	var out []Cookie
+	for _, c := range cookies {
+		if name != "" && c.Name != name {
+			continue
+		}
+		d := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+		if domain != "" && d != domain && !strings.HasSuffix(d, "."+domain) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// FormatCookies encodes cookies as json or netscape (cookies.txt).
+func FormatCookies(cookies []Cookie, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		if cookies == nil {
+			cookies = []Cookie{}
+		}
+		data, err := json.MarshalIndent(cookies, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "netscape":
+		var buf bytes.Buffer
+		buf.WriteString("# Netscape HTTP Cookie File\n")
+		for _, c := range cookies {
+			domain := c.Domain
+			if c.HTTPOnly {
+				domain = "#HttpOnly_" + domain
+			}
+			expires := int64(0)
+			if !c.Session() {
+				expires = int64(c.Expires)
+			}
+			fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", domain, netscapeBool(strings.HasPrefix(c.Domain, ".")), c.Path, netscapeBool(c.Secure), expires, c.Name, c.Value)
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown cookie format %q, expected json or netscape", format)
+}
+
+// ParseCookies decodes cookies in format json, netscape, or auto (JSON when
+// the data starts with [ or {). JSON is an array of Cookie, or a Playwright
+// storage state with a cookies array.
+func ParseCookies(data []byte, format string) ([]Cookie, error) {
+	if format == "auto" || format == "" {
+		format = "netscape"
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+			format = "json"
+		}
+	}
+	switch format {
+	case "json":
+		var cookies []Cookie
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			var state struct {
+				Cookies []Cookie `json:"cookies"`
+			}
+			if err := json.Unmarshal(data, &state); err != nil {
+				return nil, err
+			}
+			cookies = state.Cookies
+		} else if err := json.Unmarshal(data, &cookies); err != nil {
+			return nil, err
+		}
+		for i, c := range cookies {
+			if c.Name == "" || c.Domain == "" {
+				return nil, fmt.Errorf("cookie %d needs name and domain", i+1)
+			}
+		}
+		return cookies, nil
+	case "netscape":
+		return parseNetscapeCookies(data)
+	}
+	return nil, fmt.Errorf("unknown cookie format %q, expected json, netscape, or auto", format)
+}
+
+func parseNetscapeCookies(data []byte) ([]Cookie, error) {
+	var cookies []Cookie
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(text, "#HttpOnly_"); ok {
+			text, httpOnly = rest, true
+		}
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) == 6 {
+			// An empty value is sometimes written without its tab
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields, got %d", line, len(fields))
+		}
+		expires, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad expiry %q", line, fields[4])
+		}
+		if expires == 0 {
+			expires = -1
+		}
+		domain := fields[0]
+		if strings.EqualFold(fields[1], "TRUE") && !strings.HasPrefix(domain, ".") {
+			domain = "." + domain
+		}
+		cookies = append(cookies, Cookie{
+			Domain:   domain,
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  expires,
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		})
+	}
+	return cookies, scanner.Err()
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/chromedp"
+)
+
+// ExitCrashed is the exit code of any command whose tab's renderer crashed
+// (including running out of memory), so scripts can tell a dead tab apart
+// from an ordinary failure (exit 1).
+const ExitCrashed = 3
+
+// CrashMessage explains a crashed tab and how to recover it.
+const CrashMessage = "tab crashed: its renderer process is gone (out of memory, or killed); reload it with 'chrome navigate URL' or open a new tab"
+
+// ErrTabCrashed is the cause (see context.Cause) of a target context
+// cancelled because its tab crashed.
+var ErrTabCrashed = errors.New(CrashMessage)
+
+// tabCrashed records that a watched tab crashed, for Exit.
+var tabCrashed atomic.Bool
+
+// TabCrashed reports whether the tab behind any target context of this
+// process crashed.
+func TabCrashed() bool {
+	return tabCrashed.Load()
+}
+
+// watchCrash cancels ctx with ErrTabCrashed as soon as the tab behind it
+// reports Inspector.targetCrashed, instead of letting callers wait out
+// their timeouts against a dead renderer. cancel releases ctx; it never
+// closes the tab.
+func watchCrash(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if _, ok := ev.(*inspector.EventTargetCrashed); ok {
+			tabCrashed.Store(true)
+			cancel(ErrTabCrashed)
+		}
+	})
+	return ctx, func() { cancel(nil) }
+}
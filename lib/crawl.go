@@ -0,0 +1,364 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Crawl etiquette is set with the global --respect-robots, --max-rps, and
+// --per-domain-delay flags. They are passed in the environment, so commands
+// that run other commands (run, paginate, matrix) and parallel children
+// share one budget, kept in the cache directory.
+const (
+	RespectRobotsEnv = "CHROME_RESPECT_ROBOTS"
+	MaxRPSEnv        = "CHROME_MAX_RPS"
+	DomainDelayEnv   = "CHROME_DOMAIN_DELAY"
+)
+
+// RobotsAgent is the user-agent token matched against robots.txt groups,
+// besides *.
+const RobotsAgent = "chrome-cli"
+
+// Etiquette limits navigations: RespectRobots refuses URLs robots.txt
+// disallows, MaxRPS caps navigations per second across all sites, and
+// DomainDelay spaces navigations to the same host.
+type Etiquette struct {
+	RespectRobots bool
+	MaxRPS        float64
+	DomainDelay   time.Duration
+}
+
+// Active reports whether any limit is set.
+func (e Etiquette) Active() bool {
+	return e.RespectRobots || e.MaxRPS > 0 || e.DomainDelay > 0
+}
+
+// CrawlEtiquette reads the etiquette flags from the environment.
+func CrawlEtiquette() (Etiquette, error) {
+	var e Etiquette
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(RespectRobotsEnv))) {
+	case "", "0", "false", "no":
+	default:
+		e.RespectRobots = true
+	}
+	if v := strings.TrimSpace(os.Getenv(MaxRPSEnv)); v != "" {
+		rps, err := strconv.ParseFloat(v, 64)
+		if err != nil || rps <= 0 {
+			return e, fmt.Errorf("invalid %s %q, expected a positive number", MaxRPSEnv, v)
+		}
+		e.MaxRPS = rps
+	}
+	if v := strings.TrimSpace(os.Getenv(DomainDelayEnv)); v != "" {
+		delay, err := time.ParseDuration(v)
+		if err != nil || delay < 0 {
+			return e, fmt.Errorf("invalid %s %q, expected a duration like 2s", DomainDelayEnv, v)
+		}
+		e.DomainDelay = delay
+	}
+	return e, nil
+}
+
+// ErrRobotsDisallowed is returned for navigations robots.txt forbids.
+var ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+// PoliteWait enforces crawl etiquette before navigating to rawURL: it fails
+// if robots.txt disallows the URL, then sleeps until the URL's host and the
+// global rate allow another navigation. Non-HTTP URLs pass through. Call it
+// outside any action timeout, the wait can be longer.
+func PoliteWait(ctx context.Context, rawURL string) error {
+	e, err := CrawlEtiquette()
+	if err != nil || !e.Active() {
+		return err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	delay := e.DomainDelay
+	if e.RespectRobots {
+		robots, err := loadRobots(ctx, u)
+		if err != nil {
+			return err
+		}
+		if !robots.allowed(u) {
+			return fmt.Errorf("%s is %w (%s://%s/robots.txt, --respect-robots)", rawURL, ErrRobotsDisallowed, u.Scheme, u.Host)
+		}
+		delay = max(delay, robots.crawlDelay)
+	}
+	wait, err := reserveSlot(strings.ToLower(u.Host), e.MaxRPS, delay)
+	if err != nil {
+		return err
+	}
+	if wait <= 0 {
+		return nil
+	}
+	if os.Getenv("CHROME_TIMING") != "" {
+		fmt.Fprintf(os.Stderr, "crawl: waiting %s before %s\n", wait.Round(time.Millisecond), u.Host)
+	}
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// crawlSlots is the shared schedule: when the last navigation was allowed
+// overall and per host. Reserving a slot writes it before sleeping, so
+// processes sharing the file queue up behind each other.
+type crawlSlots struct {
+	Last    time.Time            `json:"last"`
+	Domains map[string]time.Time `json:"domains"`
+}
+
+// reserveSlot books the next navigation to host and returns how long to
+// wait for it.
+func reserveSlot(host string, rps float64, delay time.Duration) (time.Duration, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return 0, err
+	}
+	path := filepath.Join(dir, "crawl-slots.json")
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	slots := crawlSlots{Domains: map[string]time.Time{}}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &slots)
+		if slots.Domains == nil {
+			slots.Domains = map[string]time.Time{}
+		}
+	}
+	now := time.Now()
+	slot := now
+	if rps > 0 {
+		if next := slots.Last.Add(time.Duration(float64(time.Second) / rps)); next.After(slot) {
+			slot = next
+		}
+	}
+	if delay > 0 {
+		if next := slots.Domains[host].Add(delay); next.After(slot) {
+			slot = next
+		}
+	}
+	slots.Last = slot
+	slots.Domains[host] = slot
+	for h, t := range slots.Domains {
+		if now.Sub(t) > time.Hour {
+			delete(slots.Domains, h)
+		}
+	}
+	data, err := json.Marshal(slots)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return 0, err
+	}
+	return slot.Sub(now), nil
+}
+
+// lockFile takes an exclusive lock by creating path, breaking locks older
+// than 10s left by a killed process.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(DefaultTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > 10*time.Second {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// robotsRules are the rules of the robots.txt group that applies to us.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern *regexp.Regexp
+	length  int
+}
+
+// robotsTTL is how long a fetched robots.txt is reused.
+const robotsTTL = time.Hour
+
+// loadRobots returns the robots.txt rules for u's origin, cached for
+// robotsTTL. A missing robots.txt (4xx) allows everything; an unreachable
+// one (5xx, network error) is an error, as RFC 9309 says to assume the
+// site is fully disallowed.
+func loadRobots(ctx context.Context, u *url.URL) (robotsRules, error) {
+	origin := u.Scheme + "://" + u.Host
+	dir, err := CacheDir()
+	if err != nil {
+		return robotsRules{}, err
+	}
+	dir = filepath.Join(dir, "robots")
+	path := filepath.Join(dir, sanitizeLabel(u.Scheme+"-"+u.Host)+".txt")
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < robotsTTL {
+		if data, err := os.ReadFile(path); err == nil {
+			return parseRobots(string(data), RobotsAgent), nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return robotsRules{}, err
+	}
+	req.Header.Set("User-Agent", RobotsAgent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return robotsRules{}, fmt.Errorf("fetching %s/robots.txt: %w", origin, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var text string
+	switch {
+	case resp.StatusCode >= 500:
+		return robotsRules{}, fmt.Errorf("%s/robots.txt is unavailable (%s), treating the site as disallowed", origin, resp.Status)
+	case resp.StatusCode >= 400:
+		text = ""
+	default:
+		// RFC 9309 requires parsing at least 500 KiB
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+		if err != nil {
+			return robotsRules{}, fmt.Errorf("reading %s/robots.txt: %w", origin, err)
+		}
+		text = string(data)
+	}
+	if err := os.MkdirAll(dir, 0755); err == nil {
+		_ = os.WriteFile(path, []byte(text), 0644)
+	}
+	return parseRobots(text, RobotsAgent), nil
+}
+
+// parseRobots returns the rules of the group naming agent, or of the *
+// group when none does. Consecutive user-agent lines share a group.
+func parseRobots(text string, agent string) robotsRules {
+	type group struct {
+		agents []string
+		rules  robotsRules
+	}
+	var groups []*group
+	var current *group
+	inAgents := false
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules.rules = append(current.rules.rules, robotsRule{allow: key == "allow", pattern: robotsPattern(value), length: len(value)})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				current.rules.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		default:
+			inAgents = false
+		}
+	}
+	var fallback robotsRules
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == strings.ToLower(agent) {
+				return g.rules
+			}
+			if a == "*" {
+				fallback.rules = append(fallback.rules, g.rules.rules...)
+				fallback.crawlDelay = max(fallback.crawlDelay, g.rules.crawlDelay)
+			}
+		}
+	}
+	return fallback
+}
+
+// robotsPattern compiles a path pattern, where * matches anything and a
+// trailing $ anchors the end.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// allowed applies the longest matching rule; allow wins ties, and no match
+// allows.
+func (r robotsRules) allowed(u *url.URL) bool {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > best || (rule.length == best && rule.allow) {
+			best, allow = rule.length, rule.allow
+		}
+	}
+	return allow
+}
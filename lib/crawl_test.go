@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseRobotsGroups(t *testing.T) {
+	text := `# comment
+User-agent: other
+Disallow: /
+
+User-agent: *
+Disallow: /private
+Crawl-delay: 2
+
+User-agent: chrome-cli
+User-agent: somebot
+Disallow: /admin # trailing comment
+Allow: /admin/public
+Crawl-delay: 0.5
+`
+	rules := parseRobots(text, RobotsAgent)
+	if rules.crawlDelay != 500*time.Millisecond {
+		t.Fatalf("crawl delay %s, want 500ms", rules.crawlDelay)
+	}
+	if len(rules.rules) != 2 {
+		t.Fatalf("got %d rules from the named group, want 2", len(rules.rules))
+	}
+
+	fallback := parseRobots(text, "unknown")
+	if fallback.crawlDelay != 2*time.Second || len(fallback.rules) != 1 {
+		t.Fatalf("* group: %d rules, delay %s", len(fallback.rules), fallback.crawlDelay)
+	}
+
+	if none := parseRobots("User-agent: other\nDisallow: /\n", RobotsAgent); len(none.rules) != 0 {
+		t.Fatalf("no matching group: got %d rules", len(none.rules))
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := parseRobots(`User-agent: *
+Disallow: /admin
+Allow: /admin/public
+Disallow: /*.pdf$
+Disallow: /search?q=
+Disallow: /tie
+Allow: /tie
+Disallow:
+`, RobotsAgent)
+	cases := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/", true},
+		{"https://example.com", true},
+		{"https://example.com/admin", false},
+		{"https://example.com/admin/users", false},
+		{"https://example.com/admin/public/page", true},
+		{"https://example.com/docs/file.pdf", false},
+		{"https://example.com/docs/file.pdf?x=1", true},
+		{"https://example.com/search?q=chrome", false},
+		{"https://example.com/search", true},
+		{"https://example.com/tie", true},
+		{"https://example.com/robots.txt", true},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rules.allowed(u); got != c.want {
+			t.Errorf("allowed(%s) = %v, want %v", c.url, got, c.want)
+		}
+	}
+}
+
+func TestRobotsDisallowAll(t *testing.T) {
+	rules := parseRobots("User-agent: *\nDisallow: /\n", RobotsAgent)
+	u, _ := url.Parse("https://example.com/anything")
+	if rules.allowed(u) {
+		t.Fatal("Disallow: / allowed a page")
+	}
+	u, _ = url.Parse("https://example.com/robots.txt")
+	if !rules.allowed(u) {
+		t.Fatal("robots.txt itself must stay allowed")
+	}
+}
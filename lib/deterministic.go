@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultDeterministicTime is the frozen clock used by --deterministic.
+var DefaultDeterministicTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DeterministicBlockedURLs are Network.setBlockedURLs patterns for external
+// font hosts and common ad/analytics networks, whose responses vary per load.
+var DeterministicBlockedURLs = append(append([]string{}, BlockGroups["fonts"]...), BlockGroups["trackers"]...)
+
+// DeterministicScript returns an init script that seeds Math.random with a
+// mulberry32 PRNG and freezes Date (Date.now, new Date()) at now. Install it
+// with Page.addScriptToEvaluateOnNewDocument before navigating.
+func DeterministicScript(seed int64, now time.Time) string {
+	return fmt.Sprintf(`(() => {
+  let state = %d >>> 0;
+  Math.random = function() {
+    state = (state + 0x6D2B79F5) >>> 0;
+    let t = state;
+    t = Math.imul(t ^ (t >>> 15), t | 1);
+    t ^= t + Math.imul(t ^ (t >>> 7), t | 61);
+    return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+  };
+  const fixed = %d;
+  const RealDate = Date;
+  function FrozenDate(...args) {
+    if (!new.target) return new RealDate(fixed).toString();
+    return args.length === 0 ? new RealDate(fixed) : new RealDate(...args);
+  }
+  FrozenDate.prototype = RealDate.prototype;
+  FrozenDate.now = () => fixed;
+  FrozenDate.parse = RealDate.parse;
+  FrozenDate.UTC = RealDate.UTC;
+  Object.defineProperty(FrozenDate.prototype, 'constructor', { value: FrozenDate });
+  window.Date = FrozenDate;
+})();`, seed, now.UnixMilli())
+}
@@ -0,0 +1,145 @@
+package lib
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ReadElementValue returns the current value of a form element after the next
+// animation frame, giving React and similar frameworks a chance to settle state.
+// Background and occluded tabs get no animation frames, so a timer reads it
+// instead if no frame comes within 50ms. Contenteditable elements report their
+// textContent.
+func ReadElementValue(ctx context.Context, selector string) (string, bool, error) {
+	script := `new Promise(resolve => {
+	  let done = false;
+	  const read = () => {
+	    if (done) return;
+	    done = true;
+	    const el = document.querySelector(` + strconv.Quote(selector) + `);
+	    if (!el) return resolve({ ok: false, value: "" });
+	    if (el.isContentEditable) return resolve({ ok: true, value: el.textContent || "" });
+	    resolve({ ok: true, value: el.value === undefined ? "" : String(el.value) });
+	  };
+	  requestAnimationFrame(() => requestAnimationFrame(read));
+	  setTimeout(read, 50);
+	})`
+
+	var res struct {
+		Ok    bool   `json:"ok"`
+		Value string `json:"value"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(script, &res, awaitPromise))
+	if err != nil {
+		return "", false, err
+	}
+	return res.Value, res.Ok, nil
+}
+
+// CSSPathJS defines a cssPath(el) JavaScript function that builds a selector
+// unique within the document, preferring ids and falling back to :nth-of-type.
+// Prepend it to scripts that need to report element selectors.
+const CSSPathJS = `function cssPath(el) {
+  if (!(el instanceof Element)) return '';
+  const parts = [];
+  while (el && el.nodeType === Node.ELEMENT_NODE) {
+    if (el.id && document.querySelectorAll('#' + CSS.escape(el.id)).length === 1) {
+      parts.unshift('#' + CSS.escape(el.id));
+      break;
+    }
+    let part = el.tagName.toLowerCase();
+    const parent = el.parentElement;
+    if (parent) {
+      const same = Array.from(parent.children).filter(c => c.tagName === el.tagName);
+      if (same.length > 1) part += ':nth-of-type(' + (same.indexOf(el) + 1) + ')';
+    }
+    parts.unshift(part);
+    el = parent;
+  }
+  return parts.join(' > ');
+}
+`
+
+// FillScript returns JavaScript that sets the value of an INPUT, TEXTAREA, SELECT,
+// or contenteditable element using the native setter, then dispatches the
+// input/change events React needs. Evaluates to { ok, value, error }.
+func FillScript(selector string, value string) string {
+	return `(() => {
+	  const sel = ` + strconv.Quote(selector) + `;
+	  const val = ` + strconv.Quote(value) + `;
+	  const el = document.querySelector(sel);
+	  if (!el) return { ok: false, error: "element not found" };
+	  
+	  // Handle SELECT elements (no native setter trick needed)
+	  if (el.tagName === 'SELECT') {
+	    el.focus();
+	    el.value = val;
+	    el.dispatchEvent(new Event('change', { bubbles: true }));
+	    return { ok: true, value: el.value };
+	  }
+	  
+	  // Handle contenteditable elements
+	  if (el.isContentEditable) {
+	    el.focus();
+	    el.textContent = val;
+	    el.dispatchEvent(new InputEvent('input', {
+	      bubbles: true,
+	      cancelable: true,
+	      inputType: 'insertText',
+	      data: val,
+	    }));
+	    el.dispatchEvent(new Event('change', { bubbles: true }));
+	    return { ok: true, value: el.textContent };
+	  }
+	  
+	  // Validate element type for INPUT/TEXTAREA
+	  if (el.tagName !== 'INPUT' && el.tagName !== 'TEXTAREA') return { ok: false, error: "fill only supports INPUT, TEXTAREA, SELECT, and contenteditable elements" };
+	  
+	  // Focus the element
+	  el.focus();
+	  
+	  // For React 16+, we need to use the native setter and InputEvent
+	  // This is the most reliable way to update controlled inputs
+	  const proto = el.tagName === 'TEXTAREA' 
+	    ? HTMLTextAreaElement.prototype 
+	    : HTMLInputElement.prototype;
+	  const setter = Object.getOwnPropertyDescriptor(proto, 'value').set;
+	  setter.call(el, val);
+	  
+	  // Dispatch InputEvent (not Event) - this is what browsers actually fire
+	  // React 17+ specifically listens for this
+	  const inputEvent = new InputEvent('input', {
+	    bubbles: true,
+	    cancelable: true,
+	    inputType: 'insertText',
+	    data: val,
+	  });
+	  el.dispatchEvent(inputEvent);
+	  
+	  // Also dispatch change event for completeness
+	  el.dispatchEvent(new Event('change', { bubbles: true }));
+	  
+	  return { ok: true, value: el.value };
+	})()`
+}
+
+// SelectContentsScript returns JavaScript that selects all text inside an element
+// so the next typed text replaces it. Uses element.select() for INPUT/TEXTAREA
+// and the Selection API for contenteditable elements.
+func SelectContentsScript(selector string) string {
+	return `(() => {
+	  const el = document.querySelector(` + strconv.Quote(selector) + `);
+	  if (!el) return;
+	  if (typeof el.select === 'function') {
+	    el.select();
+	  } else if (el.isContentEditable) {
+	    const range = document.createRange();
+	    range.selectNodeContents(el);
+	    const sel = window.getSelection();
+	    sel.removeAllRanges();
+	    sel.addRange(range);
+	  }
+	})()`
+}
@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+)
+
+// Exit ends the process with code, the way every command exits, after
+// reporting timing for the command named by TimeCommand. A command whose
+// tab crashed exits with ExitCrashed instead, whatever it reported, since
+// the crash is why its context was cancelled.
+func Exit(code int) {
+	timing.mu.Lock()
+	command := timing.command
+	timing.mu.Unlock()
+	if command != "" {
+		ReportTiming(command)
+	}
+	if TabCrashed() && code != ExitCrashed {
+		fmt.Fprintf(os.Stderr, "error: %s\n", CrashMessage)
+		code = ExitCrashed
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// FixtureOptions control StartFixtureServer.
+type FixtureOptions struct {
+	// Addr is the address to listen on (default: 127.0.0.1:0, a free port).
+	Addr string
+	// SPA serves index.html for paths without a file, as single-page apps
+	// with client-side routing expect.
+	SPA bool
+	// Log, when set, gets one line per request.
+	Log io.Writer
+}
+
+// FixtureServer serves a directory of static files over HTTP, so examples
+// and tests can navigate to a known page without the network.
+type FixtureServer struct {
+	// URL is the server's base URL, e.g. http://127.0.0.1:41234.
+	URL    string
+	Dir    string
+	server *http.Server
+}
+
+// StartFixtureServer serves dir in the background until Close.
+func StartFixtureServer(dir string, opts FixtureOptions) (*FixtureServer, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	addr := opts.Addr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	host := "127.0.0.1"
+	if h, _, err := net.SplitHostPort(addr); err == nil && h != "" && h != "0.0.0.0" && h != "::" {
+		host = h
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	f := &FixtureServer{
+		URL:    "http://" + net.JoinHostPort(host, fmt.Sprint(port)),
+		Dir:    dir,
+		server: &http.Server{Handler: FixtureHandler(dir, opts.SPA, opts.Log), ReadHeaderTimeout: 10 * time.Second},
+	}
+	go func() {
+		_ = f.server.Serve(listener)
+	}()
+	return f, nil
+}
+
+// Close stops the server.
+func (f *FixtureServer) Close() error {
+	err := f.server.Close()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// FixtureHandler serves dir's files uncached, so edits show on the next
+// load. With spa, GET and HEAD requests for paths without a file get
+// dir/index.html instead of a 404; paths with an extension, like a
+// missing script, still 404.
+func FixtureHandler(dir string, spa bool, log io.Writer) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		rec := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		if spa && (r.Method == http.MethodGet || r.Method == http.MethodHead) && missing(dir, r.URL.Path) {
+			http.ServeFile(rec, r, filepath.Join(dir, "index.html"))
+		} else {
+			files.ServeHTTP(rec, r)
+		}
+		if log != nil {
+			fmt.Fprintf(log, "%s %s %s %d\n", time.Now().Format("15:04:05"), r.Method, r.URL.RequestURI(), rec.status)
+		}
+	})
+}
+
+// missing reports whether urlPath names no file in dir and has no
+// extension, so looks like a client-side route.
+func missing(dir string, urlPath string) bool {
+	clean := path.Clean("/" + urlPath)
+	if path.Ext(clean) != "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(clean)))
+	return errors.Is(err, os.ErrNotExist)
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,401 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// InterruptContext returns a context that ends on the first Ctrl+C or
+// SIGTERM, so a follow mode can flush what it has, finish its files, and
+// undo what it changed in the tab before exiting. Once it has ended,
+// signals are back to their defaults: a second Ctrl+C exits at once.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// WaitTimeout waits for wg, giving up after timeout. It reports whether
+// wg finished.
+func WaitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// EventRing is a fixed-size FIFO between an event listener and the writer
+// that drains it. Listeners run on chromedp's event goroutine, which must
+// not block (every event and command reply for the tab waits behind it), so
+// Push never blocks: when the writer falls behind and the ring is full, the
+// oldest event is overwritten and counted, and Pop reports the count. Memory
+// stays at the ring's size however long a capture runs.
+type EventRing[T any] struct {
+	mu      sync.Mutex
+	items   []T
+	head    int
+	count   int
+	dropped int
+	ready   chan struct{}
+}
+
+// NewEventRing returns a ring holding up to size events.
+func NewEventRing[T any](size int) *EventRing[T] {
+	return &EventRing[T]{items: make([]T, max(size, 1)), ready: make(chan struct{}, 1)}
+}
+
+// Push adds an event, overwriting the oldest when the ring is full. It is
+// safe to call from several goroutines.
+func (r *EventRing[T]) Push(v T) {
+	r.mu.Lock()
+	if r.count == len(r.items) {
+		r.items[r.head] = v
+		r.head = (r.head + 1) % len(r.items)
+		r.dropped++
+	} else {
+		r.items[(r.head+r.count)%len(r.items)] = v
+		r.count++
+	}
+	r.mu.Unlock()
+	select {
+	case r.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Pop returns the oldest event and how many were dropped just before it,
+// waiting for one to arrive. Queued events are returned even after ctx
+// ends; ok is false once ctx has ended and the ring is empty.
+func (r *EventRing[T]) Pop(ctx context.Context) (v T, dropped int, ok bool) {
+	for {
+		r.mu.Lock()
+		if r.count > 0 {
+			var zero T
+			v = r.items[r.head]
+			r.items[r.head] = zero
+			r.head = (r.head + 1) % len(r.items)
+			r.count--
+			dropped = r.dropped
+			r.dropped = 0
+			r.mu.Unlock()
+			return v, dropped, true
+		}
+		r.mu.Unlock()
+		select {
+		case <-r.ready:
+		case <-ctx.Done():
+			return v, 0, false
+		}
+	}
+}
+
+// OutArgs provides --out and its rotation flags for follow-mode commands.
+// Embed it in command arg structs, like TargetArgs.
+type OutArgs struct {
+	Out         string `arg:"--out" help:"write NDJSON to this file instead of stdout"`
+	RotateSize  string `arg:"--rotate-size" help:"rotate --out when it reaches this size, e.g. 10MB (default: never)"`
+	RotateEvery string `arg:"--rotate-every" help:"rotate --out when it is this old, e.g. 1h or 30m (default: never)"`
+	Keep        int    `arg:"--keep" default:"5" help:"rotated files to keep (FILE.1 is the newest)"`
+}
+
+// OpenSink opens the EventSink the flags describe.
+func (a OutArgs) OpenSink() (*EventSink, error) {
+	opts := RotateOptions{Keep: a.Keep}
+	if a.RotateSize != "" {
+		size, err := ParseByteSize(a.RotateSize)
+		if err != nil {
+			return nil, fmt.Errorf("--rotate-size: %w", err)
+		}
+		opts.MaxSize = size
+	}
+	if a.RotateEvery != "" {
+		every, err := time.ParseDuration(a.RotateEvery)
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("--rotate-every: invalid duration %q, expected e.g. 1h or 30m", a.RotateEvery)
+		}
+		opts.Every = every
+	}
+	return OpenEventSink(a.Out, opts)
+}
+
+// RotateOptions say when a RotatingWriter starts a new file, and how many
+// old ones it keeps.
+type RotateOptions struct {
+	// MaxSize rotates before a write would take the file past it, in bytes
+	MaxSize int64
+	// Every rotates once the file has been written to for this long
+	Every time.Duration
+	// Keep is how many rotated files to keep (at least 1)
+	Keep int
+}
+
+// RotatingWriter appends to a file and, once a write would take it past
+// MaxSize bytes or it is Every old, renames it to <path>.1 (shifting older
+// ones to .2, .3, ..., keeping Keep of them) and starts a new one. Age is
+// checked on each write, so a quiet file rotates with its next write.
+type RotatingWriter struct {
+	path   string
+	opts   RotateOptions
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// OpenRotatingWriter opens path for appending. Zero MaxSize and Every
+// never rotate.
+func OpenRotatingWriter(path string, opts RotateOptions) (*RotatingWriter, error) {
+	opts.Keep = max(opts.Keep, 1)
+	w := &RotatingWriter{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	w.file, w.size, w.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	full := w.opts.MaxSize > 0 && w.size+int64(len(p)) > w.opts.MaxSize
+	old := w.opts.Every > 0 && time.Since(w.opened) >= w.opts.Every
+	if w.size > 0 && (full || old) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	_ = os.Remove(fmt.Sprintf("%s.%d", w.path, w.opts.Keep))
+	for i := w.opts.Keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+	if err := os.Rename(w.path, w.path+".1"); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the current file.
+func (w *RotatingWriter) Close() error {
+	return w.file.Close()
+}
+
+// EventSink writes follow-mode events as NDJSON to stdout or a rotating file.
+type EventSink struct {
+	w     io.Writer
+	file  *RotatingWriter
+	total int
+}
+
+// OpenEventSink writes to path, rotated as opts says, or to stdout when
+// path is empty.
+func OpenEventSink(path string, opts RotateOptions) (*EventSink, error) {
+	if path == "" {
+		return &EventSink{w: os.Stdout}, nil
+	}
+	file, err := OpenRotatingWriter(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &EventSink{w: file, file: file}, nil
+}
+
+// Write writes one event as a JSON line. dropped, when non-zero, first
+// writes a {"type": "dropped"} line counting the events lost before it.
+func (s *EventSink) Write(v any, dropped int) error {
+	if dropped > 0 {
+		s.total += dropped
+		marker := map[string]any{"type": "dropped", "count": dropped, "timestamp": time.Now()}
+		if err := s.line(marker); err != nil {
+			return err
+		}
+	}
+	return s.line(v)
+}
+
+func (s *EventSink) line(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// Dropped returns the number of events lost so far.
+func (s *EventSink) Dropped() int {
+	return s.total
+}
+
+// Close closes the file, if any.
+func (s *EventSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// TailLines calls fn with the last n lines of path (every line when n < 0)
+// and, with follow, with each line appended after, until ctx ends. It
+// follows rotation: when path is replaced or truncated, the new file is
+// read from its start. A line still being written is held until its
+// newline arrives.
+func TailLines(ctx context.Context, path string, n int, follow bool, fn func(line []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	var partial []byte
+	read := func(emit func([]byte) error) error {
+		for {
+			chunk, err := reader.ReadBytes('\n')
+			offset += int64(len(chunk))
+			partial = append(partial, chunk...)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			line := bytes.TrimRight(partial, "\r\n")
+			partial = nil
+			if err := emit(line); err != nil {
+				return err
+			}
+		}
+	}
+
+	var last [][]byte
+	err = read(func(line []byte) error {
+		if n != 0 {
+			last = append(last, line)
+		}
+		if n > 0 && len(last) > n {
+			last = last[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, line := range last {
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := read(fn); err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			// Mid-rotation, the new file is not there yet
+			continue
+		}
+		current, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		switch {
+		case !os.SameFile(info, current):
+			next, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			_ = file.Close()
+			file = next
+		case info.Size() < offset:
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		reader.Reset(file)
+		offset, partial = 0, nil
+	}
+}
+
+// ParseByteSize parses sizes like 512, 64k, 10MB, or 1GiB (units are
+// powers of 1024).
+func ParseByteSize(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "ib"), "b")
+	mult := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected bytes or a size like 64k, 10MB, 1GiB", value)
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,131 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"512", 512},
+		{"64k", 64 << 10},
+		{"64K", 64 << 10},
+		{"10MB", 10 << 20},
+		{"10mb", 10 << 20},
+		{"1GiB", 1 << 30},
+		{"1.5k", 1536},
+		{" 2m ", 2 << 20},
+		{"100b", 100},
+	}
+	for _, c := range cases {
+		got, err := ParseByteSize(c.in)
+		if err != nil {
+			t.Errorf("ParseByteSize(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+	for _, in := range []string{"", "MB", "ten", "-5k", "5x"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingWriterSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "events.ndjson")
+	w, err := OpenRotatingWriter(path, RotateOptions{MaxSize: 10, Keep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		if _, err := fmt.Fprintf(w, "line%d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Each 6 byte line would take the file past 10 bytes, so each rotates;
+	// Keep 2 drops line1
+	if got := readFile(t, path); got != "line4\n" {
+		t.Fatalf("current file %q", got)
+	}
+	if got := readFile(t, path+".1"); got != "line3\n" {
+		t.Fatalf(".1 %q", got)
+	}
+	if got := readFile(t, path+".2"); got != "line2\n" {
+		t.Fatalf(".2 %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf(".3 should not exist: %v", err)
+	}
+}
+
+func TestRotatingWriterAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := OpenRotatingWriter(path, RotateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := w.Write([]byte("new\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := readFile(t, path)
+	if !strings.HasPrefix(got, "old\nnew\n") || strings.Count(got, "\n") != 101 {
+		t.Fatalf("without limits the file should only grow, got %d lines", strings.Count(got, "\n"))
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("rotated without limits: %v", err)
+	}
+}
+
+func TestRotatingWriterEvery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	w, err := OpenRotatingWriter(path, RotateOptions{Every: 50 * time.Millisecond, Keep: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := w.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path+".1"); got != "first\n" {
+		t.Fatalf(".1 %q", got)
+	}
+	if got := readFile(t, path); got != "second\n" {
+		t.Fatalf("current file %q", got)
+	}
+}
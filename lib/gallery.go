@@ -0,0 +1,234 @@
+package lib
+
+import (
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultThumbnailWidth is the width of gallery thumbnails in pixels.
+const DefaultThumbnailWidth = 320
+
+// GalleryOptions controls WriteGallery.
+type GalleryOptions struct {
+	// Output is the HTML path (default: <dir>/index.html, or index.html in
+	// the run's directory with Run).
+	Output string
+	// Run limits the gallery to one run, as accepted by LoadRunRecords.
+	Run string
+	// ThumbWidth is the thumbnail width in pixels (default: DefaultThumbnailWidth).
+	ThumbWidth int
+}
+
+type galleryItem struct {
+	Image     string
+	Thumb     string
+	Label     string
+	Command   string
+	Target    string
+	Note      string
+	Status    string
+	Error     string
+	Timestamp string
+}
+
+// WriteGallery writes a static HTML index of the step records in dir, newest
+// first, with thumbnails cached under <dir>/thumbs. Links are relative to the
+// index so the directory can be copied or served as is. It returns the index
+// path and the number of entries.
+func WriteGallery(dir string, opts GalleryOptions) (string, int, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", 0, err
+	}
+	output := strings.TrimSpace(opts.Output)
+	if output == "" {
+		output = filepath.Join(absDir, "index.html")
+	}
+	var records []StepRecord
+	if run := strings.TrimSpace(opts.Run); run != "" {
+		var runID string
+		if runID, records, err = LoadRunRecords(dir, run); err != nil {
+			return "", 0, err
+		}
+		if runDir, err := RunDir(dir, runID); err == nil && strings.TrimSpace(opts.Output) == "" {
+			if info, err := os.Stat(runDir); err == nil && info.IsDir() {
+				output = filepath.Join(runDir, "index.html")
+			}
+		}
+	} else if records, err = LoadStepRecordsFromDir(dir); err != nil {
+		return "", 0, err
+	}
+	output, err = filepath.Abs(output)
+	if err != nil {
+		return "", 0, err
+	}
+	width := opts.ThumbWidth
+	if width <= 0 {
+		width = DefaultThumbnailWidth
+	}
+	base := filepath.Dir(output)
+
+	items := make([]galleryItem, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		item := galleryItem{
+			Image:   relativeURL(base, record.Screenshot),
+			Label:   record.Label,
+			Command: strings.TrimSpace(record.Action + " " + strings.Join(record.Args, " ")),
+			Target:  record.Target,
+			Note:    record.Note,
+			Status:  record.Status,
+			Error:   record.Error,
+		}
+		if item.Label == "" {
+			item.Label = strings.TrimSuffix(filepath.Base(record.Screenshot), filepath.Ext(record.Screenshot))
+		}
+		if !record.CreatedAt.IsZero() {
+			item.Timestamp = record.CreatedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		item.Thumb = item.Image
+		thumb := filepath.Join(absDir, "thumbs", filepath.Base(record.Screenshot)+".jpg")
+		if err := writeThumbnail(record.Screenshot, thumb, width); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: thumbnail for %s: %v\n", record.Screenshot, err)
+		} else {
+			item.Thumb = relativeURL(base, thumb)
+		}
+		items = append(items, item)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return "", 0, err
+	}
+	data := struct {
+		Dir       string
+		Generated string
+		Items     []galleryItem
+	}{absDir, time.Now().Format("2006-01-02 15:04:05"), items}
+	if err := galleryTemplate.Execute(f, data); err != nil {
+		_ = f.Close()
+		return "", 0, err
+	}
+	return output, len(items), f.Close()
+}
+
+func relativeURL(base string, path string) string {
+	if rel, err := filepath.Rel(base, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// writeThumbnail scales src to width as a jpeg at dst, skipping the work when
+// dst is already newer than src.
+func writeThumbnail(src string, dst string, width int) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if dstInfo, err := os.Stat(dst); err == nil && !dstInfo.ModTime().Before(srcInfo.ModTime()) {
+		return nil
+	}
+	img, err := LoadImage(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(f, scaleToWidth(img, width), &jpeg.Options{Quality: 80}); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// scaleToWidth downsamples img by averaging each source box, which is enough
+// for thumbnails and avoids pulling in an image scaling dependency.
+func scaleToWidth(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= width || b.Dx() == 0 {
+		return img
+	}
+	height := max(1, b.Dy()*width/b.Dx())
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/height, b.Min.Y+(y+1)*b.Dy()/height
+		for x := 0; x < width; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/width, b.Min.X+(x+1)*b.Dx()/width
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+cr, g+cg, bl+cb, a+ca, n+1
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+	return out
+}
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>chrome shots</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 24px; background: #f5f5f5; color: #222; }
+header { margin-bottom: 16px; color: #666; font-size: 14px; }
+input { padding: 6px 10px; width: 320px; font-size: 14px; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(280px, 1fr)); gap: 16px; }
+.card { background: #fff; border-radius: 6px; box-shadow: 0 1px 3px rgba(0,0,0,.15); overflow: hidden; }
+.card img { width: 100%; display: block; border-bottom: 1px solid #eee; }
+.meta { padding: 8px 10px; font-size: 13px; }
+.label { font-weight: 600; }
+.cmd, .target { font-family: ui-monospace, monospace; font-size: 12px; color: #555; word-break: break-all; }
+.note { margin-top: 4px; }
+.time { color: #888; font-size: 12px; margin-top: 4px; }
+.failed { outline: 3px solid #d33; }
+.error { color: #d33; margin-top: 4px; }
+</style>
+</head>
+<body>
+<header>
+{{ len .Items }} screenshot(s) in {{ .Dir }}, generated {{ .Generated }}
+<input id="filter" placeholder="filter by label, command, note" autofocus>
+</header>
+<div class="grid">
+{{- range .Items }}
+<div class="card{{ if eq .Status "failed" }} failed{{ end }}">
+<a href="{{ .Image }}"><img src="{{ .Thumb }}" loading="lazy" alt="{{ .Label }}"></a>
+<div class="meta">
+<div class="label">{{ .Label }}</div>
+<div class="cmd">{{ .Command }}</div>
+{{- if .Target }}<div class="target">{{ .Target }}</div>{{ end }}
+{{- if .Note }}<div class="note">{{ .Note }}</div>{{ end }}
+{{- if .Error }}<div class="error">{{ .Error }}</div>{{ end }}
+{{- if .Timestamp }}<div class="time">{{ .Timestamp }}</div>{{ end }}
+</div>
+</div>
+{{- end }}
+</div>
+<script>
+document.getElementById('filter').addEventListener('input', e => {
+  const q = e.target.value.toLowerCase();
+  for (const card of document.querySelectorAll('.card')) {
+    card.style.display = card.textContent.toLowerCase().includes(q) ? '' : 'none';
+  }
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,326 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// HAR is an HTTP Archive (HAR 1.2) file, as saved by chrome har, Chrome
+// DevTools ("Save all as HAR"), and most proxies. Fields prefixed with _
+// are Chrome's extensions.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HARPage struct {
+	StartedDateTime string `json:"startedDateTime"`
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	PageTimings     struct {
+		OnContentLoad float64 `json:"onContentLoad"`
+		OnLoad        float64 `json:"onLoad"`
+	} `json:"pageTimings"`
+}
+
+type HAREntry struct {
+	Pageref         string      `json:"pageref,omitempty"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"` // ms from start to the end of the response
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []NameValue  `json:"cookies"`
+	Headers     []NameValue  `json:"headers"`
+	QueryString []NameValue  `json:"queryString"`
+	PostData    *HARPostData `json:"postData,omitempty"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARResponse struct {
+	Status       int         `json:"status"`
+	StatusText   string      `json:"statusText"`
+	HTTPVersion  string      `json:"httpVersion"`
+	Cookies      []NameValue `json:"cookies"`
+	Headers      []NameValue `json:"headers"`
+	Content      HARContent  `json:"content"`
+	RedirectURL  string      `json:"redirectURL"`
+	HeadersSize  int64       `json:"headersSize"`
+	BodySize     int64       `json:"bodySize"`
+	TransferSize int64       `json:"_transferSize"` // bytes on the wire
+	Error        string      `json:"_error,omitempty"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings are the phases of an entry's time in ms, -1 when a phase did
+// not happen (no DNS lookup on a reused connection).
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// LoadHAR reads a HAR file.
+func LoadHAR(path string) (HAR, error) {
+	var har HAR
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return har, err
+	}
+	if err := json.Unmarshal(data, &har); err != nil {
+		return har, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(har.Log.Entries) == 0 {
+		return har, fmt.Errorf("%s has no entries", path)
+	}
+	return har, nil
+}
+
+// Bytes is what the entry cost on the wire, falling back to the body and
+// headers, then the decoded size, for HARs without _transferSize.
+func (e HAREntry) Bytes() int64 {
+	r := e.Response
+	if r.TransferSize > 0 {
+		return r.TransferSize
+	}
+	if r.BodySize >= 0 && r.HeadersSize >= 0 && r.BodySize+r.HeadersSize > 0 {
+		return r.BodySize + r.HeadersSize
+	}
+	return max(r.Content.Size, 0)
+}
+
+// Domain is the host the entry was requested from.
+func (e HAREntry) Domain() string {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil || u.Host == "" {
+		return "(none)"
+	}
+	return u.Hostname()
+}
+
+// Type is Chrome's resource type, or one guessed from the MIME type.
+func (e HAREntry) Type() string {
+	if e.ResourceType != "" {
+		return strings.ToLower(e.ResourceType)
+	}
+	mime := strings.ToLower(e.Response.Content.MimeType)
+	switch {
+	case strings.Contains(mime, "html"):
+		return "document"
+	case strings.Contains(mime, "javascript") || strings.Contains(mime, "ecmascript"):
+		return "script"
+	case strings.Contains(mime, "css"):
+		return "stylesheet"
+	case strings.HasPrefix(mime, "image/"):
+		return "image"
+	case strings.HasPrefix(mime, "font/") || strings.Contains(mime, "font"):
+		return "font"
+	case strings.Contains(mime, "json") || strings.Contains(mime, "xml"):
+		return "fetch"
+	case strings.HasPrefix(mime, "video/") || strings.HasPrefix(mime, "audio/"):
+		return "media"
+	}
+	return "other"
+}
+
+// Failed reports a request that got no response or an error status.
+func (e HAREntry) Failed() bool {
+	return e.Response.Status == 0 || e.Response.Status >= 400
+}
+
+// PerfStats summarizes a group of HAR entries. Times are in ms.
+type PerfStats struct {
+	Requests int     `json:"requests"`
+	Failed   int     `json:"failed"`
+	Bytes    int64   `json:"bytes"`
+	P50      float64 `json:"p50_ms"`
+	P95      float64 `json:"p95_ms"`
+	Max      float64 `json:"max_ms"`
+}
+
+func perfStats(entries []HAREntry) PerfStats {
+	var s PerfStats
+	var times []float64
+	for _, e := range entries {
+		s.Requests++
+		s.Bytes += e.Bytes()
+		if e.Failed() {
+			s.Failed++
+		}
+		if e.Time >= 0 {
+			times = append(times, e.Time)
+		}
+	}
+	sort.Float64s(times)
+	s.P50 = percentile(times, 50)
+	s.P95 = percentile(times, 95)
+	if len(times) > 0 {
+		s.Max = times[len(times)-1]
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile p of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// PerfDiffRow compares one group of requests (all, a resource type, or a
+// domain) between two HARs.
+type PerfDiffRow struct {
+	Group       string    `json:"group"` // total, type, or domain
+	Key         string    `json:"key"`
+	A           PerfStats `json:"a"`
+	B           PerfStats `json:"b"`
+	Regressions []string  `json:"regressions,omitempty"`
+}
+
+// PerfDiff is the comparison of two HARs, run A (before) and B (after).
+type PerfDiff struct {
+	Rows      []PerfDiffRow `json:"rows"`
+	LoadA     float64       `json:"load_ms_a,omitempty"` // first page's onLoad
+	LoadB     float64       `json:"load_ms_b,omitempty"`
+	Regressed bool          `json:"regressed"`
+}
+
+// PerfThresholds decide when a change in B counts as a regression: it must
+// grow by more than Percent and by more than the absolute minimum, so small
+// groups do not flag on noise.
+type PerfThresholds struct {
+	Percent  float64
+	MinMs    float64
+	MinBytes int64
+}
+
+// ComparePerf compares two HARs overall, per resource type, and per domain.
+func ComparePerf(a HAR, b HAR, t PerfThresholds) PerfDiff {
+	var diff PerfDiff
+	group := func(name string, key func(HAREntry) string) {
+		byKey := map[string][2][]HAREntry{}
+		for i, har := range []HAR{a, b} {
+			for _, e := range har.Log.Entries {
+				k := key(e)
+				pair := byKey[k]
+				pair[i] = append(pair[i], e)
+				byKey[k] = pair
+			}
+		}
+		keys := make([]string, 0, len(byKey))
+		for k := range byKey {
+			keys = append(keys, k)
+		}
+		// Biggest first, by B's bytes then A's
+		sort.Slice(keys, func(i, j int) bool {
+			bi, bj := perfStats(byKey[keys[i]][1]).Bytes, perfStats(byKey[keys[j]][1]).Bytes
+			if bi != bj {
+				return bi > bj
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys {
+			row := PerfDiffRow{Group: name, Key: k, A: perfStats(byKey[k][0]), B: perfStats(byKey[k][1])}
+			row.Regressions = regressions(row.A, row.B, t)
+			if len(row.Regressions) > 0 {
+				diff.Regressed = true
+			}
+			diff.Rows = append(diff.Rows, row)
+		}
+	}
+	group("total", func(HAREntry) string { return "all" })
+	group("type", HAREntry.Type)
+	group("domain", HAREntry.Domain)
+	if len(a.Log.Pages) > 0 {
+		diff.LoadA = a.Log.Pages[0].PageTimings.OnLoad
+	}
+	if len(b.Log.Pages) > 0 {
+		diff.LoadB = b.Log.Pages[0].PageTimings.OnLoad
+	}
+	return diff
+}
+
+// regressions names the stats that got worse from a to b beyond t.
+func regressions(a PerfStats, b PerfStats, t PerfThresholds) []string {
+	var out []string
+	worse := func(before float64, after float64, min float64) bool {
+		delta := after - before
+		if delta <= min {
+			return false
+		}
+		return before == 0 || delta/before*100 > t.Percent
+	}
+	if worse(float64(a.Requests), float64(b.Requests), 0) && b.Requests-a.Requests > 1 {
+		out = append(out, "requests")
+	}
+	if b.Failed > a.Failed {
+		out = append(out, "failed")
+	}
+	if worse(float64(a.Bytes), float64(b.Bytes), float64(t.MinBytes)) {
+		out = append(out, "bytes")
+	}
+	if worse(a.P50, b.P50, t.MinMs) {
+		out = append(out, "p50")
+	}
+	if worse(a.P95, b.P95, t.MinMs) {
+		out = append(out, "p95")
+	}
+	return out
+}
+
+// FormatBytes prints n in B, KB, or MB (powers of 1024).
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
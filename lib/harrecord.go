@@ -0,0 +1,508 @@
+package lib
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// HAROptions control what a HARRecorder keeps.
+type HAROptions struct {
+	Bodies      bool  // fetch response bodies
+	MaxBodySize int64 // skip bodies larger than this many bytes, 0 for no limit
+}
+
+// HARRecorder builds HAR entries from the tab's Network events, from
+// RecordHAR until HAR is called.
+type HARRecorder struct {
+	ctx      context.Context
+	opts     HAROptions
+	mu       sync.Mutex
+	pending  map[network.RequestID]*harRequest
+	finished []*harRequest
+	pages    []*harPage
+	bodies   sync.WaitGroup
+	idle     time.Time // when the last request finished
+	frame    cdp.FrameID
+}
+
+type harRequest struct {
+	entry    HAREntry
+	start    float64 // monotonic seconds
+	wall     time.Time
+	timing   *network.ResourceTiming
+	hasPost  bool
+	received int64
+	page     *harPage
+}
+
+type harPage struct {
+	page  HARPage
+	start float64 // monotonic seconds
+	wall  time.Time
+}
+
+// RecordHAR starts recording the tab's network traffic. Requests already
+// in flight are left out.
+func RecordHAR(ctx context.Context, opts HAROptions) (*HARRecorder, error) {
+	r := &HARRecorder{
+		ctx:     ctx,
+		opts:    opts,
+		pending: map[network.RequestID]*harRequest{},
+		idle:    time.Now(),
+	}
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return err
+		}
+		r.frame = tree.Frame.ID
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	chromedp.ListenTarget(ctx, r.handle)
+	if err := chromedp.Run(ctx, network.Enable(), page.Enable()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func monotonic(t *cdp.MonotonicTime) float64 {
+	if t == nil {
+		return 0
+	}
+	return float64(t.Time().Sub(*cdp.MonotonicTimeEpoch)) / float64(time.Second)
+}
+
+func (r *HARRecorder) handle(ev any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if prev, ok := r.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+			// A redirect reuses the request ID, finish the hop that led here
+			r.respond(prev, ev.RedirectResponse)
+			prev.entry.Response.RedirectURL = ev.Request.URL
+			r.finish(ev.RequestID, prev, monotonic(ev.Timestamp), int64(ev.RedirectResponse.EncodedDataLength))
+		}
+		// A document request for the top frame starts a page; redirects
+		// along the way stay on it
+		if ev.Type == network.ResourceTypeDocument && ev.FrameID == r.frame && ev.RedirectResponse == nil {
+			r.newPage(ev)
+		}
+		r.pending[ev.RequestID] = r.newRequest(ev)
+	case *network.EventRequestWillBeSentExtraInfo:
+		// Raw headers, with the cookies the browser added
+		if req, ok := r.pending[ev.RequestID]; ok && len(ev.Headers) > 0 {
+			req.entry.Request.Headers = harHeaders(ev.Headers)
+			req.entry.Request.Cookies = requestCookies(req.entry.Request.Headers)
+		}
+	case *network.EventResponseReceived:
+		if req, ok := r.pending[ev.RequestID]; ok {
+			r.respond(req, ev.Response)
+		}
+	case *network.EventResponseReceivedExtraInfo:
+		if req, ok := r.pending[ev.RequestID]; ok && len(ev.Headers) > 0 {
+			req.entry.Response.Headers = harHeaders(ev.Headers)
+			req.entry.Response.Cookies = responseCookies(req.entry.Response.Headers)
+			if ev.HeadersText != "" {
+				req.entry.Response.HeadersSize = int64(len(ev.HeadersText))
+			}
+		}
+	case *network.EventDataReceived:
+		if req, ok := r.pending[ev.RequestID]; ok {
+			req.received += ev.DataLength
+		}
+	case *network.EventLoadingFinished:
+		if req, ok := r.pending[ev.RequestID]; ok {
+			r.finish(ev.RequestID, req, monotonic(ev.Timestamp), int64(ev.EncodedDataLength))
+			r.fetchBodies(ev.RequestID, req)
+		}
+	case *network.EventLoadingFailed:
+		if req, ok := r.pending[ev.RequestID]; ok {
+			req.entry.Response.Error = ev.ErrorText
+			if ev.BlockedReason != "" {
+				req.entry.Response.Error += " (" + string(ev.BlockedReason) + ")"
+			}
+			r.finish(ev.RequestID, req, monotonic(ev.Timestamp), 0)
+		}
+	case *page.EventDomContentEventFired:
+		if p := r.lastPage(); p != nil && p.page.PageTimings.OnContentLoad < 0 {
+			p.page.PageTimings.OnContentLoad = (monotonic(ev.Timestamp) - p.start) * 1000
+		}
+	case *page.EventLoadEventFired:
+		if p := r.lastPage(); p != nil && p.page.PageTimings.OnLoad < 0 {
+			p.page.PageTimings.OnLoad = (monotonic(ev.Timestamp) - p.start) * 1000
+		}
+	}
+}
+
+func (r *HARRecorder) lastPage() *harPage {
+	if len(r.pages) == 0 {
+		return nil
+	}
+	return r.pages[len(r.pages)-1]
+}
+
+func (r *HARRecorder) newPage(ev *network.EventRequestWillBeSent) {
+	p := &harPage{start: monotonic(ev.Timestamp), wall: wallTime(ev)}
+	p.page.ID = fmt.Sprintf("page_%d", len(r.pages)+1)
+	p.page.Title = ev.Request.URL
+	p.page.StartedDateTime = p.wall.UTC().Format(time.RFC3339Nano)
+	p.page.PageTimings.OnContentLoad = -1
+	p.page.PageTimings.OnLoad = -1
+	r.pages = append(r.pages, p)
+}
+
+func wallTime(ev *network.EventRequestWillBeSent) time.Time {
+	if ev.WallTime != nil {
+		return ev.WallTime.Time()
+	}
+	return time.Now()
+}
+
+func (r *HARRecorder) newRequest(ev *network.EventRequestWillBeSent) *harRequest {
+	req := &harRequest{start: monotonic(ev.Timestamp), wall: wallTime(ev), page: r.lastPage()}
+	fullURL := ev.Request.URL + ev.Request.URLFragment
+	req.entry = HAREntry{
+		StartedDateTime: req.wall.UTC().Format(time.RFC3339Nano),
+		ResourceType:    strings.ToLower(string(ev.Type)),
+		Request: HARRequest{
+			Method:      ev.Request.Method,
+			URL:         fullURL,
+			Headers:     harHeaders(ev.Request.Headers),
+			QueryString: queryString(fullURL),
+			HeadersSize: -1,
+		},
+		Response: HARResponse{
+			Cookies:     []NameValue{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+	req.entry.Request.Cookies = requestCookies(req.entry.Request.Headers)
+	if ev.Request.HasPostData {
+		var body []byte
+		for _, e := range ev.Request.PostDataEntries {
+			b, err := base64.StdEncoding.DecodeString(e.Bytes)
+			if err == nil {
+				body = append(body, b...)
+			}
+		}
+		req.entry.Request.PostData = &HARPostData{MimeType: headerValue(req.entry.Request.Headers, "Content-Type"), Text: string(body)}
+		req.entry.Request.BodySize = int64(len(body))
+		// Large bodies are left out of the event, fetch them when the request ends
+		req.hasPost = len(ev.Request.PostDataEntries) == 0
+	}
+	return req
+}
+
+func (r *HARRecorder) respond(req *harRequest, resp *network.Response) {
+	res := &req.entry.Response
+	res.Status = int(resp.Status)
+	res.StatusText = resp.StatusText
+	res.HTTPVersion = httpVersion(resp.Protocol)
+	req.entry.Request.HTTPVersion = res.HTTPVersion
+	if len(res.Headers) == 0 {
+		res.Headers = harHeaders(resp.Headers)
+		res.Cookies = responseCookies(res.Headers)
+	}
+	if len(resp.RequestHeaders) > 0 {
+		req.entry.Request.Headers = harHeaders(resp.RequestHeaders)
+		req.entry.Request.Cookies = requestCookies(req.entry.Request.Headers)
+	}
+	res.Content.MimeType = resp.MimeType
+	if resp.Charset != "" && !strings.Contains(resp.MimeType, "charset") {
+		res.Content.MimeType += "; charset=" + resp.Charset
+	}
+	req.entry.ServerIPAddress = strings.Trim(resp.RemoteIPAddress, "[]")
+	if resp.ConnectionID > 0 {
+		req.entry.Connection = fmt.Sprint(int64(resp.ConnectionID))
+	}
+	req.timing = resp.Timing
+}
+
+// finish computes the entry's timings and moves it to the finished list.
+func (r *HARRecorder) finish(id network.RequestID, req *harRequest, end float64, transferred int64) {
+	delete(r.pending, id)
+	res := &req.entry.Response
+	res.TransferSize = transferred
+	res.Content.Size = req.received
+	if transferred > 0 && res.HeadersSize >= 0 {
+		res.BodySize = max(transferred-res.HeadersSize, 0)
+	} else if transferred == 0 && res.Status > 0 {
+		res.BodySize = 0 // from cache
+	}
+	req.entry.Timings = harTimings(req, end)
+	req.entry.Time = 0
+	for _, v := range []float64{req.entry.Timings.Blocked, req.entry.Timings.DNS, req.entry.Timings.Connect, req.entry.Timings.Send, req.entry.Timings.Wait, req.entry.Timings.Receive} {
+		if v > 0 {
+			req.entry.Time += v
+		}
+	}
+	if req.page != nil {
+		req.entry.Pageref = req.page.page.ID
+	}
+	r.finished = append(r.finished, req)
+	if len(r.pending) == 0 {
+		r.idle = time.Now()
+	}
+}
+
+// harTimings splits the request's time into HAR phases the way Chrome's
+// own HAR export does: ResourceTiming offsets are ms after requestTime.
+func harTimings(req *harRequest, end float64) HARTimings {
+	t := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	total := max((end-req.start)*1000, 0)
+	rt := req.timing
+	if rt == nil || rt.RequestTime == 0 {
+		// Served from memory or data: URLs, no network phases
+		t.Wait = total
+		return t
+	}
+	queued := max((rt.RequestTime-req.start)*1000, 0)
+	firstPhase := rt.SendStart
+	for _, v := range []float64{rt.ConnectStart, rt.DNSStart} {
+		if v >= 0 && v < firstPhase {
+			firstPhase = v
+		}
+	}
+	t.Blocked = queued + max(firstPhase, 0)
+	if rt.DNSStart >= 0 {
+		t.DNS = rt.DNSEnd - rt.DNSStart
+	}
+	if rt.ConnectStart >= 0 {
+		t.Connect = rt.ConnectEnd - rt.ConnectStart
+	}
+	if rt.SslStart >= 0 {
+		t.SSL = rt.SslEnd - rt.SslStart
+	}
+	t.Send = max(rt.SendEnd-rt.SendStart, 0)
+	t.Wait = max(rt.ReceiveHeadersEnd-rt.SendEnd, 0)
+	t.Receive = max((end-rt.RequestTime)*1000-rt.ReceiveHeadersEnd, 0)
+	return t
+}
+
+// fetchBodies reads the response body and large request bodies, which
+// Chrome only keeps until the page evicts them.
+func (r *HARRecorder) fetchBodies(id network.RequestID, req *harRequest) {
+	wantBody := r.opts.Bodies && req.entry.Response.Status != 204 && req.entry.Response.Status/100 != 3 &&
+		(r.opts.MaxBodySize <= 0 || req.received <= r.opts.MaxBodySize)
+	if !wantBody && !req.hasPost {
+		return
+	}
+	c := chromedp.FromContext(r.ctx)
+	if c == nil || c.Target == nil {
+		return
+	}
+	ctx := cdp.WithExecutor(r.ctx, c.Target)
+	r.bodies.Add(1)
+	go func() {
+		defer r.bodies.Done()
+		if req.hasPost {
+			if text, err := network.GetRequestPostData(id).Do(ctx); err == nil {
+				r.mu.Lock()
+				req.entry.Request.PostData.Text = text
+				req.entry.Request.BodySize = int64(len(text))
+				r.mu.Unlock()
+			}
+		}
+		if !wantBody {
+			return
+		}
+		body, err := network.GetResponseBody(id).Do(ctx)
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		content := &req.entry.Response.Content
+		if textual(content.MimeType) && utf8.Valid(body) {
+			content.Text = string(body)
+		} else {
+			content.Text = base64.StdEncoding.EncodeToString(body)
+			content.Encoding = "base64"
+		}
+		if content.Size == 0 {
+			content.Size = int64(len(body))
+		}
+	}()
+}
+
+// WaitIdle blocks until no request has been in flight for quiet, or max
+// passes, or ctx ends.
+func (r *HARRecorder) WaitIdle(ctx context.Context, quiet time.Duration, maxWait time.Duration) {
+	deadline := time.Now().Add(maxWait)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		idle := len(r.pending) == 0 && time.Since(r.idle) >= quiet
+		r.mu.Unlock()
+		if idle {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// HAR stops collecting and returns the HAR so far, after waiting up to
+// DefaultTimeout for bodies still being read. Requests that have not
+// finished are left out and counted.
+func (r *HARRecorder) HAR(ctx context.Context) (HAR, int) {
+	done := make(chan struct{})
+	go func() {
+		r.bodies.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(DefaultTimeout):
+	}
+
+	var title string
+	_ = chromedp.Run(ctx, chromedp.Title(&title))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var har HAR
+	har.Log.Version = "1.2"
+	har.Log.Creator = HARCreator{Name: "chrome", Version: buildVersion()}
+	har.Log.Pages = []HARPage{}
+	for i, p := range r.pages {
+		if i == len(r.pages)-1 && title != "" {
+			p.page.Title = title
+		}
+		har.Log.Pages = append(har.Log.Pages, p.page)
+	}
+	har.Log.Entries = []HAREntry{}
+	sort.SliceStable(r.finished, func(i, j int) bool { return r.finished[i].start < r.finished[j].start })
+	for _, req := range r.finished {
+		har.Log.Entries = append(har.Log.Entries, req.entry)
+	}
+	return har, len(r.pending)
+}
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+func httpVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "":
+		return ""
+	case "h2":
+		return "HTTP/2.0"
+	case "h3", "quic":
+		return "HTTP/3.0"
+	}
+	return strings.ToUpper(protocol)
+}
+
+// harHeaders flattens CDP headers, where repeated headers are joined with
+// newlines, into sorted name/value pairs.
+func harHeaders(headers network.Headers) []NameValue {
+	out := []NameValue{}
+	for name, value := range headers {
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			out = append(out, NameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out
+}
+
+func headerValue(headers []NameValue, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func queryString(raw string) []NameValue {
+	out := []NameValue{}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return out
+	}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		out = append(out, NameValue{Name: name, Value: value})
+	}
+	return out
+}
+
+func requestCookies(headers []NameValue) []NameValue {
+	out := []NameValue{}
+	for _, h := range headers {
+		if !strings.EqualFold(h.Name, "Cookie") {
+			continue
+		}
+		for _, part := range strings.Split(h.Value, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				out = append(out, NameValue{Name: name, Value: value})
+			}
+		}
+	}
+	return out
+}
+
+func responseCookies(headers []NameValue) []NameValue {
+	out := []NameValue{}
+	for _, h := range headers {
+		if !strings.EqualFold(h.Name, "Set-Cookie") {
+			continue
+		}
+		first, _, _ := strings.Cut(h.Value, ";")
+		name, value, _ := strings.Cut(strings.TrimSpace(first), "=")
+		if name != "" {
+			out = append(out, NameValue{Name: name, Value: value})
+		}
+	}
+	return out
+}
+
+func textual(mime string) bool {
+	mime = strings.ToLower(mime)
+	if strings.HasPrefix(mime, "text/") {
+		return true
+	}
+	for _, s := range []string{"json", "javascript", "ecmascript", "xml", "html", "css", "svg", "x-www-form-urlencoded", "graphql"} {
+		if strings.Contains(mime, s) {
+			return true
+		}
+	}
+	return false
+}
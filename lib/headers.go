@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseHeaders parses "Name: value" strings, as curl -H takes them. An
+// empty value ("Name:") maps to "", which removes the header when set on
+// the daemon.
+func ParseHeaders(values []string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", v)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
@@ -0,0 +1,335 @@
+package lib
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"gopkg.in/yaml.v3"
+)
+
+// MockRule fulfills, blocks, or fails requests whose URL matches a glob
+// pattern ('*' matches any run of characters, '?' exactly one), and whose
+// method matches Method when set. Fail is a FailReasons name the request
+// fails with. BodyFile is read on every match, so fixtures can be edited
+// while the rule is active.
+type MockRule struct {
+	URL      string            `json:"url" yaml:"url"`
+	Method   string            `json:"method,omitempty" yaml:"method,omitempty"`
+	Status   int               `json:"status,omitempty" yaml:"status,omitempty"`
+	Body     string            `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyFile string            `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Block    bool              `json:"block,omitempty" yaml:"block,omitempty"`
+	Fail     string            `json:"fail,omitempty" yaml:"fail,omitempty"`
+}
+
+// FailReasons maps the names accepted for MockRule.Fail to the network
+// errors requests fail with, as the page sees them (net::ERR_...).
+var FailReasons = map[string]network.ErrorReason{
+	"failed":                network.ErrorReasonFailed,
+	"aborted":               network.ErrorReasonAborted,
+	"timed-out":             network.ErrorReasonTimedOut,
+	"access-denied":         network.ErrorReasonAccessDenied,
+	"connection-closed":     network.ErrorReasonConnectionClosed,
+	"connection-reset":      network.ErrorReasonConnectionReset,
+	"connection-refused":    network.ErrorReasonConnectionRefused,
+	"connection-aborted":    network.ErrorReasonConnectionAborted,
+	"connection-failed":     network.ErrorReasonConnectionFailed,
+	"name-not-resolved":     network.ErrorReasonNameNotResolved,
+	"internet-disconnected": network.ErrorReasonInternetDisconnected,
+	"address-unreachable":   network.ErrorReasonAddressUnreachable,
+	"blocked-by-client":     network.ErrorReasonBlockedByClient,
+	"blocked-by-response":   network.ErrorReasonBlockedByResponse,
+}
+
+// FailReasonNames returns the FailReasons names, sorted.
+func FailReasonNames() []string {
+	names := make([]string, 0, len(FailReasons))
+	for name := range FailReasons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFailReason looks up a FailReasons name. The protocol's own names,
+// like ConnectionRefused, are accepted too.
+func ParseFailReason(name string) (network.ErrorReason, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if reason, ok := FailReasons[key]; ok {
+		return reason, nil
+	}
+	for _, reason := range FailReasons {
+		if strings.EqualFold(string(reason), key) {
+			return reason, nil
+		}
+	}
+	return "", fmt.Errorf("unknown fail reason %q, expected one of: %s", name, strings.Join(FailReasonNames(), ", "))
+}
+
+// LoadMockRules reads mock rules from a JSON or YAML file holding a list of
+// rules or {"rules": [...]}. Relative body_file paths are resolved against
+// the file's directory.
+func LoadMockRules(path string) ([]MockRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []MockRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		var wrapped struct {
+			Rules []MockRule `yaml:"rules"`
+		}
+		if yaml.Unmarshal(data, &wrapped) != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		rules = wrapped.Rules
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s has no rules", path)
+	}
+	dir := filepath.Dir(path)
+	for i := range rules {
+		rule := &rules[i]
+		if strings.TrimSpace(rule.URL) == "" {
+			return nil, fmt.Errorf("%s: rule %d: url is required", path, i+1)
+		}
+		if rule.Fail != "" {
+			if _, err := ParseFailReason(rule.Fail); err != nil {
+				return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+			}
+		}
+		if rule.BodyFile == "" {
+			continue
+		}
+		if rule.Body != "" {
+			return nil, fmt.Errorf("%s: rule %d: use body or body_file, not both", path, i+1)
+		}
+		if !filepath.IsAbs(rule.BodyFile) {
+			rule.BodyFile = filepath.Join(dir, rule.BodyFile)
+		}
+		if rule.BodyFile, err = filepath.Abs(rule.BodyFile); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(rule.BodyFile); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// Interceptor keeps Fetch interception rules active on a tab for as long as
+// its connection lives. Later rules win when several match.
+type Interceptor struct {
+	tabCtx    context.Context
+	mu        sync.Mutex
+	rules     []MockRule
+	matchers  []*regexp.Regexp
+	listening bool
+}
+
+// NewInterceptor returns an Interceptor for an attached tab context. Paused
+// requests are answered on tabCtx, so it must outlive any step timeouts.
+func NewInterceptor(tabCtx context.Context) *Interceptor {
+	return &Interceptor{tabCtx: tabCtx}
+}
+
+// Add installs rules and (re)enables interception for all rules so far.
+// The rules are checked first and kept only once Chrome accepts them, so a
+// bad rule or a failed enable leaves the active rules as they were.
+func (ic *Interceptor) Add(ctx context.Context, rules ...MockRule) error {
+	matchers := make([]*regexp.Regexp, 0, len(rules))
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.URL) == "" {
+			return errors.New("mock rule url is required")
+		}
+		if rule.Fail != "" {
+			if _, err := ParseFailReason(rule.Fail); err != nil {
+				return err
+			}
+		}
+		matchers = append(matchers, globRegexp(rule.URL))
+	}
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	patterns := make([]*fetch.RequestPattern, 0, len(ic.rules)+len(rules))
+	for _, rule := range append(append([]MockRule{}, ic.rules...), rules...) {
+		patterns = append(patterns, &fetch.RequestPattern{URLPattern: rule.URL})
+	}
+	if !ic.listening {
+		ic.listening = true
+		chromedp.ListenTarget(ic.tabCtx, func(ev interface{}) {
+			if ev, ok := ev.(*fetch.EventRequestPaused); ok {
+				go ic.handle(ev)
+			}
+		})
+	}
+	if err := chromedp.Run(ctx, fetch.Enable().WithPatterns(patterns)); err != nil {
+		return err
+	}
+	ic.rules = append(ic.rules, rules...)
+	ic.matchers = append(ic.matchers, matchers...)
+	return nil
+}
+
+// Clear removes all rules and disables interception.
+func (ic *Interceptor) Clear(ctx context.Context) error {
+	ic.mu.Lock()
+	ic.rules = nil
+	ic.matchers = nil
+	ic.mu.Unlock()
+	return chromedp.Run(ctx, fetch.Disable())
+}
+
+// Rules returns a copy of the active rules.
+func (ic *Interceptor) Rules() []MockRule {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	return append([]MockRule{}, ic.rules...)
+}
+
+func (ic *Interceptor) match(method string, url string) (MockRule, bool) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	for i := len(ic.rules) - 1; i >= 0; i-- {
+		if ic.rules[i].Method != "" && !strings.EqualFold(ic.rules[i].Method, method) {
+			continue
+		}
+		if ic.matchers[i].MatchString(url) {
+			return ic.rules[i], true
+		}
+	}
+	return MockRule{}, false
+}
+
+func (ic *Interceptor) handle(ev *fetch.EventRequestPaused) {
+	c := chromedp.FromContext(ic.tabCtx)
+	if c == nil || c.Target == nil {
+		return
+	}
+	ctx := cdp.WithExecutor(ic.tabCtx, c.Target)
+	rule, ok := ic.match(ev.Request.Method, ev.Request.URL)
+	switch {
+	case !ok:
+		_ = fetch.ContinueRequest(ev.RequestID).Do(ctx)
+	case rule.Block:
+		_ = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient).Do(ctx)
+	case rule.Fail != "":
+		// Checked when the rule was added
+		reason, _ := ParseFailReason(rule.Fail)
+		_ = fetch.FailRequest(ev.RequestID, reason).Do(ctx)
+	default:
+		status := rule.Status
+		if status == 0 {
+			status = 200
+		}
+		body := []byte(rule.Body)
+		if rule.BodyFile != "" {
+			var err error
+			body, err = os.ReadFile(rule.BodyFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: mock %s: %v\n", rule.URL, err)
+				_ = fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed).Do(ctx)
+				return
+			}
+		}
+		var headers []*fetch.HeaderEntry
+		hasType := false
+		for name, value := range rule.Headers {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+			hasType = hasType || strings.EqualFold(name, "Content-Type")
+		}
+		if !hasType && rule.BodyFile != "" {
+			if typ := mime.TypeByExtension(filepath.Ext(rule.BodyFile)); typ != "" {
+				headers = append(headers, &fetch.HeaderEntry{Name: "Content-Type", Value: typ})
+			}
+		}
+		_ = fetch.FulfillRequest(ev.RequestID, int64(status)).
+			WithResponseHeaders(headers).
+			WithBody(base64.StdEncoding.EncodeToString(body)).
+			Do(ctx)
+	}
+}
+
+// globRegexp converts a Fetch URL pattern to an anchored regexp.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// NetworkConditions emulates offline mode, latency, and bandwidth. Throughput
+// is in kilobits per second; zero means unthrottled.
+type NetworkConditions struct {
+	Offline  bool `json:"offline,omitempty"`
+	Latency  int  `json:"latency,omitempty"`
+	Download int  `json:"download,omitempty"`
+	Upload   int  `json:"upload,omitempty"`
+}
+
+// NetworkProfiles are named network conditions matching DevTools' throttling
+// presets, with "offline" and "off" (normal networking) alongside.
+var NetworkProfiles = map[string]NetworkConditions{
+	"slow-3g": {Latency: 2000, Download: 400, Upload: 400},
+	"fast-3g": {Latency: 563, Download: 1440, Upload: 675},
+	"slow-4g": {Latency: 563, Download: 1440, Upload: 675},
+	"fast-4g": {Latency: 165, Download: 8100, Upload: 1350},
+	"offline": {Offline: true},
+	"off":     {},
+}
+
+// NetworkProfileNames returns the NetworkProfiles names, sorted.
+func NetworkProfileNames() []string {
+	names := make([]string, 0, len(NetworkProfiles))
+	for name := range NetworkProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Action applies the conditions to the current tab. The zero value restores
+// normal networking.
+func (nc NetworkConditions) Action() chromedp.Action {
+	throughput := func(kbps int) float64 {
+		if kbps <= 0 {
+			return -1
+		}
+		return float64(kbps) * 1000 / 8
+	}
+	return chromedp.Tasks{
+		network.Enable(),
+		network.EmulateNetworkConditions(nc.Offline, float64(nc.Latency), throughput(nc.Download), throughput(nc.Upload)),
+	}
+}
@@ -0,0 +1,135 @@
+package lib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	cases := []struct {
+		pattern, url string
+		want         bool
+	}{
+		{"*", "https://example.com/", true},
+		{"*/api/*", "https://example.com/api/items", true},
+		{"*/api/*", "https://example.com/app/items", false},
+		{"https://example.com/a?c", "https://example.com/abc", true},
+		{"https://example.com/a?c", "https://example.com/ac", false},
+		{"*.png", "https://cdn.example.com/logo.png", true},
+		{"*.png", "https://cdn.example.com/logo.png?v=2", false},
+		{"*.png*", "https://cdn.example.com/logo.png?v=2", true},
+		// Regexp characters are literal
+		{"https://example.com/a.b", "https://example.com/axb", false},
+		{"*/items(1)", "https://example.com/items(1)", true},
+		{"*/search+x", "https://example.com/search+x", true},
+		// Backslash escapes the glob characters
+		{`*/what\?`, "https://example.com/what?", true},
+		{`*/what\?`, "https://example.com/whatx", false},
+		{`*/a\*b`, "https://example.com/a*b", true},
+		{`*/a\*b`, "https://example.com/axxb", false},
+		// Patterns match the whole URL
+		{"example.com", "https://example.com/", false},
+	}
+	for _, c := range cases {
+		if got := globRegexp(c.pattern).MatchString(c.url); got != c.want {
+			t.Errorf("globRegexp(%q) matching %q = %v, want %v", c.pattern, c.url, got, c.want)
+		}
+	}
+}
+
+func TestInterceptorMatch(t *testing.T) {
+	ic := &Interceptor{}
+	for _, rule := range []MockRule{
+		{URL: "*/api/*", Status: 500},
+		{URL: "*/api/items", Method: "POST", Status: 201},
+		{URL: "*/api/items*", Method: "get", Body: "[]"},
+		{URL: "*.js", Block: true},
+	} {
+		ic.rules = append(ic.rules, rule)
+		ic.matchers = append(ic.matchers, globRegexp(rule.URL))
+	}
+	cases := []struct {
+		method, url string
+		status      int
+		body        string
+		block, ok   bool
+	}{
+		// Later rules win, and methods match without case
+		{"GET", "https://x.test/api/items", 0, "[]", false, true},
+		{"POST", "https://x.test/api/items", 201, "", false, true},
+		{"DELETE", "https://x.test/api/items", 500, "", false, true},
+		{"GET", "https://x.test/api/users", 500, "", false, true},
+		{"GET", "https://x.test/app.js", 0, "", true, true},
+		{"GET", "https://x.test/index.html", 0, "", false, false},
+	}
+	for _, c := range cases {
+		rule, ok := ic.match(c.method, c.url)
+		if ok != c.ok || rule.Status != c.status || rule.Body != c.body || rule.Block != c.block {
+			t.Errorf("match(%s %s) = %+v, %v", c.method, c.url, rule, ok)
+		}
+	}
+}
+
+func TestInterceptorAddKeepsRulesOnFailure(t *testing.T) {
+	tabCtx, _ := chromedp.NewContext(context.Background())
+	ic := NewInterceptor(tabCtx)
+	batches := [][]MockRule{
+		{{URL: "*/a"}, {URL: " "}},
+		{{URL: "*/a"}, {URL: "*/b", Fail: "nope"}},
+		// Valid, but enabling fails: context.Background is not a tab
+		{{URL: "*/a"}},
+	}
+	for _, batch := range batches {
+		if err := ic.Add(context.Background(), batch...); err == nil {
+			t.Errorf("Add(%+v) succeeded", batch)
+		}
+	}
+	if rules := ic.Rules(); len(rules) != 0 {
+		t.Fatalf("failed Adds kept rules %+v", rules)
+	}
+	if _, ok := ic.match("GET", "https://x.test/a"); ok {
+		t.Fatal("failed Adds kept a matcher")
+	}
+}
+
+func TestLoadMockRules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("items.json", `[]`)
+	rules, err := LoadMockRules(write("list.yaml", "- url: '*/api/items'\n  body_file: items.json\n- url: '*.js'\n  block: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].BodyFile != filepath.Join(dir, "items.json") || !rules[1].Block {
+		t.Fatalf("list: %+v", rules)
+	}
+	rules, err = LoadMockRules(write("wrapped.json", `{"rules": [{"url": "*/api/*", "method": "POST", "status": 204}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Method != "POST" || rules[0].Status != 204 {
+		t.Fatalf("wrapped: %+v", rules)
+	}
+
+	for name, content := range map[string]string{
+		"empty.yaml":   "[]\n",
+		"nourl.yaml":   "- status: 404\n",
+		"both.yaml":    "- url: '*'\n  body: x\n  body_file: items.json\n",
+		"missing.yaml": "- url: '*'\n  body_file: nope.json\n",
+		"invalid.yaml": "url: [\n",
+	} {
+		if _, err := LoadMockRules(write(name, content)); err == nil {
+			t.Errorf("LoadMockRules(%s) succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// KeyStep is one item of a key sequence: a press (down, char, up), a lone
+// down or up, a run of text, or a pause.
+type KeyStep struct {
+	Action    string        `json:"action"` // press, down, up, text, or wait
+	Key       string        `json:"key,omitempty"`
+	Code      string        `json:"code,omitempty"`
+	Modifiers []string      `json:"modifiers,omitempty"`
+	Text      string        `json:"text,omitempty"`
+	WaitMS    int64         `json:"waitMs,omitempty"`
+	Wait      time.Duration `json:"-"`
+
+	key *kb.Key
+}
+
+// KeyOptions controls the timing of DispatchKeys.
+type KeyOptions struct {
+	// Delay is the pause after each key press or text character.
+	Delay time.Duration
+	// Hold is the pause between a key's down and up events.
+	Hold time.Duration
+}
+
+// modifierKeys maps modifier names and aliases to their kb key.
+var modifierKeys = map[string]string{
+	"shift":   kb.Shift,
+	"ctrl":    kb.Control,
+	"control": kb.Control,
+	"alt":     kb.Alt,
+	"option":  kb.Alt,
+	"meta":    kb.Meta,
+	"cmd":     kb.Meta,
+	"command": kb.Meta,
+}
+
+var modifierBits = map[string]input.Modifier{
+	"Shift":   input.ModifierShift,
+	"Control": input.ModifierCtrl,
+	"Alt":     input.ModifierAlt,
+	"Meta":    input.ModifierMeta,
+}
+
+// keyAliases are names accepted for keys besides their DOM key and code.
+var keyAliases = map[string]string{
+	"esc":       "Escape",
+	"return":    "Enter",
+	"space":     " ",
+	"del":       "Delete",
+	"left":      "ArrowLeft",
+	"right":     "ArrowRight",
+	"up":        "ArrowUp",
+	"down":      "ArrowDown",
+	"pgup":      "PageUp",
+	"pgdn":      "PageDown",
+	"backspace": "Backspace",
+	"tab":       "Tab",
+}
+
+// lookupKey finds a key by single character, DOM key value (Enter,
+// ArrowLeft), DOM code (KeyA, Digit1, ShiftRight), modifier name, or alias.
+func lookupKey(name string) (*kb.Key, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		if k, ok := kb.Keys[r]; ok {
+			return k, nil
+		}
+	}
+	lower := strings.ToLower(name)
+	if _, ok := modifierKeys[lower]; ok {
+		return modifierKey(lower), nil
+	}
+	if alias, ok := keyAliases[lower]; ok {
+		name = alias
+		if r, _ := utf8.DecodeRuneInString(name); utf8.RuneCountInString(name) == 1 {
+			return kb.Keys[r], nil
+		}
+	}
+	var byCode *kb.Key
+	for _, k := range kb.Keys {
+		if strings.EqualFold(k.Key, name) && !k.Print {
+			return k, nil
+		}
+		// Codes like KeyA and Digit1 map to both cases; prefer unshifted
+		if strings.EqualFold(k.Code, name) && (byCode == nil || byCode.Shift) {
+			byCode = k
+		}
+	}
+	if byCode != nil {
+		return byCode, nil
+	}
+	// Right-hand modifiers are not in kb.Keys
+	for _, side := range []string{"Right", "Left"} {
+		if base, ok := strings.CutSuffix(name, side); ok {
+			if _, ok := modifierKeys[strings.ToLower(base)]; ok {
+				k := *modifierKey(base)
+				k.Code = strings.TrimSuffix(k.Code, "Left") + side
+				return &k, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown key %q", name)
+}
+
+// modifierKey returns the kb key for a modifier name.
+func modifierKey(name string) *kb.Key {
+	r, _ := utf8.DecodeRuneInString(modifierKeys[strings.ToLower(name)])
+	return kb.Keys[r]
+}
+
+// shifted returns the Shift variant of a printable key, so Shift+a sends A.
+func shifted(k *kb.Key) *kb.Key {
+	if !k.Print || k.Shift {
+		return k
+	}
+	for _, other := range kb.Keys {
+		if other.Shift && other.Code == k.Code {
+			return other
+		}
+	}
+	return k
+}
+
+// ParseKeys parses a key sequence. Each token is one of:
+//
+//	KEY             press a key: a, Enter, ArrowLeft, KeyA, F5
+//	MOD+...+KEY     press with modifiers: Ctrl+Shift+z, Meta+Enter
+//	down:KEY        key down only, modifiers stay held until up:KEY
+//	up:KEY          key up only
+//	text:STRING     press each character of STRING
+//	wait:DURATION   pause, e.g. wait:250ms
+func ParseKeys(tokens []string) ([]KeyStep, error) {
+	var steps []KeyStep
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		prefix, rest, hasPrefix := strings.Cut(token, ":")
+		if hasPrefix && rest != "" {
+			switch prefix {
+			case "text":
+				steps = append(steps, KeyStep{Action: "text", Text: rest})
+				continue
+			case "wait":
+				d, err := time.ParseDuration(rest)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", token, err)
+				}
+				steps = append(steps, KeyStep{Action: "wait", Wait: d, WaitMS: d.Milliseconds()})
+				continue
+			case "down", "up":
+				k, err := lookupKey(rest)
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, KeyStep{Action: prefix, Key: k.Key, Code: k.Code, key: k})
+				continue
+			}
+		}
+		parts := []string{token}
+		if len(token) > 1 && strings.Contains(token, "+") {
+			parts = strings.Split(token, "+")
+			// A trailing "+" is the plus key itself: Ctrl++
+			if strings.HasSuffix(token, "++") {
+				parts = append(strings.Split(strings.TrimSuffix(token, "++"), "+"), "+")
+			}
+		}
+		step := KeyStep{Action: "press"}
+		for _, mod := range parts[:len(parts)-1] {
+			if _, ok := modifierKeys[strings.ToLower(mod)]; !ok {
+				return nil, fmt.Errorf("%s: unknown modifier %q, expected Shift, Ctrl, Alt, or Meta", token, mod)
+			}
+			step.Modifiers = append(step.Modifiers, modifierKey(mod).Key)
+		}
+		k, err := lookupKey(parts[len(parts)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", token, err)
+		}
+		step.Key, step.Code, step.key = k.Key, k.Code, k
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+	return steps, nil
+}
+
+// DispatchKeys sends steps as raw Input.dispatchKeyEvent calls to whatever
+// has focus in the tab, with no element lookup or focus change. Keys held
+// with down: apply their modifier to every event until released with up:,
+// and any still held at the end are released.
+func DispatchKeys(ctx context.Context, steps []KeyStep, opts KeyOptions) (int, error) {
+	held := map[string]*kb.Key{}
+	var order []string
+	sent := 0
+	modifiers := func(extra []string) input.Modifier {
+		var m input.Modifier
+		for name := range held {
+			m |= modifierBits[name]
+		}
+		for _, name := range extra {
+			m |= modifierBits[name]
+		}
+		return m
+	}
+	send := func(typ input.KeyType, k *kb.Key, mods input.Modifier, char bool) error {
+		p := input.DispatchKeyEvent(typ).
+			WithKey(k.Key).
+			WithCode(k.Code).
+			WithWindowsVirtualKeyCode(k.Windows).
+			WithModifiers(mods)
+		if runtime.GOOS != "darwin" {
+			p = p.WithNativeVirtualKeyCode(k.Native)
+		}
+		if char {
+			p = p.WithText(k.Text).WithUnmodifiedText(k.Unmodified)
+		}
+		sent++
+		return chromedp.Run(ctx, p)
+	}
+	sleep := func(d time.Duration) error {
+		if d <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	press := func(k *kb.Key, extra []string) error {
+		mods := modifiers(extra)
+		if mods&input.ModifierShift != 0 {
+			k = shifted(k)
+		}
+		if k.Shift {
+			mods |= input.ModifierShift
+		}
+		// Shortcuts like Ctrl+a produce no text, as on a real keyboard
+		printable := k.Print && mods&(input.ModifierCtrl|input.ModifierAlt|input.ModifierMeta) == 0
+		typ := input.KeyRawDown
+		if printable {
+			typ = input.KeyDown
+		}
+		if err := send(typ, k, mods, printable); err != nil {
+			return err
+		}
+		if err := sleep(opts.Hold); err != nil {
+			return err
+		}
+		if err := send(input.KeyUp, k, mods, false); err != nil {
+			return err
+		}
+		return sleep(opts.Delay)
+	}
+	for _, step := range steps {
+		switch step.Action {
+		case "press":
+			for i, name := range step.Modifiers {
+				if err := send(input.KeyRawDown, modifierKey(name), modifiers(step.Modifiers[:i+1]), false); err != nil {
+					return sent, err
+				}
+			}
+			if err := press(step.key, step.Modifiers); err != nil {
+				return sent, err
+			}
+			for i := len(step.Modifiers) - 1; i >= 0; i-- {
+				if err := send(input.KeyUp, modifierKey(step.Modifiers[i]), modifiers(step.Modifiers[:i]), false); err != nil {
+					return sent, err
+				}
+			}
+		case "text":
+			for _, r := range step.Text {
+				if r == '\n' {
+					r = '\r'
+				}
+				k, ok := kb.Keys[r]
+				if !ok {
+					// Characters off the US layout go in as text, like an IME
+					if err := chromedp.Run(ctx, input.InsertText(string(r))); err != nil {
+						return sent, err
+					}
+					sent++
+					if err := sleep(opts.Delay); err != nil {
+						return sent, err
+					}
+					continue
+				}
+				if err := press(k, nil); err != nil {
+					return sent, err
+				}
+			}
+		case "down":
+			if err := send(input.KeyRawDown, step.key, modifiers(nil)|modifierBits[step.key.Key], false); err != nil {
+				return sent, err
+			}
+			if _, ok := held[step.key.Key]; !ok {
+				order = append(order, step.key.Key)
+			}
+			held[step.key.Key] = step.key
+		case "up":
+			delete(held, step.key.Key)
+			if err := send(input.KeyUp, step.key, modifiers(nil), false); err != nil {
+				return sent, err
+			}
+		case "wait":
+			if err := sleep(step.Wait); err != nil {
+				return sent, err
+			}
+		}
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		k, ok := held[order[i]]
+		if !ok {
+			continue
+		}
+		delete(held, order[i])
+		if err := send(input.KeyUp, k, modifiers(nil), false); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
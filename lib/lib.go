@@ -12,12 +12,15 @@
 // - Tab ID prefix: Selects tab by ID (shown in brackets by `chrome list`)
 // - URL prefix: Selects first tab whose URL starts with the given prefix (case-insensitive)
 // - CHROME_TARGET env var: Used when -t flag is empty
+// - Extension targets: Selectable (never the default) with --include-extensions
+// - Target cache: Tab lists and resolved IDs are reused briefly (targetcache.go)
 //
 // CONTEXT LIFECYCLE:
 // - Remote mode: Creates RemoteAllocator, attaches to existing Chrome
 // - Headless mode: Creates ExecAllocator, launches new Chrome process
 // - Tab contexts are created per-command and cancelled after execution
 // - Cancelling tab context does NOT close the tab (tabs persist)
+// - A crashed tab cancels its context with ErrTabCrashed, exit 3 in the CLI (crash.go, exit.go)
 //
 // CRITICAL LEARNINGS:
 // - Chrome tabs persist between commands when using remote debugging
@@ -26,6 +29,7 @@
 package lib
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -33,6 +37,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -253,6 +258,10 @@ func SetupContextWithTimeout(timeout time.Duration) (context.Context, context.Ca
 }
 
 func IsChromeRunning() bool {
+	defer TimePhase("resolve")()
+	if loadTargetCache() != nil {
+		return true
+	}
 	resp, err := cdpHTTPClient.Get(ChromeURL() + "/json/version")
 	if err != nil {
 		return false
@@ -282,9 +291,19 @@ func FetchTargets() ([]ChromeTarget, error) {
 		return nil, err
 	}
 
+	saveTargetCache(&targetCache{URL: ChromeURL(), FetchedAt: time.Now(), Targets: targets})
 	return targets, nil
 }
 
+// PageTargets returns the open page tabs, excluding chrome:// URLs
+func PageTargets() ([]ChromeTarget, error) {
+	targets, err := FetchTargets()
+	if err != nil {
+		return nil, err
+	}
+	return filterPageTargets(targets), nil
+}
+
 func FindFirstPageTarget() string {
 	targets, err := FetchTargets()
 	if err != nil {
@@ -316,8 +335,10 @@ func FindPreferredTarget() string {
 // 3. Check chromedp.Targets() for attached tabs (preferred)
 // 4. Fall back to first page target
 //
-// Returns empty targetID with reason string if no match found
+// Returns empty targetID with reason string if no match found.
+// Results are served from the target cache (see targetcache.go) when fresh.
 func ResolveTarget(selector string, env map[string]string) (string, string, error) {
+	defer TimePhase("resolve")()
 	selected := strings.TrimSpace(selector)
 	if selected == "" && env != nil {
 		selected = strings.TrimSpace(env["CHROME_TARGET"])
@@ -326,9 +347,33 @@ func ResolveTarget(selector string, env map[string]string) (string, string, erro
 		selected = strings.TrimSpace(os.Getenv("CHROME_TARGET"))
 	}
 
-	targets, err := FetchTargets()
-	if err != nil {
-		return "", "", err
+	// Extension targets can change what a selector resolves to
+	key := selected
+	if selected != "" && IncludeExtensions() {
+		key = "extensions:" + selected
+	}
+
+	cache := loadTargetCache()
+	if cache != nil {
+		if resolved, ok := cache.Resolved[key]; ok {
+			return resolved.ID, resolved.Reason, nil
+		}
+		// A cached list may predate the tab the selector names
+		if selected != "" && matchTargetBySelector(selectableTargets(cache.Targets), selected) == "" {
+			cache = nil
+		}
+	}
+
+	var targets []ChromeTarget
+	if cache != nil {
+		targets = cache.Targets
+	} else {
+		var err error
+		targets, err = FetchTargets()
+		if err != nil {
+			return "", "", err
+		}
+		cache = &targetCache{URL: ChromeURL(), FetchedAt: time.Now(), Targets: targets}
 	}
 
 	pages := filterPageTargets(targets)
@@ -337,8 +382,10 @@ func ResolveTarget(selector string, env map[string]string) (string, string, erro
 	}
 
 	if selected != "" {
-		if id := matchTargetBySelector(pages, selected); id != "" {
-			return id, fmt.Sprintf("matched selector %q", selected), nil
+		if id := matchTargetBySelector(selectableTargets(targets), selected); id != "" {
+			reason := fmt.Sprintf("matched selector %q", selected)
+			rememberResolved(cache, key, id, reason)
+			return id, reason, nil
 		}
 
 		// Better error message - show available tabs
@@ -346,16 +393,22 @@ func ResolveTarget(selector string, env map[string]string) (string, string, erro
 		for _, p := range pages {
 			availTabs = append(availTabs, p.URL)
 		}
-		return "", fmt.Sprintf("no tab URL starts with %q. Available: %s", selected, strings.Join(availTabs, ", ")), nil
+		hint := ""
+		if strings.HasPrefix(strings.ToLower(selected), "chrome-extension://") && !IncludeExtensions() {
+			hint = " (extension background pages and service workers need --include-extensions)"
+		}
+		return "", fmt.Sprintf("no tab URL starts with %q%s. Available: %s", selected, hint, strings.Join(availTabs, ", ")), nil
 	}
 
 	infos, err := fetchTargetInfos()
 	if err == nil {
 		if id := selectPreferredFromInfo(pages, infos); id != "" {
+			rememberResolved(cache, selected, id, "preferred attached tab")
 			return id, "preferred attached tab", nil
 		}
 	}
 
+	rememberResolved(cache, selected, pages[0].ID, "first page tab")
 	return pages[0].ID, "first page tab", nil
 }
 
@@ -375,6 +428,42 @@ func filterPageTargets(targets []ChromeTarget) []ChromeTarget {
 	return pages
 }
 
+// IncludeExtensions reports whether extension background pages and service
+// workers can be selected, from $CHROME_INCLUDE_EXTENSIONS (set by the global
+// --include-extensions flag so child commands inherit it).
+func IncludeExtensions() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("CHROME_INCLUDE_EXTENSIONS"))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// filterExtensionTargets returns the background pages and service workers of
+// extensions. Extension pages opened as tabs are page targets already.
+func filterExtensionTargets(targets []ChromeTarget) []ChromeTarget {
+	var extensions []ChromeTarget
+	for _, t := range targets {
+		if t.Type != "background_page" && t.Type != "service_worker" {
+			continue
+		}
+		if strings.HasPrefix(t.URL, "chrome-extension://") {
+			extensions = append(extensions, t)
+		}
+	}
+	return extensions
+}
+
+// selectableTargets returns the targets a selector can match: page tabs,
+// then extension targets when IncludeExtensions.
+func selectableTargets(targets []ChromeTarget) []ChromeTarget {
+	pages := filterPageTargets(targets)
+	if IncludeExtensions() {
+		pages = append(pages, filterExtensionTargets(targets)...)
+	}
+	return pages
+}
+
 func matchTargetBySelector(pages []ChromeTarget, selector string) string {
 	if selector == "" {
 		return ""
@@ -472,7 +561,9 @@ func shortID(id string) string {
 	return id[:8]
 }
 
-func ListTabs() error {
+// ListTabs prints the open tabs, followed by extension background pages and
+// service workers when extensions is set.
+func ListTabs(extensions bool) error {
 	port := GetPort()
 	if !IsChromeRunning() {
 		return fmt.Errorf("Chrome not running on port %d", port)
@@ -486,7 +577,11 @@ func ListTabs() error {
 	}
 
 	pages := filterPageTargets(targets)
-	if len(pages) == 0 {
+	var extensionTargets []ChromeTarget
+	if extensions {
+		extensionTargets = filterExtensionTargets(targets)
+	}
+	if len(pages) == 0 && len(extensionTargets) == 0 {
 		fmt.Println("no page tabs")
 		return nil
 	}
@@ -506,7 +601,7 @@ func ListTabs() error {
 
 	preferred, _, _ := ResolveTarget("", nil)
 
-	for _, page := range pages {
+	for _, page := range append(pages, extensionTargets...) {
 		marker := " "
 		if preferred != "" && page.ID == preferred {
 			marker = "*"
@@ -518,6 +613,9 @@ func ListTabs() error {
 		}
 
 		fmt.Printf("%s[%s] %s\n  %s\n", marker, shortID(page.ID), title, page.URL)
+		if page.Type != "page" {
+			fmt.Printf("  type: %s\n", page.Type)
+		}
 
 		if info := infoByID[page.ID]; info != nil {
 			status := "detached"
@@ -535,13 +633,15 @@ func ListTabs() error {
 // If selector is empty, returns the provided context unchanged
 // Otherwise resolves the selector and creates a new context with WithTargetID
 //
-// IMPORTANT: The returned cancel function is a NO-OP for remote Chrome.
-// This ensures tabs persist between commands. Cancelling the context would
-// close the tab, which breaks multi-step automation workflows.
+// IMPORTANT: The returned cancel function releases the context but never
+// closes the tab for remote Chrome. This ensures tabs persist between
+// commands. Cancelling the tab's own chromedp context would close the tab,
+// which breaks multi-step automation workflows.
 func EnsureTargetContext(ctx context.Context, selector string) (context.Context, func(), error) {
 	sel := strings.TrimSpace(selector)
 	if sel == "" {
-		return ctx, func() {}, nil
+		watched, cancel := watchCrash(ctx)
+		return connectForTiming(watched), cancel, nil
 	}
 
 	if !IsChromeRunning() {
@@ -565,7 +665,21 @@ func EnsureTargetContext(ctx context.Context, selector string) (context.Context,
 	}
 
 	tabCtx, _ := chromedp.NewContext(ctx, chromedp.WithTargetID(target.ID(id)))
-	return tabCtx, func() {}, nil
+	watched, cancel := watchCrash(tabCtx)
+	return connectForTiming(watched), cancel, nil
+}
+
+// connectForTiming attaches eagerly when timing is enabled, so the connect
+// phase is measured on its own instead of inside the command's first action.
+// Attach errors are left for that first action to report.
+func connectForTiming(ctx context.Context) context.Context {
+	if TimingMode() == "" {
+		return ctx
+	}
+	stop := TimePhase("connect")
+	_ = chromedp.Run(ctx)
+	stop()
+	return ctx
 }
 
 // PrintJSONLine marshals value to JSON and prints it as a single line (NDJSON format).
@@ -574,7 +688,25 @@ func PrintJSONLine(value any) {
 	output, err := json.Marshal(value)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		Exit(1)
 	}
 	fmt.Println(string(output))
 }
+
+// RunCommand runs another chrome command as a child process against the tab
+// with the given ID, returning its stdout. Its stderr passes through.
+func RunCommand(tabID string, args []string) ([]byte, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(execPath, args...)
+	cmd.Env = ChildEnv("CHROME_TARGET=" + tabID)
+	cmd.Stderr = os.Stderr
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NameTemplateEnv sets the default file name template for screenshots and
+// other generated files.
+const NameTemplateEnv = "CHROME_NAME_TEMPLATE"
+
+// DefaultNameTemplate names files <timestamp>-<label>, e.g.
+// 20240115-103000_123-after-login.png.
+const DefaultNameTemplate = "{timestamp}-{label}"
+
+// NameTokens documents the tokens a name template can use.
+var NameTokens = map[string]string{
+	"timestamp": "UTC date, time, and ms: 20240115-103000_123",
+	"date":      "UTC date: 20240115",
+	"time":      "UTC time: 103000",
+	"label":     "the label, lowercased with other characters as '-'",
+	"seq":       "next number in the directory, zero-padded to 4 digits: 0042",
+	"host":      "the tab's host, e.g. localhost-3000 (nohost for about:blank and files)",
+}
+
+var nameToken = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// NameTemplate returns template, else $CHROME_NAME_TEMPLATE, else
+// DefaultNameTemplate.
+func NameTemplate(template string) string {
+	if t := strings.TrimSpace(template); t != "" {
+		return t
+	}
+	if t := strings.TrimSpace(os.Getenv(NameTemplateEnv)); t != "" {
+		return t
+	}
+	return DefaultNameTemplate
+}
+
+// ValidateNameTemplate checks that template only uses known tokens, names a
+// file rather than a path, and includes {seq} (reserved by ExpandName, so
+// names never collide) or {timestamp} (unique to the millisecond).
+func ValidateNameTemplate(template string) error {
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("name template %q must be a file name, not a path", template)
+	}
+	unique := false
+	for _, m := range nameToken.FindAllStringSubmatch(template, -1) {
+		if _, ok := NameTokens[m[1]]; !ok {
+			return fmt.Errorf("unknown token {%s} in name template %q, expected one of {timestamp}, {date}, {time}, {label}, {seq}, {host}", m[1], template)
+		}
+		if m[1] == "seq" || m[1] == "timestamp" {
+			unique = true
+		}
+	}
+	if rest := nameToken.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("unbalanced braces in name template %q", template)
+	}
+	if !unique {
+		return fmt.Errorf("name template %q needs {seq} or {timestamp} so names are unique", template)
+	}
+	return nil
+}
+
+// OutputName is what a name template expands: the label, and the tab whose
+// host fills {host} (looked up only when the template uses it).
+type OutputName struct {
+	// Template is the name template ("" for NameTemplate's default).
+	Template string
+	Label    string
+	// Target is the tab selector for {host}, as for ResolveTarget.
+	Target string
+}
+
+// ExpandName expands the template for a file in dir with extension ext.
+// {seq} is one more than the highest sequence number among dir's files
+// named by the same template, so names sort in creation order. With {seq}
+// the name is reserved by creating it as an empty file, for the caller to
+// overwrite, so concurrent captures into one directory never share a name.
+func ExpandName(dir string, name OutputName, ext string) (string, error) {
+	template := NameTemplate(name.Template)
+	if err := ValidateNameTemplate(template); err != nil {
+		return "", err
+	}
+	label := sanitizeLabel(name.Label)
+	if label == "" {
+		label = "shot"
+	}
+	now := time.Now().UTC()
+	values := map[string]string{
+		"timestamp": strings.ReplaceAll(now.Format("20060102-150405.000"), ".", "_"),
+		"date":      now.Format("20060102"),
+		"time":      now.Format("150405"),
+		"label":     label,
+	}
+	if strings.Contains(template, "{host}") {
+		values["host"] = targetHost(name.Target)
+	}
+	if !strings.Contains(template, "{seq}") {
+		return expandTemplate(template, values) + ext, nil
+	}
+	seq, err := nextSeq(dir, template)
+	if err != nil {
+		return "", err
+	}
+	for ; ; seq++ {
+		values["seq"] = fmt.Sprintf("%04d", seq)
+		filename := expandTemplate(template, values) + ext
+		f, err := os.OpenFile(filepath.Join(dir, filename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			// Another capture took this number since dir was scanned
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := f.Close(); err != nil {
+			return "", err
+		}
+		return filename, nil
+	}
+}
+
+func expandTemplate(template string, values map[string]string) string {
+	return nameToken.ReplaceAllStringFunc(template, func(token string) string {
+		if value, ok := values[token[1:len(token)-1]]; ok {
+			return value
+		}
+		return token
+	})
+}
+
+// nextSeq scans dir for files matching template and returns one more than
+// the highest {seq} found.
+func nextSeq(dir string, template string) (int, error) {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range nameToken.FindAllStringSubmatchIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		switch template[loc[2]:loc[3]] {
+		case "timestamp":
+			pattern.WriteString(`\d{8}-\d{6}_\d{3}`)
+		case "date":
+			pattern.WriteString(`\d{8}`)
+		case "time":
+			pattern.WriteString(`\d{6}`)
+		case "seq":
+			pattern.WriteString(`(\d+)`)
+		default:
+			pattern.WriteString(`[a-z0-9-]*`)
+		}
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString(`\.[A-Za-z0-9]+$`)
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	highest := 0
+	for _, entry := range entries {
+		m := re.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		// A template can use {seq} more than once; the first one counts
+		if n, err := strconv.Atoi(m[1]); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest + 1, nil
+}
+
+// targetHost returns the sanitized host of the tab selector resolves to, or
+// "nohost" when there is none.
+func targetHost(selector string) string {
+	id, _, err := ResolveTarget(selector, nil)
+	if err != nil || id == "" {
+		return "nohost"
+	}
+	pages, err := PageTargets()
+	if err != nil {
+		return "nohost"
+	}
+	for _, page := range pages {
+		if page.ID != id {
+			continue
+		}
+		u, err := url.Parse(page.URL)
+		if err != nil {
+			break
+		}
+		if host := sanitizeLabel(u.Host); host != "" {
+			return host
+		}
+		break
+	}
+	return "nohost"
+}
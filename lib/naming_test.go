@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNextSeq(t *testing.T) {
+	dir := t.TempDir()
+	if n, err := nextSeq(dir, "{label}-{seq}"); err != nil || n != 1 {
+		t.Fatalf("empty dir: %d, %v", n, err)
+	}
+	if n, err := nextSeq(filepath.Join(dir, "missing"), "{seq}"); err != nil || n != 1 {
+		t.Fatalf("missing dir: %d, %v", n, err)
+	}
+	touch(t, dir,
+		"login-0001.png",
+		"login-0007.jpg",
+		"other-0042.png",  // same template, another label
+		"0099-login.png",  // another template
+		"login-0100",      // no extension
+		"login-12345.png", // wider than 4 digits
+	)
+	if n, err := nextSeq(dir, "{label}-{seq}"); err != nil || n != 12346 {
+		t.Fatalf("nextSeq = %d, %v, want 12346", n, err)
+	}
+	if n, err := nextSeq(dir, "{seq}-{label}"); err != nil || n != 100 {
+		t.Fatalf("nextSeq = %d, %v, want 100", n, err)
+	}
+	if n, err := nextSeq(dir, "{date}-{seq}"); err != nil || n != 1 {
+		t.Fatalf("nextSeq = %d, %v, want 1", n, err)
+	}
+}
+
+func TestExpandNameReservesSeq(t *testing.T) {
+	dir := t.TempDir()
+	name := OutputName{Template: "{label}-{seq}", Label: "After Login"}
+	first, err := ExpandName(dir, name, ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != "after-login-0001.png" {
+		t.Fatalf("first name %q", first)
+	}
+	if _, err := os.Stat(filepath.Join(dir, first)); err != nil {
+		t.Fatalf("name not reserved: %v", err)
+	}
+	second, err := ExpandName(dir, name, ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != "after-login-0002.png" {
+		t.Fatalf("second name %q", second)
+	}
+}
+
+func TestExpandNameConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	name := OutputName{Template: "{seq}", Label: "shot"}
+	const n = 20
+	names := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names[i], errs[i] = ExpandName(dir, name, ".png")
+		}()
+	}
+	wg.Wait()
+	seen := map[string]bool{}
+	for i, got := range names {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if seen[got] {
+			t.Fatalf("two captures got %s", got)
+		}
+		seen[got] = true
+	}
+}
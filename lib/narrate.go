@@ -0,0 +1,132 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// narrationPadding is the quiet time kept after each spoken note before the
+// slideshow moves on.
+const narrationPadding = 750 * time.Millisecond
+
+// TTSCommand returns the shell command that speaks text read from stdin into
+// the audio file {out}: custom, else $CHROME_TTS, else espeak-ng, espeak, or
+// macOS say, whichever is installed.
+func TTSCommand(custom string) (string, error) {
+	for _, command := range []string{custom, os.Getenv("CHROME_TTS")} {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+		if !strings.Contains(command, "{out}") {
+			return "", fmt.Errorf("tts command %q has no {out} placeholder", command)
+		}
+		return command, nil
+	}
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name + " --stdin -w {out}", nil
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("say"); err == nil {
+			return "say -f - -o {out} --file-format=WAVE --data-format=LEI16", nil
+		}
+	}
+	return "", errors.New("no text to speech found: install espeak-ng, or set --tts-command or $CHROME_TTS")
+}
+
+// writeNarration speaks each record's note with tts and mixes the clips
+// into one track aligned with when each record is shown. Records whose note
+// runs longer than their time on screen are stretched to fit it, so the
+// returned records should be used for the rest of the slideshow.
+func writeNarration(ffmpegPath string, tts string, records []StepRecord, frameDuration time.Duration, dir string, verbose bool) ([]StepRecord, string, error) {
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, "", errors.New("ffprobe not found in PATH")
+	}
+	records = append([]StepRecord{}, records...)
+
+	args := []string{"-y"}
+	if !verbose {
+		args = append(args, "-hide_banner", "-loglevel", "warning", "-nostats")
+	}
+	var mix []string
+	var start time.Duration
+	for idx := range records {
+		record := &records[idx]
+		shown := recordDuration(*record, frameDuration)
+		text := strings.TrimSpace(record.Note)
+		if text != "" {
+			clip := filepath.Join(dir, fmt.Sprintf("narration-%03d.wav", idx))
+			if err := speak(tts, text, clip); err != nil {
+				return nil, "", err
+			}
+			spoken, err := audioDuration(ffprobePath, clip)
+			if err != nil {
+				return nil, "", err
+			}
+			if spoken+narrationPadding > shown {
+				shown = spoken + narrationPadding
+				record.Duration = shown.Seconds()
+			}
+			ms := start.Milliseconds()
+			mix = append(mix, fmt.Sprintf("[%d:a]aresample=48000,adelay=%d|%d[a%d]", len(mix), ms, ms, len(mix)))
+			args = append(args, "-i", clip)
+		}
+		start += shown
+	}
+	if len(mix) == 0 {
+		return nil, "", errors.New("no step notes to narrate")
+	}
+	var inputs strings.Builder
+	for idx := range mix {
+		fmt.Fprintf(&inputs, "[a%d]", idx)
+	}
+	filter := strings.Join(mix, ";") + fmt.Sprintf(";%samix=inputs=%d:normalize=0,apad[out]", inputs.String(), len(mix))
+	output := filepath.Join(dir, "narration.wav")
+	args = append(args, "-filter_complex", filter, "-map", "[out]", "-t", fmt.Sprintf("%.3f", start.Seconds()), output)
+	cmd := exec.Command(ffmpegPath, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("mixing narration: %w", err)
+	}
+	return records, output, nil
+}
+
+// speak runs the tts shell command with text on stdin and {out} replaced by
+// the quoted output path.
+func speak(tts string, text string, out string) error {
+	command := strings.ReplaceAll(tts, "{out}", "'"+strings.ReplaceAll(out, "'", `'\''`)+"'")
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("tts command %q: %w", tts, err)
+	}
+	if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+		return fmt.Errorf("tts command %q wrote no audio to {out}", tts)
+	}
+	return nil
+}
+
+// audioDuration asks ffprobe how long an audio file plays.
+func audioDuration(ffprobePath string, path string) (time.Duration, error) {
+	out, err := exec.Command(ffprobePath, "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", path).Output()
+	if err != nil {
+		return 0, fmt.Errorf("probing %s: %w", path, err)
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("probing %s: %w", path, err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,202 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// NavStates maps the states a navigation can be waited for to the page
+// lifecycle events that mark them.
+var NavStates = map[string]string{
+	"domcontentloaded": "DOMContentLoaded",
+	"load":             "load",
+	"networkidle":      "networkIdle",
+}
+
+// NavStateNames returns the NavStates names, sorted.
+func NavStateNames() []string {
+	names := make([]string, 0, len(NavStates))
+	for name := range NavStates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NavResult reports the navigation a NavWatcher saw complete.
+type NavResult struct {
+	URL   string `json:"url"`
+	Until string `json:"until"`
+	// SameDocument is set for history.pushState and #fragment navigations,
+	// which load nothing, so complete as soon as they happen.
+	SameDocument bool          `json:"same_document,omitempty"`
+	Elapsed      time.Duration `json:"elapsed"`
+}
+
+// NavWatcher watches a tab's main frame for a navigation, optionally to a
+// URL matching a pattern, and for its document to reach a state.
+type NavWatcher struct {
+	until   string
+	event   string
+	pattern string
+	url     *regexp.Regexp
+	frameID cdp.FrameID
+	start   time.Time
+	mu      sync.Mutex
+	loader  cdp.LoaderID
+	navURL  string
+	seen    map[cdp.LoaderID]map[string]bool
+	done    chan NavResult
+	once    sync.Once
+}
+
+// WatchNavigation starts watching the tab in ctx for a main-frame
+// navigation that reaches until (see NavStates). Start it before the
+// action that navigates, so a fast navigation isn't missed. urlPattern,
+// when set, is a glob matched against the whole URL ('*' any run of
+// characters, '?' one); navigations elsewhere, like a redirect on the
+// way, are passed over. With current, a page already on a matching URL
+// counts too, and only needs to reach until.
+func WatchNavigation(ctx context.Context, until string, urlPattern string, current bool) (*NavWatcher, error) {
+	event, ok := NavStates[until]
+	if !ok {
+		return nil, fmt.Errorf("unknown state %q, expected one of: %s", until, strings.Join(NavStateNames(), ", "))
+	}
+	w := &NavWatcher{
+		until:   until,
+		event:   event,
+		pattern: urlPattern,
+		start:   time.Now(),
+		seen:    map[cdp.LoaderID]map[string]bool{},
+		done:    make(chan NavResult, 1),
+	}
+	if urlPattern != "" {
+		w.url = globRegexp(urlPattern)
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *page.EventFrameNavigated:
+			if ev.Frame.ParentID == "" {
+				w.navigated(ev.Frame.LoaderID, ev.Frame.URL+ev.Frame.URLFragment)
+			}
+		case *page.EventNavigatedWithinDocument:
+			if ev.FrameID == w.mainFrame() && w.matches(ev.URL) {
+				w.finish(ev.URL, true)
+			}
+		case *page.EventLifecycleEvent:
+			w.lifecycle(ev.LoaderID, ev.Name)
+		}
+	})
+
+	var tree *page.FrameTree
+	err := chromedp.Run(ctx,
+		page.Enable(),
+		// Turning lifecycle events on sends those the current document
+		// has already reached, so toggle them in case they were on
+		page.SetLifecycleEventsEnabled(false),
+		page.SetLifecycleEventsEnabled(true),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			tree, err = page.GetFrameTree().Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.frameID = tree.Frame.ID
+	w.mu.Unlock()
+
+	if current {
+		url := tree.Frame.URL + tree.Frame.URLFragment
+		if w.matches(url) {
+			// In case Chrome didn't resend its lifecycle so far
+			var state string
+			if err := chromedp.Run(ctx, chromedp.Evaluate(`document.readyState`, &state)); err != nil {
+				return nil, err
+			}
+			if (state == "complete" && until != "networkidle") || (state == "interactive" && until == "domcontentloaded") {
+				w.lifecycle(tree.Frame.LoaderID, event)
+			}
+			w.navigated(tree.Frame.LoaderID, url)
+		}
+	}
+	return w, nil
+}
+
+// Wait blocks until the navigation completes, or fails when ctx ends first.
+func (w *NavWatcher) Wait(ctx context.Context) (NavResult, error) {
+	select {
+	case res := <-w.done:
+		return res, nil
+	case <-ctx.Done():
+		w.mu.Lock()
+		navURL := w.navURL
+		w.mu.Unlock()
+		switch {
+		case navURL != "":
+			return NavResult{}, fmt.Errorf("navigated to %s but it did not reach %s before timeout", navURL, w.until)
+		case w.pattern != "":
+			return NavResult{}, fmt.Errorf("no navigation to %s before timeout", w.pattern)
+		default:
+			return NavResult{}, fmt.Errorf("no navigation before timeout")
+		}
+	}
+}
+
+func (w *NavWatcher) mainFrame() cdp.FrameID {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.frameID
+}
+
+func (w *NavWatcher) matches(url string) bool {
+	return w.url == nil || w.url.MatchString(url)
+}
+
+// navigated handles a main-frame document commit: a matching one becomes
+// the document whose lifecycle is waited for.
+func (w *NavWatcher) navigated(loader cdp.LoaderID, url string) {
+	if !w.matches(url) {
+		return
+	}
+	w.mu.Lock()
+	w.loader = loader
+	w.navURL = url
+	reached := w.seen[loader][w.event]
+	w.mu.Unlock()
+	if reached {
+		w.finish(url, false)
+	}
+}
+
+func (w *NavWatcher) lifecycle(loader cdp.LoaderID, name string) {
+	w.mu.Lock()
+	if w.seen[loader] == nil {
+		w.seen[loader] = map[string]bool{}
+	}
+	w.seen[loader][name] = true
+	reached := loader == w.loader && name == w.event
+	url := w.navURL
+	w.mu.Unlock()
+	if reached {
+		w.finish(url, false)
+	}
+}
+
+func (w *NavWatcher) finish(url string, sameDocument bool) {
+	w.once.Do(func() {
+		w.done <- NavResult{URL: url, Until: w.until, SameDocument: sameDocument, Elapsed: time.Since(w.start)}
+	})
+}
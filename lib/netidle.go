@@ -0,0 +1,139 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// IdleOptions control WaitNetworkIdle.
+type IdleOptions struct {
+	// Quiet is how long the tab must stay at or under MaxInflight.
+	Quiet time.Duration
+	// MaxInflight is how many requests may still be in flight, for pages
+	// that keep a long poll or stream open.
+	MaxInflight int
+	// Ignore are glob URL patterns (as in MockRule) of requests not
+	// counted, e.g. analytics beacons.
+	Ignore []string
+}
+
+// IdleResult reports what WaitNetworkIdle saw before the tab went idle.
+type IdleResult struct {
+	Requests int           `json:"requests"`
+	Inflight int           `json:"inflight"`
+	Elapsed  time.Duration `json:"elapsed"`
+}
+
+// WaitNetworkIdle blocks until the tab has had no more than
+// opts.MaxInflight requests in flight for opts.Quiet, tracking Network
+// events from the call on. Requests already in flight when it starts
+// aren't seen. It fails, listing what is still in flight, when ctx ends
+// first.
+func WaitNetworkIdle(ctx context.Context, opts IdleOptions) (IdleResult, error) {
+	var ignore []*regexp.Regexp
+	for _, pattern := range opts.Ignore {
+		ignore = append(ignore, globRegexp(pattern))
+	}
+	ignored := func(url string) bool {
+		for _, re := range ignore {
+			if re.MatchString(url) {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := time.Now()
+	var mu sync.Mutex
+	inflight := map[network.RequestID]string{}
+	requests := 0
+	quietSince := start
+	changed := make(chan struct{}, 1)
+	update := func() {
+		// The caller holds mu
+		if len(inflight) > opts.MaxInflight {
+			quietSince = time.Time{}
+		} else if quietSince.IsZero() {
+			quietSince = time.Now()
+		}
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	listenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			// A redirect is sent again under the same ID
+			if _, ok := inflight[ev.RequestID]; ok || ignored(ev.Request.URL) {
+				return
+			}
+			inflight[ev.RequestID] = ev.Request.URL
+			requests++
+			update()
+		case *network.EventLoadingFinished:
+			if _, ok := inflight[ev.RequestID]; ok {
+				delete(inflight, ev.RequestID)
+				update()
+			}
+		case *network.EventLoadingFailed:
+			if _, ok := inflight[ev.RequestID]; ok {
+				delete(inflight, ev.RequestID)
+				update()
+			}
+		}
+	})
+	if err := chromedp.Run(ctx, network.Enable()); err != nil {
+		return IdleResult{}, err
+	}
+
+	for {
+		mu.Lock()
+		since := quietSince
+		res := IdleResult{Requests: requests, Inflight: len(inflight)}
+		mu.Unlock()
+		// While busy only a finished request can change anything
+		var elapsed <-chan time.Time
+		if !since.IsZero() {
+			quiet := time.Since(since)
+			if quiet >= opts.Quiet {
+				res.Elapsed = time.Since(start)
+				return res, nil
+			}
+			elapsed = time.After(opts.Quiet - quiet)
+		}
+		select {
+		case <-changed:
+		case <-elapsed:
+		case <-ctx.Done():
+			mu.Lock()
+			urls := make([]string, 0, len(inflight))
+			for _, url := range inflight {
+				urls = append(urls, url)
+			}
+			mu.Unlock()
+			if len(urls) == 0 {
+				return IdleResult{}, fmt.Errorf("network not idle for %s before timeout", opts.Quiet)
+			}
+			sort.Strings(urls)
+			n := len(urls)
+			if n > 5 {
+				urls = append(urls[:5], fmt.Sprintf("and %d more", n-5))
+			}
+			return IdleResult{}, fmt.Errorf("network not idle for %s before timeout, %d in flight: %s", opts.Quiet, n, strings.Join(urls, ", "))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// RequestTiming is a finished request and how long it took from being sent
+// to its last byte (or failure), per Chrome's network timestamps.
+type RequestTiming struct {
+	RequestID string        `json:"request_id"`
+	URL       string        `json:"url"`
+	Method    string        `json:"method"`
+	Status    int64         `json:"status,omitempty"`
+	Failed    bool          `json:"failed,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// NetworkRecorder collects the timing of every request a tab completes for
+// as long as the tab context lives.
+type NetworkRecorder struct {
+	mu       sync.Mutex
+	pending  map[network.RequestID]*RequestTiming
+	started  map[network.RequestID]time.Time
+	finished []RequestTiming
+	notify   chan struct{}
+}
+
+// NewNetworkRecorder starts recording on an attached tab context.
+func NewNetworkRecorder(ctx context.Context) (*NetworkRecorder, error) {
+	r := &NetworkRecorder{
+		pending: map[network.RequestID]*RequestTiming{},
+		started: map[network.RequestID]time.Time{},
+		notify:  make(chan struct{}),
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if ev.Timestamp == nil {
+				return
+			}
+			r.pending[ev.RequestID] = &RequestTiming{RequestID: string(ev.RequestID), URL: ev.Request.URL, Method: ev.Request.Method}
+			r.started[ev.RequestID] = ev.Timestamp.Time()
+		case *network.EventResponseReceived:
+			if req, ok := r.pending[ev.RequestID]; ok {
+				req.Status = ev.Response.Status
+			}
+		case *network.EventLoadingFinished:
+			if ev.Timestamp != nil {
+				r.finish(ev.RequestID, ev.Timestamp.Time(), false)
+			}
+		case *network.EventLoadingFailed:
+			if ev.Timestamp != nil {
+				r.finish(ev.RequestID, ev.Timestamp.Time(), true)
+			}
+		}
+	})
+	if err := chromedp.Run(ctx, network.Enable()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// finish moves a request to finished; the caller holds r.mu.
+func (r *NetworkRecorder) finish(id network.RequestID, at time.Time, failed bool) {
+	req, ok := r.pending[id]
+	if !ok {
+		return
+	}
+	req.Failed = failed
+	req.Duration = at.Sub(r.started[id])
+	r.finished = append(r.finished, *req)
+	delete(r.pending, id)
+	delete(r.started, id)
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// Matching returns finished requests whose URL matches a glob pattern (as
+// in MockRule), oldest first.
+func (r *NetworkRecorder) Matching(pattern string) []RequestTiming {
+	re := globRegexp(pattern)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []RequestTiming
+	for _, req := range r.finished {
+		if re.MatchString(req.URL) {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+// WaitMatching returns the finished requests matching pattern, waiting
+// until there is at least one or ctx ends.
+func (r *NetworkRecorder) WaitMatching(ctx context.Context, pattern string) ([]RequestTiming, error) {
+	for {
+		r.mu.Lock()
+		notify := r.notify
+		r.mu.Unlock()
+		if found := r.Matching(pattern); len(found) > 0 {
+			return found, nil
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no finished request matches %q", pattern)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// BodyOptions control how ListenNetworkBodies reports payloads: with Dir
+// set, bodies are written there as <requestId>.request and
+// <requestId>.response; otherwise they are inlined, cut to MaxInline bytes.
+type BodyOptions struct {
+	Dir       string
+	MaxInline int64 // 0 for no limit
+	// Pending, if set, counts bodies being fetched, so a caller stopping
+	// early can wait for them
+	Pending *sync.WaitGroup
+}
+
+// ListenNetworkBodies calls fn with a "body" event once each request
+// finishes, carrying its post data and response body. Bodies are fetched
+// off the event goroutine, so fn may be called from several goroutines.
+// Register it before ListenNetwork, which enables the Network domain.
+func ListenNetworkBodies(ctx context.Context, opts BodyOptions, fn func(NetworkEvent)) error {
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+			return err
+		}
+	}
+	type pending struct {
+		url      string
+		post     []byte
+		fetch    bool // post data was too large for the event
+		status   int64
+		mimeType string
+	}
+	requests := map[network.RequestID]*pending{}
+
+	emit := func(id network.RequestID, req *pending, body bool) {
+		c := chromedp.FromContext(ctx)
+		if c == nil || c.Target == nil {
+			return
+		}
+		execCtx := cdp.WithExecutor(ctx, c.Target)
+		if opts.Pending != nil {
+			opts.Pending.Add(1)
+		}
+		go func() {
+			if opts.Pending != nil {
+				defer opts.Pending.Done()
+			}
+			evt := NetworkEvent{Type: "body", RequestID: string(id), URL: req.url, Status: req.status, MimeType: req.mimeType}
+			post := req.post
+			if req.fetch {
+				if text, err := network.GetRequestPostData(id).Do(execCtx); err == nil {
+					post = []byte(text)
+				}
+			}
+			if len(post) > 0 {
+				evt.PostData, evt.PostDataFile, evt.PostDataEncoding = opts.store(id, "request", post, &evt)
+			}
+			if body {
+				data, err := network.GetResponseBody(id).Do(execCtx)
+				if err != nil {
+					evt.Error = err.Error()
+				} else {
+					evt.Size = int64(len(data))
+					evt.Body, evt.BodyFile, evt.Encoding = opts.store(id, "response", data, &evt)
+				}
+			}
+			if evt.PostData == "" && evt.PostDataFile == "" && !body {
+				return
+			}
+			evt.Timestamp = time.Now()
+			fn(evt)
+		}()
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			req := &pending{url: ev.Request.URL}
+			if ev.Request.HasPostData {
+				for _, e := range ev.Request.PostDataEntries {
+					if b, err := base64.StdEncoding.DecodeString(e.Bytes); err == nil {
+						req.post = append(req.post, b...)
+					}
+				}
+				req.fetch = len(ev.Request.PostDataEntries) == 0
+			}
+			requests[ev.RequestID] = req
+		case *network.EventResponseReceived:
+			if req := requests[ev.RequestID]; req != nil {
+				req.status = ev.Response.Status
+				req.mimeType = ev.Response.MimeType
+			}
+		case *network.EventLoadingFinished:
+			if req := requests[ev.RequestID]; req != nil {
+				delete(requests, ev.RequestID)
+				// Redirects and empty responses have no body to fetch
+				emit(ev.RequestID, req, req.status != 204 && req.status/100 != 3)
+			}
+		case *network.EventLoadingFailed:
+			if req := requests[ev.RequestID]; req != nil {
+				delete(requests, ev.RequestID)
+				emit(ev.RequestID, req, false)
+			}
+		}
+	})
+	return nil
+}
+
+// store writes data to the body directory and returns the file, or returns
+// it inline, base64 encoded unless it is UTF-8 text, and marks evt truncated
+// when cut to MaxInline.
+func (o BodyOptions) store(id network.RequestID, kind string, data []byte, evt *NetworkEvent) (text string, file string, encoding string) {
+	if o.Dir != "" {
+		path := filepath.Join(o.Dir, sanitizeLabel(string(id))+"."+kind)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			evt.Error = err.Error()
+			return "", "", ""
+		}
+		return "", path, ""
+	}
+	isText := utf8.Valid(data)
+	if o.MaxInline > 0 && int64(len(data)) > o.MaxInline {
+		data = data[:o.MaxInline]
+		// Drop a rune split by the cut
+		for isText && len(data) > 0 && !utf8.Valid(data) {
+			data = data[:len(data)-1]
+		}
+		evt.Truncated = true
+	}
+	if isText {
+		return string(data), "", ""
+	}
+	return base64.StdEncoding.EncodeToString(data), "", "base64"
+}
@@ -0,0 +1,388 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Overrides is the emulation and interception state a Session keeps for a
+// tab. Chrome drops these when the DevTools connection closes, so they only
+// outlive one-shot commands when held by the daemon (chrome serve).
+type Overrides struct {
+	Target    string             `json:"target"`
+	URL       string             `json:"url,omitempty"`
+	Emulation *Preset            `json:"emulation,omitempty"`
+	Network   *NetworkConditions `json:"network,omitempty"`
+	Blocked   []string           `json:"blocked,omitempty"`
+	Headers   map[string]string  `json:"headers,omitempty"`
+	Mocks     []MockRule         `json:"mocks,omitempty"`
+}
+
+type tabOverrides struct {
+	emulation   *Preset
+	network     *NetworkConditions
+	blocked     []string
+	headers     map[string]string
+	interceptor *Interceptor
+}
+
+func (s *Session) tabOverrides(selector string) (*sessionTab, string, *tabOverrides, error) {
+	tab, id, err := s.tab(selector)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.overrides[id]
+	if !ok {
+		state = &tabOverrides{headers: map[string]string{}, interceptor: NewInterceptor(tab.ctx)}
+		s.overrides[id] = state
+	}
+	return tab, id, state, nil
+}
+
+// reapply restores the tab's overrides on a fresh connection to it.
+func (s *Session) reapply(id string, tabCtx context.Context) error {
+	s.mu.Lock()
+	state, ok := s.overrides[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	var actions []chromedp.Action
+	if state.emulation != nil {
+		actions = append(actions, state.emulation.Actions()...)
+	}
+	if state.network != nil {
+		actions = append(actions, state.network.Action())
+	}
+	if len(state.blocked) > 0 || len(state.headers) > 0 {
+		actions = append(actions,
+			network.Enable(),
+			network.SetBlockedURLs(append([]string{}, state.blocked...)),
+			network.SetExtraHTTPHeaders(toNetworkHeaders(state.headers)),
+		)
+	}
+	// The old interceptor listened on the dropped connection
+	rules := state.interceptor.Rules()
+	state.interceptor = NewInterceptor(tabCtx)
+	interceptor := state.interceptor
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(tabCtx, DefaultTimeout)
+	defer cancel()
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return fmt.Errorf("restoring overrides: %w", err)
+	}
+	if len(rules) > 0 {
+		if err := interceptor.Add(ctx, rules...); err != nil {
+			return fmt.Errorf("restoring mocks: %w", err)
+		}
+	}
+	return nil
+}
+
+// Emulate applies preset on top of the tab's current emulation. Preset
+// headers are merged into the tab's extra headers.
+func (s *Session) Emulate(selector string, preset Preset, timeout time.Duration) error {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	headers := copyHeaders(state.headers)
+	for key, value := range preset.Headers {
+		headers[key] = value
+	}
+	preset.Headers = nil
+	merged := preset
+	if state.emulation != nil {
+		merged = MergePreset(*state.emulation, preset)
+	}
+	s.mu.Unlock()
+
+	actions := append(preset.Actions(), network.Enable(), network.SetExtraHTTPHeaders(toNetworkHeaders(headers)))
+	if err := s.runOn(id, tab, timeout, actions...); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	state.emulation = &merged
+	state.headers = headers
+	s.mu.Unlock()
+	return nil
+}
+
+// Throttle sets the tab's network conditions; the zero value restores normal networking.
+func (s *Session) Throttle(selector string, conditions NetworkConditions, timeout time.Duration) error {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return err
+	}
+	if err := s.runOn(id, tab, timeout, conditions.Action()); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conditions == (NetworkConditions{}) {
+		state.network = nil
+	} else {
+		state.network = &conditions
+	}
+	return nil
+}
+
+// Block adds URL patterns ('*' wildcards, @group names) to the tab's
+// blocked list.
+func (s *Session) Block(selector string, patterns []string, timeout time.Duration) error {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	blocked, err := ExpandBlockPatterns(append(append([]string{}, state.blocked...), patterns...))
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := s.runOn(id, tab, timeout, network.Enable(), network.SetBlockedURLs(blocked)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	state.blocked = blocked
+	s.mu.Unlock()
+	return nil
+}
+
+// Unblock removes URL patterns from the tab's blocked list, or every
+// pattern when patterns is empty. It returns the patterns still blocked.
+func (s *Session) Unblock(selector string, patterns []string, timeout time.Duration) ([]string, error) {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return nil, err
+	}
+	drop := map[string]bool{}
+	for _, p := range patterns {
+		drop[p] = true
+	}
+	blocked := []string{}
+	s.mu.Lock()
+	for _, p := range state.blocked {
+		if len(patterns) > 0 && !drop[p] {
+			blocked = append(blocked, p)
+		}
+	}
+	s.mu.Unlock()
+	if err := s.runOn(id, tab, timeout, network.Enable(), network.SetBlockedURLs(blocked)); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	state.blocked = blocked
+	s.mu.Unlock()
+	return append([]string{}, blocked...), nil
+}
+
+// SetHeaders merges extra HTTP headers sent with every request from the tab.
+// An empty value removes a header.
+func (s *Session) SetHeaders(selector string, headers map[string]string, timeout time.Duration) error {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	merged := copyHeaders(state.headers)
+	s.mu.Unlock()
+	for key, value := range headers {
+		if value == "" {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	if err := s.runOn(id, tab, timeout, network.Enable(), network.SetExtraHTTPHeaders(toNetworkHeaders(merged))); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	state.headers = merged
+	s.mu.Unlock()
+	return nil
+}
+
+// Mock adds Fetch interception rules to the tab.
+func (s *Session) Mock(selector string, rules []MockRule, timeout time.Duration) error {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	interceptor := state.interceptor
+	s.mu.Unlock()
+	return s.runOn(id, tab, timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		return interceptor.Add(ctx, rules...)
+	}))
+}
+
+// Unmock removes every Fetch interception rule from the tab.
+func (s *Session) Unmock(selector string, timeout time.Duration) error {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	interceptor := state.interceptor
+	s.mu.Unlock()
+	return s.runOn(id, tab, timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		return interceptor.Clear(ctx)
+	}))
+}
+
+// Overrides returns the tab's current overrides.
+func (s *Session) Overrides(selector string) (Overrides, error) {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return Overrides{}, err
+	}
+	var url string
+	_ = s.runOn(id, tab, 5*time.Second, chromedp.Location(&url))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Overrides{
+		Target:    id,
+		URL:       url,
+		Emulation: state.emulation,
+		Network:   state.network,
+		Blocked:   append([]string{}, state.blocked...),
+		Headers:   copyHeaders(state.headers),
+		Mocks:     state.interceptor.Rules(),
+	}, nil
+}
+
+// ClearOverrides resets emulation, throttling, blocking, headers, and mocks on the tab.
+func (s *Session) ClearOverrides(selector string, timeout time.Duration) error {
+	tab, id, state, err := s.tabOverrides(selector)
+	if err != nil {
+		return err
+	}
+	err = s.runOn(id, tab, timeout,
+		emulation.ClearDeviceMetricsOverride(),
+		emulation.SetTouchEmulationEnabled(false),
+		emulation.ClearGeolocationOverride(),
+		emulation.SetLocaleOverride(),
+		emulation.SetTimezoneOverride(""),
+		emulation.SetUserAgentOverride(""),
+		browser.ResetPermissions(),
+		network.Enable(),
+		NetworkConditions{}.Action(),
+		network.SetBlockedURLs([]string{}),
+		network.SetExtraHTTPHeaders(network.Headers{}),
+		fetch.Disable(),
+	)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state.interceptor.mu.Lock()
+	state.interceptor.rules = nil
+	state.interceptor.matchers = nil
+	state.interceptor.mu.Unlock()
+	s.overrides[id] = &tabOverrides{headers: map[string]string{}, interceptor: state.interceptor}
+	return nil
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for key, value := range headers {
+		out[key] = value
+	}
+	return out
+}
+
+func toNetworkHeaders(headers map[string]string) network.Headers {
+	out := network.Headers{}
+	for key, value := range headers {
+		out[key] = value
+	}
+	return out
+}
+
+// DaemonAddr returns the address of the serve daemon CLI commands talk to
+// ($CHROME_DAEMON, default 127.0.0.1:9333).
+func DaemonAddr() string {
+	if addr := strings.TrimSpace(os.Getenv("CHROME_DAEMON")); addr != "" {
+		return addr
+	}
+	return "127.0.0.1:9333"
+}
+
+// DaemonRunning reports whether a serve daemon is listening on DaemonAddr
+// and connected to the same Chrome port as this process.
+func DaemonRunning() bool {
+	client := http.Client{Timeout: 500 * time.Millisecond}
+	resp, err := client.Get("http://" + DaemonAddr() + "/health")
+	if err != nil {
+		return false
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var health struct {
+		Ok     bool `json:"ok"`
+		Result struct {
+			Port int `json:"port"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return false
+	}
+	return health.Ok && health.Result.Port == GetPort()
+}
+
+// DaemonCall POSTs body to a daemon endpoint and decodes the result into
+// result (may be nil).
+func DaemonCall(path string, body any, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: DefaultTimeout + 5*time.Second}
+	resp, err := client.Post("http://"+DaemonAddr()+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("daemon: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var res struct {
+		Ok     bool            `json:"ok"`
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("daemon: %w", err)
+	}
+	if !res.Ok {
+		return errors.New(res.Error)
+	}
+	if result != nil && len(res.Result) > 0 {
+		return json.Unmarshal(res.Result, result)
+	}
+	return nil
+}
+
+// DaemonTarget returns the tab selector to send to the daemon: the -t value,
+// else this process's CHROME_TARGET, since the daemon cannot see our env.
+func DaemonTarget(args TargetArgs) string {
+	if selector := args.Selector(); selector != "" {
+		return selector
+	}
+	return strings.TrimSpace(os.Getenv("CHROME_TARGET"))
+}
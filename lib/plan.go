@@ -0,0 +1,209 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PlanStep is one line of a workflow's execution plan, as printed by
+// 'chrome run --dry-run'.
+type PlanStep struct {
+	// Number is the step's number at run time; steps inside a loop are
+	// numbered as in its first iteration (4.1.1)
+	Number string
+	// Depth is how many if or loop steps enclose the step
+	Depth int
+	// Branch is then or else for the steps of an if, "" otherwise
+	Branch string
+	Action string
+	// Args are the step's Describe() args with known variables substituted;
+	// variables set by capture or eval steps stay as {{name}}
+	Args []string
+}
+
+// PlanWorkflow walks wf without a browser, as RunWorkflow would run it when
+// every if takes both branches and every loop runs once. It returns the plan
+// and every problem found: variables used before they are set and selectors
+// that are not valid CSS. vars override the workflow's vars, like
+// RunOptions.Vars.
+func PlanWorkflow(wf Workflow, vars map[string]string) ([]PlanStep, error) {
+	known := map[string]string{}
+	maps.Copy(known, wf.Vars)
+	maps.Copy(known, vars)
+	p := &workflowPlan{vars: known}
+	p.plan("", 0, "", wf.Steps)
+	return p.steps, errors.Join(p.problems...)
+}
+
+type workflowPlan struct {
+	vars     map[string]string
+	steps    []PlanStep
+	problems []error
+}
+
+func (p *workflowPlan) plan(prefix string, depth int, branch string, steps []WorkflowStep) {
+	for i, step := range steps {
+		number := prefix + strconv.Itoa(i+1)
+		fail := func(err error) {
+			p.problems = append(p.problems, fmt.Errorf("step %s (%s): %w", number, step.Action, err))
+		}
+		expanded, err := step.expand(p.vars)
+		if err != nil {
+			fail(err)
+			expanded = step
+		}
+		selectors := []string{expanded.Selector}
+		for _, c := range []*WorkflowCondition{step.When, step.While, step.Until} {
+			if c == nil {
+				continue
+			}
+			assert, err := c.assert().expand(p.vars)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			selectors = append(selectors, assert.Selector)
+		}
+		for _, selector := range selectors {
+			// Selectors built from captured values are only known at run time
+			if selector == "" || strings.Contains(selector, "{{") {
+				continue
+			}
+			if err := CheckSelector(selector); err != nil {
+				fail(fmt.Errorf("selector %q: %w", selector, err))
+			}
+		}
+		p.steps = append(p.steps, PlanStep{
+			Number: number,
+			Depth:  depth,
+			Branch: branch,
+			Action: step.Action,
+			Args:   expanded.Describe(),
+		})
+		if (step.Action == "capture" || step.Action == "eval") && step.Var != "" {
+			p.vars[step.Var] = "{{" + step.Var + "}}"
+		}
+		switch step.Action {
+		case "if":
+			p.plan(number+".", depth+1, "then", step.Then)
+			p.plan(number+".", depth+1, "else", step.Else)
+		case "loop":
+			p.plan(number+".1.", depth+1, "", step.Steps)
+		}
+	}
+}
+
+// CheckSelector reports CSS selector syntax errors that document.querySelector
+// would throw on: unbalanced brackets, parentheses, and quotes, empty
+// selectors around commas and combinators, and names missing after #, ., or
+// :. It is a quick offline check, not a full CSS parser.
+func CheckSelector(selector string) error {
+	s := []rune(strings.TrimSpace(selector))
+	if len(s) == 0 {
+		return errors.New("selector is empty")
+	}
+	var open []int // positions of unclosed [ and (
+	var quote rune
+	empty := true       // nothing yet in the current comma-separated selector
+	combinator := false // the last top-level token was >, +, or ~
+	nameStart := func(i int) bool {
+		if i >= len(s) {
+			return false
+		}
+		c := s[i]
+		return c == '_' || c == '-' || c == '\\' || c == '*' || unicode.IsLetter(c) || c > unicode.MaxASCII
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\\':
+			i++
+			empty, combinator = false, false
+			continue
+		case '"', '\'':
+			if len(open) == 0 {
+				return fmt.Errorf("unexpected %c at %d, quotes belong inside [...] or (...)", c, i)
+			}
+			quote = c
+			continue
+		case '[', '(':
+			if c == '[' && len(open) > 0 && s[open[len(open)-1]] == '[' {
+				return fmt.Errorf("unexpected [ at %d", i)
+			}
+			open = append(open, i)
+			empty, combinator = false, false
+			continue
+		case ']', ')':
+			want := '['
+			if c == ')' {
+				want = '('
+			}
+			if len(open) == 0 || s[open[len(open)-1]] != want {
+				return fmt.Errorf("unmatched %c at %d", c, i)
+			}
+			if c == ']' && strings.TrimSpace(string(s[open[len(open)-1]+1:i])) == "" {
+				return fmt.Errorf("empty [] at %d", i)
+			}
+			open = open[:len(open)-1]
+			continue
+		}
+		if len(open) > 0 {
+			continue
+		}
+		switch {
+		case c == ',':
+			if empty || combinator {
+				return fmt.Errorf("empty selector before , at %d", i)
+			}
+			empty = true
+		case c == '>' || c == '+' || c == '~':
+			if empty || combinator {
+				return fmt.Errorf("combinator %c at %d has nothing before it", c, i)
+			}
+			combinator = true
+		case c == '/':
+			return fmt.Errorf("unexpected / at %d, XPath is not supported", i)
+		case c == '{' || c == '}' || c == ';' || c == '!':
+			return fmt.Errorf("unexpected %c at %d", c, i)
+		case c == '#' || c == '.':
+			if !nameStart(i+1) || s[i+1] == '*' {
+				return fmt.Errorf("%c at %d needs a name", c, i)
+			}
+			empty, combinator = false, false
+		case c == ':':
+			if i+1 < len(s) && s[i+1] == ':' {
+				i++
+			}
+			if !nameStart(i+1) || s[i+1] == '*' {
+				return fmt.Errorf(": at %d needs a pseudo-class name", i)
+			}
+			empty, combinator = false, false
+		case unicode.IsSpace(c):
+		default:
+			empty, combinator = false, false
+		}
+	}
+	switch {
+	case quote != 0:
+		return fmt.Errorf("unclosed %c", quote)
+	case len(open) > 0:
+		return fmt.Errorf("unclosed %c at %d", s[open[len(open)-1]], open[len(open)-1])
+	case combinator:
+		return errors.New("selector ends with a combinator")
+	case empty:
+		return errors.New("selector ends with ,")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckSelector(t *testing.T) {
+	valid := []string{
+		"button",
+		"#submit",
+		".a.b",
+		"div > span",
+		"ul li + li ~ li",
+		"input[type=\"email\"]",
+		"a[href^='https://']",
+		"button:not(.disabled)",
+		"li:nth-child(2n+1)",
+		"p::first-line",
+		"h1, h2,h3",
+		"*",
+		"#a\\:b",
+		"[data-label=\"a, b > c\"]",
+		"div:has(> img)",
+	}
+	for _, selector := range valid {
+		if err := CheckSelector(selector); err != nil {
+			t.Errorf("CheckSelector(%q): %v", selector, err)
+		}
+	}
+	invalid := map[string]string{
+		"":                       "empty",
+		"   ":                    "empty",
+		"div[":                   "unclosed [",
+		"div]":                   "unmatched ]",
+		"a:not(.b":               "unclosed (",
+		"[]":                     "empty []",
+		"[a[b]]":                 "unexpected [",
+		"input[value=\"x]":       "unclosed \"",
+		"'text'":                 "quotes belong inside",
+		"a,":                     "ends with ,",
+		",a":                     "empty selector before ,",
+		"a,,b":                   "empty selector before ,",
+		"> a":                    "nothing before it",
+		"a >":                    "ends with a combinator",
+		"a > > b":                "nothing before it",
+		"#":                      "needs a name",
+		".1col":                  "needs a name",
+		"a:":                     "pseudo-class name",
+		"//div[@id='x']":         "XPath",
+		"a { color: red }":       "unexpected {",
+		"button:has-text(\"x\")": "",
+	}
+	for selector, want := range invalid {
+		err := CheckSelector(selector)
+		if want == "" {
+			// Playwright pseudo-classes are valid syntax; the browser rejects them
+			if err != nil {
+				t.Errorf("CheckSelector(%q): %v", selector, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("CheckSelector(%q) passed, want an error containing %q", selector, want)
+		} else if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckSelector(%q) = %v, want an error containing %q", selector, err, want)
+		}
+	}
+}
+
+func TestPlanWorkflow(t *testing.T) {
+	wf := Workflow{
+		Vars: map[string]string{"base": "http://localhost:3000", "user": "default"},
+		Steps: []WorkflowStep{
+			{Action: "navigate", URL: "{{base}}/login"},
+			{Action: "fill", Selector: "#user", Value: "{{user}}"},
+			{Action: "capture", Var: "token", Selector: ".token"},
+			{Action: "if", When: &WorkflowCondition{Selector: "#banner"},
+				Then: []WorkflowStep{{Action: "click", Selector: "#dismiss"}},
+				Else: []WorkflowStep{{Action: "eval", Script: "window.token = '{{token}}'"}},
+			},
+			{Action: "loop", Max: 3, Until: &WorkflowCondition{Text: "done"},
+				Steps: []WorkflowStep{{Action: "clicktext", Text: "Next"}},
+			},
+		},
+	}
+	steps, err := PlanWorkflow(wf, map[string]string{"user": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type line struct {
+		Number, Branch, Action string
+		Depth                  int
+		Args                   []string
+	}
+	var got []line
+	for _, s := range steps {
+		got = append(got, line{s.Number, s.Branch, s.Action, s.Depth, s.Args})
+	}
+	want := []line{
+		{"1", "", "navigate", 0, []string{"http://localhost:3000/login"}},
+		{"2", "", "fill", 0, []string{"#user", "alice"}},
+		{"3", "", "capture", 0, []string{"token", "--selector=.token"}},
+		{"4", "", "if", 0, steps[3].Args},
+		{"4.1", "then", "click", 1, []string{"#dismiss"}},
+		{"4.1", "else", "eval", 1, []string{"window.token = '{{token}}'"}},
+		{"5", "", "loop", 0, steps[6].Args},
+		{"5.1.1", "", "clicktext", 1, []string{"Next"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("plan:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestPlanWorkflowProblems(t *testing.T) {
+	wf := Workflow{Steps: []WorkflowStep{
+		{Action: "click", Selector: "button:has-text(Login"},
+		{Action: "fill", Selector: "#q", Value: "{{query}}"},
+		{Action: "if", When: &WorkflowCondition{Selector: "div >"},
+			Then: []WorkflowStep{{Action: "waitfor", Selector: "#ok"}},
+		},
+		{Action: "eval", Script: "1", Var: "n"},
+		{Action: "waitfor", Selector: "#row-{{n}}"},
+	}}
+	steps, err := PlanWorkflow(wf, nil)
+	if len(steps) != 6 {
+		t.Fatalf("got %d plan steps, want 6 even with problems", len(steps))
+	}
+	if err == nil {
+		t.Fatal("want problems")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"step 1 (click): selector",
+		"step 2 (fill): undefined variable query",
+		"step 3 (if): selector \"div >\"",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("problems %q missing %q", msg, want)
+		}
+	}
+	// {{n}} is set by the eval step, so step 5 is only known at run time
+	if strings.Contains(msg, "step 5") {
+		t.Errorf("problems %q flag a selector built from a captured value", msg)
+	}
+}
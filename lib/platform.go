@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// FindChrome locates the Chrome executable based on the current platform.
+func FindChrome() string {
+	// Check CHROME_PATH env var first.
+	if p := strings.TrimSpace(os.Getenv("CHROME_PATH")); p != "" {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+
+	var candidates []string
+
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = []string{
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome Canary.app/Contents/MacOS/Google Chrome Canary",
+		}
+	case "linux":
+		if IsWSL() {
+			candidates = []string{
+				"/mnt/c/Program Files/Google/Chrome/Application/chrome.exe",
+				"/mnt/c/Program Files (x86)/Google/Chrome/Application/chrome.exe",
+			}
+		}
+		candidates = append(candidates,
+			"/usr/bin/google-chrome",
+			"/usr/bin/google-chrome-stable",
+			"/usr/bin/chromium",
+			"/usr/bin/chromium-browser",
+			"/snap/bin/chromium",
+		)
+	case "windows":
+		candidates = []string{
+			filepath.Join(os.Getenv("PROGRAMFILES"), "Google", "Chrome", "Application", "chrome.exe"),
+			filepath.Join(os.Getenv("PROGRAMFILES(X86)"), "Google", "Chrome", "Application", "chrome.exe"),
+			filepath.Join(os.Getenv("LOCALAPPDATA"), "Google", "Chrome", "Application", "chrome.exe"),
+		}
+	}
+
+	for _, p := range candidates {
+		if p == "" {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// DefaultUserDataDir returns the user data directory launch uses when
+// --user-data-dir is not given.
+func DefaultUserDataDir() string {
+	if IsWSL() {
+		return "C:\\temp\\chrome"
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "chrome")
+	}
+	return filepath.Join(home, ".chrome")
+}
+
+// IsWSL detects if running under Windows Subsystem for Linux.
+func IsWSL() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+
+	if _, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop"); err == nil {
+		return true
+	}
+
+	data, err := os.ReadFile("/proc/version")
+	if err != nil {
+		return false
+	}
+
+	s := string(data)
+	return strings.Contains(s, "Microsoft") || strings.Contains(s, "WSL")
+}
@@ -0,0 +1,201 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"gopkg.in/yaml.v3"
+)
+
+// Preset bundles emulation settings applied to a tab in one shot.
+// Empty fields are left untouched.
+type Preset struct {
+	Viewport    *PresetViewport    `json:"viewport,omitempty" yaml:"viewport,omitempty"`
+	UserAgent   string             `json:"user_agent,omitempty" yaml:"user_agent,omitempty"`
+	Locale      string             `json:"locale,omitempty" yaml:"locale,omitempty"`
+	Timezone    string             `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	Geolocation *PresetGeolocation `json:"geolocation,omitempty" yaml:"geolocation,omitempty"`
+	Permissions []string           `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	Headers     map[string]string  `json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+type PresetViewport struct {
+	Width  int64   `json:"width" yaml:"width"`
+	Height int64   `json:"height" yaml:"height"`
+	Scale  float64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Mobile bool    `json:"mobile,omitempty" yaml:"mobile,omitempty"`
+}
+
+type PresetGeolocation struct {
+	Latitude  float64 `json:"latitude" yaml:"latitude"`
+	Longitude float64 `json:"longitude" yaml:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty" yaml:"accuracy,omitempty"`
+}
+
+// PresetsPath returns the presets config file ($CHROME_PRESETS, else
+// $XDG_CONFIG_HOME/chrome-cli/presets.yaml or ~/.config/chrome-cli/presets.yaml).
+func PresetsPath() (string, error) {
+	if path := strings.TrimSpace(os.Getenv("CHROME_PRESETS")); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chrome-cli", "presets.yaml"), nil
+}
+
+// LoadPresets reads a YAML or JSON map of preset name to Preset.
+// An empty path means PresetsPath().
+func LoadPresets(path string) (map[string]Preset, error) {
+	if path == "" {
+		var err error
+		path, err = PresetsPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no presets config at %s", path)
+		}
+		return nil, err
+	}
+	presets := map[string]Preset{}
+	if err := yaml.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return presets, nil
+}
+
+// LoadPreset returns one named preset from the config at path.
+func LoadPreset(path string, name string) (Preset, error) {
+	presets, err := LoadPresets(path)
+	if err != nil {
+		return Preset{}, err
+	}
+	preset, ok := presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q, available: %s", name, strings.Join(PresetNames(presets), ", "))
+	}
+	return preset, nil
+}
+
+// PresetNames returns the preset names sorted.
+func PresetNames(presets map[string]Preset) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Actions returns the CDP calls that apply the preset to the current tab.
+//
+// Emulation overrides and extra headers belong to the DevTools session and
+// are cleared when it disconnects, so apply them on the connection that will
+// do the work (run, serve, repl) or hold the connection open.
+func (p Preset) Actions() []chromedp.Action {
+	var actions []chromedp.Action
+	add := func(fn func(ctx context.Context) error) {
+		actions = append(actions, chromedp.ActionFunc(fn))
+	}
+	if v := p.Viewport; v != nil {
+		scale := v.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		add(func(ctx context.Context) error {
+			return emulation.SetDeviceMetricsOverride(v.Width, v.Height, scale, v.Mobile).Do(ctx)
+		})
+		add(func(ctx context.Context) error {
+			return emulation.SetTouchEmulationEnabled(v.Mobile).Do(ctx)
+		})
+	}
+	if p.UserAgent != "" {
+		add(func(ctx context.Context) error {
+			params := emulation.SetUserAgentOverride(p.UserAgent)
+			if p.Locale != "" {
+				params = params.WithAcceptLanguage(p.Locale)
+			}
+			return params.Do(ctx)
+		})
+	}
+	if p.Locale != "" {
+		add(func(ctx context.Context) error {
+			return emulation.SetLocaleOverride().WithLocale(p.Locale).Do(ctx)
+		})
+	}
+	if p.Timezone != "" {
+		add(func(ctx context.Context) error {
+			return emulation.SetTimezoneOverride(p.Timezone).Do(ctx)
+		})
+	}
+	if len(p.Permissions) > 0 {
+		add(func(ctx context.Context) error {
+			perms := make([]browser.PermissionType, 0, len(p.Permissions))
+			for _, perm := range p.Permissions {
+				perms = append(perms, browser.PermissionType(perm))
+			}
+			return browser.GrantPermissions(perms).Do(ctx)
+		})
+	}
+	if g := p.Geolocation; g != nil {
+		accuracy := g.Accuracy
+		if accuracy == 0 {
+			accuracy = 1
+		}
+		add(func(ctx context.Context) error {
+			return emulation.SetGeolocationOverride().
+				WithLatitude(g.Latitude).
+				WithLongitude(g.Longitude).
+				WithAccuracy(accuracy).
+				Do(ctx)
+		})
+	}
+	if len(p.Headers) > 0 {
+		headers := network.Headers{}
+		for key, value := range p.Headers {
+			headers[key] = value
+		}
+		actions = append(actions, network.Enable(), network.SetExtraHTTPHeaders(headers))
+	}
+	return actions
+}
+
+// MergePreset returns base with every field set in override replacing it.
+func MergePreset(base Preset, override Preset) Preset {
+	if override.Viewport != nil {
+		base.Viewport = override.Viewport
+	}
+	if override.UserAgent != "" {
+		base.UserAgent = override.UserAgent
+	}
+	if override.Locale != "" {
+		base.Locale = override.Locale
+	}
+	if override.Timezone != "" {
+		base.Timezone = override.Timezone
+	}
+	if override.Geolocation != nil {
+		base.Geolocation = override.Geolocation
+	}
+	if len(override.Permissions) > 0 {
+		base.Permissions = override.Permissions
+	}
+	if len(override.Headers) > 0 {
+		base.Headers = override.Headers
+	}
+	return base
+}
@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// maxObjectItems caps the properties or elements kept per object when
+// resolving console arguments.
+const maxObjectItems = 100
+
+// subtypeInternalEntry is the subtype of Map and Set entries, which
+// cdproto doesn't list.
+const subtypeInternalEntry runtime.Subtype = "internal#entry"
+
+// ResolveObject turns a remote object into a JSON-friendly value, reading
+// objects' own enumerable properties (and arrays' elements, and Map and Set
+// entries) with Runtime.getProperties down to depth levels. Primitives are
+// returned as values; functions, errors, dates, regexps, DOM nodes, and
+// objects past depth as their description, e.g. "Object" or "Array(3)".
+// ctx must be able to run commands on the tab: not the event goroutine.
+func ResolveObject(ctx context.Context, obj *runtime.RemoteObject, depth int) any {
+	if obj == nil {
+		return nil
+	}
+	if len(obj.Value) > 0 {
+		var v any
+		if err := json.Unmarshal(obj.Value, &v); err != nil {
+			return string(obj.Value)
+		}
+		return v
+	}
+	if obj.UnserializableValue != "" {
+		return string(obj.UnserializableValue)
+	}
+	switch obj.Type {
+	case runtime.TypeUndefined:
+		return "undefined"
+	case runtime.TypeFunction, runtime.TypeSymbol:
+		return firstLine(obj.Description)
+	}
+	switch obj.Subtype {
+	case runtime.SubtypeNull:
+		return nil
+	case runtime.SubtypeError, runtime.SubtypeDate, runtime.SubtypeRegexp, runtime.SubtypeNode, runtime.SubtypePromise:
+		return obj.Description
+	}
+	if depth <= 0 || obj.ObjectID == "" {
+		return cmp.Or(obj.Description, obj.Type.String())
+	}
+
+	props, internal, _, _, err := runtime.GetProperties(obj.ObjectID).WithOwnProperties(true).Do(ctx)
+	if err != nil {
+		return cmp.Or(obj.Description, obj.Type.String())
+	}
+	switch obj.Subtype {
+	case runtime.SubtypeMap, runtime.SubtypeSet, runtime.SubtypeWeakmap, runtime.SubtypeWeakset:
+		for _, p := range internal {
+			if p.Name == "[[Entries]]" {
+				return ResolveObject(ctx, p.Value, depth)
+			}
+		}
+		return obj.Description
+	case runtime.SubtypeArray, runtime.SubtypeTypedarray:
+		var items []any
+		for _, p := range props {
+			if _, err := strconv.Atoi(p.Name); err != nil || p.Value == nil {
+				continue
+			}
+			if len(items) == maxObjectItems {
+				items = append(items, fmt.Sprintf("... (%s)", obj.Description))
+				break
+			}
+			items = append(items, ResolveObject(ctx, p.Value, depth-1))
+		}
+		return items
+	}
+	fields := map[string]any{}
+	for _, p := range props {
+		// Getters would run page code, so accessors are left out
+		if !p.Enumerable || p.Value == nil || p.Symbol != nil {
+			continue
+		}
+		if len(fields) == maxObjectItems {
+			fields["..."] = "more properties"
+			break
+		}
+		fields[p.Name] = ResolveObject(ctx, p.Value, depth-1)
+	}
+	// A Map or Set entry, with key (Maps only) and value
+	if obj.Subtype == subtypeInternalEntry {
+		return fields
+	}
+	if obj.ClassName != "" && obj.ClassName != "Object" {
+		fields["__class"] = obj.ClassName
+	}
+	return fields
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// serialQueue runs jobs one at a time, in the order they were queued, off
+// the caller's goroutine. Queueing never blocks, so event listeners can
+// hand it work that runs commands on the tab.
+type serialQueue struct {
+	mu      sync.Mutex
+	jobs    []func()
+	running bool
+}
+
+func (q *serialQueue) do(job func()) {
+	q.mu.Lock()
+	q.jobs = append(q.jobs, job)
+	if q.running {
+		q.mu.Unlock()
+		return
+	}
+	q.running = true
+	q.mu.Unlock()
+	go func() {
+		for {
+			q.mu.Lock()
+			if len(q.jobs) == 0 {
+				q.running = false
+				q.mu.Unlock()
+				return
+			}
+			job := q.jobs[0]
+			q.jobs = q.jobs[1:]
+			q.mu.Unlock()
+			job()
+		}
+	}()
+}
+
+// resolveTimeout bounds resolving one console message's arguments.
+const resolveTimeout = 2 * time.Second
@@ -0,0 +1,287 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image/jpeg"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// reportImageWidth caps inlined screenshots so reports of long runs stay
+// small enough to attach to CI results.
+const reportImageWidth = 1280
+
+type reportStep struct {
+	Index   int
+	Command string
+	Label   string
+	Note    string
+	Status  string
+	Error   string
+	Elapsed string
+	Time    string
+	Image   template.URL
+	Console []reportConsole
+	Network []reportRequest
+}
+
+type reportConsole struct {
+	Time    string
+	Level   string
+	Message string
+}
+
+type reportRequest struct {
+	Method string
+	URL    string
+	Status string
+	Ms     string
+	Failed bool
+	at     time.Time
+}
+
+// WriteReport writes a self-contained HTML report of one run: each step's
+// status, timing, and screenshot (inlined, scaled down), with the console
+// messages and network requests seen while it ran. records are the run's
+// steps (see LoadRunRecords) and dir holds its logs.
+func WriteReport(dir string, runID string, records []StepRecord, output string) error {
+	consoleLog, err := LoadRunLog[ConsoleMessage](dir, runID, "console")
+	if err != nil {
+		return err
+	}
+	networkLog, err := LoadRunLog[NetworkEvent](dir, runID, "network")
+	if err != nil {
+		return err
+	}
+	requests := reportRequests(networkLog)
+
+	steps := make([]reportStep, 0, len(records))
+	passed := 0
+	var total time.Duration
+	var previous time.Time
+	for i, record := range records {
+		step := reportStep{
+			Index:   i + 1,
+			Command: strings.TrimSpace(record.Action + " " + strings.Join(record.Args, " ")),
+			Label:   record.Label,
+			Note:    record.Note,
+			Status:  record.Status,
+			Error:   record.Error,
+			Time:    record.CreatedAt.Local().Format("15:04:05.000"),
+		}
+		if step.Status == "" || step.Status == "ok" {
+			passed++
+		}
+		if record.ElapsedMs > 0 {
+			step.Elapsed = formatMs(record.ElapsedMs)
+			total += time.Duration(record.ElapsedMs) * time.Millisecond
+		}
+		// Events belong to the step that was running when they happened
+		last := i == len(records)-1
+		inStep := func(at time.Time) bool {
+			return (previous.IsZero() || at.After(previous)) && (last || !at.After(record.CreatedAt))
+		}
+		for _, msg := range consoleLog {
+			if inStep(msg.Timestamp) {
+				step.Console = append(step.Console, reportConsole{
+					Time:    msg.Timestamp.Local().Format("15:04:05.000"),
+					Level:   consoleLevel(msg),
+					Message: consoleText(msg),
+				})
+			}
+		}
+		for _, req := range requests {
+			if inStep(req.at) {
+				step.Network = append(step.Network, req)
+			}
+		}
+		previous = record.CreatedAt
+		image, err := reportImage(record.Screenshot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: screenshot for step %d: %v\n", i+1, err)
+		}
+		step.Image = image
+		steps = append(steps, step)
+	}
+
+	status := "passed"
+	if passed < len(records) {
+		status = "failed"
+	}
+	data := struct {
+		RunID     string
+		Status    string
+		Passed    int
+		Total     int
+		Elapsed   string
+		Generated string
+		Logged    bool
+		Steps     []reportStep
+	}{runID, status, passed, len(records), formatMs(total.Milliseconds()), time.Now().Format("2006-01-02 15:04:05"), consoleLog != nil || networkLog != nil, steps}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(f, data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// reportRequests joins request, response, and failure events by request ID,
+// timing each request to its response headers.
+func reportRequests(events []NetworkEvent) []reportRequest {
+	var out []reportRequest
+	index := map[string]int{}
+	for _, ev := range events {
+		i, ok := index[ev.RequestID]
+		if ev.Type == "request" {
+			index[ev.RequestID] = len(out)
+			out = append(out, reportRequest{Method: ev.Method, URL: ev.URL, Status: "pending", at: ev.Timestamp})
+			continue
+		}
+		if !ok {
+			continue
+		}
+		req := &out[i]
+		switch ev.Type {
+		case "response":
+			req.Status = fmt.Sprint(ev.Status)
+			req.Failed = ev.Status >= 400
+			req.Ms = formatMs(ev.Timestamp.Sub(req.at).Milliseconds())
+		case "failed":
+			req.Status = "failed"
+			req.Failed = true
+			req.Ms = formatMs(ev.Timestamp.Sub(req.at).Milliseconds())
+		}
+	}
+	return out
+}
+
+func consoleLevel(msg ConsoleMessage) string {
+	if msg.Level != "" {
+		return msg.Level
+	}
+	return msg.Type
+}
+
+func consoleText(msg ConsoleMessage) string {
+	if msg.Args == nil {
+		return msg.Message
+	}
+	args, err := json.Marshal(msg.Args)
+	if err != nil {
+		return msg.Message
+	}
+	return strings.TrimSpace(msg.Message + " " + string(args))
+}
+
+func formatMs(ms int64) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.2fs", float64(ms)/1000)
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+// reportImage returns a screenshot as a data URL, re-encoded as a jpeg no
+// wider than reportImageWidth when it can be decoded (webp is kept as is).
+func reportImage(path string) (template.URL, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if img, err := LoadImage(path); err == nil {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaleToWidth(img, reportImageWidth), &jpeg.Options{Quality: 80}); err == nil {
+			data, mimeType = buf.Bytes(), "image/jpeg"
+		}
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .RunID }}: {{ .Status }}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 24px; background: #f5f5f5; color: #222; }
+header { margin-bottom: 16px; }
+h1 { font-size: 20px; margin: 0 0 4px; }
+.summary { color: #666; font-size: 14px; }
+.badge { display: inline-block; padding: 2px 8px; border-radius: 4px; color: #fff; font-size: 13px; font-weight: 600; background: #2a7; }
+.badge.failed { background: #d33; }
+.step { background: #fff; border-radius: 6px; box-shadow: 0 1px 3px rgba(0,0,0,.15); margin-bottom: 16px; padding: 12px 14px; }
+.step.failed { outline: 3px solid #d33; }
+.head { display: flex; gap: 12px; align-items: baseline; }
+.cmd { font-family: ui-monospace, monospace; font-size: 13px; word-break: break-all; flex: 1; }
+.time { color: #888; font-size: 12px; white-space: nowrap; }
+.note { margin-top: 4px; }
+.error { color: #d33; margin-top: 6px; font-family: ui-monospace, monospace; font-size: 13px; white-space: pre-wrap; }
+.step img { max-width: 100%; margin-top: 10px; border: 1px solid #eee; }
+details { margin-top: 8px; font-size: 13px; }
+table { border-collapse: collapse; width: 100%; font-family: ui-monospace, monospace; font-size: 12px; }
+td { padding: 2px 6px; border-top: 1px solid #eee; vertical-align: top; word-break: break-all; }
+tr.bad td, tr.error td, tr.exception td { color: #d33; }
+tr.warning td { color: #a60; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{ .RunID }} <span class="badge {{ .Status }}">{{ .Status }}</span></h1>
+<div class="summary">{{ .Passed }}/{{ .Total }} steps passed in {{ .Elapsed }}, generated {{ .Generated }}{{ if not .Logged }}; no console or network log was saved for this run{{ end }}</div>
+</header>
+{{- range .Steps }}
+<div class="step{{ if eq .Status "failed" }} failed{{ end }}">
+<div class="head">
+<span class="badge {{ if eq .Status "failed" }}failed{{ end }}">{{ .Index }}</span>
+<span class="cmd">{{ .Command }}</span>
+<span class="time">{{ .Time }}{{ if .Elapsed }}, took {{ .Elapsed }}{{ end }}</span>
+</div>
+{{- if .Note }}<div class="note">{{ .Note }}</div>{{ end }}
+{{- if .Error }}<div class="error">{{ .Error }}</div>{{ end }}
+{{- if .Console }}
+<details{{ if eq .Status "failed" }} open{{ end }}><summary>console ({{ len .Console }})</summary>
+<table>
+{{- range .Console }}
+<tr class="{{ .Level }}"><td>{{ .Time }}</td><td>{{ .Level }}</td><td>{{ .Message }}</td></tr>
+{{- end }}
+</table>
+</details>
+{{- end }}
+{{- if .Network }}
+<details{{ if eq .Status "failed" }} open{{ end }}><summary>network ({{ len .Network }})</summary>
+<table>
+{{- range .Network }}
+<tr{{ if .Failed }} class="bad"{{ end }}><td>{{ .Method }}</td><td>{{ .Status }}</td><td>{{ .Ms }}</td><td>{{ .URL }}</td></tr>
+{{- end }}
+</table>
+</details>
+{{- end }}
+{{- if .Image }}
+<img src="{{ .Image }}" alt="{{ .Label }}">
+{{- end }}
+</div>
+{{- end }}
+</body>
+</html>
+`))
@@ -0,0 +1,168 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RunLogKinds are the event logs RunWorkflow saves next to a run's steps,
+// and step --capture next to its screenshot.
+var RunLogKinds = []string{"console", "network"}
+
+// RunLogPath returns where a run's console or network events are saved as
+// NDJSON: <run ID>-<kind>.ndjson in the run's directory (see RunDir).
+func RunLogPath(dir string, runID string, kind string) (string, error) {
+	runDir, err := RunDir(dir, runID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(runDir, fmt.Sprintf("%s-%s.ndjson", runID, kind)), nil
+}
+
+// legacyRunLogPath is where runs saved their logs before they had their own
+// directory: <dir>/<run ID>-<kind>.ndjson.
+func legacyRunLogPath(dir string, runID string, kind string) (string, error) {
+	base := strings.TrimSpace(dir)
+	if base == "" {
+		base = DefaultShotsDir()
+	}
+	absDir, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(absDir, fmt.Sprintf("%s-%s.ndjson", runID, kind)), nil
+}
+
+// StepLogPath returns where step --capture saves a kind of event log for
+// the step whose screenshot is at screenshot: <shot>-<kind>.ndjson.
+func StepLogPath(screenshot string, kind string) string {
+	return strings.TrimSuffix(screenshot, filepath.Ext(screenshot)) + "-" + kind + ".ndjson"
+}
+
+// EventLog appends a tab's console messages and network events to NDJSON
+// files until it is closed.
+type EventLog struct {
+	mu      sync.Mutex
+	files   []*os.File
+	writers map[string]*bufio.Writer
+}
+
+// startRunLog opens the run's log files and starts listening on the
+// attached tab context.
+func startRunLog(ctx context.Context, dir string, runID string) (*EventLog, error) {
+	paths := map[string]string{}
+	for _, kind := range RunLogKinds {
+		path, err := RunLogPath(dir, runID, kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		paths[kind] = path
+	}
+	return StartEventLog(ctx, paths)
+}
+
+// StartEventLog opens a file for each kind in paths (console or network,
+// see RunLogKinds) and starts logging those events from the attached tab
+// context into them.
+func StartEventLog(ctx context.Context, paths map[string]string) (*EventLog, error) {
+	l := &EventLog{writers: map[string]*bufio.Writer{}}
+	for _, kind := range RunLogKinds {
+		path, ok := paths[kind]
+		if !ok {
+			continue
+		}
+		// Append so a run resumed under the same ID keeps its earlier events
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+		l.files = append(l.files, f)
+		l.writers[kind] = bufio.NewWriter(f)
+	}
+	var err error
+	if _, ok := paths["console"]; ok {
+		err = ListenConsole(ctx, func(msg ConsoleMessage) { l.write("console", msg) })
+	}
+	if _, ok := paths["network"]; ok && err == nil {
+		err = ListenNetwork(ctx, func(ev NetworkEvent) { l.write("network", ev) })
+	}
+	if err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *EventLog) write(kind string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w := l.writers[kind]; w != nil {
+		_, _ = w.Write(append(data, '\n'))
+	}
+}
+
+// Close flushes and closes the log files; later events are dropped.
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for _, w := range l.writers {
+		errs = append(errs, w.Flush())
+	}
+	for _, f := range l.files {
+		errs = append(errs, f.Close())
+	}
+	l.writers = nil
+	return errors.Join(errs...)
+}
+
+// LoadRunLog reads a run's NDJSON log of kind into values of T, returning
+// none when the run saved no log (runs from before logs were kept).
+func LoadRunLog[T any](dir string, runID string, kind string) ([]T, error) {
+	path, err := RunLogPath(dir, runID, kind)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if path, err = legacyRunLogPath(dir, runID, kind); err != nil {
+			return nil, err
+		}
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var out []T
+	decoder := json.NewDecoder(f)
+	for {
+		var value T
+		if err := decoder.Decode(&value); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return out, fmt.Errorf("reading %s: %w", path, err)
+		}
+		out = append(out, value)
+	}
+}
@@ -0,0 +1,1005 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a declarative script of steps executed by `chrome run`.
+// Files are YAML or JSON (JSON is valid YAML).
+type Workflow struct {
+	Name      string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Target    string            `json:"target,omitempty" yaml:"target,omitempty"`
+	Timeout   int               `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	OutputDir string            `json:"output_dir,omitempty" yaml:"output_dir,omitempty"`
+	Preset    string            `json:"preset,omitempty" yaml:"preset,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Steps     []WorkflowStep    `json:"steps" yaml:"steps"`
+}
+
+// WorkflowStep is one action in a Workflow. Which fields apply depends on Action.
+type WorkflowStep struct {
+	Action   string `json:"action" yaml:"action"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`
+	Selector string `json:"selector,omitempty" yaml:"selector,omitempty"`
+	Text     string `json:"text,omitempty" yaml:"text,omitempty"`
+	Value    string `json:"value,omitempty" yaml:"value,omitempty"`
+	Script   string `json:"script,omitempty" yaml:"script,omitempty"`
+	Title    string `json:"title,omitempty" yaml:"title,omitempty"`
+	Label    string `json:"label,omitempty" yaml:"label,omitempty"`
+	Note     string `json:"note,omitempty" yaml:"note,omitempty"`
+	Ms       int    `json:"ms,omitempty" yaml:"ms,omitempty"`
+	Timeout  int    `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// emulate
+	Preset    string  `json:"preset,omitempty" yaml:"preset,omitempty"`
+	Emulation *Preset `json:"emulation,omitempty" yaml:"emulation,omitempty"`
+
+	// throttle (latency in ms, throughput in kbps)
+	Offline  bool `json:"offline,omitempty" yaml:"offline,omitempty"`
+	Latency  int  `json:"latency,omitempty" yaml:"latency,omitempty"`
+	Download int  `json:"download,omitempty" yaml:"download,omitempty"`
+	Upload   int  `json:"upload,omitempty" yaml:"upload,omitempty"`
+
+	// mock (url is the glob pattern)
+	Status  int               `json:"status,omitempty" yaml:"status,omitempty"`
+	Body    string            `json:"body,omitempty" yaml:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Block   bool              `json:"block,omitempty" yaml:"block,omitempty"`
+
+	// assert response (glob) finished within max_ms
+	Response string `json:"response,omitempty" yaml:"response,omitempty"`
+	MaxMs    int    `json:"max_ms,omitempty" yaml:"max_ms,omitempty"`
+
+	// capture (and eval) store a value in var, read from script, the text or
+	// attr of selector, or the body of response, optionally one JSON field
+	Var   string `json:"var,omitempty" yaml:"var,omitempty"`
+	Attr  string `json:"attr,omitempty" yaml:"attr,omitempty"`
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+
+	// if runs then when the condition holds, else otherwise
+	When *WorkflowCondition `json:"when,omitempty" yaml:"when,omitempty"`
+	Then []WorkflowStep     `json:"then,omitempty" yaml:"then,omitempty"`
+	Else []WorkflowStep     `json:"else,omitempty" yaml:"else,omitempty"`
+
+	// loop runs steps up to max times, while or until a condition holds
+	While *WorkflowCondition `json:"while,omitempty" yaml:"while,omitempty"`
+	Until *WorkflowCondition `json:"until,omitempty" yaml:"until,omitempty"`
+	Max   int                `json:"max,omitempty" yaml:"max,omitempty"`
+	Steps []WorkflowStep     `json:"steps,omitempty" yaml:"steps,omitempty"`
+
+	// ContinueOnError records a failure of this step and runs the next one
+	ContinueOnError bool `json:"continue_on_error,omitempty" yaml:"continue_on_error,omitempty"`
+}
+
+// WorkflowCondition is the test of an if or loop step. It holds when every
+// field set matches, using the same checks as an assert step (a selector
+// alone tests that an element exists), or when none does with Not.
+type WorkflowCondition struct {
+	Selector string `json:"selector,omitempty" yaml:"selector,omitempty"`
+	Text     string `json:"text,omitempty" yaml:"text,omitempty"`
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`
+	Title    string `json:"title,omitempty" yaml:"title,omitempty"`
+	Script   string `json:"script,omitempty" yaml:"script,omitempty"`
+	Not      bool   `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// errAssertion marks an assert that ran and did not match, as opposed to one
+// that could not run, so conditions can treat it as false.
+var errAssertion = errors.New("assertion failed")
+
+// WorkflowActions lists the actions a WorkflowStep may use.
+var WorkflowActions = []string{
+	"navigate", "click", "clicktext", "fill", "type", "waitfor", "wait", "eval", "assert", "screenshot", "sleep",
+	"emulate", "throttle", "offline", "online", "mock", "unmock", "capture", "if", "loop",
+}
+
+// LoadWorkflow reads a YAML or JSON workflow file and validates it.
+func LoadWorkflow(path string) (Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Workflow{}, err
+	}
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return Workflow{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if wf.Name == "" {
+		wf.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := wf.Validate(); err != nil {
+		return Workflow{}, err
+	}
+	return wf, nil
+}
+
+// SaveWorkflow writes wf as YAML to path, or to stdout when path is "" or "-".
+func SaveWorkflow(path string, wf Workflow) error {
+	data, err := yaml.Marshal(wf)
+	if err != nil {
+		return err
+	}
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	// Write then rename, so an interrupted save never leaves half a workflow
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".workflow-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Validate checks that every step has a known action and its required fields.
+func (wf Workflow) Validate() error {
+	if len(wf.Steps) == 0 {
+		return errors.New("workflow has no steps")
+	}
+	for name := range wf.Vars {
+		if err := ValidateVarName(name); err != nil {
+			return err
+		}
+	}
+	return validateSteps("", wf.Steps)
+}
+
+func validateSteps(prefix string, steps []WorkflowStep) error {
+	for i, step := range steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("step %s%d (%s): %w", prefix, i+1, step.Action, err)
+		}
+	}
+	return nil
+}
+
+func (c *WorkflowCondition) validate(field string) error {
+	if c == nil {
+		return nil
+	}
+	if c.Selector == "" && c.Text == "" && c.URL == "" && c.Title == "" && c.Script == "" {
+		return fmt.Errorf("%s needs at least one of selector, text, url, title, script", field)
+	}
+	return nil
+}
+
+func (step WorkflowStep) Validate() error {
+	need := func(field string, value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%s is required", field)
+		}
+		return nil
+	}
+	switch step.Action {
+	case "navigate":
+		return need("url", step.URL)
+	case "click", "waitfor":
+		return need("selector", step.Selector)
+	case "clicktext", "wait":
+		return need("text", step.Text)
+	case "fill", "type":
+		return need("selector", step.Selector)
+	case "eval":
+		if step.Var != "" {
+			if err := ValidateVarName(step.Var); err != nil {
+				return err
+			}
+		}
+		return need("script", step.Script)
+	case "capture":
+		if err := need("var", step.Var); err != nil {
+			return err
+		}
+		if err := ValidateVarName(step.Var); err != nil {
+			return err
+		}
+		sources := 0
+		for _, source := range []string{step.Script, step.Selector, step.Response} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return errors.New("capture needs one of script, selector, response")
+		}
+		if step.Attr != "" && step.Selector == "" {
+			return errors.New("attr needs selector")
+		}
+		if step.Field != "" && step.Response == "" {
+			return errors.New("field needs response")
+		}
+		return nil
+	case "assert":
+		if step.Selector == "" && step.Text == "" && step.URL == "" && step.Title == "" && step.Script == "" && step.Response == "" {
+			return errors.New("assert needs at least one of selector, text, url, title, script, response")
+		}
+		if step.MaxMs < 0 || (step.MaxMs > 0 && step.Response == "") {
+			return errors.New("max_ms needs response and must be > 0")
+		}
+		return nil
+	case "screenshot":
+		return nil
+	case "sleep":
+		if step.Ms <= 0 {
+			return errors.New("ms must be > 0")
+		}
+		return nil
+	case "emulate":
+		if step.Preset == "" && step.Emulation == nil {
+			return errors.New("emulate needs preset or emulation")
+		}
+		return nil
+	case "throttle", "offline", "online", "unmock":
+		return nil
+	case "mock":
+		return need("url", step.URL)
+	case "if":
+		if step.When == nil {
+			return errors.New("when is required")
+		}
+		if err := step.When.validate("when"); err != nil {
+			return err
+		}
+		if len(step.Then) == 0 && len(step.Else) == 0 {
+			return errors.New("if needs then or else steps")
+		}
+		if err := validateSteps("then ", step.Then); err != nil {
+			return err
+		}
+		return validateSteps("else ", step.Else)
+	case "loop":
+		if step.Max <= 0 {
+			return errors.New("max must be > 0")
+		}
+		if step.While != nil && step.Until != nil {
+			return errors.New("loop takes while or until, not both")
+		}
+		if err := step.While.validate("while"); err != nil {
+			return err
+		}
+		if err := step.Until.validate("until"); err != nil {
+			return err
+		}
+		if len(step.Steps) == 0 {
+			return errors.New("loop needs steps")
+		}
+		return validateSteps("", step.Steps)
+	case "":
+		return errors.New("action is required")
+	default:
+		return fmt.Errorf("unknown action, expected one of: %s", strings.Join(WorkflowActions, ", "))
+	}
+}
+
+// Describe returns the step as CLI-style args for StepRecord.Args and logs.
+func (step WorkflowStep) Describe() []string {
+	var args []string
+	add := func(flag string, value string) {
+		if value != "" {
+			args = append(args, flag+"="+value)
+		}
+	}
+	switch step.Action {
+	case "navigate":
+		args = append(args, step.URL)
+	case "click", "waitfor":
+		args = append(args, step.Selector)
+	case "clicktext", "wait":
+		args = append(args, step.Text)
+		add("--selector", step.Selector)
+	case "fill":
+		args = append(args, step.Selector, step.Value)
+	case "type":
+		args = append(args, step.Selector, step.Text)
+	case "eval":
+		args = append(args, step.Script)
+		add("--var", step.Var)
+	case "capture":
+		args = append(args, step.Var)
+		add("--script", step.Script)
+		add("--selector", step.Selector)
+		add("--attr", step.Attr)
+		add("--response", step.Response)
+		add("--field", step.Field)
+	case "assert":
+		add("--selector", step.Selector)
+		add("--text", step.Text)
+		add("--url", step.URL)
+		add("--title", step.Title)
+		add("--script", step.Script)
+		add("--response", step.Response)
+		if step.MaxMs > 0 {
+			add("--max-ms", strconv.Itoa(step.MaxMs))
+		}
+	case "screenshot":
+		add("--label", step.Label)
+		add("--selector", step.Selector)
+	case "sleep":
+		args = append(args, strconv.Itoa(step.Ms)+"ms")
+	case "emulate":
+		add("--preset", step.Preset)
+		if step.Emulation != nil {
+			args = append(args, "--inline")
+		}
+	case "throttle":
+		if step.Offline {
+			args = append(args, "--offline")
+		}
+		for _, flag := range []struct {
+			name  string
+			value int
+		}{{"--latency", step.Latency}, {"--download", step.Download}, {"--upload", step.Upload}} {
+			if flag.value > 0 {
+				add(flag.name, strconv.Itoa(flag.value))
+			}
+		}
+	case "mock":
+		args = append(args, step.URL)
+		if step.Block {
+			args = append(args, "--block")
+		} else if step.Status != 0 {
+			add("--status", strconv.Itoa(step.Status))
+		}
+	case "if":
+		args = append(args, step.When.describe("--when")...)
+	case "loop":
+		args = append(args, step.While.describe("--while")...)
+		args = append(args, step.Until.describe("--until")...)
+		add("--max", strconv.Itoa(step.Max))
+	}
+	if step.ContinueOnError {
+		args = append(args, "--continue-on-error")
+	}
+	return args
+}
+
+// assert returns the assert step that checks the condition, ignoring Not.
+func (c *WorkflowCondition) assert() WorkflowStep {
+	return WorkflowStep{
+		Action:   "assert",
+		Selector: c.Selector,
+		Text:     c.Text,
+		URL:      c.URL,
+		Title:    c.Title,
+		Script:   c.Script,
+	}
+}
+
+// describe returns the condition as CLI-style args after flag.
+func (c *WorkflowCondition) describe(flag string) []string {
+	if c == nil {
+		return nil
+	}
+	args := []string{flag}
+	if c.Not {
+		args = append(args, "--not")
+	}
+	for _, field := range []struct{ name, value string }{
+		{"--selector", c.Selector}, {"--text", c.Text}, {"--url", c.URL}, {"--title", c.Title}, {"--script", c.Script},
+	} {
+		if field.value != "" {
+			args = append(args, field.name+"="+field.value)
+		}
+	}
+	return args
+}
+
+// RunOptions controls RunWorkflow.
+type RunOptions struct {
+	// OutputDir holds the run's directory (see RunDir), which receives a
+	// screenshot and StepRecord per step (default: shots dir)
+	OutputDir string
+	// Target is recorded in StepRecords (the context is already bound to the tab)
+	Target string
+	// OnStep is called after each step with its number and record. Steps
+	// inside an if or loop are numbered from their parent: 3.1 is the first
+	// step of step 3's branch, 4.2.1 the first step of step 4's second
+	// iteration. An if or loop is reported after the steps it ran.
+	OnStep func(number string, record StepRecord)
+	// Screenshot sets the format and quality of per-step screenshots
+	Screenshot ScreenshotOptions
+	// RunID tags every StepRecord of the run (default: NewRunID(wf.Name))
+	RunID string
+	// Vars set workflow variables, overriding the workflow's vars
+	Vars map[string]string
+}
+
+// RunWorkflow executes steps in order against an attached tab context, capturing
+// a screenshot and StepRecord after each step, and saving the tab's console and
+// network events to the run's logs (see RunLogPath). It stops at the first
+// failing step without ContinueOnError and returns its error along with the
+// records produced so far. If and loop steps run their nested steps and get
+// a record without a screenshot.
+//
+// ctx must already be attached (chromedp.Run called once without a deadline):
+// per-step timeouts derive from it, and chromedp ties the tab connection to the
+// context used on the first Run.
+func RunWorkflow(ctx context.Context, wf Workflow, opts RunOptions) ([]StepRecord, error) {
+	dir := opts.OutputDir
+	if dir == "" {
+		dir = wf.OutputDir
+	}
+	runID := opts.RunID
+	if runID == "" {
+		runID = NewRunID(wf.Name)
+	}
+	runDir, err := RunDir(dir, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := startRunLog(ctx, dir, runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to log console and network: %v\n", err)
+	} else {
+		defer func() { _ = logs.Close() }()
+	}
+
+	ic := NewInterceptor(ctx)
+	var net *NetworkRecorder
+	if assertsResponse(wf.Steps) {
+		// Record from the start so earlier steps' requests count
+		if net, err = NewNetworkRecorder(ctx); err != nil {
+			return nil, err
+		}
+	}
+	vars := map[string]string{}
+	maps.Copy(vars, wf.Vars)
+	maps.Copy(vars, opts.Vars)
+	r := &workflowRun{wf: wf, opts: opts, dir: runDir, runID: runID, ic: ic, net: net, vars: vars}
+	err = r.runSteps(ctx, "", wf.Steps)
+	return r.records, err
+}
+
+// assertsResponse reports whether any step, including nested ones, asserts
+// on a response.
+func assertsResponse(steps []WorkflowStep) bool {
+	for _, step := range steps {
+		if step.Response != "" || assertsResponse(step.Then) || assertsResponse(step.Else) || assertsResponse(step.Steps) {
+			return true
+		}
+	}
+	return false
+}
+
+// workflowRun is the state RunWorkflow threads through nested steps.
+type workflowRun struct {
+	wf      Workflow
+	opts    RunOptions
+	dir     string
+	runID   string
+	ic      *Interceptor
+	net     *NetworkRecorder
+	vars    map[string]string
+	records []StepRecord
+}
+
+// runSteps runs steps numbered after prefix, stopping at the first failure
+// not covered by ContinueOnError.
+func (r *workflowRun) runSteps(ctx context.Context, prefix string, steps []WorkflowStep) error {
+	for i, step := range steps {
+		number := prefix + strconv.Itoa(i+1)
+		var err error
+		if step.Action == "if" || step.Action == "loop" {
+			err = r.runControl(ctx, number, step)
+		} else {
+			err = r.runStep(ctx, number, step)
+		}
+		if err != nil && !step.ContinueOnError {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *workflowRun) timeout(step WorkflowStep) time.Duration {
+	timeout := DefaultTimeout
+	if r.wf.Timeout > 0 {
+		timeout = time.Duration(r.wf.Timeout) * time.Second
+	}
+	if step.Timeout > 0 {
+		timeout = time.Duration(step.Timeout) * time.Second
+	}
+	return timeout
+}
+
+func (r *workflowRun) record(number string, step WorkflowStep, stepErr error) StepRecord {
+	label := step.Label
+	if label == "" {
+		top, rest, _ := strings.Cut(number, ".")
+		n, _ := strconv.Atoi(top)
+		label = fmt.Sprintf("%02d", n)
+		if rest != "" {
+			label += "." + rest
+		}
+		label += "-" + step.Action
+	}
+	record := StepRecord{
+		Action:    step.Action,
+		Args:      step.Describe(),
+		Target:    r.opts.Target,
+		Label:     label,
+		Note:      step.Note,
+		Status:    "ok",
+		RunID:     r.runID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if stepErr != nil {
+		record.Status = "failed"
+		record.Error = stepErr.Error()
+	}
+	return record
+}
+
+func (r *workflowRun) report(number string, record StepRecord) {
+	r.records = append(r.records, record)
+	if r.opts.OnStep != nil {
+		r.opts.OnStep(number, record)
+	}
+}
+
+// runStep runs one action and saves its screenshot and record.
+func (r *workflowRun) runStep(ctx context.Context, number string, step WorkflowStep) error {
+	timeout := r.timeout(step)
+	var point *Point
+	expanded, stepErr := step.expand(r.vars)
+	if stepErr == nil && expanded.Action == "navigate" {
+		// Crawl etiquette waits do not count against the step's timeout
+		stepErr = PoliteWait(ctx, expanded.URL)
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	started := time.Now()
+	if stepErr == nil {
+		step = expanded
+		point = StepPoint(stepCtx, step)
+		started = time.Now()
+		stepErr = runWorkflowStep(stepCtx, step, r.ic, r.net, r.vars)
+	}
+	elapsed := time.Since(started)
+	if stepErr != nil && stepCtx.Err() == context.DeadlineExceeded {
+		stepErr = fmt.Errorf("timeout after %s: %w", timeout, stepErr)
+	}
+	cancel()
+
+	record := r.record(number, step, stepErr)
+	record.Point = point
+	record.ElapsedMs = elapsed.Milliseconds()
+
+	path, err := PrepareScreenshotPathWithFormat("", r.dir, record.Label, r.opts.Screenshot.Format)
+	if err == nil {
+		shot := r.opts.Screenshot
+		if step.Action == "screenshot" {
+			shot.Element = step.Selector
+		}
+		err = captureToFile(ctx, path, shot)
+	}
+	if err == nil {
+		record.Screenshot = path
+		if saveErr := RememberStep(record); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: unable to persist metadata: %v\n", saveErr)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "warning: step %s screenshot failed: %v\n", number, err)
+	}
+
+	r.report(number, record)
+	if stepErr != nil {
+		return fmt.Errorf("step %s (%s) failed: %w", number, step.Action, stepErr)
+	}
+	return nil
+}
+
+// runControl runs an if or loop step's nested steps, then reports the step
+// itself with the branch taken or the number of iterations.
+func (r *workflowRun) runControl(ctx context.Context, number string, step WorkflowStep) error {
+	started := time.Now()
+	var outcome string
+	var err error
+	if step.Action == "if" {
+		outcome, err = r.runIf(ctx, number, step)
+	} else {
+		outcome, err = r.runLoop(ctx, number, step)
+	}
+	record := r.record(number, step, err)
+	if outcome != "" {
+		record.Args = append(record.Args, outcome)
+	}
+	record.ElapsedMs = time.Since(started).Milliseconds()
+	r.report(number, record)
+	if err != nil {
+		return fmt.Errorf("step %s (%s) failed: %w", number, step.Action, err)
+	}
+	return nil
+}
+
+func (r *workflowRun) runIf(ctx context.Context, number string, step WorkflowStep) (string, error) {
+	ok, err := r.check(ctx, step, step.When)
+	if err != nil {
+		return "", err
+	}
+	branch, steps := "then", step.Then
+	if !ok {
+		branch, steps = "else", step.Else
+	}
+	if len(steps) == 0 {
+		return "-> skip", nil
+	}
+	return "-> " + branch, r.runSteps(ctx, number+".", steps)
+}
+
+func (r *workflowRun) runLoop(ctx context.Context, number string, step WorkflowStep) (string, error) {
+	for i := 1; i <= step.Max; i++ {
+		if step.While != nil {
+			ok, err := r.check(ctx, step, step.While)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return fmt.Sprintf("-> %d iterations", i-1), nil
+			}
+		}
+		if err := r.runSteps(ctx, fmt.Sprintf("%s.%d.", number, i), step.Steps); err != nil {
+			return fmt.Sprintf("-> %d iterations", i), err
+		}
+		if step.Until != nil {
+			ok, err := r.check(ctx, step, step.Until)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return fmt.Sprintf("-> %d iterations", i), nil
+			}
+		}
+	}
+	done := fmt.Sprintf("-> %d iterations", step.Max)
+	switch {
+	case step.While != nil:
+		return done, fmt.Errorf("while condition still true after %d iterations", step.Max)
+	case step.Until != nil:
+		return done, fmt.Errorf("until condition not met after %d iterations", step.Max)
+	}
+	return done, nil
+}
+
+// check evaluates a condition once, within the control step's timeout.
+func (r *workflowRun) check(ctx context.Context, step WorkflowStep, c *WorkflowCondition) (bool, error) {
+	timeout := r.timeout(step)
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	assert, err := c.assert().expand(r.vars)
+	if err != nil {
+		return false, err
+	}
+	err = assertStep(checkCtx, assert, nil)
+	switch {
+	case err == nil:
+		return !c.Not, nil
+	case errors.Is(err, errAssertion):
+		return c.Not, nil
+	case checkCtx.Err() == context.DeadlineExceeded:
+		return false, fmt.Errorf("timeout after %s checking condition: %w", timeout, err)
+	}
+	return false, err
+}
+
+func captureToFile(ctx context.Context, path string, opts ScreenshotOptions) error {
+	shotCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	buf, err := ScreenshotWithOptions(shotCtx, opts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0644)
+}
+
+// runWorkflowStep runs one action; capture and eval steps with a var store
+// their value in vars.
+func runWorkflowStep(ctx context.Context, step WorkflowStep, ic *Interceptor, net *NetworkRecorder, vars map[string]string) error {
+	switch step.Action {
+	case "navigate":
+		return Navigate(ctx, step.URL)
+	case "click":
+		return Click(ctx, step.Selector)
+	case "clicktext":
+		return ClickText(ctx, step.Text, step.Selector)
+	case "fill":
+		_, err := Fill(ctx, step.Selector, step.Value)
+		return err
+	case "type":
+		return Type(ctx, step.Selector, step.Text, false)
+	case "waitfor":
+		return WaitVisible(ctx, step.Selector)
+	case "wait":
+		return pollTrue(ctx, fmt.Sprintf(`document.body && document.body.innerText.includes(%s)`, strconv.Quote(step.Text)))
+	case "eval", "capture":
+		if step.Var == "" {
+			return Eval(ctx, step.Script, nil)
+		}
+		value, err := captureValue(ctx, step, net)
+		if err != nil {
+			return err
+		}
+		vars[step.Var] = value
+		return nil
+	case "assert":
+		return assertStep(ctx, step, net)
+	case "screenshot":
+		// The runner captures after every step; nothing else to do
+		return nil
+	case "sleep":
+		select {
+		case <-time.After(time.Duration(step.Ms) * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case "emulate":
+		preset := Preset{}
+		if step.Preset != "" {
+			var err error
+			preset, err = LoadPreset("", step.Preset)
+			if err != nil {
+				return err
+			}
+		}
+		if step.Emulation != nil {
+			preset = MergePreset(preset, *step.Emulation)
+		}
+		return chromedp.Run(ctx, preset.Actions()...)
+	case "throttle":
+		return chromedp.Run(ctx, NetworkConditions{
+			Offline:  step.Offline,
+			Latency:  step.Latency,
+			Download: step.Download,
+			Upload:   step.Upload,
+		}.Action())
+	case "offline":
+		return chromedp.Run(ctx, NetworkConditions{Offline: true}.Action())
+	case "online":
+		return chromedp.Run(ctx, NetworkConditions{}.Action())
+	case "mock":
+		return ic.Add(ctx, MockRule{
+			URL:     step.URL,
+			Status:  step.Status,
+			Body:    step.Body,
+			Headers: step.Headers,
+			Block:   step.Block,
+		})
+	case "unmock":
+		return ic.Clear(ctx)
+	default:
+		return fmt.Errorf("unknown action %q", step.Action)
+	}
+}
+
+// pollTrue evaluates script every 100ms until it returns true or ctx ends.
+func pollTrue(ctx context.Context, script string) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var ok bool
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &ok)); err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// assertStep checks every condition set on the step once, without waiting,
+// except that a response assertion waits for a matching request to finish.
+func assertStep(ctx context.Context, step WorkflowStep, net *NetworkRecorder) error {
+	script := `(() => {
+	  const sel = ` + strconv.Quote(step.Selector) + `;
+	  const text = ` + strconv.Quote(step.Text) + `;
+	  const url = ` + strconv.Quote(step.URL) + `;
+	  const title = ` + strconv.Quote(step.Title) + `;
+	  let scope = document.body;
+	  if (sel) {
+	    scope = document.querySelector(sel);
+	    if (!scope) return { ok: false, error: 'no element matches ' + JSON.stringify(sel) };
+	  }
+	  if (text) {
+	    const actual = scope ? (scope.innerText || scope.textContent || '') : '';
+	    if (!actual.includes(text)) return { ok: false, error: 'text ' + JSON.stringify(text) + ' not found' + (sel ? ' in ' + JSON.stringify(sel) : '') };
+	  }
+	  if (url && !location.href.startsWith(url)) return { ok: false, error: 'url ' + JSON.stringify(location.href) + ' does not start with ' + JSON.stringify(url) };
+	  if (title && document.title !== title) return { ok: false, error: 'title ' + JSON.stringify(document.title) + ' != ' + JSON.stringify(title) };
+	  return { ok: true };
+	})()`
+	var res struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &res)); err != nil {
+		return err
+	}
+	if !res.Ok {
+		return fmt.Errorf("%w: %s", errAssertion, res.Error)
+	}
+	if step.Script != "" {
+		var truthy bool
+		if err := chromedp.Run(ctx, chromedp.Evaluate("!!("+step.Script+")", &truthy)); err != nil {
+			return err
+		}
+		if !truthy {
+			return fmt.Errorf("%w: script %q is falsy", errAssertion, step.Script)
+		}
+	}
+	if step.Response != "" {
+		return assertResponse(ctx, step, net)
+	}
+	return nil
+}
+
+// assertResponse checks that a request matching step.Response finished
+// without failing and that each one took at most step.MaxMs.
+func assertResponse(ctx context.Context, step WorkflowStep, net *NetworkRecorder) error {
+	found, err := net.WaitMatching(ctx, step.Response)
+	if err != nil {
+		return fmt.Errorf("assertion failed: %w", err)
+	}
+	budget := time.Duration(step.MaxMs) * time.Millisecond
+	for _, req := range found {
+		switch {
+		case req.Failed:
+			return fmt.Errorf("assertion failed: %s %s failed", req.Method, req.URL)
+		case budget > 0 && req.Duration > budget:
+			return fmt.Errorf("assertion failed: %s %s took %dms, budget %dms", req.Method, req.URL, req.Duration.Milliseconds(), step.MaxMs)
+		}
+	}
+	return nil
+}
+
+// stepValueFlags are CLI flags that take a value, for parsing StepRecord.Args.
+var stepValueFlags = map[string]bool{
+	"-t": true, "--target": true, "--selector": true, "--index": true, "--within": true,
+	"--timeout": true, "--text": true, "--url": true, "--title": true, "--script": true,
+	"--label": true, "-l": true, "--note": true, "-n": true, "--response": true, "--max-ms": true,
+	"-s": true, "--count": true, "-c": true, "--var": true,
+}
+
+// StepFromRecord converts a StepRecord (from step, run, record, or serve) back
+// into a WorkflowStep. Returns an error for actions or options a workflow
+// cannot express.
+func StepFromRecord(record StepRecord) (WorkflowStep, error) {
+	var positional []string
+	flags := map[string]string{}
+	for i := 0; i < len(record.Args); i++ {
+		arg := record.Args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		if arg == "--" {
+			positional = append(positional, record.Args[i+1:]...)
+			break
+		}
+		if name, value, ok := strings.Cut(arg, "="); ok {
+			flags[name] = value
+		} else if stepValueFlags[arg] && i+1 < len(record.Args) {
+			flags[arg] = record.Args[i+1]
+			i++
+		} else {
+			flags[arg] = "true"
+		}
+	}
+	pos := func(i int) string {
+		if i < len(positional) {
+			return positional[i]
+		}
+		return ""
+	}
+
+	step := WorkflowStep{Action: record.Action, Note: record.Note}
+	switch record.Action {
+	case "navigate":
+		step.URL = pos(0)
+	case "click":
+		if flags["--within"] != "" || (flags["--index"] != "" && flags["--index"] != "0") {
+			return step, errors.New("click --index/--within has no workflow equivalent")
+		}
+		step.Selector = pos(0)
+	case "clicktext":
+		if flags["--index"] != "" && flags["--index"] != "0" {
+			return step, errors.New("clicktext --index has no workflow equivalent")
+		}
+		step.Text = pos(0)
+		step.Selector = flags["--selector"]
+	case "fill":
+		step.Selector, step.Value = pos(0), pos(1)
+	case "type":
+		if flags["--append"] != "" || flags["-a"] != "" {
+			return step, errors.New("type --append has no workflow equivalent")
+		}
+		step.Selector, step.Text = pos(0), pos(1)
+	case "waitfor":
+		step.Selector = pos(0)
+	case "wait":
+		step.Text = pos(0)
+	case "eval":
+		step.Script = strings.Join(positional, " ")
+		step.Var = flags["--var"]
+	case "assert":
+		step.Selector = flags["--selector"]
+		step.Text = flags["--text"]
+		step.URL = flags["--url"]
+		step.Title = flags["--title"]
+		step.Script = flags["--script"]
+		step.Response = flags["--response"]
+		if value := flags["--max-ms"]; value != "" {
+			ms, err := strconv.Atoi(value)
+			if err != nil {
+				return step, fmt.Errorf("bad --max-ms %q", value)
+			}
+			step.MaxMs = ms
+		}
+	case "asserttext":
+		step.Action = "assert"
+		step.Text = pos(0)
+		step.Selector = flags["--selector"]
+		if step.Selector == "" {
+			step.Selector = flags["-s"]
+		}
+	case "assertselector":
+		if flags["--not"] != "" || flags["--count"] != "" || flags["-c"] != "" {
+			return step, errors.New("assertselector --not/--count has no workflow equivalent")
+		}
+		step.Action = "assert"
+		step.Selector = pos(0)
+	case "asserturl":
+		if flags["--exact"] != "" {
+			return step, errors.New("asserturl --exact has no workflow equivalent")
+		}
+		step.Action = "assert"
+		step.URL = pos(0)
+	case "asserttitle":
+		if flags["--contains"] != "" {
+			return step, errors.New("asserttitle --contains has no workflow equivalent")
+		}
+		step.Action = "assert"
+		step.Title = pos(0)
+	case "screenshot":
+		step.Label = record.Label
+		step.Selector = flags["--selector"]
+	case "sleep":
+		ms, err := strconv.Atoi(strings.TrimSuffix(pos(0), "ms"))
+		if err != nil {
+			return step, fmt.Errorf("bad sleep duration %q", pos(0))
+		}
+		step.Ms = ms
+	default:
+		return step, fmt.Errorf("action %q has no workflow equivalent", record.Action)
+	}
+	return step, step.Validate()
+}
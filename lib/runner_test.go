@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStepFromRecord(t *testing.T) {
+	cases := []struct {
+		record StepRecord
+		want   WorkflowStep
+	}{
+		{StepRecord{Action: "navigate", Args: []string{"https://example.com"}},
+			WorkflowStep{Action: "navigate", URL: "https://example.com"}},
+		{StepRecord{Action: "click", Args: []string{"-t", "localhost", "#submit"}},
+			WorkflowStep{Action: "click", Selector: "#submit"}},
+		{StepRecord{Action: "click", Args: []string{"--index", "0", "#submit"}},
+			WorkflowStep{Action: "click", Selector: "#submit"}},
+		{StepRecord{Action: "clicktext", Args: []string{"--selector=nav a", "Home"}},
+			WorkflowStep{Action: "clicktext", Text: "Home", Selector: "nav a"}},
+		{StepRecord{Action: "fill", Args: []string{"--", "#q", "-dash"}, Note: "search"},
+			WorkflowStep{Action: "fill", Selector: "#q", Value: "-dash", Note: "search"}},
+		{StepRecord{Action: "type", Args: []string{"#msg", "hello"}},
+			WorkflowStep{Action: "type", Selector: "#msg", Text: "hello"}},
+		{StepRecord{Action: "eval", Args: []string{"document.title", "+", "'!'"}},
+			WorkflowStep{Action: "eval", Script: "document.title + '!'"}},
+		{StepRecord{Action: "assert", Args: []string{"--selector", ".msg", "--text=Saved"}},
+			WorkflowStep{Action: "assert", Selector: ".msg", Text: "Saved"}},
+		{StepRecord{Action: "assert", Args: []string{"--response", "*/api/items", "--max-ms", "500"}},
+			WorkflowStep{Action: "assert", Response: "*/api/items", MaxMs: 500}},
+		{StepRecord{Action: "eval", Args: []string{"--var", "title", "document.title"}},
+			WorkflowStep{Action: "eval", Script: "document.title", Var: "title"}},
+		{StepRecord{Action: "asserttext", Args: []string{"-s", ".msg", "Saved"}},
+			WorkflowStep{Action: "assert", Text: "Saved", Selector: ".msg"}},
+		{StepRecord{Action: "assertselector", Args: []string{".row"}},
+			WorkflowStep{Action: "assert", Selector: ".row"}},
+		{StepRecord{Action: "asserturl", Args: []string{"/dashboard"}},
+			WorkflowStep{Action: "assert", URL: "/dashboard"}},
+		{StepRecord{Action: "screenshot", Label: "home", Args: []string{"--selector", "main"}},
+			WorkflowStep{Action: "screenshot", Label: "home", Selector: "main"}},
+		{StepRecord{Action: "sleep", Args: []string{"250ms"}},
+			WorkflowStep{Action: "sleep", Ms: 250}},
+	}
+	for _, c := range cases {
+		got, err := StepFromRecord(c.record)
+		if err != nil {
+			t.Errorf("StepFromRecord(%s %v): %v", c.record.Action, c.record.Args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("StepFromRecord(%s %v) = %+v, want %+v", c.record.Action, c.record.Args, got, c.want)
+		}
+	}
+}
+
+func TestStepFromRecordUnsupported(t *testing.T) {
+	for _, record := range []StepRecord{
+		{Action: "click", Args: []string{"--index", "2", "button"}},
+		{Action: "click", Args: []string{"--within", "#row", "button"}},
+		{Action: "type", Args: []string{"--append", "#msg", "x"}},
+		{Action: "sleep", Args: []string{"soon"}},
+		{Action: "pdf"},
+		{Action: "asserturl", Args: []string{"--exact", "/a"}},
+	} {
+		if _, err := StepFromRecord(record); err == nil {
+			t.Errorf("StepFromRecord(%s %v) succeeded, want an error", record.Action, record.Args)
+		}
+	}
+}
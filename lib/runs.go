@@ -0,0 +1,176 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RunIdle is how long a run stays current between steps: a step after a
+// longer pause starts a new run unless it names one.
+const RunIdle = 30 * time.Minute
+
+var runIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateRunID rejects run IDs that cannot be used as a directory name.
+func ValidateRunID(id string) error {
+	if !runIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid run ID %q, use letters, digits, '.', '_', and '-'", id)
+	}
+	return nil
+}
+
+// RunDir returns the directory holding a run's steps, logs, and report:
+// <dir>/runs/<run ID>, where dir defaults to the shots directory. It is
+// created when the first step is saved.
+func RunDir(dir string, runID string) (string, error) {
+	if err := ValidateRunID(runID); err != nil {
+		return "", err
+	}
+	base := strings.TrimSpace(dir)
+	if base == "" {
+		base = DefaultShotsDir()
+	}
+	absDir, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(absDir, "runs", runID), nil
+}
+
+// runDirs returns absDir followed by its run directories: every place a
+// StepRecord in the shots directory can live.
+func runDirs(absDir string) []string {
+	dirs := []string{absDir}
+	entries, err := os.ReadDir(filepath.Join(absDir, "runs"))
+	if err != nil {
+		return dirs
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(absDir, "runs", entry.Name()))
+		}
+	}
+	return dirs
+}
+
+type currentRun struct {
+	ID        string    `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func currentRunPath() (string, error) {
+	cache, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, "current-run.json"), nil
+}
+
+func loadCurrentRun() (currentRun, error) {
+	var run currentRun
+	path, err := currentRunPath()
+	if err != nil {
+		return run, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return run, err
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return run, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return run, nil
+}
+
+// CurrentRunID returns the run a step belongs to and keeps it current for
+// the next step: id when given, a fresh ID for "new", or for "" the run the
+// previous step used, unless that was more than RunIdle ago.
+func CurrentRunID(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	switch id {
+	case "new":
+		id = NewRunID("")
+	case "":
+		run, err := loadCurrentRun()
+		if err == nil && run.ID != "" && time.Since(run.UpdatedAt) < RunIdle {
+			id = run.ID
+		} else {
+			id = NewRunID("")
+		}
+	}
+	if err := ValidateRunID(id); err != nil {
+		return "", err
+	}
+	path, err := currentRunPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(currentRun{ID: id, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return "", err
+	}
+	return id, os.WriteFile(path, data, 0644)
+}
+
+// LoadCurrentRunID returns the run steps are being added to, without
+// starting or extending one.
+func LoadCurrentRunID() (string, error) {
+	run, err := loadCurrentRun()
+	if errors.Is(err, os.ErrNotExist) || (err == nil && run.ID == "") {
+		return "", errors.New("no current run, 'chrome step' starts one")
+	}
+	return run.ID, err
+}
+
+// RunSummary describes one run in a shots directory.
+type RunSummary struct {
+	ID      string
+	Steps   int
+	Failed  int
+	Started time.Time
+	Ended   time.Time
+}
+
+// ListRuns summarizes the runs with steps in dir, most recent first.
+func ListRuns(dir string) ([]RunSummary, error) {
+	records, err := LoadStepRecordsFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]*RunSummary{}
+	var runs []*RunSummary
+	for _, record := range records {
+		if record.RunID == "" {
+			continue
+		}
+		run, ok := byID[record.RunID]
+		if !ok {
+			run = &RunSummary{ID: record.RunID, Started: record.CreatedAt}
+			byID[record.RunID] = run
+			runs = append(runs, run)
+		}
+		run.Steps++
+		if record.Status == "failed" {
+			run.Failed++
+		}
+		if record.CreatedAt.Before(run.Started) {
+			run.Started = record.CreatedAt
+		}
+		if record.CreatedAt.After(run.Ended) {
+			run.Ended = record.CreatedAt
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Ended.After(runs[j].Ended) })
+	out := make([]RunSummary, len(runs))
+	for i, run := range runs {
+		out[i] = *run
+	}
+	return out, nil
+}
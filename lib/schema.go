@@ -0,0 +1,201 @@
+package lib
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CommandSchema describes a registered command for tool generators.
+type CommandSchema struct {
+	Name        string         `json:"name"`
+	Summary     string         `json:"summary"`
+	Description string         `json:"description"`
+	Args        []ArgSchema    `json:"args"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// ArgSchema describes one flag or positional argument.
+type ArgSchema struct {
+	Name       string `json:"name"`
+	Long       string `json:"long,omitempty"`
+	Short      string `json:"short,omitempty"`
+	Type       string `json:"type"`
+	Default    string `json:"default,omitempty"`
+	Help       string `json:"help,omitempty"`
+	Positional bool   `json:"positional,omitempty"`
+	Required   bool   `json:"required,omitempty"`
+	// Choices are the subcommand names, for the "subcommand" argument.
+	Choices []string `json:"choices,omitempty"`
+}
+
+// Schemas returns schemas for every registered command, sorted by name.
+func Schemas() []CommandSchema {
+	names := make([]string, 0, len(Args))
+	for name := range Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schemas := make([]CommandSchema, 0, len(names))
+	for _, name := range names {
+		schemas = append(schemas, Schema(name, Args[name]))
+	}
+	return schemas
+}
+
+// Schema derives a CommandSchema from an arg struct using the same go-arg
+// tags the parser reads. InputSchema is a JSON Schema object keyed by
+// argument name, with positionals and flags side by side.
+func Schema(name string, args ArgsStruct) CommandSchema {
+	description := strings.TrimSpace(args.Description())
+	summary := strings.SplitN(description, "\n", 2)[0]
+	summary = strings.TrimSpace(strings.TrimPrefix(summary, name+" - "))
+
+	specs := argSchemas(reflect.TypeOf(args))
+	properties := map[string]any{}
+	required := []string{}
+	for _, spec := range specs {
+		prop := map[string]any{"type": spec.Type}
+		if spec.Type == "array" {
+			prop["items"] = map[string]any{"type": "string"}
+		}
+		if len(spec.Choices) > 0 {
+			prop["enum"] = spec.Choices
+		}
+		if spec.Help != "" {
+			prop["description"] = spec.Help
+		}
+		if spec.Default != "" {
+			prop["default"] = typedDefault(spec)
+		}
+		properties[spec.Name] = prop
+		if spec.Required {
+			required = append(required, spec.Name)
+		}
+	}
+	return CommandSchema{
+		Name:        name,
+		Summary:     summary,
+		Description: description,
+		Args:        specs,
+		InputSchema: map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		},
+	}
+}
+
+// argSchemas lists t's arguments. Subcommands become one positional
+// "subcommand" argument, followed by the arguments of every subcommand,
+// none required since each applies to one subcommand only.
+func argSchemas(t reflect.Type) []ArgSchema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	specs := []ArgSchema{}
+	subcommand := -1
+	seen := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("arg")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			specs = append(specs, argSchemas(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(tag, "subcommand"); ok {
+			name := strings.TrimPrefix(strings.SplitN(rest, ",", 2)[0], ":")
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if subcommand < 0 {
+				subcommand = len(specs)
+				specs = append(specs, ArgSchema{Name: "subcommand", Type: "string", Positional: true, Required: true})
+			}
+			specs[subcommand].Choices = append(specs[subcommand].Choices, name)
+			for _, spec := range argSchemas(field.Type) {
+				if !seen[spec.Name] {
+					seen[spec.Name] = true
+					spec.Required = false
+					specs = append(specs, spec)
+				}
+			}
+			continue
+		}
+		spec := ArgSchema{
+			Long:    strings.ToLower(field.Name),
+			Type:    jsonType(field.Type),
+			Default: field.Tag.Get("default"),
+			Help:    field.Tag.Get("help"),
+		}
+		for _, key := range strings.Split(tag, ",") {
+			key = strings.TrimSpace(key)
+			switch {
+			case strings.HasPrefix(key, "--"):
+				spec.Long = key[2:]
+			case strings.HasPrefix(key, "-") && len(key) == 2:
+				spec.Short = key[1:]
+			case key == "positional":
+				spec.Positional = true
+			case key == "required":
+				spec.Required = true
+			}
+		}
+		spec.Name = spec.Long
+		if spec.Positional {
+			spec.Name = strings.ToLower(field.Name)
+			spec.Long = ""
+		}
+		specs = append(specs, spec)
+	}
+	if subcommand >= 0 {
+		specs[subcommand].Help = "one of: " + strings.Join(specs[subcommand].Choices, ", ")
+	}
+	return specs
+}
+
+// typedDefault converts a default tag to the JSON type of its argument.
+func typedDefault(spec ArgSchema) any {
+	switch spec.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(spec.Default, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(spec.Default, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(spec.Default); err == nil {
+			return b
+		}
+	}
+	return spec.Default
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.String:
+		return "string"
+	default:
+		return fmt.Sprint(t.Kind())
+	}
+}
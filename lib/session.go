@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// Session keeps a CDP connection open per tab and reuses its attached
+// context, so long-running processes (serve, repl) pay connection setup
+// once per tab instead of per action.
+//
+// Each tab gets its own connection, like the one-shot commands, so
+// dropping one (Forget) closes only the connection and leaves the tab open;
+// cancelling a chromedp context that shares a connection closes its tab.
+type Session struct {
+	mu        sync.Mutex
+	tabs      map[string]*sessionTab
+	overrides map[string]*tabOverrides
+}
+
+// sessionTab is a tab's attached context. ready is closed once attaching
+// finishes, with err set if it failed, so concurrent callers for the same
+// tab wait on one attach.
+type sessionTab struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	ready    chan struct{}
+	err      error
+	detached chan struct{}
+}
+
+// NewSession connects to Chrome on the current port. Requires remote Chrome.
+func NewSession() (*Session, error) {
+	if !IsChromeRunning() {
+		return nil, fmt.Errorf("Chrome not running on port %d", GetPort())
+	}
+	return &Session{
+		tabs:      map[string]*sessionTab{},
+		overrides: map[string]*tabOverrides{},
+	}, nil
+}
+
+// Tab resolves selector like -t and returns an attached context for that tab.
+func (s *Session) Tab(selector string) (context.Context, string, error) {
+	tab, id, err := s.tab(selector)
+	if err != nil {
+		return nil, "", err
+	}
+	return tab.ctx, id, nil
+}
+
+func (s *Session) tab(selector string) (*sessionTab, string, error) {
+	id, reason, err := ResolveTarget(selector, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if id == "" {
+		return nil, "", errors.New(reason)
+	}
+
+	s.mu.Lock()
+	tab, ok := s.tabs[id]
+	if !ok {
+		tab = &sessionTab{ready: make(chan struct{}), detached: make(chan struct{})}
+		s.tabs[id] = tab
+	}
+	s.mu.Unlock()
+	if ok {
+		<-tab.ready
+		if tab.err != nil {
+			return nil, "", tab.err
+		}
+		return tab, id, nil
+	}
+
+	// Attach outside the lock, so a slow tab doesn't hold up the others.
+	// Chrome dropped the tab's overrides with any earlier connection, so
+	// they are put back before anyone else gets the tab
+	tab.err = tab.attach(id)
+	if tab.err == nil {
+		if tab.err = s.reapply(id, tab.ctx); tab.err != nil {
+			tab.cancel()
+		}
+	}
+	if tab.err != nil {
+		s.mu.Lock()
+		if s.tabs[id] == tab {
+			delete(s.tabs, id)
+		}
+		s.mu.Unlock()
+	}
+	close(tab.ready)
+	if tab.err != nil {
+		return nil, "", tab.err
+	}
+	return tab, id, nil
+}
+
+func (t *sessionTab) attach(id string) error {
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(context.Background(), ChromeURL())
+	ctx, _ := chromedp.NewContext(allocCtx, chromedp.WithTargetID(target.ID(id)))
+	// Cancelling the allocator drops the connection; cancelling the tab's
+	// own context would close the tab
+	t.ctx, t.cancel = ctx, allocCancel
+
+	// Attach with the long-lived context, never a per-call timeout context:
+	// chromedp ties the browser connection to the context used on first Run
+	attached := make(chan error, 1)
+	go func() { attached <- chromedp.Run(ctx) }()
+	select {
+	case err := <-attached:
+		if err != nil {
+			allocCancel()
+			return err
+		}
+		// Inspector.detached: the tab closed, or another client took it over
+		var once sync.Once
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			if _, ok := ev.(*inspector.EventDetached); ok {
+				once.Do(func() { close(t.detached) })
+			}
+		})
+		return nil
+	case <-time.After(DefaultTimeout):
+		allocCancel()
+		return fmt.Errorf("timeout attaching to tab %s", shortID(id))
+	}
+}
+
+// gone reports whether the tab's connection is unusable: its context is
+// done, Chrome detached it, or err says the websocket closed.
+func (t *sessionTab) gone(err error) bool {
+	select {
+	case <-t.ctx.Done():
+		return true
+	case <-t.detached:
+		return true
+	default:
+	}
+	return errors.Is(err, chromedp.ErrChannelClosed)
+}
+
+// Run resolves the tab and runs actions with a per-call timeout. A run
+// that fails because the tab's connection is gone drops the connection so
+// the next call re-attaches; any other failure keeps it.
+func (s *Session) Run(selector string, timeout time.Duration, actions ...chromedp.Action) error {
+	tab, id, err := s.tab(selector)
+	if err != nil {
+		return err
+	}
+	return s.runOn(id, tab, timeout, actions...)
+}
+
+func (s *Session) runOn(id string, tab *sessionTab, timeout time.Duration, actions ...chromedp.Action) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(tab.ctx, timeout)
+	defer cancel()
+	err := chromedp.Run(ctx, actions...)
+	if err != nil && tab.gone(err) {
+		s.drop(id, tab)
+	}
+	return err
+}
+
+// Forget drops the tab's connection. The overrides held for the tab are
+// kept, and reapplied when the next call re-attaches, since Chrome drops
+// them with the connection.
+func (s *Session) Forget(id string) {
+	s.mu.Lock()
+	tab, ok := s.tabs[id]
+	s.mu.Unlock()
+	if ok {
+		<-tab.ready
+		s.drop(id, tab)
+	}
+}
+
+// drop closes an attached tab's connection, unless a newer one already
+// replaced it.
+func (s *Session) drop(id string, tab *sessionTab) {
+	s.mu.Lock()
+	if s.tabs[id] == tab {
+		delete(s.tabs, id)
+	}
+	s.mu.Unlock()
+	if tab.cancel != nil {
+		tab.cancel()
+	}
+}
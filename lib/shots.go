@@ -0,0 +1,191 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PruneOptions is a shots retention policy. A shot is kept when it is newer
+// than KeepDays or among the KeepLast newest; zero disables that rule.
+type PruneOptions struct {
+	KeepDays int
+	KeepLast int
+	DryRun   bool
+}
+
+// PruneResult lists what PruneShots removed (or would remove).
+type PruneResult struct {
+	Kept    int
+	Removed []string
+	Bytes   int64
+}
+
+type shotEntry struct {
+	path    string
+	created time.Time
+	runID   string
+}
+
+// PruneShots deletes screenshots with StepRecord sidecars in dir and its
+// run directories (see RunDir) that fall outside the policy, along with
+// their sidecar, gallery thumbnail, vr diff, before image, and captured
+// events. Sidecars whose screenshot is gone are removed too, as are the
+// logs, report, and gallery of runs with no steps left, and run directories
+// left empty. Other files and subdirectories such as baselines are left
+// alone.
+func PruneShots(dir string, opts PruneOptions) (PruneResult, error) {
+	var res PruneResult
+	if opts.KeepDays <= 0 && opts.KeepLast <= 0 {
+		return res, errors.New("a retention policy is required: keep days or keep last")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return res, err
+	}
+	if _, err := os.ReadDir(absDir); err != nil {
+		return res, err
+	}
+
+	var shots []shotEntry
+	var orphans []string
+	recordDirs := runDirs(absDir)
+	for _, recordDir := range recordDirs {
+		entries, err := os.ReadDir(recordDir)
+		if err != nil {
+			return res, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+				continue
+			}
+			path := filepath.Join(recordDir, strings.TrimSuffix(name, ".json"))
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".png", ".jpg", ".jpeg", ".webp", ".pdf":
+			default:
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				orphans = append(orphans, path+".json")
+				continue
+			}
+			shot := shotEntry{path: path, created: info.ModTime()}
+			if record, err := LoadStepMetadata(path); err == nil {
+				if !record.CreatedAt.IsZero() {
+					shot.created = record.CreatedAt
+				}
+				shot.runID = record.RunID
+			}
+			shots = append(shots, shot)
+		}
+	}
+	sort.SliceStable(shots, func(i, j int) bool {
+		return shots[i].created.After(shots[j].created)
+	})
+
+	cutoff := time.Now().AddDate(0, 0, -opts.KeepDays)
+	var remove []string
+	keptRuns := map[string]bool{}
+	prunedRuns := map[string]bool{}
+	for i, shot := range shots {
+		if (opts.KeepLast > 0 && i < opts.KeepLast) || (opts.KeepDays > 0 && shot.created.After(cutoff)) {
+			res.Kept++
+			keptRuns[shot.runID] = true
+			continue
+		}
+		if shot.runID != "" {
+			prunedRuns[shot.runID] = true
+		}
+		base := strings.TrimSuffix(shot.path, filepath.Ext(shot.path))
+		remove = append(remove,
+			shot.path,
+			shot.path+".json",
+			filepath.Join(absDir, "thumbs", filepath.Base(shot.path)+".jpg"),
+			base+"-diff.png",
+			base+"-before"+filepath.Ext(shot.path),
+		)
+		for _, kind := range RunLogKinds {
+			remove = append(remove, StepLogPath(shot.path, kind))
+		}
+	}
+	remove = append(remove, orphans...)
+	for runID := range prunedRuns {
+		if keptRuns[runID] {
+			continue
+		}
+		for _, kind := range RunLogKinds {
+			remove = append(remove, filepath.Join(absDir, fmt.Sprintf("%s-%s.ndjson", runID, kind)))
+		}
+		remove = append(remove, filepath.Join(absDir, fmt.Sprintf("report-%s.html", runID)))
+		if runDir, err := RunDir(absDir, runID); err == nil {
+			for _, kind := range RunLogKinds {
+				remove = append(remove, filepath.Join(runDir, fmt.Sprintf("%s-%s.ndjson", runID, kind)))
+			}
+			remove = append(remove, filepath.Join(runDir, fmt.Sprintf("report-%s.html", runID)), filepath.Join(runDir, "index.html"))
+		}
+	}
+
+	for _, path := range remove {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !opts.DryRun {
+			if err := os.Remove(path); err != nil {
+				return res, err
+			}
+		}
+		res.Removed = append(res.Removed, path)
+		res.Bytes += info.Size()
+	}
+	if !opts.DryRun {
+		// Drop run directories left empty; fails harmlessly on the others
+		for _, recordDir := range recordDirs[1:] {
+			_ = os.Remove(recordDir)
+		}
+	}
+	return res, nil
+}
+
+// CompactCache removes cache entries that no longer point at anything: the
+// last-step record once its screenshot is gone, and expired target caches.
+// It returns the removed paths.
+func CompactCache(dryRun bool) ([]string, error) {
+	cache, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	lastStep := filepath.Join(cache, "last-step.json")
+	if data, err := os.ReadFile(lastStep); err == nil {
+		var record StepRecord
+		if json.Unmarshal(data, &record) != nil || record.Screenshot == "" {
+			stale = append(stale, lastStep)
+		} else if _, err := os.Stat(record.Screenshot); errors.Is(err, os.ErrNotExist) {
+			stale = append(stale, lastStep)
+		}
+	}
+	targets, _ := filepath.Glob(filepath.Join(cache, "targets-*.json"))
+	temps, _ := filepath.Glob(filepath.Join(cache, ".targets-*"))
+	for _, path := range append(targets, temps...) {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > time.Minute {
+			stale = append(stale, path)
+		}
+	}
+	if dryRun {
+		return stale, nil
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return stale, nil
+}
@@ -2,26 +2,38 @@ package lib
 
 import (
 	"bufio"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const (
 	slideshowFrameDurationSeconds = 5
+	slideshowTransitionSeconds    = 0.5
+	slideshowRealTimeMinSeconds   = 1
 	subtitleFontName              = "DejaVu Sans"
 	subtitleFontSize              = 32
+	cardFontSize                  = 56
+	captionWrapWidth              = 72
+	cardAction                    = "slideshow-card"
 )
 
+// LoadStepRecordsFromDir returns the step records in dir and its run
+// directories (see RunDir) whose screenshots still exist, oldest first.
 func LoadStepRecordsFromDir(dir string) ([]StepRecord, error) {
 	trimmed := strings.TrimSpace(dir)
 	if trimmed == "" {
@@ -31,31 +43,36 @@ func LoadStepRecordsFromDir(dir string) ([]StepRecord, error) {
 	if err != nil {
 		return nil, err
 	}
-	entries, err := os.ReadDir(absDir)
-	if err != nil {
+	if _, err := os.ReadDir(absDir); err != nil {
 		return nil, err
 	}
 
 	var records []StepRecord
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if !strings.HasSuffix(entry.Name(), ".json") {
-			continue
-		}
-		metaPath := filepath.Join(absDir, entry.Name())
-		record, loadErr := LoadStepMetadata(strings.TrimSuffix(metaPath, ".json"))
-		if loadErr != nil {
-			continue
-		}
-		if record.Screenshot == "" {
-			continue
+	for _, recordDir := range runDirs(absDir) {
+		entries, err := os.ReadDir(recordDir)
+		if err != nil {
+			return nil, err
 		}
-		if _, statErr := os.Stat(record.Screenshot); statErr != nil {
-			continue
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			metaPath := filepath.Join(recordDir, entry.Name())
+			record, loadErr := LoadStepMetadata(strings.TrimSuffix(metaPath, ".json"))
+			if loadErr != nil {
+				continue
+			}
+			if record.Screenshot == "" {
+				continue
+			}
+			if _, statErr := os.Stat(record.Screenshot); statErr != nil {
+				continue
+			}
+			records = append(records, record)
 		}
-		records = append(records, record)
 	}
 
 	sort.SliceStable(records, func(i, j int) bool {
@@ -79,12 +96,200 @@ func LoadStepRecordsFromDir(dir string) ([]StepRecord, error) {
 	return records, nil
 }
 
-func GenerateSlideshow(records []StepRecord, outputPath string, fps int, verbose bool) error {
+// SlideshowFormats are the output formats GenerateSlideshow supports.
+var SlideshowFormats = []string{"mp4", "webm", "gif"}
+
+// ParseSlideshowFormat picks the output format from an explicit format or,
+// when that is empty, the output path's extension (default mp4).
+func ParseSlideshowFormat(format string, outputPath string) (string, error) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(strings.TrimSpace(outputPath))), ".")
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = ext
+		if format == "" {
+			format = "mp4"
+		}
+	}
+	for _, known := range SlideshowFormats {
+		if format == known {
+			if ext != "" && ext != format {
+				return "", fmt.Errorf("output %s does not match format %s", outputPath, format)
+			}
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported format %q, expected %s", format, strings.Join(SlideshowFormats, ", "))
+}
+
+// videoCodecArgs returns the ffmpeg output arguments for mp4 (H.264) or
+// webm (VP9).
+func videoCodecArgs(format string) []string {
+	if format == "webm" {
+		return []string{"-pix_fmt", "yuv420p", "-c:v", "libvpx-vp9", "-b:v", "0", "-crf", "32"}
+	}
+	return []string{"-pix_fmt", "yuv420p", "-c:v", "libx264"}
+}
+
+// SlideshowTransitions are the ffmpeg xfade transitions the slideshow
+// accepts between frames; none means hard cuts.
+var SlideshowTransitions = []string{"none", "fade", "dissolve", "fadeblack", "fadewhite", "slideleft", "slideright", "slideup", "slidedown", "wipeleft", "wiperight", "wipeup", "wipedown"}
+
+// SlideshowOptions controls GenerateSlideshow.
+type SlideshowOptions struct {
+	Format             string        // mp4, webm, or gif; see ParseSlideshowFormat
+	FPS                int           // 0 means 30, or 10 for gif
+	Duration           time.Duration // per record without its own Duration; 0 means 5s
+	RealTime           bool          // show records for their ElapsedMs, see realTimeDurations
+	Speed              float64       // divides real-time durations; 0 means 1
+	Transition         string        // one of SlideshowTransitions; empty means fade
+	TransitionDuration time.Duration // 0 means 0.5s
+	Title              string        // text for a generated first frame
+	Outro              string        // text for a generated last frame
+	Captions           CaptionStyle
+	Stamp              string // corner for step number and time; empty means none
+	Narrate            bool   // speak each note, see TTSCommand
+	TTSCommand         string // overrides TTSCommand's default
+	Verbose            bool
+}
+
+// StampCorners are where SlideshowOptions.Stamp can put the step number and
+// CreatedAt time, mapped to their ASS alignment.
+var StampCorners = map[string]int{"top-left": 7, "top-right": 9, "bottom-left": 1, "bottom-right": 3}
+
+// CaptionPositions are where CaptionStyle.Position can put captions; below
+// adds a band under the screenshots so captions never cover the page.
+var CaptionPositions = []string{"bottom", "top", "below"}
+
+// CaptionStyle controls the step captions. Zero values mean the defaults:
+// DejaVu Sans, size 32, white, at the bottom. Size is in libass units, where
+// the frame is 288 units tall, so captions scale with the screenshots.
+type CaptionStyle struct {
+	Font     string
+	Size     int
+	Color    string // hex RRGGBB, with or without #
+	Position string // one of CaptionPositions
+	Disabled bool   // no step captions; title and end cards still show text
+}
+
+// withDefaults validates the style and fills in defaults.
+func (c CaptionStyle) withDefaults() (CaptionStyle, error) {
+	c.Font = strings.TrimSpace(c.Font)
+	if c.Font == "" {
+		c.Font = subtitleFontName
+	}
+	if c.Size < 0 {
+		return c, fmt.Errorf("invalid caption size %d", c.Size)
+	}
+	if c.Size == 0 {
+		c.Size = subtitleFontSize
+	}
+	c.Color = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(c.Color)), "#")
+	if c.Color == "" {
+		c.Color = "ffffff"
+	}
+	if _, err := assColor(c.Color); err != nil {
+		return c, err
+	}
+	c.Position = strings.ToLower(strings.TrimSpace(c.Position))
+	if c.Position == "" {
+		c.Position = "bottom"
+	}
+	for _, known := range CaptionPositions {
+		if c.Position == known {
+			return c, nil
+		}
+	}
+	return c, fmt.Errorf("unsupported caption position %q, expected %s", c.Position, strings.Join(CaptionPositions, ", "))
+}
+
+// forceStyle returns the subtitles filter force_style value.
+func (c CaptionStyle) forceStyle() string {
+	alignment := 2
+	if c.Position == "top" {
+		alignment = 8
+	}
+	color, _ := assColor(c.Color)
+	return fmt.Sprintf("FontName=%s,FontSize=%d,PrimaryColour=%s,OutlineColour=\u0026H00000000\u0026,BorderStyle=3,Outline=1,Shadow=0,Alignment=%d",
+		c.Font, c.Size, color, alignment)
+}
+
+// band returns the height to add under frames of height for the below
+// position, fitting lines of caption plus the default vertical margin. The
+// band counts toward the height libass scales fonts by, hence the solve.
+func (c CaptionStyle) band(height, lines int) int {
+	if c.Position != "below" || c.Disabled || lines == 0 {
+		return 0
+	}
+	share := (float64(c.Size)*1.3*float64(lines) + 2*20) / 288
+	if share > 0.75 {
+		share = 0.75
+	}
+	band := int(math.Ceil(float64(height) * share / (1 - share)))
+	return band + band%2
+}
+
+// assColor converts hex RRGGBB to an ASS &HAABBGGRR& color.
+func assColor(hex string) (string, error) {
+	if len(hex) != 6 {
+		return "", fmt.Errorf("invalid caption color %q, expected RRGGBB", hex)
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return "", fmt.Errorf("invalid caption color %q, expected RRGGBB", hex)
+	}
+	return strings.ToUpper("&H00" + hex[4:6] + hex[2:4] + hex[0:2] + "&"), nil
+}
+
+// audioCodecArgs returns the ffmpeg audio arguments for mp4 (AAC) or webm
+// (Opus).
+func audioCodecArgs(format string) []string {
+	if format == "webm" {
+		return []string{"-c:a", "libopus", "-b:a", "96k"}
+	}
+	return []string{"-c:a", "aac", "-b:a", "128k"}
+}
+
+// GenerateSlideshow renders records as a captioned slideshow. Each record is
+// shown for its Duration, or opts.Duration when it has none, and blends into
+// the next with opts.Transition. A GIF stores every frame, so without a
+// transition it drops to 2 fps, which is plenty for still frames.
+func GenerateSlideshow(records []StepRecord, outputPath string, opts SlideshowOptions) error {
 	if len(records) == 0 {
 		return errors.New("no step records provided for slideshow")
 	}
+	format, err := ParseSlideshowFormat(opts.Format, outputPath)
+	if err != nil {
+		return err
+	}
+	transition, err := parseSlideshowTransition(opts.Transition)
+	if err != nil {
+		return err
+	}
+	captions, err := opts.Captions.withDefaults()
+	if err != nil {
+		return err
+	}
+	stamp := strings.ToLower(strings.TrimSpace(opts.Stamp))
+	if _, ok := StampCorners[stamp]; stamp != "" && !ok {
+		return fmt.Errorf("unsupported stamp corner %q, expected top-left, top-right, bottom-left, or bottom-right", opts.Stamp)
+	}
+	tts := ""
+	if opts.Narrate {
+		if format == "gif" {
+			return errors.New("narration needs mp4 or webm, gif has no audio")
+		}
+		if tts, err = TTSCommand(opts.TTSCommand); err != nil {
+			return err
+		}
+	}
+	fps := opts.FPS
 	if fps <= 0 {
 		fps = 30
+		if format == "gif" {
+			fps = 10
+			if transition == "none" {
+				fps = 2
+			}
+		}
 	}
 	absOutput, err := filepath.Abs(strings.TrimSpace(outputPath))
 	if err != nil {
@@ -108,8 +313,10 @@ func GenerateSlideshow(records []StepRecord, outputPath string, fps int, verbose
 	concatPath := filepath.Join(tempDir, "inputs.txt")
 	captionsPath := filepath.Join(tempDir, "captions.srt")
 
-	frameDuration := time.Duration(slideshowFrameDurationSeconds) * time.Second
-
+	frameDuration := opts.Duration
+	if frameDuration <= 0 {
+		frameDuration = time.Duration(slideshowFrameDurationSeconds) * time.Second
+	}
 	maxWidth, maxHeight, err := maxScreenshotDimensions(records)
 	if err != nil {
 		return err
@@ -120,39 +327,115 @@ func GenerateSlideshow(records []StepRecord, outputPath string, fps int, verbose
 	if maxHeight%2 != 0 {
 		maxHeight++
 	}
+	if opts.Title != "" || opts.Outro != "" {
+		card, err := writeCard(tempDir, maxWidth, maxHeight)
+		if err != nil {
+			return err
+		}
+		if opts.Title != "" {
+			records = append([]StepRecord{{Action: cardAction, Note: opts.Title, Screenshot: card}}, records...)
+		}
+		if opts.Outro != "" {
+			records = append(records, StepRecord{Action: cardAction, Note: opts.Outro, Screenshot: card})
+		}
+	}
 
-	if err := writeConcatFile(concatPath, records, frameDuration); err != nil {
-		return err
+	if opts.RealTime {
+		records = realTimeDurations(records, opts.Speed)
+	}
+
+	narration := ""
+	if opts.Narrate {
+		if records, narration, err = writeNarration(ffmpegPath, tts, records, frameDuration, tempDir, opts.Verbose); err != nil {
+			return err
+		}
+	}
+	var fade time.Duration
+	if transition != "none" && len(records) > 1 {
+		fade = opts.TransitionDuration
+		if fade <= 0 {
+			fade = time.Duration(slideshowTransitionSeconds * float64(time.Second))
+		}
+		for _, record := range records {
+			if recordDuration(record, frameDuration) <= fade {
+				return fmt.Errorf("transition of %s must be shorter than every frame (%s)", fade, recordDuration(record, frameDuration))
+			}
+		}
 	}
-	if err := writeCaptionsFile(captionsPath, records, frameDuration); err != nil {
+
+	written, lines, err := writeCaptionsFile(captionsPath, records, frameDuration, !captions.Disabled)
+	if err != nil {
 		return err
 	}
 
-	filterParts := []string{}
+	pad := ""
 	if maxWidth > 0 && maxHeight > 0 {
-		filterParts = append(filterParts, fmt.Sprintf("pad=%d:%d:(%d-iw)/2:(%d-ih)/2", maxWidth, maxHeight, maxWidth, maxHeight))
+		band := captions.band(maxHeight, lines)
+		pad = fmt.Sprintf("pad=%d:%d:(%d-iw)/2:(%d-ih)/2", maxWidth, maxHeight+band, maxWidth, maxHeight)
+	}
+	var post []string
+	if written > 0 {
+		post = append(post, fmt.Sprintf("subtitles='%s':force_style='%s'", escapeForFilter(captionsPath), captions.forceStyle()))
+	}
+	if stamp != "" {
+		stampsPath := filepath.Join(tempDir, "stamps.srt")
+		if err := writeStampsFile(stampsPath, records, frameDuration); err != nil {
+			return err
+		}
+		post = append(post, fmt.Sprintf("subtitles='%s':force_style='FontName=%s,FontSize=14,PrimaryColour=\u0026H00FFFFFF\u0026,OutlineColour=\u0026H80000000\u0026,BorderStyle=3,Outline=1,Shadow=0,MarginL=8,MarginR=8,MarginV=8,Alignment=%d'",
+			escapeForFilter(stampsPath), captions.Font, StampCorners[stamp]))
+	}
+	if format == "gif" {
+		// One palette for the whole clip keeps text and UI colors crisp
+		post = append(post, "split[a][b];[a]palettegen=stats_mode=diff[p];[b][p]paletteuse=dither=bayer")
 	}
-
-	filterParts = append(filterParts, fmt.Sprintf("subtitles='%s':force_style='FontName=%s,FontSize=%d,PrimaryColour=\u0026H00FFFFFF\u0026,OutlineColour=\u0026H00000000\u0026,BorderStyle=3,Outline=1,Shadow=0,Alignment=2'",
-		escapeForFilter(captionsPath), subtitleFontName, subtitleFontSize))
-	filter := strings.Join(filterParts, ",")
 
 	args := []string{"-y"}
-	if !verbose {
+	if !opts.Verbose {
 		args = append(args, "-hide_banner", "-loglevel", "warning", "-nostats")
 	}
-	args = append(
-		args,
-		"-f", "concat",
-		"-safe", "0",
-		"-i", concatPath,
-		"-r", fmt.Sprintf("%d", fps),
-		"-vf", filter,
-		"-pix_fmt", "yuv420p",
-		"-c:v", "libx264",
-		"-vsync", "cfr",
-		absOutput,
-	)
+	if fade > 0 {
+		filter := strings.Join(post, ",")
+		if filter == "" {
+			filter = "null"
+		}
+		args = append(args, transitionArgs(records, frameDuration, fade, transition, fps, pad, filter)...)
+		if narration != "" {
+			args = append(args, "-i", narration, "-map", fmt.Sprintf("%d:a", len(records)))
+		}
+	} else {
+		if err := writeConcatFile(concatPath, records, frameDuration); err != nil {
+			return err
+		}
+		if pad != "" {
+			post = append([]string{pad}, post...)
+		}
+		if len(post) == 0 {
+			post = []string{"null"}
+		}
+		filter := strings.Join(post, ",")
+		args = append(
+			args,
+			"-f", "concat",
+			"-safe", "0",
+			"-i", concatPath,
+			"-r", fmt.Sprintf("%d", fps),
+			"-vf", filter,
+		)
+		if narration != "" {
+			args = append(args, "-i", narration, "-map", "0:v", "-map", "1:a")
+		}
+	}
+	args = append(args, "-vsync", "cfr")
+	if format == "gif" {
+		args = append(args, "-loop", "0")
+	} else {
+		args = append(args, videoCodecArgs(format)...)
+	}
+	if narration != "" {
+		args = append(args, audioCodecArgs(format)...)
+	}
+	args = append(args, absOutput)
 	cmd := exec.Command(ffmpegPath, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -160,6 +443,64 @@ func GenerateSlideshow(records []StepRecord, outputPath string, fps int, verbose
 	return cmd.Run()
 }
 
+// parseSlideshowTransition validates a transition name, defaulting to fade.
+func parseSlideshowTransition(transition string) (string, error) {
+	transition = strings.ToLower(strings.TrimSpace(transition))
+	if transition == "" {
+		return "fade", nil
+	}
+	for _, known := range SlideshowTransitions {
+		if transition == known {
+			return transition, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported transition %q, expected %s", transition, strings.Join(SlideshowTransitions, ", "))
+}
+
+// transitionArgs returns ffmpeg input and filter arguments that loop each
+// screenshot and chain xfade between neighbours. Each transition is centered
+// on the cut it replaces, so the total length and caption timing match the
+// hard cut slideshow. filter is applied to the joined stream.
+func transitionArgs(records []StepRecord, frameDuration, fade time.Duration, transition string, fps int, pad, filter string) []string {
+	var args []string
+	var graph []string
+	for idx, record := range records {
+		// Half a transition of overlap on each side that has a neighbour
+		length := recordDuration(record, frameDuration) + fade
+		if idx == 0 || idx == len(records)-1 {
+			length -= fade / 2
+		}
+		args = append(args, "-loop", "1", "-t", fmt.Sprintf("%.3f", length.Seconds()), "-i", record.Screenshot)
+		prep := fmt.Sprintf("fps=%d,format=yuv420p,setsar=1", fps)
+		if pad != "" {
+			prep = pad + "," + prep
+		}
+		graph = append(graph, fmt.Sprintf("[%d:v]%s[v%d]", idx, prep, idx))
+	}
+	last := "v0"
+	var cut time.Duration
+	for idx := 1; idx < len(records); idx++ {
+		cut += recordDuration(records[idx-1], frameDuration)
+		offset := cut - fade/2
+		out := fmt.Sprintf("x%d", idx)
+		graph = append(graph, fmt.Sprintf("[%s][v%d]xfade=transition=%s:duration=%.3f:offset=%.3f[%s]", last, idx, transition, fade.Seconds(), offset.Seconds(), out))
+		last = out
+	}
+	graph = append(graph, fmt.Sprintf("[%s]%s[out]", last, filter))
+	return append(args, "-filter_complex", strings.Join(graph, ";"), "-map", "[out]")
+}
+
+// writeCard saves the dark background of title and end cards, whose text
+// is drawn by the subtitles filter like every other caption.
+func writeCard(dir string, width, height int) (string, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 0x11, 0x11, 0x11, 0xff
+	}
+	path := filepath.Join(dir, "card.png")
+	return path, SavePNG(path, img)
+}
+
 func writeConcatFile(path string, records []StepRecord, frameDuration time.Duration) error {
 	file, err := os.Create(path)
 	if err != nil {
@@ -171,7 +512,7 @@ func writeConcatFile(path string, records []StepRecord, frameDuration time.Durat
 	for idx, record := range records {
 		abs := record.Screenshot
 		fmt.Fprintf(writer, "file '%s'\n", escapeForConcat(abs))
-		fmt.Fprintf(writer, "duration %.3f\n", frameDuration.Seconds())
+		fmt.Fprintf(writer, "duration %.3f\n", recordDuration(record, frameDuration).Seconds())
 		if idx == len(records)-1 {
 			fmt.Fprintf(writer, "file '%s'\n", escapeForConcat(abs))
 		}
@@ -179,31 +520,107 @@ func writeConcatFile(path string, records []StepRecord, frameDuration time.Durat
 	return writer.Flush()
 }
 
-func writeCaptionsFile(path string, records []StepRecord, frameDuration time.Duration) error {
+// writeCaptionsFile writes the SRT captions, leaving out step captions
+// unless captions is set. It returns how many it wrote and the most lines
+// any step caption wraps to.
+func writeCaptionsFile(path string, records []StepRecord, frameDuration time.Duration, captions bool) (int, int, error) {
 	file, err := os.Create(path)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	defer func() { _ = file.Close() }()
 
 	writer := bufio.NewWriter(file)
+	var end time.Duration
+	written, maxLines := 0, 0
 	for idx, record := range records {
-		start := frameDuration * time.Duration(idx)
-		end := frameDuration * time.Duration(idx+1)
+		start := end
+		end += recordDuration(record, frameDuration)
 		text := slideshowCaption(record)
-		if text == "" {
+		if text == "" || (!captions && record.Action != cardAction) {
 			continue
 		}
 		fmt.Fprintf(writer, "%d\n", idx+1)
 		fmt.Fprintf(writer, "%s --> %s\n", formatSRTTime(start), formatSRTTime(end))
-		for _, line := range wrapText(text, 72) {
+		lines := wrapText(text, captionWrapWidth)
+		if record.Action == cardAction {
+			// Centered and larger, with no box behind it
+			lines = wrapText(text, 40)
+			lines[0] = fmt.Sprintf("{\\an5\\fs%d\\bord0\\3a&HFF&}", cardFontSize) + lines[0]
+		} else if len(lines) > maxLines {
+			maxLines = len(lines)
+		}
+		for _, line := range lines {
 			fmt.Fprintln(writer, line)
 		}
 		fmt.Fprintln(writer)
+		written++
+	}
+	return written, maxLines, writer.Flush()
+}
+
+// writeStampsFile writes an SRT of each step's number and CreatedAt time
+// (UTC, to the millisecond, as in JSON logs). Title and end cards are not
+// numbered.
+func writeStampsFile(path string, records []StepRecord, frameDuration time.Duration) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	total := 0
+	for _, record := range records {
+		if record.Action != cardAction {
+			total++
+		}
+	}
+	writer := bufio.NewWriter(file)
+	var end time.Duration
+	step := 0
+	for idx, record := range records {
+		start := end
+		end += recordDuration(record, frameDuration)
+		if record.Action == cardAction {
+			continue
+		}
+		step++
+		text := fmt.Sprintf("%d/%d", step, total)
+		if !record.CreatedAt.IsZero() {
+			text += "  " + record.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z")
+		}
+		fmt.Fprintf(writer, "%d\n%s --> %s\n%s\n\n", idx+1, formatSRTTime(start), formatSRTTime(end), text)
 	}
 	return writer.Flush()
 }
 
+// realTimeDurations returns records with each Duration set from how long its
+// action took, divided by speed, so the slideshow follows the session's
+// cadence. Frames stay up for at least a second to remain readable; records
+// without a recorded ElapsedMs, and cards, keep their duration.
+func realTimeDurations(records []StepRecord, speed float64) []StepRecord {
+	if speed <= 0 {
+		speed = 1
+	}
+	paced := make([]StepRecord, len(records))
+	for i, record := range records {
+		if record.ElapsedMs > 0 {
+			seconds := float64(record.ElapsedMs) / 1000 / speed
+			record.Duration = max(seconds, slideshowRealTimeMinSeconds)
+		}
+		paced[i] = record
+	}
+	return paced
+}
+
+// recordDuration is how long the slideshow shows record.
+func recordDuration(record StepRecord, frameDuration time.Duration) time.Duration {
+	if record.Duration > 0 {
+		return time.Duration(record.Duration * float64(time.Second))
+	}
+	return frameDuration
+}
+
 func maxScreenshotDimensions(records []StepRecord) (int, int, error) {
 	maxWidth := 0
 	maxHeight := 0
@@ -299,3 +716,37 @@ func wrapText(text string, width int) []string {
 	}
 	return lines
 }
+
+func init() {
+	// Screenshots may be webp; the slideshow only needs their dimensions
+	image.RegisterFormat("webp", "RIFF????WEBP", decodeWebP, decodeWebPConfig)
+}
+
+func decodeWebP(r io.Reader) (image.Image, error) {
+	return nil, errors.New("webp: decoding pixels is not supported")
+}
+
+// decodeWebPConfig reads dimensions from the lossy (VP8), lossless (VP8L),
+// or extended (VP8X) WebP header.
+func decodeWebPConfig(r io.Reader) (image.Config, error) {
+	var header [30]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return image.Config{}, err
+	}
+	var width, height int
+	switch string(header[12:16]) {
+	case "VP8 ":
+		width = int(binary.LittleEndian.Uint16(header[26:28]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(header[28:30]) & 0x3fff)
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(header[21:25])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		width = int(uint32(header[24])|uint32(header[25])<<8|uint32(header[26])<<16) + 1
+		height = int(uint32(header[27])|uint32(header[28])<<8|uint32(header[29])<<16) + 1
+	default:
+		return image.Config{}, fmt.Errorf("webp: unknown chunk %q", header[12:16])
+	}
+	return image.Config{ColorModel: color.RGBAModel, Width: width, Height: height}, nil
+}
@@ -0,0 +1,243 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// StorageState is a Playwright storage state file: every cookie in the
+// browser context plus localStorage per origin. sessionStorage, which
+// Playwright does not save, is added per origin and ignored by Playwright.
+type StorageState struct {
+	Cookies []Cookie      `json:"cookies"`
+	Origins []OriginState `json:"origins"`
+}
+
+// OriginState is the web storage of one origin, like https://example.com.
+type OriginState struct {
+	Origin         string      `json:"origin"`
+	LocalStorage   []NameValue `json:"localStorage"`
+	SessionStorage []NameValue `json:"sessionStorage,omitempty"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// StateResult counts what LoadState applied.
+type StateResult struct {
+	Cookies        int
+	ExpiredCookies int
+	Origins        int
+	Keys           int
+	// Origins whose sessionStorage was dropped: it lives in a single tab,
+	// so it is only restored for the tab's own origin.
+	SkippedSession []string
+}
+
+// LoadStorageState reads a storage state file.
+func LoadStorageState(path string) (StorageState, error) {
+	var state StorageState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(state.Cookies) == 0 && len(state.Origins) == 0 {
+		return state, fmt.Errorf("%s has no cookies or origins", path)
+	}
+	return state, nil
+}
+
+// NormalizeOrigin reduces a URL or host to its origin, assuming https for
+// a bare host.
+func NormalizeOrigin(raw string) (string, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("not an http(s) origin: %s", raw)
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// SaveState captures the tab's cookies and the web storage of origins, the
+// tab's own origin when none are given. Other origins are read in a
+// scratch tab that loads an empty page, so only their localStorage is
+// saved. Origins with no storage are left out.
+func SaveState(ctx context.Context, origins []string) (StorageState, error) {
+	var state StorageState
+	cookies, err := GetCookies(ctx)
+	if err != nil {
+		return state, err
+	}
+	state.Cookies = cookies
+	state.Origins = []OriginState{}
+
+	current, err := tabOrigin(ctx)
+	if err != nil {
+		return state, err
+	}
+	if len(origins) == 0 {
+		if current == "" {
+			return state, nil
+		}
+		origins = []string{current}
+	}
+	for _, raw := range origins {
+		origin, err := NormalizeOrigin(raw)
+		if err != nil {
+			return state, err
+		}
+		var dump map[string]map[string]string
+		if origin == current {
+			dump, err = DumpStorage(ctx, StorageAreas)
+		} else {
+			err = onOrigin(ctx, origin, func(ctx context.Context) error {
+				var err error
+				dump, err = DumpStorage(ctx, []string{"localStorage"})
+				return err
+			})
+		}
+		if err != nil {
+			return state, fmt.Errorf("%s: %w", origin, err)
+		}
+		o := OriginState{
+			Origin:         origin,
+			LocalStorage:   nameValues(dump["localStorage"]),
+			SessionStorage: nameValues(dump["sessionStorage"]),
+		}
+		if len(o.LocalStorage) > 0 || len(o.SessionStorage) > 0 {
+			state.Origins = append(state.Origins, o)
+		}
+	}
+	return state, nil
+}
+
+// LoadState sets the state's cookies in the tab's browser context and
+// writes each origin's storage, in the tab for its own origin, else in a
+// scratch tab. Expired cookies are skipped. Reload the tab afterwards so
+// the page reads the new state.
+func LoadState(ctx context.Context, state StorageState) (StateResult, error) {
+	var res StateResult
+	now := float64(time.Now().Unix())
+	var live []Cookie
+	for _, c := range state.Cookies {
+		if c.Session() || c.Expires > now {
+			live = append(live, c)
+		}
+	}
+	res.Cookies = len(live)
+	res.ExpiredCookies = len(state.Cookies) - len(live)
+	if len(live) > 0 {
+		if err := SetCookies(ctx, live, ""); err != nil {
+			return res, err
+		}
+	}
+
+	current, err := tabOrigin(ctx)
+	if err != nil {
+		return res, err
+	}
+	for _, o := range state.Origins {
+		origin, err := NormalizeOrigin(o.Origin)
+		if err != nil {
+			return res, err
+		}
+		values := map[string][]NameValue{"localStorage": o.LocalStorage}
+		if origin == current {
+			values["sessionStorage"] = o.SessionStorage
+		} else if len(o.SessionStorage) > 0 {
+			res.SkippedSession = append(res.SkippedSession, origin)
+		}
+		write := func(ctx context.Context) error {
+			return writeStorage(ctx, values)
+		}
+		if origin == current {
+			err = write(ctx)
+		} else {
+			err = onOrigin(ctx, origin, write)
+		}
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", origin, err)
+		}
+		res.Origins++
+		res.Keys += len(values["localStorage"]) + len(values["sessionStorage"])
+	}
+	return res, nil
+}
+
+// tabOrigin returns the tab's origin, or "" when it has none (about:blank).
+func tabOrigin(ctx context.Context) (string, error) {
+	var origin string
+	if err := chromedp.Run(ctx, chromedp.Evaluate("location.origin", &origin)); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(origin, "http") {
+		return "", nil
+	}
+	return origin, nil
+}
+
+// onOrigin runs fn in a scratch tab on origin, closed afterwards. Every
+// request is answered with an empty page, so no site code runs and nothing
+// is fetched.
+func onOrigin(ctx context.Context, origin string, fn func(ctx context.Context) error) error {
+	// Cancelling a context that created its tab closes the tab
+	tabCtx, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+	if err := chromedp.Run(tabCtx); err != nil {
+		return err
+	}
+	ic := NewInterceptor(tabCtx)
+	blank := MockRule{URL: "*", Body: "<!doctype html><title></title>", Headers: map[string]string{"Content-Type": "text/html"}}
+	if err := ic.Add(tabCtx, blank); err != nil {
+		return err
+	}
+	if err := Navigate(tabCtx, origin+"/"); err != nil {
+		return err
+	}
+	return fn(tabCtx)
+}
+
+// writeStorage sets keys in the tab's storage areas.
+func writeStorage(ctx context.Context, values map[string][]NameValue) error {
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf(`(() => {
+  const values = %s;
+  for (const [area, items] of Object.entries(values)) {
+    for (const {name, value} of items || []) window[area].setItem(name, value);
+  }
+})()`, encoded)
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, nil)); err != nil {
+		return StorageError(err)
+	}
+	return nil
+}
+
+func nameValues(values map[string]string) []NameValue {
+	out := []NameValue{}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		out = append(out, NameValue{Name: name, Value: values[name]})
+	}
+	return out
+}
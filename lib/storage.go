@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// StorageSeed is the --seed-storage file: keys to set in localStorage and
+// sessionStorage before page scripts run. String values are stored as is,
+// anything else as its JSON text, the way apps usually serialize them.
+type StorageSeed struct {
+	LocalStorage   map[string]json.RawMessage `json:"localStorage"`
+	SessionStorage map[string]json.RawMessage `json:"sessionStorage"`
+}
+
+// LoadStorageSeed reads a StorageSeed JSON file.
+func LoadStorageSeed(path string) (StorageSeed, error) {
+	var seed StorageSeed
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return seed, err
+	}
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return seed, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(seed.LocalStorage) == 0 && len(seed.SessionStorage) == 0 {
+		return seed, fmt.Errorf("%s sets neither localStorage nor sessionStorage", path)
+	}
+	return seed, nil
+}
+
+// StorageSeedScript returns an init script that writes seed into the top
+// frame's storage. Install it with Page.addScriptToEvaluateOnNewDocument
+// before navigating; it is dropped when the connection closes.
+func StorageSeedScript(seed StorageSeed) (string, error) {
+	local, err := storageStrings(seed.LocalStorage)
+	if err != nil {
+		return "", err
+	}
+	session, err := storageStrings(seed.SessionStorage)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(map[string]map[string]string{"localStorage": local, "sessionStorage": session})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`(() => {
+  if (window.top !== window) return;
+  const seed = %s;
+  for (const area of ['localStorage', 'sessionStorage']) {
+    try {
+      for (const [key, value] of Object.entries(seed[area])) window[area].setItem(key, value);
+    } catch (e) {
+      console.warn('seed-storage: ' + area + ': ' + e);
+    }
+  }
+})();`, encoded), nil
+}
+
+// storageStrings converts seed values to the strings storage holds.
+func storageStrings(values map[string]json.RawMessage) (map[string]string, error) {
+	out := map[string]string{}
+	for key, raw := range values {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			out[key] = text
+			continue
+		}
+		if !json.Valid(raw) {
+			return nil, errors.New("invalid value for " + key)
+		}
+		out[key] = string(raw)
+	}
+	return out, nil
+}
+
+// StorageAreas are the web storage areas, in the order dumps list them.
+var StorageAreas = []string{"localStorage", "sessionStorage"}
+
+// StorageArea maps "local" or "session" (or the full area name) to the
+// window property, defaulting to localStorage.
+func StorageArea(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", "local", "localstorage":
+		return "localStorage", nil
+	case "session", "sessionstorage":
+		return "sessionStorage", nil
+	}
+	return "", fmt.Errorf("unknown storage area %q, expected local or session", name)
+}
+
+// DumpStorage returns every key in the given areas of the tab's top frame.
+// The result has the --seed-storage shape, so it can be replayed with
+// navigate --seed-storage.
+func DumpStorage(ctx context.Context, areas []string) (map[string]map[string]string, error) {
+	encoded, err := json.Marshal(areas)
+	if err != nil {
+		return nil, err
+	}
+	var dump map[string]map[string]string
+	script := fmt.Sprintf(`(() => {
+  const out = {};
+  for (const area of %s) {
+    const storage = window[area];
+    out[area] = {};
+    for (let i = 0; i < storage.length; i++) {
+      const key = storage.key(i);
+      out[area][key] = storage.getItem(key);
+    }
+  }
+  return out;
+})()`, encoded)
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &dump)); err != nil {
+		return nil, StorageError(err)
+	}
+	return dump, nil
+}
+
+// StorageError explains the usual failure: pages without an origin, like
+// about:blank and data: URLs, have no web storage.
+func StorageError(err error) error {
+	if strings.Contains(err.Error(), "SecurityError") || strings.Contains(err.Error(), "Access is denied") {
+		return fmt.Errorf("the page has no web storage (about:blank, data:, and sandboxed pages have no origin): %w", err)
+	}
+	return err
+}
@@ -0,0 +1,326 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// StructuredItem is one top-level schema.org item found in a page,
+// normalized to compact JSON-LD whatever its source format: "@type" holds
+// bare type names (Product, not https://schema.org/Product), other keys are
+// properties, and properties given several times become arrays.
+type StructuredItem struct {
+	Format string         `json:"format"` // json-ld, microdata, or rdfa
+	Item   map[string]any `json:"item"`
+	Errors []string       `json:"errors,omitempty"`
+}
+
+// RawStructured is what the page script collects: JSON-LD script bodies,
+// unparsed, and microdata and RDFa items already shaped as compact JSON-LD.
+type RawStructured struct {
+	JSONLD    []string         `json:"jsonld"`
+	Microdata []map[string]any `json:"microdata"`
+	RDFa      []map[string]any `json:"rdfa"`
+}
+
+// StructuredDataJS collects structured data from the document and
+// evaluates to a RawStructured.
+const StructuredDataJS = `(() => {
+  const urlValue = el => {
+    const attr = { A: 'href', AREA: 'href', LINK: 'href', IMG: 'src', AUDIO: 'src', VIDEO: 'src',
+      SOURCE: 'src', TRACK: 'src', IFRAME: 'src', EMBED: 'src', OBJECT: 'data' }[el.tagName];
+    return attr && el.hasAttribute(attr) ? el[attr] || el.getAttribute(attr) : null;
+  };
+  const add = (obj, key, value) => {
+    if (!(key in obj)) obj[key] = value;
+    else if (Array.isArray(obj[key])) obj[key].push(value);
+    else obj[key] = [obj[key], value];
+  };
+
+  const microdataValue = el => {
+    if (el.hasAttribute('itemscope')) return microdataItem(el);
+    if (el.tagName === 'META') return el.getAttribute('content') || '';
+    const url = urlValue(el);
+    if (url !== null) return url;
+    if (el.tagName === 'DATA' || el.tagName === 'METER') return el.getAttribute('value') || '';
+    if (el.tagName === 'TIME' && el.hasAttribute('datetime')) return el.getAttribute('datetime');
+    if (el.hasAttribute('content')) return el.getAttribute('content');
+    return (el.textContent || '').trim().replace(/\s+/g, ' ');
+  };
+  const microdataItem = scope => {
+    const item = {};
+    const types = (scope.getAttribute('itemtype') || '').trim().split(/\s+/).filter(Boolean);
+    if (types.length) item['@type'] = types.length === 1 ? types[0] : types;
+    if (scope.hasAttribute('itemid')) item['@id'] = scope.getAttribute('itemid');
+    const props = [];
+    const walk = el => {
+      for (const child of el.children) {
+        if (child.hasAttribute('itemprop')) props.push(child);
+        if (!child.hasAttribute('itemscope')) walk(child);
+      }
+    };
+    walk(scope);
+    for (const ref of (scope.getAttribute('itemref') || '').trim().split(/\s+/).filter(Boolean)) {
+      const el = document.getElementById(ref);
+      if (!el) continue;
+      if (el.hasAttribute('itemprop')) props.push(el);
+      if (!el.hasAttribute('itemscope')) walk(el);
+    }
+    for (const el of props) {
+      for (const name of el.getAttribute('itemprop').trim().split(/\s+/)) add(item, name, microdataValue(el));
+    }
+    return item;
+  };
+  const microdata = Array.from(document.querySelectorAll('[itemscope]'))
+    .filter(el => !el.hasAttribute('itemprop'))
+    .map(microdataItem);
+
+  const rdfaValue = el => {
+    if (el.hasAttribute('typeof')) return rdfaItem(el);
+    if (el.hasAttribute('content')) return el.getAttribute('content');
+    for (const attr of ['resource', 'href', 'src']) if (el.hasAttribute(attr)) return el[attr] || el.getAttribute(attr);
+    if (el.tagName === 'TIME' && el.hasAttribute('datetime')) return el.getAttribute('datetime');
+    return (el.textContent || '').trim().replace(/\s+/g, ' ');
+  };
+  const rdfaItem = scope => {
+    const item = {};
+    const vocab = (scope.closest('[vocab]') || { getAttribute: () => '' }).getAttribute('vocab') || '';
+    const types = scope.getAttribute('typeof').trim().split(/\s+/).filter(Boolean);
+    const full = types.map(t => t.includes(':') ? t : vocab + t);
+    if (full.length) item['@type'] = full.length === 1 ? full[0] : full;
+    if (scope.hasAttribute('resource')) item['@id'] = scope.getAttribute('resource');
+    const walk = el => {
+      for (const child of el.children) {
+        if (child.hasAttribute('property')) {
+          for (const name of child.getAttribute('property').trim().split(/\s+/)) add(item, name.replace(/^.*[/#:]/, ''), rdfaValue(child));
+        }
+        if (!child.hasAttribute('typeof')) walk(child);
+      }
+    };
+    walk(scope);
+    return item;
+  };
+  const rdfa = Array.from(document.querySelectorAll('[typeof]'))
+    .filter(el => !el.hasAttribute('property'))
+    .map(rdfaItem);
+
+  const jsonld = Array.from(document.querySelectorAll('script[type="application/ld+json" i]')).map(s => s.textContent);
+  return { jsonld, microdata, rdfa };
+})()`
+
+// NormalizeStructured parses and validates raw structured data. JSON-LD
+// @graph arrays are split into items; each item is checked for a type and
+// the properties schema.org rich results require.
+func NormalizeStructured(raw RawStructured) []StructuredItem {
+	var items []StructuredItem
+	for i, text := range raw.JSONLD {
+		var value any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &value); err != nil {
+			items = append(items, StructuredItem{Format: "json-ld", Item: map[string]any{}, Errors: []string{fmt.Sprintf("script %d: invalid JSON: %v", i+1, err)}})
+			continue
+		}
+		for _, obj := range jsonLDObjects(value) {
+			item := StructuredItem{Format: "json-ld", Item: normalizeNode(obj).(map[string]any)}
+			if _, ok := obj["@context"]; !ok && !hasContext(value) {
+				item.Errors = append(item.Errors, "no @context")
+			}
+			delete(item.Item, "@context")
+			item.Errors = append(item.Errors, validateNode(item.Item, typeLabel(item.Item))...)
+			items = append(items, item)
+		}
+	}
+	for format, objs := range map[string][]map[string]any{"microdata": raw.Microdata, "rdfa": raw.RDFa} {
+		for _, obj := range objs {
+			item := StructuredItem{Format: format, Item: normalizeNode(obj).(map[string]any)}
+			item.Errors = validateNode(item.Item, typeLabel(item.Item))
+			items = append(items, item)
+		}
+	}
+	order := map[string]int{"json-ld": 0, "microdata": 1, "rdfa": 2}
+	sort.SliceStable(items, func(i, j int) bool { return order[items[i].Format] < order[items[j].Format] })
+	return items
+}
+
+// jsonLDObjects flattens a JSON-LD document to its top-level nodes.
+func jsonLDObjects(value any) []map[string]any {
+	switch v := value.(type) {
+	case []any:
+		var out []map[string]any
+		for _, e := range v {
+			out = append(out, jsonLDObjects(e)...)
+		}
+		return out
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			out := jsonLDObjects(graph)
+			for _, o := range out {
+				if _, ok := o["@context"]; !ok && v["@context"] != nil {
+					o["@context"] = v["@context"]
+				}
+			}
+			return out
+		}
+		return []map[string]any{v}
+	}
+	return nil
+}
+
+func hasContext(value any) bool {
+	obj, ok := value.(map[string]any)
+	return ok && obj["@context"] != nil
+}
+
+// normalizeNode shortens schema.org type URLs and property IRIs.
+func normalizeNode(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := map[string]any{}
+		for key, val := range v {
+			if key == "@type" {
+				out["@type"] = normalizeTypes(val)
+				continue
+			}
+			out[schemaName(key)] = normalizeNode(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = normalizeNode(e)
+		}
+		return out
+	}
+	return value
+}
+
+func normalizeTypes(value any) any {
+	switch v := value.(type) {
+	case string:
+		return schemaName(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = normalizeTypes(e)
+		}
+		if len(out) == 1 {
+			return out[0]
+		}
+		return out
+	}
+	return value
+}
+
+// schemaName strips a schema.org prefix: https://schema.org/Product and
+// schema:Product become Product. Other vocabularies keep their IRIs.
+func schemaName(name string) string {
+	for _, prefix := range []string{"https://schema.org/", "http://schema.org/", "https://www.schema.org/", "http://www.schema.org/", "schema:"} {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+// requiredProps are the properties Google requires for rich results of a
+// type; "a|b" is satisfied by either.
+var requiredProps = map[string][]string{
+	"Product":         {"name", "offers|review|aggregateRating"},
+	"Offer":           {"price|priceSpecification", "priceCurrency|priceSpecification"},
+	"AggregateOffer":  {"lowPrice", "priceCurrency"},
+	"AggregateRating": {"ratingValue", "ratingCount|reviewCount"},
+	"Review":          {"author", "itemReviewed|reviewRating"},
+	"Rating":          {"ratingValue"},
+	"Article":         {"headline"},
+	"NewsArticle":     {"headline"},
+	"BlogPosting":     {"headline"},
+	"BreadcrumbList":  {"itemListElement"},
+	"ListItem":        {"position"},
+	"Organization":    {"name"},
+	"Person":          {"name"},
+	"LocalBusiness":   {"name", "address"},
+	"Event":           {"name", "startDate", "location"},
+	"Recipe":          {"name", "image"},
+	"FAQPage":         {"mainEntity"},
+	"Question":        {"name", "acceptedAnswer|suggestedAnswer"},
+	"Answer":          {"text"},
+	"VideoObject":     {"name", "thumbnailUrl", "uploadDate"},
+	"JobPosting":      {"title", "datePosted", "description", "hiringOrganization"},
+	"WebSite":         {"name|url"},
+}
+
+// validateNode checks node and its nested typed values, naming each
+// problem by its path, like Product.offers: Offer missing priceCurrency.
+func validateNode(node map[string]any, path string) []string {
+	var errs []string
+	types := typeNames(node)
+	if len(types) == 0 {
+		if _, ok := node["@id"]; !ok || len(node) > 1 {
+			errs = append(errs, path+": no @type")
+		}
+	}
+	for _, t := range types {
+		for _, req := range requiredProps[t] {
+			if !slices.ContainsFunc(strings.Split(req, "|"), func(p string) bool { return present(node[p]) }) {
+				errs = append(errs, fmt.Sprintf("%s: %s missing %s", path, t, strings.ReplaceAll(req, "|", " or ")))
+			}
+		}
+	}
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+		values, ok := node[key].([]any)
+		if !ok {
+			values = []any{node[key]}
+		}
+		for _, v := range values {
+			if child, ok := v.(map[string]any); ok {
+				errs = append(errs, validateNode(child, path+"."+key)...)
+			}
+		}
+	}
+	return errs
+}
+
+func typeNames(node map[string]any) []string {
+	switch v := node["@type"].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var out []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func typeLabel(node map[string]any) string {
+	if types := typeNames(node); len(types) > 0 {
+		return strings.Join(types, ",")
+	}
+	return "item"
+}
+
+func present(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []any:
+		return len(v) > 0
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Target cache: rapid command sequences otherwise query /json/list (and, for
+// the default tab, open a websocket for chromedp.Targets) on every command.
+// The target list and resolved IDs are cached per debug endpoint for
+// DefaultTargetCacheTTL. Commands that can change tab URLs or the tab set
+// drop the cache and bypass it (see PrepareTargetCache); a selector that
+// misses against a cached list is retried against a fresh one.
+
+// DefaultTargetCacheTTL is how long a cached target list is reused.
+const DefaultTargetCacheTTL = 2 * time.Second
+
+// navigatingCommands can navigate, open, close, or switch tabs, so they start
+// from and leave behind no cached targets. Commands that run a chrome
+// command after -- (abort, headers, mock, throttle, waitnav) are listed
+// too, so the child inherits the disabled cache; interception commands
+// without one, like block, change no targets and are not.
+var navigatingCommands = map[string]bool{
+	"abort":           true,
+	"checkpoint":      true,
+	"click":           true,
+	"clicktext":       true,
+	"clickxy":         true,
+	"close":           true,
+	"compare":         true,
+	"discard":         true,
+	"dismiss-banners": true,
+	"eval":            true,
+	"fill":            true,
+	"har":             true,
+	"headers":         true,
+	"keys":            true,
+	"launch":          true,
+	"mcp":             true,
+	"mock":            true,
+	"navigate":        true,
+	"newtab":          true,
+	"paginate":        true,
+	"quit":            true,
+	"record":          true,
+	"repl":            true,
+	"run":             true,
+	"serve":           true,
+	"state":           true,
+	"step":            true,
+	"tabto":           true,
+	"throttle":        true,
+	"type":            true,
+	"undiscard":       true,
+	"waitnav":         true,
+}
+
+type targetCache struct {
+	URL       string                    `json:"url"`
+	FetchedAt time.Time                 `json:"fetched_at"`
+	Targets   []ChromeTarget            `json:"targets"`
+	Resolved  map[string]resolvedTarget `json:"resolved,omitempty"`
+}
+
+type resolvedTarget struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// PrepareTargetCache runs before a command: navigating commands invalidate
+// the cache and disable it for themselves and any child commands.
+func PrepareTargetCache(command string) {
+	if !navigatingCommands[command] {
+		return
+	}
+	InvalidateTargetCache()
+	_ = os.Setenv("CHROME_TARGET_CACHE_TTL", "0")
+}
+
+// TargetCacheTTL returns $CHROME_TARGET_CACHE_TTL (a duration, 0 disables)
+// or DefaultTargetCacheTTL.
+func TargetCacheTTL() time.Duration {
+	value := strings.TrimSpace(os.Getenv("CHROME_TARGET_CACHE_TTL"))
+	if value == "" {
+		return DefaultTargetCacheTTL
+	}
+	if value == "0" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return DefaultTargetCacheTTL
+	}
+	return ttl
+}
+
+// InvalidateTargetCache removes the cached targets for the current port.
+func InvalidateTargetCache() {
+	if path, err := targetCachePath(); err == nil {
+		_ = os.Remove(path)
+	}
+}
+
+func targetCachePath() (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("targets-%d.json", GetPort())), nil
+}
+
+// loadTargetCache returns the cache if it is enabled, fresh, and for the
+// current endpoint, or nil.
+func loadTargetCache() *targetCache {
+	ttl := TargetCacheTTL()
+	if ttl == 0 {
+		return nil
+	}
+	path, err := targetCachePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cache targetCache
+	if json.Unmarshal(data, &cache) != nil || cache.URL != ChromeURL() || time.Since(cache.FetchedAt) > ttl {
+		return nil
+	}
+	return &cache
+}
+
+// saveTargetCache writes through a temp file so concurrent commands never
+// read a partial cache.
+func saveTargetCache(cache *targetCache) {
+	if TargetCacheTTL() == 0 {
+		return
+	}
+	path, err := targetCachePath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".targets-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmp.Name(), path) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// rememberResolved adds a resolved selector to the cache built from targets.
+func rememberResolved(cache *targetCache, selector string, id string, reason string) {
+	if cache == nil {
+		return
+	}
+	if cache.Resolved == nil {
+		cache.Resolved = map[string]resolvedTarget{}
+	}
+	cache.Resolved[selector] = resolvedTarget{ID: id, Reason: reason}
+	saveTargetCache(cache)
+}
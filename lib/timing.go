@@ -0,0 +1,114 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing breaks a command's wall time into target resolution (debug endpoint
+// HTTP calls), CDP connect (attaching to the tab, or launching headless
+// Chrome), and the action itself. It is enabled by the global --verbose or
+// --json flags, which set CHROME_TIMING, and writes to stderr so stdout
+// stays parseable. Phases print as they finish, and Exit reports the total,
+// so a command that exits on error still shows where its time went. Child
+// commands run without CHROME_TIMING (ChildEnv), so their lines never mix
+// into the parent's.
+
+var timing = struct {
+	mu      sync.Mutex
+	start   time.Time
+	totals  map[string]time.Duration
+	command string
+}{start: time.Now(), totals: map[string]time.Duration{}}
+
+// TimingMode returns "text", "json", or "" (disabled) from $CHROME_TIMING.
+func TimingMode() string {
+	switch mode := strings.TrimSpace(os.Getenv("CHROME_TIMING")); mode {
+	case "text", "json":
+		return mode
+	default:
+		return ""
+	}
+}
+
+// TimePhase starts timing phase and returns a func that records it.
+func TimePhase(phase string) func() {
+	mode := TimingMode()
+	if mode == "" {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		timing.mu.Lock()
+		timing.totals[phase] += d
+		timing.mu.Unlock()
+		if mode == "json" {
+			printTimingJSON(map[string]any{"phase": phase, "ms": ms(d)})
+		} else {
+			fmt.Fprintf(os.Stderr, "timing: %s %s\n", phase, d.Round(10*time.Microsecond))
+		}
+	}
+}
+
+// TimeCommand names the command whose total Exit reports.
+func TimeCommand(command string) {
+	timing.mu.Lock()
+	timing.command = command
+	timing.mu.Unlock()
+}
+
+// ChildEnv returns this process's environment for a child command, plus
+// extra KEY=value entries, without CHROME_TIMING.
+func ChildEnv(extra ...string) []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		if !strings.HasPrefix(entry, "CHROME_TIMING=") {
+			env = append(env, entry)
+		}
+	}
+	return append(env, extra...)
+}
+
+// ReportTiming prints the command total split into resolve, connect, and
+// action, where action is whatever the other phases do not account for.
+func ReportTiming(command string) {
+	mode := TimingMode()
+	if mode == "" {
+		return
+	}
+	timing.mu.Lock()
+	total := time.Since(timing.start)
+	resolve, connect := timing.totals["resolve"], timing.totals["connect"]
+	timing.mu.Unlock()
+	action := total - resolve - connect
+	if mode == "json" {
+		printTimingJSON(map[string]any{
+			"command":    command,
+			"total_ms":   ms(total),
+			"resolve_ms": ms(resolve),
+			"connect_ms": ms(connect),
+			"action_ms":  ms(action),
+		})
+		return
+	}
+	round := func(d time.Duration) time.Duration { return d.Round(10 * time.Microsecond) }
+	fmt.Fprintf(os.Stderr, "timing: %s total %s = resolve %s + connect %s + action %s\n",
+		command, round(total), round(resolve), round(connect), round(action))
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func printTimingJSON(value map[string]any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
@@ -0,0 +1,334 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Point is where a step clicked or typed, in screenshot pixels (CSS pixels
+// times devicePixelRatio), measured just before the action ran.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// NewRunID names a workflow run: its start time, plus the workflow name.
+func NewRunID(name string) string {
+	id := time.Now().UTC().Format("20060102-150405")
+	if label := sanitizeLabel(name); label != "" {
+		id += "-" + label
+	}
+	return id
+}
+
+// stepPointScript scrolls the step's element into view the way the action
+// will and returns its center in device pixels, or null.
+func stepPointScript(step WorkflowStep) string {
+	find := "document.querySelector(" + strconv.Quote(step.Selector) + ")"
+	if step.Action == "clicktext" {
+		selector := step.Selector
+		if selector == "" {
+			selector = "button, a, [role='button']"
+		}
+		find = "Array.from(document.querySelectorAll(" + strconv.Quote(selector) + ")).find(n => (n.textContent || '').trim() === " + strconv.Quote(step.Text) + ")"
+	}
+	return `(() => {
+  const el = ` + find + `;
+  if (!el) return null;
+  el.scrollIntoView({block:'center', inline:'center'});
+  const r = el.getBoundingClientRect();
+  const dpr = window.devicePixelRatio || 1;
+  return {x: (r.left + r.width / 2) * dpr, y: (r.top + r.height / 2) * dpr};
+})()`
+}
+
+// StepPoint returns where a click, clicktext, fill, or type step will act, or
+// nil for other steps and elements that do not exist yet.
+func StepPoint(ctx context.Context, step WorkflowStep) *Point {
+	switch step.Action {
+	case "click", "fill", "type":
+		if step.Selector == "" {
+			return nil
+		}
+	case "clicktext":
+	default:
+		return nil
+	}
+	var point *Point
+	if err := chromedp.Run(ctx, chromedp.Evaluate(stepPointScript(step), &point)); err != nil {
+		return nil
+	}
+	return point
+}
+
+// LoadRunRecords returns the StepRecords of a run in dir, in step order. The
+// id "last" selects the most recent run and "current" the one 'chrome step'
+// is adding to (see CurrentRunID); otherwise a unique prefix matches.
+func LoadRunRecords(dir string, id string) (string, []StepRecord, error) {
+	records, err := LoadStepRecordsFromDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	runs := map[string][]StepRecord{}
+	var latest string
+	var latestAt time.Time
+	for _, record := range records {
+		if record.RunID == "" {
+			continue
+		}
+		runs[record.RunID] = append(runs[record.RunID], record)
+		if !record.CreatedAt.Before(latestAt) {
+			latest, latestAt = record.RunID, record.CreatedAt
+		}
+	}
+	if len(runs) == 0 {
+		return "", nil, fmt.Errorf("no runs found in %s", dir)
+	}
+
+	want := strings.TrimSpace(id)
+	if want == "current" {
+		if want, err = LoadCurrentRunID(); err != nil {
+			return "", nil, err
+		}
+	}
+	if want == "last" {
+		return latest, runs[latest], nil
+	}
+	if found, ok := runs[want]; ok {
+		return want, found, nil
+	}
+	var matches []string
+	for runID := range runs {
+		if strings.HasPrefix(runID, want) {
+			matches = append(matches, runID)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return "", nil, fmt.Errorf("no run %q in %s", want, dir)
+	case 1:
+		return matches[0], runs[matches[0]], nil
+	default:
+		return "", nil, fmt.Errorf("run %q is ambiguous: %s", want, strings.Join(matches, ", "))
+	}
+}
+
+// TrailMark is one numbered marker on a journey map.
+type TrailMark struct {
+	N      int
+	Action string
+	Point  Point
+}
+
+var (
+	trailClick = color.RGBA{230, 40, 40, 255}
+	trailType  = color.RGBA{30, 110, 230, 255}
+	trailLine  = color.RGBA{230, 40, 40, 160}
+)
+
+// DrawTrail copies base and draws marks on it: a line through them in order
+// and a numbered circle at each (red for clicks, blue for typing).
+func DrawTrail(base image.Image, marks []TrailMark) *image.RGBA {
+	return drawTrail(base, marks, max(14, base.Bounds().Dx()/90))
+}
+
+func drawTrail(base image.Image, marks []TrailMark, radius int) *image.RGBA {
+	b := base.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), base, b.Min, draw.Src)
+
+	for i := 1; i < len(marks); i++ {
+		drawLine(out, marks[i-1].Point, marks[i].Point, max(2, radius/5), trailLine)
+	}
+	for _, mark := range marks {
+		fill := trailClick
+		if mark.Action == "fill" || mark.Action == "type" {
+			fill = trailType
+		}
+		x, y := int(mark.Point.X), int(mark.Point.Y)
+		drawDisc(out, x, y, radius+2, color.RGBA{255, 255, 255, 255})
+		drawDisc(out, x, y, radius, fill)
+		drawNumber(out, x, y, mark.N, max(1, radius/5), color.RGBA{255, 255, 255, 255})
+	}
+	return out
+}
+
+// TrailSheet tiles frames left to right, top to bottom, each scaled to width.
+func TrailSheet(frames []image.Image, columns int, width int) (*image.RGBA, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("no frames")
+	}
+	columns = max(1, min(columns, len(frames)))
+	const gap = 8
+	scaled := make([]image.Image, len(frames))
+	rowHeights := make([]int, (len(frames)+columns-1)/columns)
+	for i, frame := range frames {
+		scaled[i] = scaleToWidth(frame, width)
+		row := i / columns
+		rowHeights[row] = max(rowHeights[row], scaled[i].Bounds().Dy())
+	}
+	height := gap
+	for _, h := range rowHeights {
+		height += h + gap
+	}
+	out := image.NewRGBA(image.Rect(0, 0, gap+columns*(width+gap), height))
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.RGBA{240, 240, 240, 255}), image.Point{}, draw.Src)
+	y := gap
+	for row, h := range rowHeights {
+		for col := 0; col < columns; col++ {
+			i := row*columns + col
+			if i >= len(scaled) {
+				break
+			}
+			x := gap + col*(width+gap)
+			frame := scaled[i]
+			draw.Draw(out, image.Rect(x, y, x+frame.Bounds().Dx(), y+frame.Bounds().Dy()), frame, frame.Bounds().Min, draw.Src)
+		}
+		y += h + gap
+	}
+	return out, nil
+}
+
+func drawDisc(img *image.RGBA, cx, cy, r int, c color.RGBA) {
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			if x*x+y*y <= r*r && image.Pt(cx+x, cy+y).In(img.Bounds()) {
+				img.SetRGBA(cx+x, cy+y, c)
+			}
+		}
+	}
+}
+
+func drawLine(img *image.RGBA, from, to Point, width int, c color.RGBA) {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	steps := int(max(abs(dx), abs(dy)))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		x, y := int(from.X+dx*t), int(from.Y+dy*t)
+		for oy := -width / 2; oy <= width/2; oy++ {
+			for ox := -width / 2; ox <= width/2; ox++ {
+				if p := image.Pt(x+ox, y+oy); p.In(img.Bounds()) {
+					img.Set(p.X, p.Y, blend(img.RGBAAt(p.X, p.Y), c))
+				}
+			}
+		}
+	}
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func blend(dst, src color.RGBA) color.RGBA {
+	a := uint32(src.A)
+	mix := func(d, s uint8) uint8 { return uint8((uint32(s)*a + uint32(d)*(255-a)) / 255) }
+	return color.RGBA{mix(dst.R, src.R), mix(dst.G, src.G), mix(dst.B, src.B), 255}
+}
+
+// digitGlyphs are 3x5 bitmaps, one row per string, for drawing step numbers
+// without a font dependency.
+var digitGlyphs = [10][5]string{
+	{"###", "#.#", "#.#", "#.#", "###"},
+	{".#.", "##.", ".#.", ".#.", "###"},
+	{"###", "..#", "###", "#..", "###"},
+	{"###", "..#", "###", "..#", "###"},
+	{"#.#", "#.#", "###", "..#", "..#"},
+	{"###", "#..", "###", "..#", "###"},
+	{"###", "#..", "###", "#.#", "###"},
+	{"###", "..#", "..#", "..#", "..#"},
+	{"###", "#.#", "###", "#.#", "###"},
+	{"###", "#.#", "###", "..#", "###"},
+}
+
+// drawNumber draws n centered on (cx, cy) with each glyph pixel scale wide.
+func drawNumber(img *image.RGBA, cx, cy, n int, scale int, c color.RGBA) {
+	digits := strconv.Itoa(n)
+	width := (len(digits)*4 - 1) * scale
+	left, top := cx-width/2, cy-5*scale/2
+	for i, d := range digits {
+		glyph := digitGlyphs[d-'0']
+		for row, line := range glyph {
+			for col, bit := range line {
+				if bit != '#' {
+					continue
+				}
+				x0, y0 := left+(i*4+col)*scale, top+row*scale
+				for y := y0; y < y0+scale; y++ {
+					for x := x0; x < x0+scale; x++ {
+						if image.Pt(x, y).In(img.Bounds()) {
+							img.SetRGBA(x, y, c)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// TrailMarks numbers the records that have a Point by step order.
+func TrailMarks(records []StepRecord) []TrailMark {
+	var marks []TrailMark
+	for i, record := range records {
+		if record.Point != nil {
+			marks = append(marks, TrailMark{N: i + 1, Action: record.Action, Point: *record.Point})
+		}
+	}
+	return marks
+}
+
+// JourneyMap draws every mark of a run on its final screenshot.
+func JourneyMap(records []StepRecord) (*image.RGBA, error) {
+	marks := TrailMarks(records)
+	if len(marks) == 0 {
+		return nil, errors.New("run has no click or typing steps with recorded positions")
+	}
+	base, err := LoadImage(records[len(records)-1].Screenshot)
+	if err != nil {
+		return nil, err
+	}
+	return DrawTrail(base, marks), nil
+}
+
+// JourneySheet draws each mark on the screenshot of the page it acted on,
+// which is the previous step's screenshot (the step's own for the first
+// step), and tiles the frames into one image.
+func JourneySheet(records []StepRecord, columns int, width int) (*image.RGBA, error) {
+	var frames []image.Image
+	for i, record := range records {
+		if record.Point == nil {
+			continue
+		}
+		before := record
+		if i > 0 {
+			before = records[i-1]
+		}
+		base, err := LoadImage(before.Screenshot)
+		if err != nil {
+			return nil, err
+		}
+		// Size markers for the scaled-down frame
+		radius := max(14, 14*base.Bounds().Dx()/max(1, width))
+		frames = append(frames, drawTrail(base, []TrailMark{{N: i + 1, Action: record.Action, Point: *record.Point}}, radius))
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("run has no click or typing steps with recorded positions")
+	}
+	return TrailSheet(frames, columns, width)
+}
@@ -0,0 +1,192 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+var (
+	varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	varRefPattern  = regexp.MustCompile(`\{\{\s*(?:vars\.)?([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// ValidateVarName rejects workflow variable names that {{name}} cannot
+// reference.
+func ValidateVarName(name string) error {
+	if !varNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q, use letters, digits, and '_'", name)
+	}
+	return nil
+}
+
+// ParseVars parses name=value pairs, as given to 'chrome run --var'.
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("variable %q must be name=value", pair)
+		}
+		if err := ValidateVarName(name); err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// expandVars replaces {{name}} and {{vars.name}} in s with the variable's
+// value, failing on variables that have not been set.
+func expandVars(s string, vars map[string]string) (string, error) {
+	var missing []string
+	out := varRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := varRefPattern.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return ref
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// expand returns the step with variables substituted in its string fields.
+// Nested if and loop steps are expanded when they run.
+func (step WorkflowStep) expand(vars map[string]string) (WorkflowStep, error) {
+	var err error
+	field := func(s *string) {
+		if err == nil && *s != "" {
+			*s, err = expandVars(*s, vars)
+		}
+	}
+	for _, s := range []*string{
+		&step.URL, &step.Selector, &step.Text, &step.Value, &step.Script, &step.Title,
+		&step.Label, &step.Note, &step.Body, &step.Response, &step.Attr, &step.Field,
+	} {
+		field(s)
+	}
+	if len(step.Headers) > 0 {
+		headers := make(map[string]string, len(step.Headers))
+		for name, value := range step.Headers {
+			field(&value)
+			headers[name] = value
+		}
+		step.Headers = headers
+	}
+	return step, err
+}
+
+// captureValue returns the value a capture or eval step stores in its var,
+// as text: strings as is, anything else as JSON.
+func captureValue(ctx context.Context, step WorkflowStep, net *NetworkRecorder) (string, error) {
+	var value any
+	switch {
+	case step.Script != "":
+		if err := chromedp.Run(ctx, chromedp.Evaluate(step.Script, &value, awaitPromise)); err != nil {
+			return "", err
+		}
+	case step.Selector != "":
+		// Wait for the element, like waitfor, then read it once
+		if err := pollTrue(ctx, "!!document.querySelector("+strconv.Quote(step.Selector)+")"); err != nil {
+			return "", fmt.Errorf("no element matches %q: %w", step.Selector, err)
+		}
+		script := `(() => {
+	  const el = document.querySelector(` + strconv.Quote(step.Selector) + `);
+	  const attr = ` + strconv.Quote(step.Attr) + `;
+	  if (!attr) return (el.innerText || el.textContent || '').trim();
+	  if (attr in el && typeof el[attr] !== 'function') return el[attr];
+	  return el.getAttribute(attr);
+	})()`
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &value)); err != nil {
+			return "", err
+		}
+		if value == nil {
+			return "", fmt.Errorf("%q has no attribute %q", step.Selector, step.Attr)
+		}
+	case step.Response != "":
+		body, err := responseBody(ctx, step.Response, net)
+		if err != nil {
+			return "", err
+		}
+		if step.Field == "" {
+			return body, nil
+		}
+		var doc any
+		if err := json.Unmarshal([]byte(body), &doc); err != nil {
+			return "", fmt.Errorf("response body is not JSON: %w", err)
+		}
+		if value, err = jsonField(doc, step.Field); err != nil {
+			return "", err
+		}
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// responseBody returns the body of the latest successful request matching
+// pattern, waiting for one to finish.
+func responseBody(ctx context.Context, pattern string, net *NetworkRecorder) (string, error) {
+	found, err := net.WaitMatching(ctx, pattern)
+	if err != nil {
+		return "", err
+	}
+	for i := len(found) - 1; i >= 0; i-- {
+		req := found[i]
+		if req.Failed {
+			continue
+		}
+		var body []byte
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			body, err = network.GetResponseBody(network.RequestID(req.RequestID)).Do(ctx)
+			return err
+		}))
+		if err != nil {
+			return "", fmt.Errorf("reading body of %s %s: %w", req.Method, req.URL, err)
+		}
+		return string(body), nil
+	}
+	return "", fmt.Errorf("every request matching %q failed", pattern)
+}
+
+// jsonField follows a dotted path such as "order.items.0.id" into a decoded
+// JSON document; numeric parts index arrays.
+func jsonField(doc any, path string) (any, error) {
+	value := doc
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[part]
+			if !ok {
+				return nil, fmt.Errorf("response has no field %q (at %q)", path, part)
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("response has no field %q (index %q of %d items)", path, part, len(v))
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("response has no field %q (%q is not an object or array)", path, part)
+		}
+	}
+	return value, nil
+}
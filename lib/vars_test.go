@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"host": "localhost:3000", "id": "42", "empty": ""}
+	cases := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"http://{{host}}/items/{{id}}", "http://localhost:3000/items/42"},
+		{"{{ host }}", "localhost:3000"},
+		{"{{vars.id}}", "42"},
+		{"[{{empty}}]", "[]"},
+		{"{{id}}{{id}}", "4242"},
+		{"{single}", "{single}"},
+		{"{{not a var}}", "{{not a var}}"},
+	}
+	for _, c := range cases {
+		got, err := expandVars(c.in, vars)
+		if err != nil {
+			t.Errorf("expandVars(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("expandVars(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+	_, err := expandVars("{{host}}/{{user}}/{{token}}", vars)
+	if err == nil || !strings.Contains(err.Error(), "undefined variable user, token") {
+		t.Fatalf("undefined variables: %v", err)
+	}
+}
+
+func TestJSONField(t *testing.T) {
+	var doc any
+	body := `{"order": {"id": "o-1", "items": [{"id": 7, "tags": ["a", "b"]}], "paid": true, "note": null}}`
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path string
+		want any
+	}{
+		{"order.id", "o-1"},
+		{"order.items.0.id", float64(7)},
+		{"order.items.0.tags.1", "b"},
+		{"order.paid", true},
+		{"order.note", nil},
+		{"order.items.0.tags", []any{"a", "b"}},
+	}
+	for _, c := range cases {
+		got, err := jsonField(doc, c.path)
+		if err != nil {
+			t.Errorf("jsonField(%q): %v", c.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("jsonField(%q) = %#v, want %#v", c.path, got, c.want)
+		}
+	}
+	for _, path := range []string{"missing", "order.items.1", "order.items.-1", "order.items.x", "order.id.length", "order.note.x"} {
+		if _, err := jsonField(doc, path); err == nil {
+			t.Errorf("jsonField(%q) succeeded, want an error", path)
+		}
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"user=alice", "query=a=b", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"user": "alice", "query": "a=b", "empty": ""}
+	if !reflect.DeepEqual(vars, want) {
+		t.Fatalf("ParseVars = %v, want %v", vars, want)
+	}
+	for _, pair := range []string{"novalue", "1st=x", "a-b=x", "=x"} {
+		if _, err := ParseVars([]string{pair}); err == nil {
+			t.Errorf("ParseVars(%q) succeeded, want an error", pair)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// ScreencastOptions controls RecordScreencast. Zero values use Chrome's defaults.
+type ScreencastOptions struct {
+	Quality       int
+	MaxWidth      int
+	MaxHeight     int
+	EveryNthFrame int
+}
+
+// VideoFrame is one screencast frame on disk and when it arrived.
+type VideoFrame struct {
+	Path string
+	At   time.Time
+}
+
+// RecordScreencast streams jpeg frames of the tab into dir until stop is
+// closed or ctx ends, and returns the frames with the time recording stopped.
+// Chrome only sends a frame when the page repaints, so each frame lasts
+// until the next one (see EncodeVideo). ctx must already be attached.
+func RecordScreencast(ctx context.Context, dir string, opts ScreencastOptions, stop <-chan struct{}) ([]VideoFrame, time.Time, error) {
+	type frame struct {
+		data      string
+		sessionID int64
+		at        time.Time
+	}
+	incoming := make(chan frame, 64)
+	var mu sync.Mutex
+	stopped := false
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if e, ok := ev.(*page.EventScreencastFrame); ok {
+			mu.Lock()
+			defer mu.Unlock()
+			if !stopped {
+				incoming <- frame{data: e.Data, sessionID: e.SessionID, at: time.Now()}
+			}
+		}
+	})
+
+	var frames []VideoFrame
+	var writeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for f := range incoming {
+			// Chrome waits for each ack before sending the next frame
+			go func(id int64) { _ = chromedp.Run(ctx, page.ScreencastFrameAck(id)) }(f.sessionID)
+			if writeErr != nil {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(f.data)
+			if err != nil {
+				writeErr = err
+				continue
+			}
+			path := filepath.Join(dir, fmt.Sprintf("frame-%06d.jpg", len(frames)))
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				writeErr = err
+				continue
+			}
+			frames = append(frames, VideoFrame{Path: path, At: f.at})
+		}
+	}()
+
+	start := page.StartScreencast().WithFormat(page.ScreencastFormatJpeg)
+	if opts.Quality > 0 {
+		start = start.WithQuality(int64(opts.Quality))
+	}
+	if opts.MaxWidth > 0 {
+		start = start.WithMaxWidth(int64(opts.MaxWidth))
+	}
+	if opts.MaxHeight > 0 {
+		start = start.WithMaxHeight(int64(opts.MaxHeight))
+	}
+	if opts.EveryNthFrame > 0 {
+		start = start.WithEveryNthFrame(int64(opts.EveryNthFrame))
+	}
+	startErr := chromedp.Run(ctx, start)
+	if startErr == nil {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+		}
+	}
+	end := time.Now()
+
+	mu.Lock()
+	stopped = true
+	close(incoming)
+	mu.Unlock()
+	<-done
+	if startErr != nil {
+		return nil, end, startErr
+	}
+	if ctx.Err() == nil {
+		stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		_ = chromedp.Run(stopCtx, page.StopScreencast())
+		cancel()
+	}
+	return frames, end, writeErr
+}
+
+// EncodeVideo encodes screencast frames with ffmpeg, holding each frame until
+// the next (the last until end) and resampling to a constant fps. The codec
+// follows the output extension: .webm is VP9, anything else H.264.
+func EncodeVideo(frames []VideoFrame, end time.Time, outputPath string, fps int, verbose bool) error {
+	if len(frames) == 0 {
+		return errors.New("no frames recorded")
+	}
+	if fps <= 0 {
+		fps = 25
+	}
+	absOutput, err := filepath.Abs(strings.TrimSpace(outputPath))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(absOutput), 0755); err != nil {
+		return err
+	}
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return errors.New("ffmpeg not found in PATH")
+	}
+
+	width, height, err := frameSize(frames[0].Path)
+	if err != nil {
+		return err
+	}
+
+	concatPath := filepath.Join(filepath.Dir(frames[0].Path), "inputs.txt")
+	file, err := os.Create(concatPath)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for i, f := range frames {
+		next := end
+		if i+1 < len(frames) {
+			next = frames[i+1].At
+		}
+		duration := max(next.Sub(f.At), time.Millisecond)
+		fmt.Fprintf(writer, "file '%s'\n", escapeForConcat(f.Path))
+		fmt.Fprintf(writer, "duration %.3f\n", duration.Seconds())
+	}
+	// The concat demuxer ignores the last duration unless the file repeats
+	fmt.Fprintf(writer, "file '%s'\n", escapeForConcat(frames[len(frames)-1].Path))
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	args := []string{"-y"}
+	if !verbose {
+		args = append(args, "-hide_banner", "-loglevel", "warning", "-nostats")
+	}
+	args = append(args,
+		"-f", "concat",
+		"-safe", "0",
+		"-i", concatPath,
+		"-r", fmt.Sprintf("%d", fps),
+		// Even dimensions for yuv420p; frames from a resized tab are scaled to the first
+		"-vf", fmt.Sprintf("scale=%d:%d", width&^1, height&^1),
+		"-vsync", "cfr",
+	)
+	format := "mp4"
+	if strings.EqualFold(filepath.Ext(absOutput), ".webm") {
+		format = "webm"
+	}
+	args = append(args, videoCodecArgs(format)...)
+	args = append(args, absOutput)
+	cmd := exec.Command(ffmpegPath, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func frameSize(path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = f.Close() }()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BaselineDir returns the directory holding visual regression baselines
+// (default: <shots dir>/baselines).
+func BaselineDir(dir string) string {
+	if trimmed := strings.TrimSpace(dir); trimmed != "" {
+		return trimmed
+	}
+	return filepath.Join(DefaultShotsDir(), "baselines")
+}
+
+// BaselinePath returns the baseline PNG for label in dir.
+func BaselinePath(dir string, label string) (string, error) {
+	name := sanitizeLabel(label)
+	if name == "" {
+		return "", errors.New("baseline label is required")
+	}
+	return filepath.Join(BaselineDir(dir), name+".png"), nil
+}
+
+// DiffOptions controls DiffImages.
+type DiffOptions struct {
+	// Tolerance is the largest per-channel difference (0-255) still
+	// counted as equal, absorbing compression and color-profile noise.
+	Tolerance int
+	// AntiAlias ignores a differing pixel when a neighbor within one pixel
+	// in the other image matches it, the signature of anti-aliased edges
+	// and subpixel text rendering shifting by a pixel.
+	AntiAlias bool
+}
+
+// DiffResult summarizes a pixel comparison.
+type DiffResult struct {
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	Pixels       int     `json:"pixels"`
+	Different    int     `json:"different"`
+	AntiAliased  int     `json:"anti_aliased"`
+	Ratio        float64 `json:"ratio"`
+	SizeMismatch bool    `json:"size_mismatch,omitempty"`
+}
+
+// DiffImages compares two images pixel by pixel over the union of their
+// bounds; pixels outside either image count as different. The returned diff
+// image is a faded copy of want with differing pixels in red and ignored
+// anti-aliasing in yellow.
+func DiffImages(want image.Image, got image.Image, opts DiffOptions) (DiffResult, *image.RGBA) {
+	wb, gb := want.Bounds(), got.Bounds()
+	width := max(wb.Dx(), gb.Dx())
+	height := max(wb.Dy(), gb.Dy())
+	res := DiffResult{
+		Width:        width,
+		Height:       height,
+		Pixels:       width * height,
+		SizeMismatch: wb.Dx() != gb.Dx() || wb.Dy() != gb.Dy(),
+	}
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	at := func(img image.Image, x, y int) (color.RGBA, bool) {
+		b := img.Bounds()
+		if x < 0 || y < 0 || x >= b.Dx() || y >= b.Dy() {
+			return color.RGBA{}, false
+		}
+		return color.RGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA), true
+	}
+	equal := func(a, b color.RGBA) bool {
+		return absDiff(a.R, b.R) <= opts.Tolerance && absDiff(a.G, b.G) <= opts.Tolerance &&
+			absDiff(a.B, b.B) <= opts.Tolerance && absDiff(a.A, b.A) <= opts.Tolerance
+	}
+	// neighborMatch reports whether c appears within one pixel of (x, y) in img.
+	neighborMatch := func(img image.Image, x, y int, c color.RGBA) bool {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if n, ok := at(img, x+dx, y+dy); ok && equal(n, c) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			w, wok := at(want, x, y)
+			g, gok := at(got, x, y)
+			switch {
+			case wok && gok && equal(w, g):
+				// Faded grayscale of the baseline as context
+				gray := uint8((299*int(w.R) + 587*int(w.G) + 114*int(w.B)) / 1000)
+				fade := 255 - (255-gray)/4
+				out.SetRGBA(x, y, color.RGBA{fade, fade, fade, 255})
+			case wok && gok && opts.AntiAlias && neighborMatch(got, x, y, w) && neighborMatch(want, x, y, g):
+				res.AntiAliased++
+				out.SetRGBA(x, y, color.RGBA{255, 200, 0, 255})
+			default:
+				res.Different++
+				out.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+			}
+		}
+	}
+	if res.Pixels > 0 {
+		res.Ratio = float64(res.Different) / float64(res.Pixels)
+	}
+	return res, out
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// LoadImage decodes a png or jpeg file.
+func LoadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// SavePNG encodes img to path, creating parent directories.
+func SavePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func solid(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestDiffImagesIdentical(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	res, diff := DiffImages(solid(4, 3, white), solid(4, 3, white), DiffOptions{})
+	if res.Different != 0 || res.Ratio != 0 || res.SizeMismatch {
+		t.Fatalf("identical images: %+v", res)
+	}
+	if res.Pixels != 12 || diff.Bounds().Dx() != 4 || diff.Bounds().Dy() != 3 {
+		t.Fatalf("pixels %d, diff bounds %v", res.Pixels, diff.Bounds())
+	}
+}
+
+func TestDiffImagesTolerance(t *testing.T) {
+	want := solid(2, 2, color.RGBA{100, 100, 100, 255})
+	got := solid(2, 2, color.RGBA{103, 100, 100, 255})
+	if res, _ := DiffImages(want, got, DiffOptions{Tolerance: 3}); res.Different != 0 {
+		t.Fatalf("within tolerance: %d different", res.Different)
+	}
+	res, diff := DiffImages(want, got, DiffOptions{Tolerance: 2})
+	if res.Different != 4 || res.Ratio != 1 {
+		t.Fatalf("beyond tolerance: %+v", res)
+	}
+	if c := diff.RGBAAt(0, 0); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("differing pixel drawn as %v, want red", c)
+	}
+}
+
+func TestDiffImagesSizeMismatch(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	res, _ := DiffImages(solid(2, 2, black), solid(3, 2, black), DiffOptions{})
+	// The extra column exists in only one image, so it counts as different
+	if !res.SizeMismatch || res.Width != 3 || res.Pixels != 6 || res.Different != 2 {
+		t.Fatalf("size mismatch: %+v", res)
+	}
+}
+
+func TestDiffImagesAntiAlias(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	black := color.RGBA{0, 0, 0, 255}
+	want := solid(5, 5, white)
+	got := solid(5, 5, white)
+	// An edge shifted by one pixel
+	want.SetRGBA(2, 2, black)
+	got.SetRGBA(3, 2, black)
+	if res, _ := DiffImages(want, got, DiffOptions{}); res.Different != 2 {
+		t.Fatalf("without AntiAlias: %d different, want 2", res.Different)
+	}
+	res, diff := DiffImages(want, got, DiffOptions{AntiAlias: true})
+	if res.Different != 0 || res.AntiAliased != 2 {
+		t.Fatalf("with AntiAlias: %+v", res)
+	}
+	if c := diff.RGBAAt(2, 2); c != (color.RGBA{255, 200, 0, 255}) {
+		t.Fatalf("anti-aliased pixel drawn as %v, want yellow", c)
+	}
+}
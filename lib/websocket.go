@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// WebSocketOptions control ListenWebSockets: frame payloads are cut to
+// MaxFrameSize bytes.
+type WebSocketOptions struct {
+	MaxFrameSize int64 // 0 for no limit
+}
+
+// ListenWebSockets calls fn for WebSocket activity in the tab until the
+// context ends: "ws-open" when a socket is created, "ws-handshake" with the
+// upgrade response status, "ws-sent" and "ws-received" for each frame,
+// "ws-error", and "ws-closed". Frame events carry the socket's URL, the
+// opcode (1 text, 2 binary), the payload in Body (binary as base64 with
+// Encoding "base64"), and its full Size. fn runs on the event goroutine and
+// must not block. Register it before ListenNetwork, which enables the
+// Network domain.
+func ListenWebSockets(ctx context.Context, opts WebSocketOptions, fn func(NetworkEvent)) error {
+	urls := map[network.RequestID]string{}
+	frame := func(kind string, id network.RequestID, f *network.WebSocketFrame) {
+		evt := NetworkEvent{Type: kind, RequestID: string(id), URL: urls[id], Opcode: int64(f.Opcode), Timestamp: time.Now()}
+		evt.Body, evt.Encoding, evt.Size, evt.Truncated = opts.payload(f)
+		fn(evt)
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventWebSocketCreated:
+			urls[ev.RequestID] = ev.URL
+			fn(NetworkEvent{Type: "ws-open", RequestID: string(ev.RequestID), URL: ev.URL, Timestamp: time.Now()})
+		case *network.EventWebSocketHandshakeResponseReceived:
+			fn(NetworkEvent{
+				Type:       "ws-handshake",
+				RequestID:  string(ev.RequestID),
+				URL:        urls[ev.RequestID],
+				Status:     ev.Response.Status,
+				StatusText: ev.Response.StatusText,
+				Timestamp:  time.Now(),
+			})
+		case *network.EventWebSocketFrameSent:
+			frame("ws-sent", ev.RequestID, ev.Response)
+		case *network.EventWebSocketFrameReceived:
+			frame("ws-received", ev.RequestID, ev.Response)
+		case *network.EventWebSocketFrameError:
+			fn(NetworkEvent{Type: "ws-error", RequestID: string(ev.RequestID), URL: urls[ev.RequestID], Error: ev.ErrorMessage, Timestamp: time.Now()})
+		case *network.EventWebSocketClosed:
+			fn(NetworkEvent{Type: "ws-closed", RequestID: string(ev.RequestID), URL: urls[ev.RequestID], Timestamp: time.Now()})
+			delete(urls, ev.RequestID)
+		}
+	})
+	return nil
+}
+
+// payload returns a frame's payload as text, or base64 for binary and
+// non-UTF-8 frames, cut to MaxFrameSize, with its full size in bytes.
+// Chrome sends binary payloads base64 encoded already.
+func (o WebSocketOptions) payload(f *network.WebSocketFrame) (string, string, int64, bool) {
+	data := []byte(f.PayloadData)
+	binary := f.Opcode != 1
+	if binary {
+		decoded, err := base64.StdEncoding.DecodeString(f.PayloadData)
+		if err != nil {
+			return f.PayloadData, "base64", int64(len(data)), false
+		}
+		data = decoded
+	}
+	size := int64(len(data))
+	truncated := false
+	if o.MaxFrameSize > 0 && size > o.MaxFrameSize {
+		data = data[:o.MaxFrameSize]
+		truncated = true
+		if !binary {
+			// Don't cut a multi-byte character in half
+			for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(data); i++ {
+				data = data[:len(data)-1]
+			}
+		}
+	}
+	if binary || !utf8.Valid(data) {
+		return base64.StdEncoding.EncodeToString(data), "base64", size, truncated
+	}
+	return string(data), "", size, truncated
+}
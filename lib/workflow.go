@@ -8,10 +8,11 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/chromedp/chromedp"
+	"github.com/chromedp/cdproto/runtime"
 	"github.com/gorilla/websocket"
 )
 
@@ -19,20 +20,117 @@ var labelCleanup = regexp.MustCompile("[^a-z0-9-]+")
 
 // StepRecord captures the outcome of a chrome action + screenshot loop.
 type StepRecord struct {
-	Action     string    `json:"action"`
-	Args       []string  `json:"args"`
-	Target     string    `json:"target"`
-	Label      string    `json:"label"`
-	Note       string    `json:"note"`
-	Screenshot string    `json:"screenshot"`
-	CreatedAt  time.Time `json:"created_at"`
+	Action     string     `json:"action"`
+	Args       []string   `json:"args"`
+	Target     string     `json:"target"`
+	Label      string     `json:"label"`
+	Note       string     `json:"note"`
+	Screenshot string     `json:"screenshot"`
+	Before     string     `json:"before,omitempty"`      // screenshot taken before the action (step --before)
+	Diff       string     `json:"diff,omitempty"`        // pixel diff of Before against Screenshot (step --diff)
+	ConsoleLog string     `json:"console_log,omitempty"` // NDJSON console messages during the action (step --capture)
+	NetworkLog string     `json:"network_log,omitempty"` // NDJSON network events during the action (step --capture)
+	Status     string     `json:"status,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ExitCode   int        `json:"exit_code,omitempty"` // the action's exit status when it failed
+	Stderr     string     `json:"stderr,omitempty"`    // the end of the action's stderr when it failed
+	RunID      string     `json:"run_id,omitempty"`
+	Duration   float64    `json:"duration,omitempty"`   // seconds shown in a slideshow (0: default)
+	ElapsedMs  int64      `json:"elapsed_ms,omitempty"` // how long the step's action took to run
+	Point      *Point     `json:"point,omitempty"`
+	Attempts   []Attempt  `json:"attempts,omitempty"`  // every try of the action when step --retries is set
+	Assertion  *Assertion `json:"assertion,omitempty"` // expected and actual values of an assert command
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Attempt is one try of a step's action.
+type Attempt struct {
+	Error     string `json:"error,omitempty"`
+	ExitCode  int    `json:"exit_code,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
 }
 
 func (record StepRecord) MetadataPath() string {
 	return record.Screenshot + ".json"
 }
 
+// ScreenshotOptions controls how CaptureScreenshotWithOptions captures the tab.
+type ScreenshotOptions struct {
+	// FreezeAnimations disables CSS transitions, pauses running animations,
+	// and pauses the Animation domain clock for the capture, resuming them
+	// after.
+	FreezeAnimations bool
+	// Element clips the capture to the bounding box of the first element
+	// matching this CSS selector, scrolled into view first.
+	Element string
+	// Format is png (default), jpeg, or webp.
+	Format string
+	// Quality is the jpeg or webp compression quality, 1-100 (0: Chrome's default).
+	Quality int
+	// Media emulates a CSS media type (print or screen) for the capture
+	// only, so print stylesheets can be checked without a PDF.
+	Media string
+}
+
+// ParseScreenshotFormat normalizes a format name ("" is png, "jpg" is jpeg).
+func ParseScreenshotFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "png":
+		return "png", nil
+	case "jpeg", "jpg":
+		return "jpeg", nil
+	case "webp":
+		return "webp", nil
+	default:
+		return "", fmt.Errorf("unknown screenshot format %q, expected png, jpeg, or webp", format)
+	}
+}
+
+// ScreenshotExtension returns the file extension for a screenshot format.
+func ScreenshotExtension(format string) string {
+	format, err := ParseScreenshotFormat(format)
+	if err != nil || format == "png" {
+		return ".png"
+	}
+	if format == "jpeg" {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// Normalize checks Format and Quality and returns opts with Format normalized.
+func (opts ScreenshotOptions) Normalize() (ScreenshotOptions, error) {
+	format, err := ParseScreenshotFormat(opts.Format)
+	if err != nil {
+		return opts, err
+	}
+	opts.Format = format
+	if opts.Quality < 0 || opts.Quality > 100 {
+		return opts, fmt.Errorf("quality must be 1-100, got %d", opts.Quality)
+	}
+	if opts.Quality > 0 && format == "png" {
+		return opts, errors.New("quality applies only to jpeg and webp")
+	}
+	opts.Media = strings.ToLower(strings.TrimSpace(opts.Media))
+	if opts.Media != "" && opts.Media != "print" && opts.Media != "screen" {
+		return opts, fmt.Errorf("unknown media %q, expected print or screen", opts.Media)
+	}
+	return opts, nil
+}
+
+// errElementNotFound is returned by the remote capture path without falling
+// back to chromedp, which would only wait out the timeout for the same element.
+var errElementNotFound = errors.New("element not found")
+
 func CaptureScreenshot(selector string, path string) error {
+	return CaptureScreenshotWithOptions(selector, path, ScreenshotOptions{})
+}
+
+func CaptureScreenshotWithOptions(selector string, path string, opts ScreenshotOptions) error {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return err
+	}
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return err
@@ -42,9 +140,13 @@ func CaptureScreenshot(selector string, path string) error {
 	}
 
 	if IsChromeRunning() {
-		if err := captureScreenshotRemote(selector, absPath); err == nil {
+		err := captureScreenshotRemote(selector, absPath, opts)
+		if err == nil {
 			return nil
 		}
+		if errors.Is(err, errElementNotFound) {
+			return err
+		}
 	}
 
 	ctx, cancel := SetupContext()
@@ -56,15 +158,61 @@ func CaptureScreenshot(selector string, path string) error {
 	}
 	defer targetCancel()
 
-	var buf []byte
-	if err := chromedp.Run(targetCtx, chromedp.CaptureScreenshot(&buf)); err != nil {
+	buf, err := ScreenshotWithOptions(targetCtx, opts)
+	if err != nil {
 		return err
 	}
 
 	return os.WriteFile(absPath, buf, 0644)
 }
 
-func captureScreenshotRemote(selector string, path string) error {
+// freezeAnimationsScript stops visual motion so captures are stable: transitions
+// are disabled and running animations are paused where they are, their
+// currentTime recorded for unfreezeAnimationsScript. Nothing is finished or
+// cancelled, so the page's animation event handlers don't run. Resolves after
+// two frames, or a timeout in background tabs where frames never come.
+const freezeAnimationsScript = `(() => {
+  const style = document.createElement('style');
+  style.id = '__chrome_cli_freeze';
+  style.textContent = '*, *::before, *::after { transition: none !important; animation-play-state: paused !important; caret-color: transparent !important; scroll-behavior: auto !important; }';
+  document.documentElement.appendChild(style);
+  const paused = [];
+  for (const a of document.getAnimations()) {
+    try {
+      if (a.playState !== 'running') continue;
+      paused.push({animation: a, currentTime: a.currentTime});
+      a.pause();
+    } catch (e) {}
+  }
+  window.__chromeCliFrozen = paused;
+  return new Promise(resolve => {
+    let done = false;
+    const finish = () => { if (!done) { done = true; resolve(true); } };
+    requestAnimationFrame(() => requestAnimationFrame(finish));
+    setTimeout(finish, 100);
+  });
+})()`
+
+// unfreezeAnimationsScript undoes freezeAnimationsScript: the style is
+// removed and each paused animation resumes from the time it was paused at.
+const unfreezeAnimationsScript = `(() => {
+  const style = document.getElementById('__chrome_cli_freeze');
+  if (style) style.remove();
+  for (const {animation, currentTime} of window.__chromeCliFrozen || []) {
+    try {
+      if (currentTime !== null) animation.currentTime = currentTime;
+      animation.play();
+    } catch (e) {}
+  }
+  delete window.__chromeCliFrozen;
+  return true;
+})()`
+
+func awaitPromise(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	return p.WithAwaitPromise(true)
+}
+
+func captureScreenshotRemote(selector string, path string, opts ScreenshotOptions) error {
 	targetID, reason, err := ResolveTarget(selector, nil)
 	if err != nil {
 		return err
@@ -96,29 +244,92 @@ func captureScreenshotRemote(selector string, path string) error {
 	}
 	defer conn.Close()
 
-	if err := conn.WriteJSON(map[string]any{"id": 1, "method": "Page.enable"}); err != nil {
+	client := &cdpConn{conn: conn}
+	if _, err := client.call("Page.enable", nil); err != nil {
 		return err
 	}
-	_ = conn.WriteJSON(map[string]any{"id": 2, "method": "Page.bringToFront"})
-	if err := conn.WriteJSON(map[string]any{
-		"id":     3,
-		"method": "Page.captureScreenshot",
-		"params": map[string]any{
-			"format":      "png",
-			"fromSurface": true,
-		},
-	}); err != nil {
-		return err
+	_ = client.send("Page.bringToFront", nil)
+
+	if opts.Media != "" {
+		if _, err := client.call("Emulation.setEmulatedMedia", map[string]any{"media": opts.Media}); err != nil {
+			return err
+		}
+		defer func() { _, _ = client.call("Emulation.setEmulatedMedia", map[string]any{"media": ""}) }()
+	}
+	if opts.FreezeAnimations {
+		if err := client.freezeAnimations(); err != nil {
+			return err
+		}
+		defer client.unfreezeAnimations()
 	}
 
-	if err := conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+	params := map[string]any{
+		"format":      opts.Format,
+		"fromSurface": true,
+	}
+	if opts.Quality > 0 {
+		params["quality"] = opts.Quality
+	}
+	if opts.Element != "" {
+		clip, err := client.elementClip(opts.Element)
+		if err != nil {
+			return err
+		}
+		params["clip"] = clip
+		params["captureBeyondViewport"] = true
+	}
+	result, err := client.call("Page.captureScreenshot", params)
+	if err != nil {
+		return fmt.Errorf("capture screenshot %w", err)
+	}
+	var payload struct {
+		Data string `json:"data"`
+	}
+	if err := json.Unmarshal(result, &payload); err != nil {
 		return err
 	}
+	if payload.Data == "" {
+		return errors.New("empty screenshot data")
+	}
+	bytes, err := base64.StdEncoding.DecodeString(payload.Data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bytes, 0644)
+}
+
+// cdpConn issues CDP commands over a raw target websocket, used where a full
+// chromedp attach is slower than needed (screenshots).
+type cdpConn struct {
+	conn   *websocket.Conn
+	nextID int
+}
+
+func (c *cdpConn) send(method string, params map[string]any) error {
+	c.nextID++
+	msg := map[string]any{"id": c.nextID, "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	return c.conn.WriteJSON(msg)
+}
+
+// call sends a command and waits for its response, skipping events and
+// responses to earlier fire-and-forget sends.
+func (c *cdpConn) call(method string, params map[string]any) (json.RawMessage, error) {
+	if err := c.send(method, params); err != nil {
+		return nil, err
+	}
+	id := c.nextID
+
+	if err := c.conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return nil, err
+	}
 
 	for {
-		_, data, err := conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		var resp struct {
 			ID     int             `json:"id"`
@@ -131,30 +342,104 @@ func captureScreenshotRemote(selector string, path string) error {
 		if err := json.Unmarshal(data, &resp); err != nil {
 			continue
 		}
-		if resp.ID != 3 {
+		if resp.ID != id {
 			continue
 		}
 		if resp.Error != nil {
-			return fmt.Errorf("capture screenshot error %d: %s", resp.Error.Code, resp.Error.Message)
-		}
-		var payload struct {
-			Data string `json:"data"`
-		}
-		if err := json.Unmarshal(resp.Result, &payload); err != nil {
-			return err
-		}
-		if payload.Data == "" {
-			return errors.New("empty screenshot data")
+			return nil, fmt.Errorf("error %d: %s", resp.Error.Code, resp.Error.Message)
 		}
-		bytes, err := base64.StdEncoding.DecodeString(payload.Data)
-		if err != nil {
-			return err
-		}
-		return os.WriteFile(path, bytes, 0644)
+		return resp.Result, nil
+	}
+}
+
+// evaluate runs a script with awaitPromise and reports exceptions as errors.
+func (c *cdpConn) evaluate(script string) (json.RawMessage, error) {
+	result, err := c.call("Runtime.evaluate", map[string]any{
+		"expression":    script,
+		"awaitPromise":  true,
+		"returnByValue": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var payload struct {
+		Result           json.RawMessage `json:"result"`
+		ExceptionDetails *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	if err := json.Unmarshal(result, &payload); err != nil {
+		return nil, err
+	}
+	if payload.ExceptionDetails != nil {
+		return nil, fmt.Errorf("evaluate: %s", payload.ExceptionDetails.Text)
+	}
+	return payload.Result, nil
+}
+
+// elementClip scrolls the element into view and returns its page-relative
+// bounding box as a Page.captureScreenshot clip.
+func (c *cdpConn) elementClip(selector string) (map[string]any, error) {
+	result, err := c.evaluate(elementClipScript(selector))
+	if err != nil {
+		return nil, err
+	}
+	var payload struct {
+		Value *struct {
+			X      float64 `json:"x"`
+			Y      float64 `json:"y"`
+			Width  float64 `json:"width"`
+			Height float64 `json:"height"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(result, &payload); err != nil {
+		return nil, err
 	}
+	if payload.Value == nil {
+		return nil, fmt.Errorf("%w: %s", errElementNotFound, selector)
+	}
+	box := payload.Value
+	if box.Width <= 0 || box.Height <= 0 {
+		return nil, fmt.Errorf("element %s has zero size", selector)
+	}
+	return map[string]any{"x": box.X, "y": box.Y, "width": box.Width, "height": box.Height, "scale": 1}, nil
+}
+
+// elementClipScript scrolls the first element matching selector into view
+// and resolves to its page-relative box, or null if there is none.
+func elementClipScript(selector string) string {
+	return `(() => {
+  const el = document.querySelector(` + strconv.Quote(selector) + `);
+  if (!el) return null;
+  el.scrollIntoView({block: 'center', inline: 'center'});
+  return new Promise(resolve => requestAnimationFrame(() => {
+    const r = el.getBoundingClientRect();
+    resolve({x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height});
+  }));
+})()`
+}
+
+func (c *cdpConn) freezeAnimations() error {
+	if _, err := c.call("Animation.enable", nil); err != nil {
+		return err
+	}
+	if _, err := c.evaluate(freezeAnimationsScript); err != nil {
+		return err
+	}
+	_, err := c.call("Animation.setPlaybackRate", map[string]any{"playbackRate": 0})
+	return err
+}
+
+func (c *cdpConn) unfreezeAnimations() {
+	_, _ = c.call("Animation.setPlaybackRate", map[string]any{"playbackRate": 1})
+	_, _ = c.evaluate(unfreezeAnimationsScript)
 }
 
 func DefaultShotsDir() string {
+	// $CHROME_SHOTS_DIR overrides it; 'chrome matrix' sets one per device
+	if dir := strings.TrimSpace(os.Getenv("CHROME_SHOTS_DIR")); dir != "" {
+		return dir
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return filepath.Join(os.TempDir(), "chrome-shots")
@@ -179,6 +464,25 @@ func PrepareShotsDir(dir string) (string, error) {
 }
 
 func PrepareScreenshotPath(path string, dir string, label string) (string, error) {
+	return PrepareOutputPath(path, dir, label, ".png")
+}
+
+// PrepareScreenshotPathWithFormat is PrepareScreenshotPath with the file
+// extension for format (see ScreenshotExtension).
+func PrepareScreenshotPathWithFormat(path string, dir string, label string, format string) (string, error) {
+	return PrepareOutputPath(path, dir, label, ScreenshotExtension(format))
+}
+
+// PrepareOutputPath is PrepareScreenshotPath for any file extension: an exact
+// path if given, else a file in the shots directory named by the name
+// template (default <timestamp>-<label><ext>, see NameTemplate).
+func PrepareOutputPath(path string, dir string, label string, ext string) (string, error) {
+	return PrepareNamedOutputPath(path, dir, OutputName{Label: label}, ext)
+}
+
+// PrepareNamedOutputPath is PrepareOutputPath with the name template and
+// target for {host} given in name.
+func PrepareNamedOutputPath(path string, dir string, name OutputName, ext string) (string, error) {
 	trimmed := strings.TrimSpace(path)
 	if trimmed != "" {
 		absPath, err := filepath.Abs(trimmed)
@@ -197,13 +501,10 @@ func PrepareScreenshotPath(path string, dir string, label string) (string, error
 		return "", err
 	}
 
-	sanitized := sanitizeLabel(label)
-	if sanitized == "" {
-		sanitized = "shot"
+	filename, err := ExpandName(shotsDir, name, ext)
+	if err != nil {
+		return "", err
 	}
-	timestamp := time.Now().UTC().Format("20060102-150405.000")
-	timestamp = strings.ReplaceAll(timestamp, ".", "_")
-	filename := fmt.Sprintf("%s-%s.png", timestamp, sanitized)
 	return filepath.Join(shotsDir, filename), nil
 }
 
@@ -231,7 +532,7 @@ func SaveStepRecord(record StepRecord) error {
 }
 
 func SaveLastStep(record StepRecord) error {
-	cache, err := cacheDir()
+	cache, err := CacheDir()
 	if err != nil {
 		return err
 	}
@@ -244,7 +545,7 @@ func SaveLastStep(record StepRecord) error {
 }
 
 func LoadLastStep() (StepRecord, error) {
-	cache, err := cacheDir()
+	cache, err := CacheDir()
 	if err != nil {
 		return StepRecord{}, err
 	}
@@ -296,8 +597,8 @@ func RememberStep(record StepRecord) error {
 	return SaveLastStep(record)
 }
 
-// cacheDir returns the cache directory ($XDG_CACHE_HOME/chrome-cli or ~/.cache/chrome-cli).
-func cacheDir() (string, error) {
+// CacheDir returns the cache directory ($XDG_CACHE_HOME/chrome-cli or ~/.cache/chrome-cli).
+func CacheDir() (string, error) {
 	cache, err := os.UserCacheDir()
 	if err != nil {
 		home, homeErr := os.UserHomeDir()
@@ -11,29 +11,86 @@ import (
 	"strings"
 
 	"github.com/alexflint/go-arg"
+	_ "github.com/nathants/chrome/cmd/abort"
+	_ "github.com/nathants/chrome/cmd/assertselector"
+	_ "github.com/nathants/chrome/cmd/asserttext"
+	_ "github.com/nathants/chrome/cmd/asserttitle"
+	_ "github.com/nathants/chrome/cmd/asserturl"
+	_ "github.com/nathants/chrome/cmd/at"
+	_ "github.com/nathants/chrome/cmd/autoscroll"
+	_ "github.com/nathants/chrome/cmd/block"
+	_ "github.com/nathants/chrome/cmd/canvas"
+	_ "github.com/nathants/chrome/cmd/checkpoint"
+	_ "github.com/nathants/chrome/cmd/clear"
 	_ "github.com/nathants/chrome/cmd/click"
 	_ "github.com/nathants/chrome/cmd/clicktext"
 	_ "github.com/nathants/chrome/cmd/clickxy"
 	_ "github.com/nathants/chrome/cmd/close"
+	_ "github.com/nathants/chrome/cmd/color"
+	_ "github.com/nathants/chrome/cmd/compare"
 	_ "github.com/nathants/chrome/cmd/console"
+	_ "github.com/nathants/chrome/cmd/cookies"
+	_ "github.com/nathants/chrome/cmd/discard"
+	_ "github.com/nathants/chrome/cmd/dismissbanners"
+	_ "github.com/nathants/chrome/cmd/doctor"
 	_ "github.com/nathants/chrome/cmd/eval"
+	_ "github.com/nathants/chrome/cmd/export"
 	_ "github.com/nathants/chrome/cmd/fill"
+	_ "github.com/nathants/chrome/cmd/find"
+	_ "github.com/nathants/chrome/cmd/fixture"
+	_ "github.com/nathants/chrome/cmd/har"
+	_ "github.com/nathants/chrome/cmd/headers"
+	_ "github.com/nathants/chrome/cmd/health"
 	_ "github.com/nathants/chrome/cmd/html"
 	_ "github.com/nathants/chrome/cmd/instances"
+	_ "github.com/nathants/chrome/cmd/keys"
 	_ "github.com/nathants/chrome/cmd/launch"
 	_ "github.com/nathants/chrome/cmd/list"
+	_ "github.com/nathants/chrome/cmd/matrix"
+	_ "github.com/nathants/chrome/cmd/mcp"
+	_ "github.com/nathants/chrome/cmd/mock"
 	_ "github.com/nathants/chrome/cmd/navigate"
 	_ "github.com/nathants/chrome/cmd/network"
 	_ "github.com/nathants/chrome/cmd/newtab"
+	_ "github.com/nathants/chrome/cmd/notifications"
+	_ "github.com/nathants/chrome/cmd/options"
+	_ "github.com/nathants/chrome/cmd/pageinfo"
+	_ "github.com/nathants/chrome/cmd/paginate"
+	_ "github.com/nathants/chrome/cmd/pdf"
+	_ "github.com/nathants/chrome/cmd/perfdiff"
+	_ "github.com/nathants/chrome/cmd/pixel"
 	_ "github.com/nathants/chrome/cmd/quit"
+	_ "github.com/nathants/chrome/cmd/quota"
+	_ "github.com/nathants/chrome/cmd/record"
 	_ "github.com/nathants/chrome/cmd/rect"
+	_ "github.com/nathants/chrome/cmd/repl"
+	_ "github.com/nathants/chrome/cmd/report"
+	_ "github.com/nathants/chrome/cmd/run"
+	_ "github.com/nathants/chrome/cmd/schema"
 	_ "github.com/nathants/chrome/cmd/screenshot"
+	_ "github.com/nathants/chrome/cmd/serve"
+	_ "github.com/nathants/chrome/cmd/session"
+	_ "github.com/nathants/chrome/cmd/sessions"
+	_ "github.com/nathants/chrome/cmd/shots"
 	_ "github.com/nathants/chrome/cmd/slideshow"
+	_ "github.com/nathants/chrome/cmd/state"
 	_ "github.com/nathants/chrome/cmd/step"
+	_ "github.com/nathants/chrome/cmd/storage"
+	_ "github.com/nathants/chrome/cmd/structured"
+	_ "github.com/nathants/chrome/cmd/tabto"
+	_ "github.com/nathants/chrome/cmd/throttle"
 	_ "github.com/nathants/chrome/cmd/title"
+	_ "github.com/nathants/chrome/cmd/trail"
 	_ "github.com/nathants/chrome/cmd/type"
+	_ "github.com/nathants/chrome/cmd/undiscard"
+	_ "github.com/nathants/chrome/cmd/video"
+	_ "github.com/nathants/chrome/cmd/vr"
 	_ "github.com/nathants/chrome/cmd/wait"
+	_ "github.com/nathants/chrome/cmd/waitfn"
 	_ "github.com/nathants/chrome/cmd/waitfor"
+	_ "github.com/nathants/chrome/cmd/waitidle"
+	_ "github.com/nathants/chrome/cmd/waitnav"
+	_ "github.com/nathants/chrome/cmd/waitstable"
 	"github.com/nathants/chrome/lib"
 )
 
@@ -47,7 +104,14 @@ func usage() {
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Global Options (must appear before command):")
 	fmt.Fprintln(os.Stderr, "  -p, --port PORT                          # Chrome debug port (default: 9222, env: CHROME_PORT)")
+	fmt.Fprintln(os.Stderr, "  --as ACCOUNT                             # Use the port of a named login (see 'chrome sessions --help')")
 	fmt.Fprintln(os.Stderr, "  -t, --target URL_PREFIX                  # Select tab by URL prefix (env: CHROME_TARGET)")
+	fmt.Fprintln(os.Stderr, "  -v, --verbose                            # Print resolve/connect/action timing to stderr")
+	fmt.Fprintln(os.Stderr, "  --json                                   # Print timing to stderr as NDJSON (env: CHROME_TIMING=json)")
+	fmt.Fprintln(os.Stderr, "  --include-extensions                     # Let -t select extension background pages and service workers (env: CHROME_INCLUDE_EXTENSIONS=1)")
+	fmt.Fprintln(os.Stderr, "  --respect-robots                         # Refuse navigations robots.txt disallows, honor Crawl-delay (env: CHROME_RESPECT_ROBOTS=1)")
+	fmt.Fprintln(os.Stderr, "  --max-rps N                              # Cap navigations per second across all sites (env: CHROME_MAX_RPS)")
+	fmt.Fprintln(os.Stderr, "  --per-domain-delay DUR                   # Space navigations to the same host, e.g. 2s (env: CHROME_DOMAIN_DELAY)")
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Multi-Instance Usage:")
 	fmt.Fprintln(os.Stderr, "  chrome launch --port 9223 --user-data-dir ~/.chrome-twitter")
@@ -102,25 +166,33 @@ func usage() {
 	}
 }
 
+// crawlFlags are the global crawl etiquette flags that take a value, and
+// the environment variables they set.
+var crawlFlags = map[string]string{
+	"--max-rps":          lib.MaxRPSEnv,
+	"--per-domain-delay": lib.DomainDelayEnv,
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		usage()
-		os.Exit(1)
+		lib.Exit(1)
 	}
 
 	args := append([]string{}, os.Args[1:]...)
 	target := ""
 	port := ""
+	account := ""
 	for len(args) > 0 {
 		arg := args[0]
 		if arg == "-h" || arg == "--help" {
 			usage()
-			os.Exit(0)
+			lib.Exit(0)
 		}
 		if arg == "-p" || arg == "--port" {
 			if len(args) < 2 {
 				fmt.Fprintln(os.Stderr, "error: --port requires a value")
-				os.Exit(1)
+				lib.Exit(1)
 			}
 			port = args[1]
 			args = args[2:]
@@ -136,10 +208,24 @@ func main() {
 			args = args[1:]
 			continue
 		}
+		if arg == "--as" {
+			if len(args) < 2 {
+				fmt.Fprintln(os.Stderr, "error: --as requires a value")
+				lib.Exit(1)
+			}
+			account = args[1]
+			args = args[2:]
+			continue
+		}
+		if strings.HasPrefix(arg, "--as=") {
+			account = strings.TrimPrefix(arg, "--as=")
+			args = args[1:]
+			continue
+		}
 		if arg == "-t" || arg == "--target" {
 			if len(args) < 2 {
 				fmt.Fprintln(os.Stderr, "error: --target requires a value")
-				os.Exit(1)
+				lib.Exit(1)
 			}
 			target = args[1]
 			args = args[2:]
@@ -155,30 +241,94 @@ func main() {
 			args = args[1:]
 			continue
 		}
+		if arg == "--include-extensions" {
+			if err := os.Setenv("CHROME_INCLUDE_EXTENSIONS", "1"); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				lib.Exit(1)
+			}
+			args = args[1:]
+			continue
+		}
+		if arg == "--respect-robots" {
+			if err := os.Setenv(lib.RespectRobotsEnv, "1"); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				lib.Exit(1)
+			}
+			args = args[1:]
+			continue
+		}
+		if env, ok := crawlFlags[arg]; ok {
+			if len(args) < 2 {
+				fmt.Fprintf(os.Stderr, "error: %s requires a value\n", arg)
+				lib.Exit(1)
+			}
+			if err := os.Setenv(env, args[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				lib.Exit(1)
+			}
+			args = args[2:]
+			continue
+		}
+		if name, value, ok := strings.Cut(arg, "="); ok && crawlFlags[name] != "" {
+			if err := os.Setenv(crawlFlags[name], value); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				lib.Exit(1)
+			}
+			args = args[1:]
+			continue
+		}
+		if arg == "-v" || arg == "--verbose" || arg == "--json" {
+			mode := "text"
+			if arg == "--json" {
+				mode = "json"
+			}
+			if err := os.Setenv("CHROME_TIMING", mode); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				lib.Exit(1)
+			}
+			args = args[1:]
+			continue
+		}
 		break
 	}
 	if len(args) == 0 {
 		usage()
-		os.Exit(1)
+		lib.Exit(1)
+	}
+	if _, err := lib.CrawlEtiquette(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		lib.Exit(1)
+	}
+	if strings.TrimSpace(account) != "" {
+		if strings.TrimSpace(port) != "" {
+			fmt.Fprintln(os.Stderr, "error: use --as or --port, not both")
+			lib.Exit(1)
+		}
+		resolved, err := lib.LoadAccount("", strings.TrimSpace(account))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			lib.Exit(1)
+		}
+		port = strconv.Itoa(resolved.Port)
 	}
 	if strings.TrimSpace(port) != "" {
 		// Validate port is a number in valid range
 		p, err := strconv.Atoi(port)
 		if err != nil || p <= 0 || p >= 65536 {
 			fmt.Fprintf(os.Stderr, "error: invalid port: %s (must be 1-65535)\n", port)
-			os.Exit(1)
+			lib.Exit(1)
 		}
 		err = os.Setenv("CHROME_PORT", port)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+			lib.Exit(1)
 		}
 	}
 	if strings.TrimSpace(target) != "" {
 		err := os.Setenv("CHROME_TARGET", target)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+			lib.Exit(1)
 		}
 	}
 
@@ -187,8 +337,13 @@ func main() {
 	if !ok {
 		usage()
 		fmt.Fprintln(os.Stderr, "\nunknown command:", cmd)
-		os.Exit(1)
+		lib.Exit(1)
 	}
 	os.Args = args
+	lib.PrepareTargetCache(cmd)
+	lib.TimeCommand(cmd)
 	fn()
+	// A command whose tab crashed exits with lib.ExitCrashed even when it
+	// returned normally
+	lib.Exit(0)
 }